@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -25,14 +31,23 @@ For GHES 3.16+, this command supports both organization-level and enterprise-lev
 func init() {
 	// Add template-org flag specific to apply command
 	applyCmd.Flags().StringP("template-org", "t", "", "Template organization to fetch security configurations from (required)")
+	applyCmd.Flags().String("probe", "", probeOrgFlagUsage)
 
 	// Non-interactive input flags
 	applyCmd.Flags().String("config-source", "", "Source of the configuration to apply when --config-name is ambiguous (organization, enterprise)")
 	applyCmd.Flags().String("scope", "", "Repository attachment scope (all, public, private_or_internal)")
+	applyCmd.Flags().String("exclude-repos", "", "Path to a file of org/repo lines (one per line) that must never have the configuration attached")
+	addCodeQLRepoFlags(applyCmd)
+	applyCmd.Flags().Bool("skip-orgs-with-existing-default", false, "Skip organizations whose default configuration for new repositories is a different configuration")
+	applyCmd.Flags().String("sync-settings-from", "", "Update each organization's configuration to match the settings in a JSON file or in the same-named configuration of a reference organization before attaching")
 	applyCmd.Flags().String("set-as-default", "", "Whether to set this configuration as default for new repositories (true/false)")
+	addChangeRefFlags(applyCmd, false)
+	addPostHookFlags(applyCmd)
 }
 
 func runApply(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("GitHub Security Configuration Applier")
 	pterm.Println()
 
@@ -59,7 +74,7 @@ func runApply(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get flag values for enterprise settings
-	enterpriseFlag, err := cmd.Flags().GetString("enterprise-slug")
+	enterpriseFlags, err := cmd.Flags().GetStringSlice("enterprise-slug")
 	if err != nil {
 		return err
 	}
@@ -95,6 +110,28 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	excludeReposFlag, err := cmd.Flags().GetString("exclude-repos")
+	if err != nil {
+		return err
+	}
+	var exclusions types.RepositoryExclusions
+	if excludeReposFlag != "" {
+		exclusions, err = utils.ReadRepositoryExclusions(excludeReposFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	onlyCodeQL, codeQLLanguagesFlag, codeQLLanguages, err := extractCodeQLRepoFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	skipOrgsWithExistingDefault, err := cmd.Flags().GetBool("skip-orgs-with-existing-default")
+	if err != nil {
+		return err
+	}
+
 	setAsDefaultFlag, err := cmd.Flags().GetString("set-as-default")
 	if err != nil {
 		return err
@@ -104,16 +141,31 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	syncSettingsFromFlag, err := cmd.Flags().GetString("sync-settings-from")
+	if err != nil {
+		return err
+	}
+
 	force, err := extractSkipConfirmationFlag(cmd)
 	if err != nil {
 		return err
 	}
+	changeRef, _, err := extractChangeRefFlags(cmd)
+	if err != nil {
+		return err
+	}
+	recordChangeRef(changeRef)
+	postHook, ignoreHookFailures, err := extractPostHookFlags(cmd)
+	if err != nil {
+		return err
+	}
 
-	// Get enterprise name
-	enterprise, err := ui.GetEnterpriseInput(enterpriseFlag)
+	// Get enterprise names
+	enterprises, err := resolveEnterprises(enterpriseFlags, commonFlags)
 	if err != nil {
 		return err
 	}
+	enterprise := configEnterprise(enterprises)
 
 	// Get GitHub Enterprise URL if needed
 	serverURL, err := ui.GetServerURLInput(serverURLFlag)
@@ -140,8 +192,8 @@ func runApply(cmd *cobra.Command, args []string) error {
 	var enterpriseConfigNames []string
 	enterpriseConfigMap := make(map[string]types.SecurityConfiguration)
 
-	// Fetch enterprise configurations if GHES 3.16+
-	if api.SupportsEnterpriseConfigurations(ghesVersion) {
+	// Fetch enterprise configurations if GHES 3.16+ and an enterprise is known
+	if enterprise != "" && api.SupportsEnterpriseConfigurations(ghesVersion) {
 		pterm.Info.Println("Fetching enterprise security configurations...")
 		enterpriseConfigs, err := api.FetchEnterpriseSecurityConfigurations(enterprise)
 		if err != nil {
@@ -157,37 +209,18 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// If no org targeting method is provided, prompt user to select one
-	if !utils.HasOrgTargeting(commonFlags) {
-		if len(enterpriseConfigNames) > 0 {
-			pterm.Info.Println("Organization-level security configurations applied by this command will be in addition to existing enterprise configurations.")
-		}
-
-		targetingMethod, err := ui.SelectOrgTargetingMethod()
-		if err != nil {
-			return err
-		}
+	if !utils.HasOrgTargeting(commonFlags) && len(enterpriseConfigNames) > 0 {
+		pterm.Info.Println("Organization-level security configurations applied by this command will be in addition to existing enterprise configurations.")
+	}
 
-		switch targetingMethod {
-		case "all-orgs":
-			commonFlags.AllOrgs = true
-		case "single-org":
-			orgName, err := ui.GetSingleOrgName()
-			if err != nil {
-				return err
-			}
-			commonFlags.Org = orgName
-		case "org-list":
-			csvPath, err := ui.GetOrgListPath()
-			if err != nil {
-				return err
-			}
-			commonFlags.OrgListPath = csvPath
-			// Validate the CSV file
-			if err := utils.ValidateOrgFlagsOptional(commonFlags); err != nil {
-				return err
-			}
-		}
+	// Settle the organization targeting, fetch the organizations and stop if none remain to process
+	orgs, breakdown, err := resolveTargetOrganizations(enterprises, commonFlags, "")
+	if err != nil {
+		return err
+	}
+	probeOrg, err := extractProbeOrg(cmd, orgs)
+	if err != nil {
+		return err
 	}
 
 	// Get template organization name
@@ -200,15 +233,13 @@ func runApply(cmd *cobra.Command, args []string) error {
 
 	// Fetch org-level configuration names from template organization only
 	pterm.Info.Printf("Fetching security configurations from template organization '%s'...\n", templateOrg)
-	status, err := api.CheckSingleOrganizationMembership(templateOrg)
-	if err != nil || !status.IsMember || !status.IsOwner {
-		if err != nil {
-			ui.LogWarningf("Could not access template organization '%s': %v", templateOrg, err)
-		} else {
-			ui.LogWarningf("You must be an owner of template organization '%s' to fetch configurations", templateOrg)
-		}
+	status, err := api.CheckSingleOrganizationMembership(context.Background(), templateOrg)
+	if err != nil {
+		ui.LogWarningf("Could not access template organization '%s': %v", templateOrg, err)
+	} else if problem := status.Problem(); problem != "" {
+		ui.LogWarningf("Cannot fetch configurations from template organization '%s': %s", templateOrg, problem)
 	} else {
-		configs, err := api.FetchSecurityConfigurations(templateOrg)
+		configs, err := api.FetchSecurityConfigurations(context.Background(), templateOrg)
 		if err != nil {
 			ui.LogWarningf("Could not fetch configurations from template organization '%s': %v", templateOrg, err)
 		} else {
@@ -236,17 +267,6 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no security configurations found at enterprise or organization level")
 	}
 
-	// Fetch organizations
-	orgs, err := api.GetOrganizations(enterprise, commonFlags.Org, commonFlags.OrgListPath, commonFlags.AllOrgs)
-	if err != nil {
-		return err
-	}
-
-	if len(orgs) == 0 {
-		ui.ShowNoOrganizationsWarning(commonFlags)
-		return nil
-	}
-
 	// Get configuration details based on target type
 	var configDetails *types.SecurityConfigurationDetails
 	var sourceOrg string
@@ -263,20 +283,11 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 		pterm.Info.Printf("Selected enterprise configuration: '%s'\n", configName)
 	} else {
-		// Get organization configuration details from template org
-		configs, err := api.FetchSecurityConfigurations(templateOrg)
-		if err != nil {
-			return fmt.Errorf("failed to fetch configurations from template org: %w", err)
-		}
-
-		configID, found := api.FindConfigurationByName(configs, configName)
-		if !found {
-			return fmt.Errorf("configuration '%s' not found in template organization '%s'", configName, templateOrg)
-		}
-
-		details, err := api.GetSecurityConfigurationDetails(templateOrg, configID)
+		// Organization configuration settings are read from the template org only, never searched
+		// for across the target organizations
+		details, err := processors.FetchSourceConfiguration(templateOrg, configName)
 		if err != nil {
-			return fmt.Errorf("failed to get configuration details: %w", err)
+			return err
 		}
 		configDetails = details
 		sourceOrg = templateOrg
@@ -284,9 +295,31 @@ func runApply(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show configuration details
-	ui.DisplayCurrentSettings(configDetails.Settings, configDetails.Description)
+	ui.DisplayCurrentSettings(configDetails.Settings, configDetails.Enforcement, configDetails.Description)
 	pterm.Println()
 
+	// Resolve the settings each organization's configuration is converged to before attaching
+	var syncSettings map[string]interface{}
+	var syncEnforcement string
+	if syncSettingsFromFlag != "" {
+		if targetType == "enterprise" {
+			return fmt.Errorf("--sync-settings-from cannot be used with enterprise configurations; their settings are managed at the enterprise level")
+		}
+		syncSettings, syncEnforcement, err = resolveReferenceSettings("sync-settings-from", syncSettingsFromFlag, configName)
+		if err != nil {
+			return err
+		}
+		pterm.Info.Printf("Configurations that differ from '%s' will be updated before attaching:\n", syncSettingsFromFlag)
+		for _, key := range slices.Sorted(maps.Keys(syncSettings)) {
+			pterm.Printf("  %s: %v\n", pterm.Cyan(key), syncSettings[key])
+		}
+		if syncEnforcement != "" {
+			pterm.Printf("  Enforcement: %s\n", types.DescribeEnforcement(syncEnforcement))
+		}
+		pterm.Println()
+		warnSettingDependencies(syncSettings)
+	}
+
 	// Get repository attachment scope (without 'none' option)
 	scope, err := ui.GetAttachmentScopeForApplication(scopeFlag)
 	if err != nil {
@@ -299,15 +332,39 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Refuse to start a run that would exhaust the rate limit part way through
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{
+		Command:      "apply",
+		Orgs:         len(orgs),
+		Attach:       true,
+		SetAsDefault: setAsDefault,
+		CheckDefault: setAsDefault || skipOrgsWithExistingDefault,
+		SyncSettings: syncSettings != nil,
+
+		ConfigLookups: 1 + referenceLookups(syncSettingsFromFlag),
+	}); err != nil {
+		return err
+	}
+
+	// Stop before the confirmation when the user owns none of the targets
+	if err := requireActionableOrganizations(orgs, breakdown, commonFlags.Concurrency); err != nil {
+		return err
+	}
+
+	identity := resolveRunIdentity()
+	if err := confirmTargetCount(orgs, enterprises, breakdown, commonFlags, force); err != nil {
+		return err
+	}
+
 	// Confirm before proceeding
-	confirmed, err := ui.ConfirmApplyOperation(orgs, configName, configDetails.Description, configDetails.Settings, scope, setAsDefault, force)
+	confirmed, err := ui.ConfirmApplyOperation(identity, orgs, configName, configDetails.Description, configDetails.Settings, configDetails.Enforcement, scope, setAsDefault, force)
 	if err != nil {
 		return err
 	}
 
 	if !confirmed {
 		ui.ShowOperationCancelled()
-		return nil
+		return types.ErrOperationCancelled
 	}
 
 	// Create processor for apply command
@@ -318,21 +375,26 @@ func runApply(cmd *cobra.Command, args []string) error {
 		Scope:              scope,
 		SetAsDefault:       setAsDefault,
 		IsEnterpriseConfig: targetType == "enterprise",
+		Exclusions:         exclusions,
+		Languages:          codeQLLanguages,
+		SyncSettings:       syncSettings,
+		SyncEnforcement:    syncEnforcement,
+
+		SkipOrgsWithExistingDefault: skipOrgsWithExistingDefault,
 	}
 
 	// Process each organization - use sequential processor if delay is specified
-	var successCount, skippedCount, errorCount int
-	if commonFlags.Delay > 0 {
-		ui.ShowProcessingStartWithDelay(len(orgs), commonFlags.Delay)
-		sequentialProcessor := processors.NewSequentialProcessor(orgs, processor, commonFlags.Delay)
-		successCount, skippedCount, errorCount = sequentialProcessor.Process()
-	} else {
-		ui.ShowProcessingStart(len(orgs), commonFlags.Concurrency)
-		concurrentProcessor := processors.NewConcurrentProcessor(orgs, processor, commonFlags.Concurrency)
-		successCount, skippedCount, errorCount = concurrentProcessor.Process()
+	outcome, err := processOrganizationsWithProbe(probeOrg, orgs, breakdown, processors.WithPostHook(processor, postHook), commonFlags, force)
+	if err != nil {
+		return err
 	}
+	outcome.IgnoreHookFailures = ignoreHookFailures
 
-	utils.PrintCompletionHeader("Security Configuration Application", successCount, skippedCount, errorCount)
+	utils.PrintCompletionHeader("Security Configuration Application", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showDefaultCounts(outcome.Results)
+	showChangeRef(changeRef)
+	showAPICallUsage()
 
 	// Extract log level flag
 	logLevel, err := cmd.Flags().GetString("log-level")
@@ -342,17 +404,27 @@ func runApply(cmd *cobra.Command, args []string) error {
 
 	// Build and display replication command
 	replicationFlags := map[string]interface{}{
-		"enterprise-slug":              enterprise,
-		"github-enterprise-server-url": serverURL,
-		"template-org":                 templateOrg,
-		"concurrency":                  commonFlags.Concurrency,
-		"delay":                        commonFlags.Delay,
-		"log-level":                    logLevel,
-		"config-name":                  configName,
-		"config-source":                targetType,
-		"scope":                        scope,
-		"set-as-default":               fmt.Sprintf("%t", setAsDefault),
-		"skip-confirmation-message":    fmt.Sprintf("%t", force),
+		"enterprise-slug":                 strings.Join(enterprises, ","),
+		"github-enterprise-server-url":    serverURL,
+		"template-org":                    templateOrg,
+		"concurrency":                     commonFlags.Concurrency,
+		"max-orgs-per-minute":             commonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":                 commonFlags.PerOrgTimeout,
+		"report-order":                    commonFlags.ReportOrder,
+		"delay":                           commonFlags.Delay,
+		"log-level":                       logLevel,
+		"config-name":                     configName,
+		"config-source":                   targetType,
+		"scope":                           scope,
+		"set-as-default":                  fmt.Sprintf("%t", setAsDefault),
+		"skip-orgs-with-existing-default": skipOrgsWithExistingDefault,
+		"exclude-repos":                   excludeReposFlag,
+		"only-codeql-supported-repos":     onlyCodeQL,
+		"codeql-languages":                codeQLLanguagesFlag,
+		"sync-settings-from":              syncSettingsFromFlag,
+		"skip-confirmation-message":       fmt.Sprintf("%t", force),
+		"probe":                           probeOrg,
+		"change-ref":                      changeRef,
 	}
 
 	// Add org targeting flags
@@ -360,12 +432,65 @@ func runApply(cmd *cobra.Command, args []string) error {
 		replicationFlags["org"] = commonFlags.Org
 	} else if commonFlags.OrgListPath != "" {
 		replicationFlags["org-list"] = commonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = commonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = commonFlags.FailOnInvalidOrgs
 	} else if commonFlags.AllOrgs {
 		replicationFlags["all-orgs"] = true
 	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(commonFlags.ExcludeProperties)
+
+	addPostHookReplicationFlags(replicationFlags, postHook, ignoreHookFailures)
 
 	replicationCommand := utils.BuildReplicationCommand("apply", replicationFlags)
 	utils.ShowReplicationCommand(replicationCommand)
+	showRetryCommand("apply", replicationFlags, outcome)
+
+	return outcomeError("security configuration application", outcome)
+}
+
+// referenceLookups returns how many configuration lookups resolveReferenceSettings makes for
+// source: none when it is empty or a settings file, and one for a reference organization
+func referenceLookups(source string) int {
+	if source == "" {
+		return 0
+	}
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		return 0
+	}
+	return 1
+}
+
+// resolveReferenceSettings reads the settings and enforcement named by flagName, such as apply
+// --sync-settings-from or drift --baseline. An existing file is read as a JSON settings file, whose
+// "enforcement" key is split from the other settings; any other value names a reference
+// organization whose configuration with the same name supplies them. A file is validated, as the
+// user wrote it; an organization's settings are returned as the server reports them, which may
+// include values this extension never sends, such as advanced_security: code_security.
+func resolveReferenceSettings(flagName, source, configName string) (map[string]interface{}, string, error) {
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		settings, err := utils.ReadSettingsFile(source)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := utils.ValidateSettings(settings); err != nil {
+			return nil, "", err
+		}
+		settings, enforcement := types.SplitEnforcement(settings)
+		return settings, enforcement, nil
+	}
 
-	return nil
+	configs, err := api.FetchSecurityConfigurations(context.Background(), source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch configurations from reference organization '%s': %w", source, err)
+	}
+	configID, found := api.FindConfigurationByName(configs, configName)
+	if !found {
+		return nil, "", fmt.Errorf("configuration '%s' not found in reference organization '%s' (--%s is neither a file nor an organization with that configuration)", configName, source, flagName)
+	}
+	details, err := api.GetSecurityConfigurationDetails(context.Background(), source, configID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get configuration details from reference organization '%s': %w", source, err)
+	}
+	// Settings read from the server are passed through as they are; only user-supplied settings are validated
+	return details.Settings, details.Enforcement, nil
 }
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check security configurations against a compliance policy",
+	Long:  "Evaluate the organization-level security configurations of each organization against the rules of a policy file and report every violation",
+	RunE:  runCheck,
+}
+
+func init() {
+	checkCmd.Flags().String("policy", "", "Path to a YAML or JSON policy file defining the allowed values of each setting (required)")
+	checkCmd.Flags().Bool("include-config-id", false, "Add a column with each configuration's ID, for scripts that call the API by ID")
+	checkCmd.Flags().Bool("no-request-cache", false, "Send every read request to the API instead of reusing responses already fetched during this run")
+	_ = checkCmd.MarkFlagRequired("policy")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("GitHub Security Configuration Policy Check")
+	pterm.Println()
+
+	policyPath, err := cmd.Flags().GetString("policy")
+	if err != nil {
+		return err
+	}
+	configName, err := cmd.Flags().GetString("config-name")
+	if err != nil {
+		return err
+	}
+	includeConfigID, err := cmd.Flags().GetBool("include-config-id")
+	if err != nil {
+		return err
+	}
+
+	// Read the policy first so a malformed file fails before any prompts or API calls
+	policy, err := utils.ReadPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveCommandTargets(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "check", Orgs: len(targets.Orgs)}); err != nil {
+		return err
+	}
+
+	noRequestCache, err := enableRequestCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	processor := &processors.PolicyCheckProcessor{Policy: policy, ConfigName: configName}
+	outcome := processOrganizations(targets.Orgs, targets.Breakdown, processor, targets.CommonFlags)
+
+	utils.PrintCompletionHeader("Policy Check", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showAPICallUsage()
+	showRequestCacheStats()
+	pterm.Println()
+
+	violations := processor.Violations()
+	if err := renderPolicyViolations(violations, includeConfigID); err != nil {
+		return err
+	}
+
+	replicationFlags, err := targetReplicationFlags(cmd, targets)
+	if err != nil {
+		return err
+	}
+	replicationFlags["policy"] = policyPath
+	if configName != "" {
+		replicationFlags["config-name"] = configName
+	}
+	if includeConfigID {
+		replicationFlags["include-config-id"] = true
+	}
+	if noRequestCache {
+		replicationFlags["no-request-cache"] = true
+	}
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("check", replicationFlags))
+	showRetryCommand("check", replicationFlags, outcome)
+
+	// A confirmed violation fails the check even when some organizations could not be read
+	if err := policyViolationError(violations); err != nil {
+		return err
+	}
+	return outcomeError("policy check", outcome)
+}
+
+// policyViolationError returns a types.PolicyViolationError when any violation has error severity
+func policyViolationError(violations []types.PolicyViolation) error {
+	errorCount := 0
+	for _, violation := range violations {
+		if violation.Severity == types.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount == 0 {
+		return nil
+	}
+	return &types.PolicyViolationError{Violations: errorCount}
+}
+
+// renderPolicyViolations prints one row per violation followed by the count of each severity.
+// includeConfigID adds a column with each configuration's ID.
+func renderPolicyViolations(violations []types.PolicyViolation, includeConfigID bool) error {
+	if len(violations) == 0 {
+		pterm.Success.Println("No policy violations found.")
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, violation := range violations {
+		counts[violation.Severity]++
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(policyViolationTable(violations, includeConfigID)).Render(); err != nil {
+		return err
+	}
+	pterm.Println()
+	// Links are listed below the table rather than in it, as escape sequences would skew the column widths
+	renderViolationLinks(violations)
+	pterm.Info.Printf("Policy violations: %d error(s), %d warning(s)\n", counts[types.SeverityError], counts[types.SeverityWarning])
+	return nil
+}
+
+// policyViolationTable returns the rows of the violations table under a header row
+func policyViolationTable(violations []types.PolicyViolation, includeConfigID bool) pterm.TableData {
+	tableData := pterm.TableData{withConfigIDColumn([]string{"Organization", "Configuration", "Setting", "Value", "Allowed", "Severity"}, "Configuration ID", includeConfigID)}
+	for _, violation := range violations {
+		severity := pterm.Yellow(violation.Severity)
+		if violation.Severity == types.SeverityError {
+			severity = pterm.Red(violation.Severity)
+		}
+		tableData = append(tableData, withConfigIDColumn([]string{
+			violation.Organization,
+			violation.Configuration,
+			violation.Setting,
+			violation.Actual,
+			strings.Join(violation.Allowed, ", "),
+			severity,
+		}, configIDCell(violation.ConfigurationID), includeConfigID))
+	}
+	return tableData
+}
+
+// renderViolationLinks lists the settings page of each configuration with a violation, once per
+// organization and configuration
+func renderViolationLinks(violations []types.PolicyViolation) {
+	seen := make(map[string]bool)
+	var links []string
+	for _, violation := range violations {
+		if violation.ConfigurationURL == "" || seen[violation.ConfigurationURL] {
+			continue
+		}
+		seen[violation.ConfigurationURL] = true
+		links = append(links, ui.Hyperlink(violation.ConfigurationURL, fmt.Sprintf("%s / %s", violation.Organization, violation.Configuration)))
+	}
+	if len(links) == 0 {
+		return
+	}
+	pterm.Println("Configurations with violations:")
+	for _, link := range links {
+		pterm.Printf("  - %s\n", link)
+	}
+	pterm.Println()
+}
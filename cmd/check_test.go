@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestPolicyViolationError(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities []string
+		wantErrors int
+	}{
+		{name: "no violations"},
+		{name: "warnings only", severities: []string{types.SeverityWarning, types.SeverityWarning}},
+		{name: "errors counted", severities: []string{types.SeverityError, types.SeverityWarning, types.SeverityError}, wantErrors: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []types.PolicyViolation
+			for _, severity := range tt.severities {
+				violations = append(violations, types.PolicyViolation{Severity: severity})
+			}
+
+			err := policyViolationError(violations)
+			var policyErr *types.PolicyViolationError
+			if tt.wantErrors == 0 {
+				if err != nil {
+					t.Errorf("policyViolationError() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &policyErr) || policyErr.Violations != tt.wantErrors {
+				t.Errorf("policyViolationError() = %v, want %d error-severity violations", err, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestPolicyViolationTable_IncludeConfigID(t *testing.T) {
+	violations := []types.PolicyViolation{{Organization: "org-a", Configuration: "Baseline", ConfigurationID: 17, Setting: "secret_scanning", Actual: "disabled", Allowed: []string{"enabled"}, Severity: types.SeverityError}}
+	assertConfigIDColumn(t, policyViolationTable(violations, false), policyViolationTable(violations, true),
+		[]string{"org-a", "Baseline", "secret_scanning", "disabled", "enabled", "", "17"})
+}
+
+// assertConfigIDColumn checks a table built without and with --include-config-id: only the second
+// has the ID, in a last column of its own. Empty cells of wantRow, such as colored ones, are not
+// compared.
+func assertConfigIDColumn(t *testing.T, without, with [][]string, wantRow []string) {
+	t.Helper()
+	if len(with) != 2 || len(without) != 2 {
+		t.Fatalf("tables have %d and %d rows, want a header and one row", len(without), len(with))
+	}
+	if len(with[0]) != len(with[1]) {
+		t.Errorf("header %v and row %v differ in length", with[0], with[1])
+	}
+	if slices.Contains(without[1], "17") {
+		t.Errorf("row %v shows the ID without --include-config-id", without[1])
+	}
+	for i, want := range wantRow {
+		if want != "" && with[1][i] != want {
+			t.Errorf("row cell %d = %q, want %q", i, with[1][i], want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a completion script for gh security-config in the given shell.
+
+Load it in the current shell session, for example:
+
+  bash:       source <(gh security-config completion bash)
+  zsh:        source <(gh security-config completion zsh)
+  fish:       gh security-config completion fish | source
+  powershell: gh security-config completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+// completionEnterpriseEnv names the environment variable that supplies the enterprise slug for
+// completions when --enterprise-slug is not already on the command line
+const completionEnterpriseEnv = "GH_SECURITY_CONFIG_ENTERPRISE"
+
+// fetchCompletionOrganizations lists an enterprise's organizations for completion. It is a
+// variable so tests do not call the API.
+var fetchCompletionOrganizations = api.FetchOrganizations
+
+// registerFlagCompletions wires dynamic completions to the root command's persistent flags. It is
+// called from the root command's init once those flags are defined. Completions for a subcommand's
+// own flags are registered in that subcommand's init, after the flag, so neither depends on the
+// order in which files are initialized.
+func registerFlagCompletions() {
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("config-name", completeConfigNames))
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("org", completeOrganizationNames))
+}
+
+// runCompletion writes the completion script for the requested shell to standard output
+func runCompletion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletionV2(out, true)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(out)
+	case "fish":
+		return cmd.Root().GenFishCompletion(out, true)
+	case "powershell":
+		return cmd.Root().GenPowerShellCompletionWithDesc(out)
+	default:
+		return fmt.Errorf("unsupported shell %q", args[0])
+	}
+}
+
+// completeOrganizationNames offers the organization logins of the enterprises. Completion only
+// runs when the enterprises are known from --enterprise-slug or the GH_SECURITY_CONFIG_ENTERPRISE
+// environment variable, and the fetched logins are cached on disk so repeated tab presses do not
+// call the API.
+func completeOrganizationNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	enterprises := completionEnterprises(cmd)
+	if len(enterprises) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	serverURL, _ := cmd.Flags().GetString("github-enterprise-server-url")
+	if serverURL != "" {
+		os.Setenv("GH_HOST", serverURL)
+	}
+
+	var orgs []string
+	for _, enterprise := range enterprises {
+		cacheKey := fmt.Sprintf("orgs-%s-%s", serverURL, enterprise)
+		enterpriseOrgs, ok := utils.ReadCompletionCache(cacheKey, utils.CompletionCacheTTL)
+		if !ok {
+			// Completions are read from standard output, so API messages must not be printed there
+			pterm.DisableOutput()
+			fetched, err := fetchCompletionOrganizations(enterprise)
+			pterm.EnableOutput()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError | cobra.ShellCompDirectiveNoFileComp
+			}
+			enterpriseOrgs = fetched
+			utils.WriteCompletionCache(cacheKey, enterpriseOrgs)
+		}
+		orgs = append(orgs, enterpriseOrgs...)
+	}
+
+	return filterCompletions(orgs, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionEnterprises returns the enterprise slugs from --enterprise-slug, or from the
+// GH_SECURITY_CONFIG_ENTERPRISE environment variable (comma-separated) when the flag is not set,
+// without blanks or repeats
+func completionEnterprises(cmd *cobra.Command) []string {
+	enterprises, _ := cmd.Flags().GetStringSlice("enterprise-slug")
+	if len(enterprises) == 0 {
+		if value := os.Getenv(completionEnterpriseEnv); value != "" {
+			enterprises = strings.Split(value, ",")
+		}
+	}
+	var slugs []string
+	for _, enterprise := range enterprises {
+		// Flags can be parsed more than once while completing, which repeats slice values
+		if enterprise = strings.TrimSpace(enterprise); enterprise != "" && !slices.Contains(slugs, enterprise) {
+			slugs = append(slugs, enterprise)
+		}
+	}
+	return slugs
+}
+
+// filterCompletions returns the candidates that start with prefix, ignoring case
+func filterCompletions(candidates []string, prefix string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(prefix)) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// configNameCompletionSources lists the commands whose --config-name names an existing
+// configuration, and whether enterprise-level configurations can be selected by that command
+var configNameCompletionSources = map[string]struct{ Enterprise bool }{
+	"apply":  {Enterprise: true},
+	"modify": {Enterprise: true},
+	"delete": {Enterprise: false},
+	"check":  {Enterprise: false},
+	"drift":  {Enterprise: false},
+}
+
+// fetchCompletionConfigurations lists an organization's configurations for completion. It is a
+// variable so tests do not call the API.
+var fetchCompletionConfigurations = api.FetchSecurityConfigurations
+
+// fetchCompletionEnterpriseConfigurations lists an enterprise's configurations for completion when
+// the GHES version supports them. It is a variable so tests do not call the API.
+var fetchCompletionEnterpriseConfigurations = func(enterprise string) ([]types.SecurityConfiguration, error) {
+	version, err := api.GetGHESVersion()
+	if err != nil || !api.SupportsEnterpriseConfigurations(version) {
+		return nil, err
+	}
+	return api.FetchEnterpriseSecurityConfigurations(enterprise)
+}
+
+// completeConfigNames offers the names of existing configurations for apply, check, delete, drift, and modify.
+// Names come from --template-org, or --org when no template is given, and for GHES also from the
+// enterprise. Other commands name a new configuration, so nothing is offered for them.
+func completeConfigNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	source, ok := configNameCompletionSources[cmd.Name()]
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	org, _ := cmd.Flags().GetString("template-org")
+	if org == "" {
+		org, _ = cmd.Flags().GetString("org")
+	}
+	// Enterprise configurations are only offered for a single enterprise, as commands do
+	var enterprise string
+	if enterprises := completionEnterprises(cmd); len(enterprises) == 1 {
+		enterprise = enterprises[0]
+	}
+	serverURL, _ := cmd.Flags().GetString("github-enterprise-server-url")
+	if serverURL != "" {
+		os.Setenv("GH_HOST", serverURL)
+	}
+
+	// Completions are read from standard output, so API messages must not be printed there, and
+	// any read repeated within this invocation is answered from memory
+	pterm.DisableOutput()
+	defer pterm.EnableOutput()
+	api.EnableRequestCache()
+
+	var configs []types.SecurityConfiguration
+	if org != "" {
+		if orgConfigs, err := fetchCompletionConfigurations(context.Background(), org); err == nil {
+			configs = append(configs, orgConfigs...)
+		}
+	}
+	if source.Enterprise && enterprise != "" && serverURL != "" {
+		if enterpriseConfigs, err := fetchCompletionEnterpriseConfigurations(enterprise); err == nil {
+			configs = append(configs, enterpriseConfigs...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, config := range configs {
+		if !seen[config.Name] {
+			seen[config.Name] = true
+			names = append(names, config.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs([]string{"completion", shell})
+			t.Cleanup(func() {
+				rootCmd.SetOut(nil)
+				rootCmd.SetArgs(nil)
+			})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("completion %s error = %v", shell, err)
+			}
+			if !strings.Contains(out.String(), "security-config") {
+				t.Errorf("completion %s script does not mention the command name", shell)
+			}
+		})
+	}
+}
+
+func TestCompletionCommand_RejectsUnknownShell(t *testing.T) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs([]string{"completion", "tcsh"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("completion tcsh expected an error")
+	}
+}
+
+// runCompletionRequest runs cobra's hidden __complete command and returns the candidates it
+// printed, without the trailing directive line
+func runCompletionRequest(t *testing.T, args ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append([]string{"__complete"}, args...))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		resetFlags(rootCmd)
+	})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("__complete %v error = %v", args, err)
+	}
+	var candidates []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line != "" && !strings.HasPrefix(line, ":") {
+			candidates = append(candidates, line)
+		}
+	}
+	return candidates
+}
+
+// resetFlags restores every flag of cmd and its subcommands to its default, since the commands are
+// package-level and keep parsed values between executions
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		// Setting a slice flag appends to it, so slices are replaced instead
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// stubCompletionOrganizations isolates the completion cache and replaces the organization fetch.
+// It returns a pointer to the number of fetches made.
+func stubCompletionOrganizations(t *testing.T, orgs []string) *int {
+	t.Helper()
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("HOME", cacheDir)
+	t.Setenv(completionEnterpriseEnv, "")
+
+	fetches := 0
+	original := fetchCompletionOrganizations
+	fetchCompletionOrganizations = func(enterprise string) ([]string, error) {
+		fetches++
+		return orgs, nil
+	}
+	t.Cleanup(func() { fetchCompletionOrganizations = original })
+	return &fetches
+}
+
+func TestFlagCompletionsRegistered(t *testing.T) {
+	tests := []struct {
+		cmd  *cobra.Command
+		flag string
+	}{
+		{cmd: rootCmd, flag: "config-name"},
+		{cmd: rootCmd, flag: "org"},
+		{cmd: generateCmd, flag: "copy-from-org"},
+	}
+	for _, tt := range tests {
+		if _, ok := tt.cmd.GetFlagCompletionFunc(tt.flag); !ok {
+			t.Errorf("%s --%s has no completion registered", tt.cmd.Name(), tt.flag)
+		}
+	}
+}
+
+func TestCompleteOrganizationNames(t *testing.T) {
+	fetches := stubCompletionOrganizations(t, []string{"acme-web", "Acme-Mobile", "globex"})
+
+	got := runCompletionRequest(t, "generate", "--enterprise-slug", "acme", "--org", "acme")
+	if want := []string{"acme-web", "Acme-Mobile"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("--org candidates = %v, want %v", got, want)
+	}
+
+	got = runCompletionRequest(t, "generate", "--enterprise-slug", "acme", "--copy-from-org", "g")
+	if want := []string{"globex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("--copy-from-org candidates = %v, want %v", got, want)
+	}
+
+	if *fetches != 1 {
+		t.Errorf("organizations fetched %d times, want 1 (later completions should use the cache)", *fetches)
+	}
+}
+
+func TestCompleteOrganizationNames_UsesEnterpriseFromEnvironment(t *testing.T) {
+	stubCompletionOrganizations(t, []string{"acme-web", "globex"})
+	t.Setenv(completionEnterpriseEnv, "acme")
+
+	got := runCompletionRequest(t, "apply", "--org", "")
+	if want := []string{"acme-web", "globex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("--org candidates = %v, want %v", got, want)
+	}
+}
+
+func TestCompleteOrganizationNames_RequiresEnterprise(t *testing.T) {
+	fetches := stubCompletionOrganizations(t, []string{"acme-web"})
+
+	if got := runCompletionRequest(t, "delete", "--org", ""); len(got) != 0 {
+		t.Errorf("--org candidates = %v, want none without an enterprise", got)
+	}
+	if *fetches != 0 {
+		t.Errorf("organizations fetched %d times, want 0", *fetches)
+	}
+}
+
+// stubCompletionConfigurations replaces the configuration fetches used by --config-name completion
+func stubCompletionConfigurations(t *testing.T, orgConfigs map[string][]string, enterpriseConfigs []string) {
+	t.Helper()
+	t.Setenv(completionEnterpriseEnv, "")
+	t.Cleanup(api.DisableRequestCache)
+
+	toConfigs := func(names []string) []types.SecurityConfiguration {
+		var configs []types.SecurityConfiguration
+		for _, name := range names {
+			configs = append(configs, types.SecurityConfiguration{Name: name})
+		}
+		return configs
+	}
+	originalOrg, originalEnterprise := fetchCompletionConfigurations, fetchCompletionEnterpriseConfigurations
+	fetchCompletionConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return toConfigs(orgConfigs[org]), nil
+	}
+	fetchCompletionEnterpriseConfigurations = func(enterprise string) ([]types.SecurityConfiguration, error) {
+		return toConfigs(enterpriseConfigs), nil
+	}
+	t.Cleanup(func() {
+		fetchCompletionConfigurations, fetchCompletionEnterpriseConfigurations = originalOrg, originalEnterprise
+	})
+}
+
+func TestCompleteConfigNames(t *testing.T) {
+	orgConfigs := map[string][]string{
+		"template": {"Strict", "Baseline", "Shared"},
+		"single":   {"Single org config"},
+	}
+	enterpriseConfigs := []string{"Enterprise default", "Shared"}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "delete lists template org configurations",
+			args: []string{"delete", "--template-org", "template", "--config-name", ""},
+			want: []string{"Baseline", "Shared", "Strict"},
+		},
+		{
+			name: "prefix filters case-insensitively",
+			args: []string{"modify", "--template-org", "template", "--config-name", "s"},
+			want: []string{"Shared", "Strict"},
+		},
+		{
+			name: "falls back to --org without a template",
+			args: []string{"apply", "--org", "single", "--config-name", ""},
+			want: []string{"Single org config"},
+		},
+		{
+			name: "apply on GHES adds enterprise configurations without duplicates",
+			args: []string{"apply", "--template-org", "template", "--enterprise-slug", "acme", "--github-enterprise-server-url", "ghes.example.com", "--config-name", ""},
+			want: []string{"Baseline", "Enterprise default", "Shared", "Strict"},
+		},
+		{
+			name: "delete never offers enterprise configurations",
+			args: []string{"delete", "--template-org", "template", "--enterprise-slug", "acme", "--github-enterprise-server-url", "ghes.example.com", "--config-name", ""},
+			want: []string{"Baseline", "Shared", "Strict"},
+		},
+		{
+			name: "generate names a new configuration",
+			args: []string{"generate", "--org", "single", "--config-name", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubCompletionConfigurations(t, orgConfigs, enterpriseConfigs)
+			t.Setenv("GH_HOST", "")
+
+			got := runCompletionRequest(t, tt.args...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("--config-name candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+)
+
+// fetchTemplateConfigurations lists the organization-level configurations in the template
+// organization. It is a variable so tests do not call the API.
+var fetchTemplateConfigurations = func(templateOrg string) ([]types.SecurityConfiguration, error) {
+	return ui.FetchOrgConfigurations(templateOrg, "template", false)
+}
+
+// pickTemplateConfiguration returns the name of the configuration an operation (verb) targets.
+// When the template organization lists configurations, the user picks one of them, or override is
+// checked against them. When the template organization cannot be read, override is used as given,
+// or the name is typed in with enterConfig.
+func pickTemplateConfiguration(templateOrg, override, verb string, enterConfig func() (string, error)) (string, error) {
+	configs, err := fetchTemplateConfigurations(templateOrg)
+	if errors.Is(err, types.ErrNoConfigurations) {
+		return "", err
+	}
+	if err == nil {
+		config, err := ui.SelectConfiguration(configs, override, verb)
+		return config.Name, err
+	}
+	ui.LogWarningf("Could not list configurations, so the configuration name is not checked: %v", err)
+	if override != "" {
+		return override, nil
+	}
+	return enterConfig()
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestPickTemplateConfiguration(t *testing.T) {
+	listed := []types.SecurityConfiguration{{ID: 1, Name: "baseline"}, {ID: 2, Name: "strict"}}
+	empty := fmt.Errorf("template organization 'template' %w", types.ErrNoConfigurations)
+	unreadable := errors.New("cannot read configurations from template organization 'template': not a member")
+
+	tests := []struct {
+		name      string
+		configs   []types.SecurityConfiguration
+		fetchErr  error
+		override  string
+		want      string
+		wantTyped bool
+		wantErr   string
+	}{
+		{name: "override checked against list", configs: listed, override: "strict", want: "strict"},
+		{name: "override missing from list", configs: listed, override: "missing", wantErr: "available to modify"},
+		{name: "accessible but empty", fetchErr: empty, wantErr: "has no security configurations"},
+		{name: "inaccessible uses override", fetchErr: unreadable, override: "baseline", want: "baseline"},
+		{name: "inaccessible falls back to typed name", fetchErr: unreadable, want: "typed", wantTyped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := fetchTemplateConfigurations
+			defer func() { fetchTemplateConfigurations = orig }()
+			fetchTemplateConfigurations = func(string) ([]types.SecurityConfiguration, error) { return tt.configs, tt.fetchErr }
+
+			typed := false
+			enterConfig := func() (string, error) {
+				typed = true
+				return "typed", nil
+			}
+
+			got, err := pickTemplateConfiguration("template", tt.override, "modify", enterConfig)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if typed != tt.wantTyped {
+				t.Errorf("typed prompt shown = %v, want %v", typed, tt.wantTyped)
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
-	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
 	"github.com/callmegreg/gh-security-config/internal/utils"
 )
@@ -22,9 +26,58 @@ var deleteCmd = &cobra.Command{
 func init() {
 	// Add template-org flag specific to delete command
 	deleteCmd.Flags().StringP("template-org", "t", "", "Template organization to fetch security configurations from (required)")
+	deleteCmd.Flags().Bool("all-configs", false, "Delete every organization-level configuration in each target organization instead of --config-name")
+	deleteCmd.Flags().String("managed-prefix", "", "With --all-configs, only delete configurations whose name starts with this prefix")
+	deleteCmd.Flags().String("older-than", "", "With --all-configs and --managed-prefix, only delete configurations created at least this long ago (e.g. 90d, 2w, 3m)")
+	deleteCmd.Flags().Bool("unattached-only", false, "With --all-configs and --managed-prefix, only delete configurations attached to no repositories")
+	addChangeRefFlags(deleteCmd, false)
+}
+
+// extractDeleteAllFlags reads --all-configs and the flags that narrow it. --all-configs cannot be
+// combined with --config-name, and the other flags only apply to --all-configs. --older-than and
+// --unattached-only also require --managed-prefix, so only configurations this tool manages can
+// qualify for unattended cleanup.
+func extractDeleteAllFlags(cmd *cobra.Command) (bool, processors.DeletionFilter, error) {
+	var filter processors.DeletionFilter
+	allConfigs, err := cmd.Flags().GetBool("all-configs")
+	if err != nil {
+		return false, filter, err
+	}
+	if filter.ManagedPrefix, err = cmd.Flags().GetString("managed-prefix"); err != nil {
+		return false, filter, err
+	}
+	olderThan, err := cmd.Flags().GetString("older-than")
+	if err != nil {
+		return false, filter, err
+	}
+	if filter.UnattachedOnly, err = cmd.Flags().GetBool("unattached-only"); err != nil {
+		return false, filter, err
+	}
+	if allConfigs && cmd.Flags().Changed("config-name") {
+		return false, filter, fmt.Errorf("--all-configs cannot be used together with --config-name")
+	}
+	for _, name := range []string{"managed-prefix", "older-than", "unattached-only"} {
+		if cmd.Flags().Changed(name) && !allConfigs {
+			return false, filter, fmt.Errorf("--%s requires --all-configs", name)
+		}
+	}
+	if cmd.Flags().Changed("managed-prefix") && strings.TrimSpace(filter.ManagedPrefix) == "" {
+		return false, filter, fmt.Errorf("--managed-prefix cannot be empty")
+	}
+	if olderThan != "" {
+		if filter.OlderThan, err = utils.ParseAge(olderThan); err != nil {
+			return false, filter, fmt.Errorf("--older-than: %w", err)
+		}
+	}
+	if (filter.OlderThan > 0 || filter.UnattachedOnly) && filter.ManagedPrefix == "" {
+		return false, filter, fmt.Errorf("--older-than and --unattached-only require --managed-prefix, so only configurations this tool created can qualify")
+	}
+	return allConfigs, filter, nil
 }
 
 func runDelete(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgRed)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("GitHub Security Configuration Deleter")
 	pterm.Println()
 
@@ -51,7 +104,7 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get flag values for enterprise settings
-	enterpriseFlag, err := cmd.Flags().GetString("enterprise-slug")
+	enterpriseFlags, err := cmd.Flags().GetStringSlice("enterprise-slug")
 	if err != nil {
 		return err
 	}
@@ -71,13 +124,23 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	allConfigs, deletionFilter, err := extractDeleteAllFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	force, err := extractSkipConfirmationFlag(cmd)
 	if err != nil {
 		return err
 	}
+	changeRef, _, err := extractChangeRefFlags(cmd)
+	if err != nil {
+		return err
+	}
+	recordChangeRef(changeRef)
 
-	// Get enterprise name
-	enterprise, err := ui.GetEnterpriseInput(enterpriseFlag)
+	// Get enterprise names
+	enterprises, err := resolveEnterprises(enterpriseFlags, commonFlags)
 	if err != nil {
 		return err
 	}
@@ -91,36 +154,14 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	// Set hostname if using GitHub Enterprise Server
 	ui.SetupGitHubHost(serverURL)
 
-	// Collect available configurations from template organization
-	var orgConfigNames []string
-
-	// If no org targeting method is provided, prompt user to select one
-	if !utils.HasOrgTargeting(commonFlags) {
-		targetingMethod, err := ui.SelectOrgTargetingMethod()
-		if err != nil {
-			return err
-		}
+	// Settle the organization targeting, fetch the organizations and stop if none remain to process
+	orgs, breakdown, err := resolveTargetOrganizations(enterprises, commonFlags, "")
+	if err != nil {
+		return err
+	}
 
-		switch targetingMethod {
-		case "all-orgs":
-			commonFlags.AllOrgs = true
-		case "single-org":
-			orgName, err := ui.GetSingleOrgName()
-			if err != nil {
-				return err
-			}
-			commonFlags.Org = orgName
-		case "org-list":
-			csvPath, err := ui.GetOrgListPath()
-			if err != nil {
-				return err
-			}
-			commonFlags.OrgListPath = csvPath
-			// Validate the CSV file
-			if err := utils.ValidateOrgFlagsOptional(commonFlags); err != nil {
-				return err
-			}
-		}
+	if allConfigs {
+		return runDeleteAll(cmd, start, orgs, breakdown, enterprises, serverURL, commonFlags, deletionFilter, force, changeRef)
 	}
 
 	// Get template organization name
@@ -131,63 +172,36 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 	pterm.Info.Printf("Using template organization: %s\n", templateOrg)
 
-	// Fetch org-level configuration names from template organization only
-	pterm.Info.Printf("Fetching security configurations from template organization '%s'...\n", templateOrg)
-	status, err := api.CheckSingleOrganizationMembership(templateOrg)
-	if err != nil {
-		ui.LogWarningf("Could not access template organization '%s': %v", templateOrg, err)
-	} else if !status.IsMember {
-		ui.LogWarningf("You must be a member of template organization '%s' to fetch configurations", templateOrg)
-	} else if !status.IsOwner {
-		ui.LogWarningf("You must be an owner of template organization '%s' to fetch configurations", templateOrg)
-	} else {
-		configs, err := api.FetchSecurityConfigurations(templateOrg)
-		if err != nil {
-			ui.LogWarningf("Could not fetch configurations from template organization '%s': %v", templateOrg, err)
-		} else {
-			for _, config := range configs {
-				// Only add organization-level configs (not enterprise configs shown at org level)
-				if config.TargetType != "enterprise" {
-					orgConfigNames = append(orgConfigNames, config.Name)
-				}
-			}
-			if len(orgConfigNames) > 0 {
-				pterm.Success.Printf("Found %d organization security configuration(s) in template org\n", len(orgConfigNames))
-			}
-		}
+	// Let user select a configuration to delete
+	configName, err := pickTemplateConfiguration(templateOrg, configNameFlag, "delete", ui.GetConfigNameForDeletion)
+	if err != nil {
+		return err
 	}
 
-	// Let user select a configuration to delete
-	var configName string
-	if len(orgConfigNames) > 0 {
-		configName, err = ui.SelectConfigurationForDeletion(orgConfigNames, configNameFlag)
-		if err != nil {
-			return err
-		}
-	} else {
-		return fmt.Errorf("no security configurations found in template organization '%s'", templateOrg)
+	// Refuse to start a run that would exhaust the rate limit part way through
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "delete", Orgs: len(orgs), ConfigLookups: 1}); err != nil {
+		return err
 	}
 
-	// Fetch organizations
-	orgs, err := api.GetOrganizations(enterprise, commonFlags.Org, commonFlags.OrgListPath, commonFlags.AllOrgs)
-	if err != nil {
+	// Stop before the confirmation when the user owns none of the targets
+	if err := requireActionableOrganizations(orgs, breakdown, commonFlags.Concurrency); err != nil {
 		return err
 	}
 
-	if len(orgs) == 0 {
-		ui.ShowNoOrganizationsWarning(commonFlags)
-		return nil
+	identity := resolveRunIdentity()
+	if err := confirmTargetCount(orgs, enterprises, breakdown, commonFlags, force); err != nil {
+		return err
 	}
 
 	// Confirm before proceeding
-	confirmed, err := ui.ConfirmDeleteOperation(orgs, configName, force)
+	confirmed, err := ui.ConfirmDeleteOperation(identity, orgs, configName, force)
 	if err != nil {
 		return err
 	}
 
 	if !confirmed {
 		ui.ShowOperationCancelled()
-		return nil
+		return types.ErrOperationCancelled
 	}
 
 	// Create processor for delete command
@@ -196,18 +210,12 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	// Process each organization - use sequential processor if delay is specified
-	var successCount, skippedCount, errorCount int
-	if commonFlags.Delay > 0 {
-		ui.ShowProcessingStartWithDelay(len(orgs), commonFlags.Delay)
-		sequentialProcessor := processors.NewSequentialProcessor(orgs, processor, commonFlags.Delay)
-		successCount, skippedCount, errorCount = sequentialProcessor.Process()
-	} else {
-		ui.ShowProcessingStart(len(orgs), commonFlags.Concurrency)
-		concurrentProcessor := processors.NewConcurrentProcessor(orgs, processor, commonFlags.Concurrency)
-		successCount, skippedCount, errorCount = concurrentProcessor.Process()
-	}
+	outcome := processOrganizations(orgs, breakdown, processor, commonFlags)
 
-	utils.PrintCompletionHeader("Security Configuration Deletion", successCount, skippedCount, errorCount)
+	utils.PrintCompletionHeader("Security Configuration Deletion", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showChangeRef(changeRef)
+	showAPICallUsage()
 
 	// Extract log level flag
 	logLevel, err := cmd.Flags().GetString("log-level")
@@ -217,14 +225,18 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 	// Build and display replication command
 	replicationFlags := map[string]interface{}{
-		"enterprise-slug":              enterprise,
+		"enterprise-slug":              strings.Join(enterprises, ","),
 		"github-enterprise-server-url": serverURL,
 		"template-org":                 templateOrg,
 		"concurrency":                  commonFlags.Concurrency,
+		"max-orgs-per-minute":          commonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":              commonFlags.PerOrgTimeout,
+		"report-order":                 commonFlags.ReportOrder,
 		"delay":                        commonFlags.Delay,
 		"log-level":                    logLevel,
 		"config-name":                  configName,
 		"skip-confirmation-message":    fmt.Sprintf("%t", force),
+		"change-ref":                   changeRef,
 	}
 
 	// Add org targeting flags
@@ -232,12 +244,175 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		replicationFlags["org"] = commonFlags.Org
 	} else if commonFlags.OrgListPath != "" {
 		replicationFlags["org-list"] = commonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = commonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = commonFlags.FailOnInvalidOrgs
 	} else if commonFlags.AllOrgs {
 		replicationFlags["all-orgs"] = true
 	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(commonFlags.ExcludeProperties)
 
 	replicationCommand := utils.BuildReplicationCommand("delete", replicationFlags)
 	utils.ShowReplicationCommand(replicationCommand)
+	showRetryCommand("delete", replicationFlags, outcome)
+
+	return outcomeError("security configuration deletion", outcome)
+}
+
+// runDeleteAll deletes every managed configuration in the target organizations. The full set is
+// listed and confirmed with a typed phrase before anything is deleted, and only the confirmed
+// configurations are removed.
+func runDeleteAll(cmd *cobra.Command, start time.Time, orgs []string, breakdown types.TargetBreakdown, enterprises []string, serverURL string, commonFlags *utils.CommonFlags, filter processors.DeletionFilter, force bool, changeRef string) error {
+	pterm.Info.Printf("Listing configurations in %d organizations...\n", len(orgs))
+	filter.Now = time.Now()
+	plan := processors.PlanAllConfigsDeletion(orgs, filter)
+	if len(plan) == 0 {
+		if filter.OlderThan > 0 || filter.UnattachedOnly {
+			return fmt.Errorf("no organization-level configurations starting with '%s' match %s in the target organizations", filter.ManagedPrefix, strings.Join(deletionFilterCriteria(filter), " and "))
+		}
+		if filter.ManagedPrefix != "" {
+			return fmt.Errorf("no organization-level configurations starting with '%s' found in the target organizations", filter.ManagedPrefix)
+		}
+		return fmt.Errorf("no organization-level configurations found in the target organizations")
+	}
 
-	return nil
+	deletes := 0
+	for _, configs := range plan {
+		deletes += len(configs)
+	}
+
+	// Refuse to start a run that would exhaust the rate limit part way through
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "delete", Orgs: len(orgs), Deletes: deletes}); err != nil {
+		return err
+	}
+
+	// Stop before the confirmation when the user owns none of the targets
+	if err := requireActionableOrganizations(orgs, breakdown, commonFlags.Concurrency); err != nil {
+		return err
+	}
+
+	identity := resolveRunIdentity()
+	confirmed, err := ui.ConfirmDeleteAllOperation(identity, plan, filter.ManagedPrefix, deletionFilterSummary(filter), force)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		ui.ShowOperationCancelled()
+		return types.ErrOperationCancelled
+	}
+
+	processor := &processors.DeleteProcessor{
+		AllConfigs: true,
+		Planned:    plan,
+	}
+
+	// Process each organization - use sequential processor if delay is specified
+	outcome := processOrganizations(orgs, breakdown, processor, commonFlags)
+
+	utils.PrintCompletionHeader("Security Configuration Deletion", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	if filter.OlderThan > 0 || filter.UnattachedOnly {
+		if err := renderDeletionReport(plan, outcome.Results); err != nil {
+			return err
+		}
+	}
+	showChangeRef(changeRef)
+	showAPICallUsage()
+
+	logLevel, err := cmd.Flags().GetString("log-level")
+	if err != nil {
+		return err
+	}
+
+	replicationFlags := map[string]interface{}{
+		"enterprise-slug":              strings.Join(enterprises, ","),
+		"github-enterprise-server-url": serverURL,
+		"concurrency":                  commonFlags.Concurrency,
+		"max-orgs-per-minute":          commonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":              commonFlags.PerOrgTimeout,
+		"report-order":                 commonFlags.ReportOrder,
+		"delay":                        commonFlags.Delay,
+		"log-level":                    logLevel,
+		"all-configs":                  true,
+		"managed-prefix":               filter.ManagedPrefix,
+		"unattached-only":              filter.UnattachedOnly,
+		"skip-confirmation-message":    fmt.Sprintf("%t", force),
+		"change-ref":                   changeRef,
+	}
+	if filter.OlderThan > 0 {
+		replicationFlags["older-than"] = utils.FormatAge(filter.OlderThan)
+	}
+	if commonFlags.Org != "" {
+		replicationFlags["org"] = commonFlags.Org
+	} else if commonFlags.OrgListPath != "" {
+		replicationFlags["org-list"] = commonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = commonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = commonFlags.FailOnInvalidOrgs
+	} else if commonFlags.AllOrgs {
+		replicationFlags["all-orgs"] = true
+	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(commonFlags.ExcludeProperties)
+
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("delete", replicationFlags))
+	showRetryCommand("delete", replicationFlags, outcome)
+
+	return outcomeError("security configuration deletion", outcome)
+}
+
+// deletionFilterCriteria describes the age and attachment filters of a delete --all-configs run
+func deletionFilterCriteria(filter processors.DeletionFilter) []string {
+	var criteria []string
+	if filter.OlderThan > 0 {
+		criteria = append(criteria, fmt.Sprintf("created over %s ago", utils.FormatAge(filter.OlderThan)))
+	}
+	if filter.UnattachedOnly {
+		criteria = append(criteria, "attached to no repositories")
+	}
+	return criteria
+}
+
+// deletionFilterSummary lists the age and attachment filters for the deletion summary
+func deletionFilterSummary(filter processors.DeletionFilter) []string {
+	var lines []string
+	if filter.OlderThan > 0 {
+		lines = append(lines, fmt.Sprintf("Older Than: %s", utils.FormatAge(filter.OlderThan)))
+	}
+	if filter.UnattachedOnly {
+		lines = append(lines, "Unattached Only: true")
+	}
+	return lines
+}
+
+// renderDeletionReport lists each planned configuration with its age, attachment count and
+// outcome once a filtered delete --all-configs run has finished
+func renderDeletionReport(plan map[string][]types.DeletionCandidate, results []types.ProcessingResult) error {
+	outcomes := make(map[string]map[string]string)
+	for _, result := range results {
+		outcomes[result.Organization] = make(map[string]string)
+		for _, config := range result.Configurations {
+			outcome := "deleted"
+			if config.Error != nil {
+				outcome = "failed"
+			} else if config.Skipped {
+				outcome = "skipped"
+			}
+			outcomes[result.Organization][config.Name] = outcome
+		}
+	}
+
+	tableData := pterm.TableData{{"Organization", "Configuration", "Age", "Repositories", "Outcome"}}
+	for _, org := range slices.Sorted(maps.Keys(plan)) {
+		for _, config := range plan[org] {
+			repositories := "unknown"
+			if config.Repositories >= 0 {
+				repositories = fmt.Sprintf("%d", config.Repositories)
+			}
+			outcome := outcomes[org][config.Name]
+			if outcome == "" {
+				outcome = "not deleted"
+			}
+			tableData = append(tableData, []string{org, config.Name, utils.FormatAge(config.Age), repositories, outcome})
+		}
+	}
+	pterm.Println()
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
 }
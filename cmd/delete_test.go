@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+func TestExtractDeleteAllFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantAll    bool
+		wantPrefix string
+		wantOlder  time.Duration
+		wantErr    string
+	}{
+		{name: "single configuration", args: []string{"--config-name", "Baseline"}},
+		{name: "all configurations", args: []string{"--all-configs"}, wantAll: true},
+		{name: "all configurations with prefix", args: []string{"--all-configs", "--managed-prefix", "sec-"}, wantAll: true, wantPrefix: "sec-"},
+		{name: "all configurations and a name", args: []string{"--all-configs", "--config-name", "Baseline"}, wantErr: "cannot be used together with --config-name"},
+		{name: "prefix without all configurations", args: []string{"--managed-prefix", "sec-"}, wantErr: "requires --all-configs"},
+		{name: "empty prefix", args: []string{"--all-configs", "--managed-prefix", " "}, wantErr: "cannot be empty"},
+		{name: "older than with prefix", args: []string{"--all-configs", "--managed-prefix", "sec-", "--older-than", "90d"}, wantAll: true, wantPrefix: "sec-", wantOlder: 90 * utils.Day},
+		{name: "unattached only with prefix", args: []string{"--all-configs", "--managed-prefix", "sec-", "--unattached-only"}, wantAll: true, wantPrefix: "sec-"},
+		{name: "older than without prefix", args: []string{"--all-configs", "--older-than", "90d"}, wantErr: "require --managed-prefix"},
+		{name: "unattached only without all configurations", args: []string{"--unattached-only"}, wantErr: "requires --all-configs"},
+		{name: "invalid age", args: []string{"--all-configs", "--managed-prefix", "sec-", "--older-than", "soon"}, wantErr: "--older-than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("config-name", "", "")
+			cmd.Flags().Bool("all-configs", false, "")
+			cmd.Flags().String("managed-prefix", "", "")
+			cmd.Flags().String("older-than", "", "")
+			cmd.Flags().Bool("unattached-only", false, "")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags: %v", err)
+			}
+			all, filter, err := extractDeleteAllFlags(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if all != tt.wantAll || filter.ManagedPrefix != tt.wantPrefix || filter.OlderThan != tt.wantOlder {
+				t.Errorf("got (%t, %q, %s), want (%t, %q, %s)", all, filter.ManagedPrefix, filter.OlderThan, tt.wantAll, tt.wantPrefix, tt.wantOlder)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Report configurations whose settings differ from a baseline",
+	Long:  "Compare the same-named organization-level configuration in each organization with a baseline, from a JSON settings file or a reference organization, and report every setting that differs",
+	RunE:  runDrift,
+}
+
+func init() {
+	driftCmd.Flags().String("baseline", "", "JSON settings file, or reference organization whose same-named configuration is the baseline (required)")
+	driftCmd.Flags().String("output", utils.DriftOutputTable, "Output format for the differences (table, json, yaml); json and yaml are written to stdout and progress to stderr")
+	driftCmd.Flags().Bool("include-config-id", false, "Add a column with each configuration's ID to the table, for scripts that call the API by ID")
+	driftCmd.Flags().String("csv-report", "", "Also write the differences to a CSV file at this path")
+	driftCmd.Flags().Bool("no-request-cache", false, "Send every read request to the API instead of reusing responses already fetched during this run")
+	_ = driftCmd.MarkFlagRequired("baseline")
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
+	baseline, err := cmd.Flags().GetString("baseline")
+	if err != nil {
+		return err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateEnumValue("output", output, []string{utils.DriftOutputTable, utils.DriftOutputJSON, utils.DriftOutputYAML}); err != nil {
+		return err
+	}
+	csvReport, err := cmd.Flags().GetString("csv-report")
+	if err != nil {
+		return err
+	}
+	includeConfigID, err := cmd.Flags().GetBool("include-config-id")
+	if err != nil {
+		return err
+	}
+	configName, err := cmd.Flags().GetString("config-name")
+	if err != nil {
+		return err
+	}
+	if configName == "" {
+		return fmt.Errorf("--config-name is required: it names the configuration compared with the baseline in every organization")
+	}
+
+	// Keep stdout for the JSON or YAML document so it can be piped; everything else goes to stderr
+	if output != utils.DriftOutputTable {
+		pterm.SetDefaultOutput(os.Stderr)
+		defer pterm.SetDefaultOutput(os.Stdout)
+	}
+
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("GitHub Security Configuration Drift")
+	pterm.Println()
+
+	targets, err := resolveCommandTargets(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The baseline is read after the host is set up, as it may name a reference organization
+	settings, enforcement, err := resolveReferenceSettings("baseline", baseline, configName)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "drift", Orgs: len(targets.Orgs), ConfigLookups: referenceLookups(baseline)}); err != nil {
+		return err
+	}
+
+	noRequestCache, err := enableRequestCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	processor := &processors.DriftProcessor{ConfigName: configName, Settings: settings, Enforcement: enforcement}
+	outcome := processOrganizations(targets.Orgs, targets.Breakdown, processor, targets.CommonFlags)
+
+	utils.PrintCompletionHeader("Drift Check", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showAPICallUsage()
+	showRequestCacheStats()
+	pterm.Println()
+
+	drift := processor.Drift()
+	switch output {
+	case utils.DriftOutputJSON:
+		if err := utils.WriteDriftJSON(os.Stdout, drift); err != nil {
+			return err
+		}
+	case utils.DriftOutputYAML:
+		if err := utils.WriteDriftYAML(os.Stdout, drift); err != nil {
+			return err
+		}
+	default:
+		if err := renderDrift(drift, processor.DriftedOrganizations(), includeConfigID); err != nil {
+			return err
+		}
+	}
+	if csvReport != "" {
+		if err := utils.WriteDriftCSV(csvReport, drift); err != nil {
+			return err
+		}
+		pterm.Info.Printf("Drift report written to %s\n", csvReport)
+	}
+
+	replicationFlags, err := targetReplicationFlags(cmd, targets)
+	if err != nil {
+		return err
+	}
+	replicationFlags["baseline"] = baseline
+	replicationFlags["config-name"] = configName
+	replicationFlags["csv-report"] = csvReport
+	if output != utils.DriftOutputTable {
+		replicationFlags["output"] = output
+	}
+	if includeConfigID {
+		replicationFlags["include-config-id"] = true
+	}
+	if noRequestCache {
+		replicationFlags["no-request-cache"] = true
+	}
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("drift", replicationFlags))
+	showRetryCommand("drift", replicationFlags, outcome)
+
+	// Confirmed drift fails the check even when some organizations could not be read
+	if drifted := processor.DriftedOrganizations(); drifted > 0 {
+		return &types.DriftError{Organizations: drifted}
+	}
+	return outcomeError("drift check", outcome)
+}
+
+// renderDrift prints one row per differing setting followed by the number of drifted
+// organizations. includeConfigID adds a column with each configuration's ID.
+func renderDrift(drift []types.SettingDrift, driftedOrgs int, includeConfigID bool) error {
+	if len(drift) == 0 {
+		pterm.Success.Println("No drift from the baseline found.")
+		return nil
+	}
+
+	if err := pterm.DefaultTable.WithHasHeader().WithData(driftTable(drift, includeConfigID)).Render(); err != nil {
+		return err
+	}
+	pterm.Println()
+	// Links are listed below the table rather than in it, as escape sequences would skew the column widths
+	seen := make(map[string]bool)
+	for _, d := range drift {
+		if d.ConfigurationURL == "" || seen[d.ConfigurationURL] {
+			continue
+		}
+		if len(seen) == 0 {
+			pterm.Println("Configurations with drift:")
+		}
+		seen[d.ConfigurationURL] = true
+		pterm.Printf("  - %s\n", ui.Hyperlink(d.ConfigurationURL, fmt.Sprintf("%s / %s", d.Organization, d.Configuration)))
+	}
+	if len(seen) > 0 {
+		pterm.Println()
+	}
+	pterm.Info.Printf("Drift: %d setting(s) in %d organization(s)\n", len(drift), driftedOrgs)
+	return nil
+}
+
+// driftTable returns the rows of the drift table under a header row
+func driftTable(drift []types.SettingDrift, includeConfigID bool) pterm.TableData {
+	tableData := pterm.TableData{withConfigIDColumn([]string{"Organization", "Configuration", "Setting", "Baseline", "Actual"}, "Configuration ID", includeConfigID)}
+	for _, d := range drift {
+		row := []string{d.Organization, d.Configuration, d.Setting, ui.RenderSettingValue(d.Baseline), ui.RenderSettingValue(d.Actual)}
+		tableData = append(tableData, withConfigIDColumn(row, configIDCell(d.ConfigurationID), includeConfigID))
+	}
+	return tableData
+}
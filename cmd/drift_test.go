@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestDriftTable_IncludeConfigID(t *testing.T) {
+	drift := []types.SettingDrift{{Organization: "org-a", Configuration: "Baseline", ConfigurationID: 17, Setting: "secret_scanning", Baseline: "enabled", Actual: "disabled"}}
+	assertConfigIDColumn(t, driftTable(drift, false), driftTable(drift, true),
+		[]string{"org-a", "Baseline", "secret_scanning", "", "", "17"})
+}
@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
-	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
 	"github.com/callmegreg/gh-security-config/internal/utils"
 )
@@ -22,20 +25,41 @@ var generateCmd = &cobra.Command{
 func init() {
 	// Command-specific flags
 	generateCmd.Flags().StringP("copy-from-org", "o", "", "Organization name to copy an existing configuration from")
+	cobra.CheckErr(generateCmd.RegisterFlagCompletionFunc("copy-from-org", completeOrganizationNames))
+	generateCmd.Flags().String("probe", "", probeOrgFlagUsage)
 
 	// Non-interactive input flags
+	generateCmd.Flags().String("new-name", "", "Name for the copied configuration in the target organizations (requires --copy-from-org; defaults to the source name)")
+	generateCmd.Flags().String("name-prefix", "", "Text added before the copied configuration's name (requires --copy-from-org)")
+	generateCmd.Flags().String("name-suffix", "", "Text added after the copied configuration's name (requires --copy-from-org)")
 	generateCmd.Flags().String("config-description", "", "Description for the new security configuration")
+	generateCmd.Flags().String("description-file", "", "Read the description for the new security configuration from a file (- for stdin)")
+	generateCmd.MarkFlagsMutuallyExclusive("config-description", "description-file")
 
 	// Security settings (shared with modify)
 	addSecuritySettingFlags(generateCmd)
+	addChangeRefFlags(generateCmd, true)
+	addPostHookFlags(generateCmd)
 
 	// Application options
 	generateCmd.Flags().String("scope", "", "Repository attachment scope (all, public, private_or_internal, none)")
+	generateCmd.Flags().String("exclude-repos", "", "Path to a file of org/repo lines (one per line) that must never have the configuration attached")
+	generateCmd.Flags().Bool("no-attach", false, "Create the configuration without attaching it to any repositories (same as --scope none)")
+	generateCmd.MarkFlagsMutuallyExclusive("scope", "no-attach")
+	addCodeQLRepoFlags(generateCmd)
+	generateCmd.MarkFlagsMutuallyExclusive("only-codeql-supported-repos", "no-attach")
 	generateCmd.Flags().String("set-as-default", "", "Whether to set this configuration as default for new repositories (true/false)")
+	generateCmd.Flags().Bool("apply-to-existing", false, "Set the configuration as default for new repositories, then attach it to the existing repositories in --scope (implies --set-as-default true)")
+	generateCmd.MarkFlagsMutuallyExclusive("apply-to-existing", "no-attach")
 	generateCmd.Flags().String("overwrite", "", "Overwrite any existing configuration with the same name instead of skipping (true/false)")
+	generateCmd.Flags().Bool("skip-orgs-with-existing-default", false, "Skip organizations whose default configuration for new repositories is a different configuration")
+	generateCmd.Flags().Bool("probe-feature-support", false, "Create, read back and delete a scratch configuration to find settings this instance accepts but does not apply")
+	generateCmd.Flags().Bool("with-audit-copy", false, "Also create an unenforced, unattached companion configuration named \"<name> (audit)\" in each organization")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgBlue)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("GitHub Security Configuration Generator")
 	pterm.Println()
 
@@ -55,25 +79,97 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	changeRef, stampChangeRef, err := extractChangeRefFlags(cmd)
+	if err != nil {
+		return err
+	}
+	recordChangeRef(changeRef)
+	postHook, ignoreHookFailures, err := extractPostHookFlags(cmd)
+	if err != nil {
+		return err
+	}
 
 	overwrite, err := extractOverwriteFlag(cmd)
 	if err != nil {
 		return err
 	}
 
+	excludeReposFlag, err := cmd.Flags().GetString("exclude-repos")
+	if err != nil {
+		return err
+	}
+	var exclusions types.RepositoryExclusions
+	if excludeReposFlag != "" {
+		exclusions, err = utils.ReadRepositoryExclusions(excludeReposFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	onlyCodeQL, codeQLLanguagesFlag, codeQLLanguages, err := extractCodeQLRepoFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	skipOrgsWithExistingDefault, err := cmd.Flags().GetBool("skip-orgs-with-existing-default")
+	if err != nil {
+		return err
+	}
+
+	withAuditCopy, err := cmd.Flags().GetBool("with-audit-copy")
+	if err != nil {
+		return err
+	}
+
 	copyFromOrg, err := cmd.Flags().GetString("copy-from-org")
 	if err != nil {
 		return err
 	}
+	if copyFromOrg != "" {
+		if err := utils.ValidateCopyFromOrg(copyFromOrg); err != nil {
+			return err
+		}
+	}
 
 	configNameFlag, err := cmd.Flags().GetString("config-name")
 	if err != nil {
 		return err
 	}
+	newNameFlag, err := cmd.Flags().GetString("new-name")
+	if err != nil {
+		return err
+	}
+	if newNameFlag != "" && copyFromOrg == "" {
+		return fmt.Errorf("--new-name can only be used together with --copy-from-org")
+	}
+	namePrefix, err := cmd.Flags().GetString("name-prefix")
+	if err != nil {
+		return err
+	}
+	nameSuffix, err := cmd.Flags().GetString("name-suffix")
+	if err != nil {
+		return err
+	}
+	if (namePrefix != "" || nameSuffix != "") && copyFromOrg == "" {
+		return fmt.Errorf("--name-prefix and --name-suffix can only be used together with --copy-from-org")
+	}
 	configDescriptionFlag, err := cmd.Flags().GetString("config-description")
 	if err != nil {
 		return err
 	}
+	descriptionFileFlag, err := cmd.Flags().GetString("description-file")
+	if err != nil {
+		return err
+	}
+	if descriptionFileFlag != "" {
+		if copyFromOrg != "" {
+			return fmt.Errorf("--description-file cannot be used with --copy-from-org; the description is copied from the source configuration")
+		}
+		configDescriptionFlag, err = utils.ReadDescription(descriptionFileFlag, os.Stdin)
+		if err != nil {
+			return err
+		}
+	}
 
 	scopeFlag, err := cmd.Flags().GetString("scope")
 	if err != nil {
@@ -82,6 +178,14 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if err := utils.ValidateEnumValue("scope", scopeFlag, []string{"all", "public", "private_or_internal", "none"}); err != nil {
 		return err
 	}
+	noAttach, err := cmd.Flags().GetBool("no-attach")
+	if err != nil {
+		return err
+	}
+	if noAttach {
+		// Forcing the scope skips the attachment prompt in both the new and copy flows
+		scopeFlag = "none"
+	}
 
 	setAsDefaultFlag, err := cmd.Flags().GetString("set-as-default")
 	if err != nil {
@@ -91,6 +195,20 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	applyToExisting, err := cmd.Flags().GetBool("apply-to-existing")
+	if err != nil {
+		return err
+	}
+	if applyToExisting {
+		if setAsDefaultOverride != nil && !*setAsDefaultOverride {
+			return fmt.Errorf("--apply-to-existing sets the configuration as default, so it cannot be combined with --set-as-default false")
+		}
+		if scopeFlag == "none" {
+			return fmt.Errorf("--apply-to-existing attaches to existing repositories, so it cannot be combined with --scope none")
+		}
+		setAsDefault := true
+		setAsDefaultOverride = &setAsDefault
+	}
 
 	// Read security setting overrides
 	settingsOverrides, err := extractSecuritySettingOverrides(cmd)
@@ -110,7 +228,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get flag values for enterprise settings
-	enterpriseFlag, err := cmd.Flags().GetString("enterprise-slug")
+	enterpriseFlags, err := cmd.Flags().GetStringSlice("enterprise-slug")
 	if err != nil {
 		return err
 	}
@@ -120,8 +238,8 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Get enterprise name
-	enterprise, err := ui.GetEnterpriseInput(enterpriseFlag)
+	// Get enterprise names
+	enterprises, err := resolveEnterprises(enterpriseFlags, commonFlags)
 	if err != nil {
 		return err
 	}
@@ -135,90 +253,50 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	// Set hostname if using GitHub Enterprise Server
 	ui.SetupGitHubHost(serverURL)
 
-	// If no org targeting method is provided, prompt user to select one
-	if !utils.HasOrgTargeting(commonFlags) {
-		targetingMethod, err := ui.SelectOrgTargetingMethod()
-		if err != nil {
-			return err
-		}
-
-		switch targetingMethod {
-		case "all-orgs":
-			commonFlags.AllOrgs = true
-		case "single-org":
-			orgName, err := ui.GetSingleOrgName()
-			if err != nil {
-				return err
-			}
-			commonFlags.Org = orgName
-		case "org-list":
-			csvPath, err := ui.GetOrgListPath()
-			if err != nil {
-				return err
-			}
-			commonFlags.OrgListPath = csvPath
-			// Validate the CSV file
-			if err := utils.ValidateOrgFlagsOptional(commonFlags); err != nil {
-				return err
-			}
-		}
-	}
-
-	// Check Dependabot availability
-	dependabotAlertsAvailable, err := ui.GetDependabotAlertsAvailability(commonFlags.DependabotAlertsAvailable)
+	// Settle the organization targeting, fetch the organizations and stop if none remain to process
+	orgs, breakdown, err := resolveTargetOrganizations(enterprises, commonFlags, copyFromOrg)
 	if err != nil {
 		return err
 	}
-
-	dependabotSecurityUpdatesAvailable, err := ui.GetDependabotSecurityUpdatesAvailability(commonFlags.DependabotSecurityUpdatesAvailable)
+	probeOrg, err := extractProbeOrg(cmd, orgs)
 	if err != nil {
 		return err
 	}
 
-	// Fetch organizations
-	orgs, err := api.GetOrganizations(enterprise, commonFlags.Org, commonFlags.OrgListPath, commonFlags.AllOrgs)
+	// Check Dependabot availability
+	dependabotAlertsAvailable, dependabotSecurityUpdatesAvailable, err := getDependabotAvailability(commonFlags)
 	if err != nil {
 		return err
 	}
 
-	if len(orgs) == 0 {
-		ui.ShowNoOrganizationsWarning(commonFlags)
-		return nil
-	}
-
-	var configName, configDescription string
+	var configName, configDescription, copySourceName, copyBaseName, enforcement string
 	var settings map[string]interface{}
 	var scope string
 	var setAsDefault bool
 
 	// Check if we should copy from an existing organization
 	if copyFromOrg != "" {
-		// Filter out the source organization from target organizations to avoid copying to itself
-		var filteredOrgs []string
-		for _, org := range orgs {
-			if org != copyFromOrg {
-				filteredOrgs = append(filteredOrgs, org)
-			}
-		}
-
-		if len(filteredOrgs) == 0 {
-			return fmt.Errorf("no target organizations available after excluding source organization '%s'", copyFromOrg)
-		}
-
-		if len(filteredOrgs) < len(orgs) {
-			pterm.Info.Printf("Excluding source organization '%s' from targets. Will process %d organizations.\n", copyFromOrg, len(filteredOrgs))
-			orgs = filteredOrgs
-		}
-
 		// Copy configuration logic
-		configName, configDescription, settings, scope, setAsDefault, err = ui.HandleCopyFromOrg(copyFromOrg, ui.CopyFromOrgOverrides{
-			ConfigName:   configNameFlag,
-			Scope:        scopeFlag,
-			SetAsDefault: setAsDefaultOverride,
+		copied, err := ui.HandleCopyFromOrg(copyFromOrg, ui.CopyFromOrgOverrides{
+			ConfigName:    configNameFlag,
+			NewName:       newNameFlag,
+			NamePrefix:    namePrefix,
+			NameSuffix:    nameSuffix,
+			Scope:         scopeFlag,
+			SetAsDefault:  setAsDefaultOverride,
+			AllowExisting: overwrite,
 		})
 		if err != nil {
 			return err
 		}
+		copySourceName = copied.SourceName
+		copyBaseName = copied.BaseName
+		configName = copied.Name
+		configDescription = copied.Description
+		settings = copied.Settings
+		enforcement = copied.Enforcement
+		scope = copied.Scope
+		setAsDefault = copied.SetAsDefault
 	} else {
 		// Original logic for creating new configuration
 		configName, configDescription, err = ui.GetSecurityConfigInput(configNameFlag, configDescriptionFlag)
@@ -231,6 +309,11 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		enforcement, err = ui.GetEnforcement(settingsOverrides.Enforcement)
+		if err != nil {
+			return err
+		}
+
 		scope, err = ui.GetAttachmentScope(scopeFlag)
 		if err != nil {
 			return err
@@ -242,15 +325,81 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Stamp the change reference into the description so the GitHub UI shows which change created it
+	if stampChangeRef {
+		configDescription = utils.StampChangeRef(configDescription, changeRef)
+		if err := utils.ValidateDescriptionLength(configDescription); err != nil {
+			return fmt.Errorf("description with the change reference stamp: %w", err)
+		}
+	}
+
+	// The scope may have come from a prompt or the copied configuration, so check it again here
+	if applyToExisting && scope == "none" {
+		return fmt.Errorf("--apply-to-existing needs an attachment scope other than none")
+	}
+
+	var auditCopyName string
+	if withAuditCopy {
+		auditCopyName = processors.AuditCopyName(configName)
+	}
+
+	// Reject invalid setting values once, before any organization is processed
+	if err := utils.ValidateSettings(settings); err != nil {
+		return err
+	}
+	warnSettingDependencies(settings)
+
+	// A copy searches for its source configuration in the --copy-from-org organization
+	copyLookups := 0
+	if copyFromOrg != "" {
+		copyLookups = 1
+	}
+
+	// Refuse to start a run that would exhaust the rate limit part way through
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{
+		Command:      "generate",
+		Orgs:         len(orgs),
+		Attach:       scope != "none",
+		SetAsDefault: setAsDefault,
+		AuditCopy:    withAuditCopy,
+		Overwrite:    overwrite,
+		CheckDefault: setAsDefault || skipOrgsWithExistingDefault,
+
+		ConfigLookups: copyLookups,
+	}); err != nil {
+		return err
+	}
+
+	// Stop before the confirmation when the user owns none of the targets
+	if err := requireActionableOrganizations(orgs, breakdown, commonFlags.Concurrency); err != nil {
+		return err
+	}
+
+	// Optionally find settings that are accepted but inactive, using the source organization when
+	// copying and otherwise the first target organization
+	probeReferenceOrg := orgs[0]
+	if copyFromOrg != "" {
+		probeReferenceOrg = copyFromOrg
+	}
+	probeFeatureSupport, inactiveSettings, err := probeInactiveSettings(cmd, probeReferenceOrg, settings)
+	if err != nil {
+		return err
+	}
+
+	identity := resolveRunIdentity()
+	if err := confirmTargetCount(orgs, enterprises, breakdown, commonFlags, force); err != nil {
+		return err
+	}
+
 	// Confirm before proceeding (force skips the prompt)
-	confirmed, err := ui.ConfirmOperation(orgs, configName, configDescription, settings, scope, setAsDefault, force)
+	confirmed, err := ui.ConfirmOperation(identity, orgs, copySourceName, configName, configDescription, settings, enforcement, inactiveSettings, scope, setAsDefault, applyToExisting, auditCopyName, force)
 	if err != nil {
 		return err
 	}
 
 	if !confirmed {
 		ui.ShowOperationCancelled()
-		return nil
+		return types.ErrOperationCancelled
 	}
 
 	// Create processor for generate command
@@ -258,24 +407,32 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		ConfigName:        configName,
 		ConfigDescription: configDescription,
 		Settings:          settings,
+		Enforcement:       enforcement,
 		Scope:             scope,
 		SetAsDefault:      setAsDefault,
+		ApplyToExisting:   applyToExisting,
 		Overwrite:         overwrite,
+		WithAuditCopy:     withAuditCopy,
+		NoAttach:          noAttach,
+		Exclusions:        exclusions,
+		Languages:         codeQLLanguages,
+
+		SkipOrgsWithExistingDefault: skipOrgsWithExistingDefault,
 	}
 
 	// Process each organization - use sequential processor if delay is specified
-	var successCount, skippedCount, errorCount int
-	if commonFlags.Delay > 0 {
-		ui.ShowProcessingStartWithDelay(len(orgs), commonFlags.Delay)
-		sequentialProcessor := processors.NewSequentialProcessor(orgs, processor, commonFlags.Delay)
-		successCount, skippedCount, errorCount = sequentialProcessor.Process()
-	} else {
-		ui.ShowProcessingStart(len(orgs), commonFlags.Concurrency)
-		concurrentProcessor := processors.NewConcurrentProcessor(orgs, processor, commonFlags.Concurrency)
-		successCount, skippedCount, errorCount = concurrentProcessor.Process()
+	outcome, err := processOrganizationsWithProbe(probeOrg, orgs, breakdown, processors.WithPostHook(processor, postHook), commonFlags, force)
+	if err != nil {
+		return err
 	}
+	outcome.IgnoreHookFailures = ignoreHookFailures
 
-	utils.PrintCompletionHeader("Security Configuration Generation", successCount, skippedCount, errorCount)
+	utils.PrintCompletionHeader("Security Configuration Generation", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showDefaultCounts(outcome.Results)
+	showCompliantCount(outcome.Results)
+	showChangeRef(changeRef)
+	showAPICallUsage()
 
 	// Extract log level flag
 	logLevel, err := cmd.Flags().GetString("log-level")
@@ -285,18 +442,35 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 
 	// Build and display replication command
 	replicationFlags := map[string]interface{}{
-		"enterprise-slug":                       enterprise,
+		"enterprise-slug":                       strings.Join(enterprises, ","),
 		"github-enterprise-server-url":          serverURL,
 		"dependabot-alerts-available":           fmt.Sprintf("%t", dependabotAlertsAvailable),
 		"dependabot-security-updates-available": fmt.Sprintf("%t", dependabotSecurityUpdatesAvailable),
 		"concurrency":                           commonFlags.Concurrency,
+		"max-orgs-per-minute":                   commonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":                       commonFlags.PerOrgTimeout,
+		"report-order":                          commonFlags.ReportOrder,
 		"delay":                                 commonFlags.Delay,
 		"log-level":                             logLevel,
 		"config-name":                           configName,
 		"scope":                                 scope,
 		"set-as-default":                        fmt.Sprintf("%t", setAsDefault),
+		"apply-to-existing":                     applyToExisting,
 		"skip-confirmation-message":             fmt.Sprintf("%t", force),
+		"probe":                                 probeOrg,
 		"overwrite":                             fmt.Sprintf("%t", overwrite),
+		"with-audit-copy":                       withAuditCopy,
+		"probe-feature-support":                 probeFeatureSupport,
+		"skip-orgs-with-existing-default":       skipOrgsWithExistingDefault,
+		"exclude-repos":                         excludeReposFlag,
+		"only-codeql-supported-repos":           onlyCodeQL,
+		"codeql-languages":                      codeQLLanguagesFlag,
+		"change-ref":                            changeRef,
+		"stamp-change-ref":                      stampChangeRef,
+	}
+	if noAttach {
+		replicationFlags["no-attach"] = true
+		delete(replicationFlags, "scope")
 	}
 	if copyFromOrg == "" {
 		// The config-description and explicit per-setting flags only apply when creating
@@ -314,7 +488,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		replicationFlags["secret-scanning"] = fmt.Sprintf("%v", settings["secret_scanning"])
 		replicationFlags["secret-scanning-push-protection"] = fmt.Sprintf("%v", settings["secret_scanning_push_protection"])
 		replicationFlags["secret-scanning-non-provider-patterns"] = fmt.Sprintf("%v", settings["secret_scanning_non_provider_patterns"])
-		replicationFlags["enforcement"] = fmt.Sprintf("%v", settings["enforcement"])
+		replicationFlags["enforcement"] = enforcement
 	}
 
 	// Add org targeting flags
@@ -322,17 +496,29 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		replicationFlags["org"] = commonFlags.Org
 	} else if commonFlags.OrgListPath != "" {
 		replicationFlags["org-list"] = commonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = commonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = commonFlags.FailOnInvalidOrgs
 	} else if commonFlags.AllOrgs {
 		replicationFlags["all-orgs"] = true
 	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(commonFlags.ExcludeProperties)
 
-	// Add copy-from-org flag if used
+	// Add copy-from-org flag if used. In copy mode --config-name selects the source configuration.
 	if copyFromOrg != "" {
 		replicationFlags["copy-from-org"] = copyFromOrg
+		replicationFlags["config-name"] = copySourceName
+		if copyBaseName != copySourceName {
+			replicationFlags["new-name"] = copyBaseName
+		}
+		replicationFlags["name-prefix"] = namePrefix
+		replicationFlags["name-suffix"] = nameSuffix
 	}
 
+	addPostHookReplicationFlags(replicationFlags, postHook, ignoreHookFailures)
+
 	replicationCommand := utils.BuildReplicationCommand("generate", replicationFlags)
 	utils.ShowReplicationCommand(replicationCommand)
+	showRetryCommand("generate", replicationFlags, outcome)
 
-	return nil
+	return outcomeError("security configuration generation", outcome)
 }
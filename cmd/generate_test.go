@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// flagReference finds a long flag name in a message, e.g. "--github-enterprise-server-url"
+var flagReference = regexp.MustCompile(`--([a-z][a-z-]*)`)
+
+func TestCopyFromOrgErrorsNameRegisteredFlags(t *testing.T) {
+	// Merge the root command's persistent flags so they can be looked up on generate
+	generateCmd.InheritedFlags()
+
+	errs := map[string]error{
+		"host in --copy-from-org":  utils.ValidateCopyFromOrg("github.com/source-org"),
+		"source not found on host": utils.CopySourceHostError("source-org", "was not found", "github.example.com"),
+	}
+	for name, err := range errs {
+		t.Run(name, func(t *testing.T) {
+			if err == nil {
+				t.Fatal("want an error")
+			}
+			matches := flagReference.FindAllStringSubmatch(err.Error(), -1)
+			if len(matches) == 0 {
+				t.Fatalf("%q names no flag, want it to name the host flag", err)
+			}
+			namesHostFlag := false
+			for _, match := range matches {
+				if generateCmd.Flag(match[1]) == nil {
+					t.Errorf("%q names --%s, which generate does not register", err, match[1])
+				}
+				if rootCmd.PersistentFlags().Lookup(match[1]) != nil {
+					namesHostFlag = true
+				}
+			}
+			if !namesHostFlag {
+				t.Errorf("%q names no flag registered on the root command", err)
+			}
+		})
+	}
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
 	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
 	"github.com/callmegreg/gh-security-config/internal/utils"
 )
@@ -22,17 +27,25 @@ var modifyCmd = &cobra.Command{
 func init() {
 	// Add template-org flag specific to modify command
 	modifyCmd.Flags().StringP("template-org", "t", "", "Template organization to fetch security configurations from (required)")
+	modifyCmd.Flags().String("probe", "", probeOrgFlagUsage)
 
 	// Non-interactive input flags
 	modifyCmd.Flags().String("new-name", "", "Updated name for the configuration (empty means keep current)")
 	modifyCmd.Flags().String("new-description", "", "Updated description for the configuration (empty means keep current)")
+	modifyCmd.Flags().String("description-file", "", "Read the updated description for the configuration from a file (- for stdin)")
+	modifyCmd.MarkFlagsMutuallyExclusive("new-description", "description-file")
+	modifyCmd.Flags().Bool("probe-feature-support", false, "Create, read back and delete a scratch configuration in the template organization to find settings this instance accepts but does not apply")
+	modifyCmd.Flags().Bool("force-overwrite", false, "Update configurations even if they changed since their current settings were read")
 
 	// Security settings (shared with generate): override specific settings non-interactively.
 	// Any setting omitted keeps the current value.
 	addSecuritySettingFlags(modifyCmd)
+	addChangeRefFlags(modifyCmd, true)
 }
 
 func runModify(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgMagenta)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("GitHub Security Configuration Modifier")
 	pterm.Println()
 
@@ -59,7 +72,7 @@ func runModify(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get flag values for enterprise settings
-	enterpriseFlag, err := cmd.Flags().GetString("enterprise-slug")
+	enterpriseFlags, err := cmd.Flags().GetStringSlice("enterprise-slug")
 	if err != nil {
 		return err
 	}
@@ -86,6 +99,16 @@ func runModify(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	descriptionFileFlag, err := cmd.Flags().GetString("description-file")
+	if err != nil {
+		return err
+	}
+	if descriptionFileFlag != "" {
+		newDescriptionFlag, err = utils.ReadDescription(descriptionFileFlag, os.Stdin)
+		if err != nil {
+			return err
+		}
+	}
 
 	settingsOverrides, err := extractSecuritySettingOverrides(cmd)
 	if err != nil {
@@ -96,12 +119,22 @@ func runModify(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	changeRef, stampChangeRef, err := extractChangeRefFlags(cmd)
+	if err != nil {
+		return err
+	}
+	recordChangeRef(changeRef)
+	forceOverwrite, err := cmd.Flags().GetBool("force-overwrite")
+	if err != nil {
+		return err
+	}
 
-	// Get enterprise name
-	enterprise, err := ui.GetEnterpriseInput(enterpriseFlag)
+	// Get enterprise names
+	enterprises, err := resolveEnterprises(enterpriseFlags, commonFlags)
 	if err != nil {
 		return err
 	}
+	enterprise := configEnterprise(enterprises)
 
 	// Get GitHub Enterprise URL if needed
 	serverURL, err := ui.GetServerURLInput(serverURLFlag)
@@ -124,8 +157,8 @@ func runModify(cmd *cobra.Command, args []string) error {
 		pterm.Success.Printf("Detected GHES version: %s\n", ghesVersion)
 	}
 
-	// Fetch enterprise configurations if GHES 3.16+
-	if api.SupportsEnterpriseConfigurations(ghesVersion) {
+	// Fetch enterprise configurations if GHES 3.16+ and an enterprise is known
+	if enterprise != "" && api.SupportsEnterpriseConfigurations(ghesVersion) {
 		pterm.Info.Println("Fetching enterprise security configurations...")
 		enterpriseConfigs, err := api.FetchEnterpriseSecurityConfigurations(enterprise)
 		if err != nil {
@@ -138,112 +171,46 @@ func runModify(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// If no org targeting method is provided, prompt user to select one
-	if !utils.HasOrgTargeting(commonFlags) {
-		if enterpriseConfigCount > 0 {
-			pterm.Info.Println("Organization-level security configurations modified by this command will not affect existing enterprise configurations.")
-		}
-
-		targetingMethod, err := ui.SelectOrgTargetingMethod()
-		if err != nil {
-			return err
-		}
-
-		switch targetingMethod {
-		case "all-orgs":
-			commonFlags.AllOrgs = true
-		case "single-org":
-			orgName, err := ui.GetSingleOrgName()
-			if err != nil {
-				return err
-			}
-			commonFlags.Org = orgName
-		case "org-list":
-			csvPath, err := ui.GetOrgListPath()
-			if err != nil {
-				return err
-			}
-			commonFlags.OrgListPath = csvPath
-			// Validate the CSV file
-			if err := utils.ValidateOrgFlagsOptional(commonFlags); err != nil {
-				return err
-			}
-		}
+	if !utils.HasOrgTargeting(commonFlags) && enterpriseConfigCount > 0 {
+		pterm.Info.Println("Organization-level security configurations modified by this command will not affect existing enterprise configurations.")
 	}
 
-	// Get template organization name
-	templateOrg, err := ui.GetTemplateOrgInput(templateOrgFlag)
+	// Settle the organization targeting, fetch the organizations and stop if none remain to process
+	orgs, breakdown, err := resolveTargetOrganizations(enterprises, commonFlags, "")
 	if err != nil {
 		return err
 	}
-
-	pterm.Info.Printf("Using template organization: %s\n", templateOrg)
-
-	// Fetch org-level configuration names from template organization only
-	pterm.Info.Printf("Fetching security configurations from template organization '%s'...\n", templateOrg)
-	var orgConfigNames []string
-	status, err := api.CheckSingleOrganizationMembership(templateOrg)
+	probeOrg, err := extractProbeOrg(cmd, orgs)
 	if err != nil {
-		ui.LogWarningf("Could not access template organization '%s': %v", templateOrg, err)
-	} else if !status.IsMember {
-		ui.LogWarningf("You must be a member of template organization '%s' to fetch configurations", templateOrg)
-	} else if !status.IsOwner {
-		ui.LogWarningf("You must be an owner of template organization '%s' to fetch configurations", templateOrg)
-	} else {
-		configs, err := api.FetchSecurityConfigurations(templateOrg)
-		if err != nil {
-			ui.LogWarningf("Could not fetch configurations from template organization '%s': %v", templateOrg, err)
-		} else {
-			for _, config := range configs {
-				// Only add organization-level configs (not enterprise configs shown at org level)
-				if config.TargetType != "enterprise" {
-					orgConfigNames = append(orgConfigNames, config.Name)
-				}
-			}
-			if len(orgConfigNames) > 0 {
-				pterm.Success.Printf("Found %d organization security configuration(s) in template org\n", len(orgConfigNames))
-			}
-		}
-	}
-
-	// Let user select a configuration to modify
-	var configName string
-	if len(orgConfigNames) > 0 {
-		configName, err = ui.SelectConfigurationForModification(orgConfigNames, configNameFlag)
-		if err != nil {
-			return err
-		}
-	} else {
-		return fmt.Errorf("no security configurations found in template organization '%s'", templateOrg)
+		return err
 	}
 
-	// Check Dependabot availability
-	dependabotAlertsAvailable, err := ui.GetDependabotAlertsAvailability(commonFlags.DependabotAlertsAvailable)
+	// Get template organization name
+	templateOrg, err := ui.GetTemplateOrgInput(templateOrgFlag)
 	if err != nil {
 		return err
 	}
 
-	dependabotSecurityUpdatesAvailable, err := ui.GetDependabotSecurityUpdatesAvailability(commonFlags.DependabotSecurityUpdatesAvailable)
+	pterm.Info.Printf("Using template organization: %s\n", templateOrg)
+
+	// Let user select a configuration to modify
+	configName, err := pickTemplateConfiguration(templateOrg, configNameFlag, "modify", ui.GetConfigNameForModification)
 	if err != nil {
 		return err
 	}
 
-	// Fetch organizations
-	orgs, err := api.GetOrganizations(enterprise, commonFlags.Org, commonFlags.OrgListPath, commonFlags.AllOrgs)
+	// Check Dependabot availability
+	dependabotAlertsAvailable, dependabotSecurityUpdatesAvailable, err := getDependabotAvailability(commonFlags)
 	if err != nil {
 		return err
 	}
 
-	if len(orgs) == 0 {
-		ui.ShowNoOrganizationsWarning(commonFlags)
-		return nil
-	}
-
 	// Fetch existing configuration details from template organization to show current settings
 	var currentSettings map[string]interface{}
-	var currentDescription string
+	var currentDescription, currentEnforcement string
+	var currentUpdatedAt time.Time
 
-	configs, err := api.FetchSecurityConfigurations(templateOrg)
+	configs, err := api.FetchSecurityConfigurations(context.Background(), templateOrg)
 	if err != nil {
 		return fmt.Errorf("failed to fetch configurations from template org: %w", err)
 	}
@@ -251,10 +218,12 @@ func runModify(cmd *cobra.Command, args []string) error {
 	configID, found := api.FindConfigurationByName(configs, configName)
 	if found {
 		// Get detailed configuration
-		configDetails, err := api.GetSecurityConfigurationDetails(templateOrg, configID)
+		configDetails, err := api.GetSecurityConfigurationDetails(context.Background(), templateOrg, configID)
 		if err == nil {
 			currentSettings = configDetails.Settings
 			currentDescription = configDetails.Description
+			currentEnforcement = configDetails.Enforcement
+			currentUpdatedAt = configDetails.UpdatedAt
 		} else {
 			return fmt.Errorf("failed to get configuration details: %w", err)
 		}
@@ -267,7 +236,7 @@ func runModify(cmd *cobra.Command, args []string) error {
 
 	// Show current settings and get new settings
 	pterm.Info.Println("Current configuration settings:")
-	ui.DisplayCurrentSettings(currentSettings, currentDescription)
+	ui.DisplayCurrentSettings(currentSettings, currentEnforcement, currentDescription)
 	pterm.Println()
 
 	// Get new name
@@ -282,44 +251,102 @@ func runModify(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Stamp the change reference into the description so the GitHub UI shows which change last
+	// touched the configuration
+	if stampChangeRef {
+		newDescription = utils.StampChangeRef(newDescription, changeRef)
+		if err := utils.ValidateDescriptionLength(newDescription); err != nil {
+			return fmt.Errorf("description with the change reference stamp: %w", err)
+		}
+	}
+
 	// Get updated security settings
 	newSettings, err := ui.GetSecuritySettingsForUpdate(currentSettings, settingsOverrides, dependabotAlertsAvailable, dependabotSecurityUpdatesAvailable)
 	if err != nil {
 		return err
 	}
 
+	// Get updated enforcement
+	newEnforcement, err := ui.GetEnforcementForUpdate(currentEnforcement, settingsOverrides.Enforcement)
+	if err != nil {
+		return err
+	}
+
+	// Reject invalid setting values once, before any organization is processed. Only the settings
+	// being changed are checked: kept values come from the server, which may report values this
+	// extension never sends.
+	changed := make(map[string]interface{})
+	for _, key := range processors.SettingsDiff(currentSettings, newSettings) {
+		changed[key] = newSettings[key]
+	}
+	if err := utils.ValidateSettings(changed); err != nil {
+		return err
+	}
+	for key, value := range changed {
+		newSettings[key] = value
+	}
+	warnSettingDependencies(newSettings)
+
+	// Refuse to start a run that would exhaust the rate limit part way through
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "modify", Orgs: len(orgs), ConfigLookups: 1}); err != nil {
+		return err
+	}
+
+	// Stop before the confirmation when the user owns none of the targets
+	if err := requireActionableOrganizations(orgs, breakdown, commonFlags.Concurrency); err != nil {
+		return err
+	}
+
+	// Optionally find settings that are accepted but inactive on this instance
+	probeFeatureSupport, inactiveSettings, err := probeInactiveSettings(cmd, templateOrg, newSettings)
+	if err != nil {
+		return err
+	}
+
+	identity := resolveRunIdentity()
+	if err := confirmTargetCount(orgs, enterprises, breakdown, commonFlags, force); err != nil {
+		return err
+	}
+
+	// Record each organization's configuration as it is when the run is confirmed, so one changed
+	// while the run is underway is skipped instead of overwritten
+	pterm.Info.Printf("Recording the current state of '%s' in %d organization(s)...\n", configName, len(orgs))
+	expectedUpdatedAt := processors.SnapshotUpdatedAt(orgs, configName, commonFlags.Concurrency)
+	// The template organization is checked against the settings shown above
+	expectedUpdatedAt[templateOrg] = currentUpdatedAt
+
 	// Confirm before proceeding
-	confirmed, err := ui.ConfirmModifyOperation(orgs, configName, newName, currentDescription, newDescription, currentSettings, newSettings, force)
+	confirmed, err := ui.ConfirmModifyOperation(identity, orgs, configName, newName, currentDescription, newDescription, currentSettings, newSettings, currentEnforcement, newEnforcement, inactiveSettings, force)
 	if err != nil {
 		return err
 	}
 
 	if !confirmed {
 		ui.ShowOperationCancelled()
-		return nil
+		return types.ErrOperationCancelled
 	}
 
 	// Create processor for modify command
 	processor := &processors.ModifyProcessor{
-		ConfigName:     configName,
-		NewName:        newName,
-		NewDescription: newDescription,
-		NewSettings:    newSettings,
+		ConfigName:        configName,
+		NewName:           newName,
+		NewDescription:    newDescription,
+		NewSettings:       newSettings,
+		NewEnforcement:    newEnforcement,
+		ExpectedUpdatedAt: expectedUpdatedAt,
+		ForceOverwrite:    forceOverwrite,
 	}
 
 	// Process each organization - use sequential processor if delay is specified
-	var successCount, skippedCount, errorCount int
-	if commonFlags.Delay > 0 {
-		ui.ShowProcessingStartWithDelay(len(orgs), commonFlags.Delay)
-		sequentialProcessor := processors.NewSequentialProcessor(orgs, processor, commonFlags.Delay)
-		successCount, skippedCount, errorCount = sequentialProcessor.Process()
-	} else {
-		ui.ShowProcessingStart(len(orgs), commonFlags.Concurrency)
-		concurrentProcessor := processors.NewConcurrentProcessor(orgs, processor, commonFlags.Concurrency)
-		successCount, skippedCount, errorCount = concurrentProcessor.Process()
+	outcome, err := processOrganizationsWithProbe(probeOrg, orgs, breakdown, processor, commonFlags, force)
+	if err != nil {
+		return err
 	}
 
-	utils.PrintCompletionHeader("Security Configuration Modification", successCount, skippedCount, errorCount)
+	utils.PrintCompletionHeader("Security Configuration Modification", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showChangeRef(changeRef)
+	showAPICallUsage()
 
 	// Extract log level flag
 	logLevel, err := cmd.Flags().GetString("log-level")
@@ -329,12 +356,15 @@ func runModify(cmd *cobra.Command, args []string) error {
 
 	// Build and display replication command
 	replicationFlags := map[string]interface{}{
-		"enterprise-slug":                       enterprise,
+		"enterprise-slug":                       strings.Join(enterprises, ","),
 		"github-enterprise-server-url":          serverURL,
 		"template-org":                          templateOrg,
 		"dependabot-alerts-available":           fmt.Sprintf("%t", dependabotAlertsAvailable),
 		"dependabot-security-updates-available": fmt.Sprintf("%t", dependabotSecurityUpdatesAvailable),
 		"concurrency":                           commonFlags.Concurrency,
+		"max-orgs-per-minute":                   commonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":                       commonFlags.PerOrgTimeout,
+		"report-order":                          commonFlags.ReportOrder,
 		"delay":                                 commonFlags.Delay,
 		"log-level":                             logLevel,
 		"config-name":                           configName,
@@ -344,8 +374,13 @@ func runModify(cmd *cobra.Command, args []string) error {
 		"secret-scanning":                       fmt.Sprintf("%v", newSettings["secret_scanning"]),
 		"secret-scanning-push-protection":       fmt.Sprintf("%v", newSettings["secret_scanning_push_protection"]),
 		"secret-scanning-non-provider-patterns": fmt.Sprintf("%v", newSettings["secret_scanning_non_provider_patterns"]),
-		"enforcement":                           fmt.Sprintf("%v", newSettings["enforcement"]),
+		"enforcement":                           newEnforcement,
 		"skip-confirmation-message":             fmt.Sprintf("%t", force),
+		"probe":                                 probeOrg,
+		"force-overwrite":                       forceOverwrite,
+		"probe-feature-support":                 probeFeatureSupport,
+		"change-ref":                            changeRef,
+		"stamp-change-ref":                      stampChangeRef,
 	}
 	if v, ok := newSettings["dependabot_alerts"]; ok {
 		replicationFlags["dependabot-alerts"] = fmt.Sprintf("%v", v)
@@ -359,12 +394,16 @@ func runModify(cmd *cobra.Command, args []string) error {
 		replicationFlags["org"] = commonFlags.Org
 	} else if commonFlags.OrgListPath != "" {
 		replicationFlags["org-list"] = commonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = commonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = commonFlags.FailOnInvalidOrgs
 	} else if commonFlags.AllOrgs {
 		replicationFlags["all-orgs"] = true
 	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(commonFlags.ExcludeProperties)
 
 	replicationCommand := utils.BuildReplicationCommand("modify", replicationFlags)
 	utils.ShowReplicationCommand(replicationCommand)
+	showRetryCommand("modify", replicationFlags, outcome)
 
-	return nil
+	return outcomeError("security configuration modification", outcome)
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/notify"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+)
+
+// runNotification collects what the --notify-webhook summary reports as a run progresses. It is
+// reset before every command.
+var runNotification struct {
+	webhook   string
+	command   string
+	start     time.Time
+	changeRef string
+	identity  types.RunIdentity
+	outcome   *processingOutcome
+}
+
+// startNotification reads --notify-webhook and resets the collected run state
+func startNotification(cmd *cobra.Command) error {
+	webhook, err := cmd.Flags().GetString("notify-webhook")
+	if err != nil {
+		return err
+	}
+	if webhook != "" {
+		if err := notify.ValidateURL(webhook); err != nil {
+			return err
+		}
+	}
+	runNotification.webhook = webhook
+	runNotification.command = cmd.CommandPath()
+	runNotification.start = time.Now()
+	runNotification.changeRef = ""
+	runNotification.identity = types.RunIdentity{}
+	runNotification.outcome = nil
+	return nil
+}
+
+// sendNotification posts the run summary to --notify-webhook once processing has finished. Runs
+// that stopped before processing organizations send nothing. Delivery failures are reported as a
+// warning and never change the exit code.
+func sendNotification(exitCode int, err error) {
+	if runNotification.webhook == "" || runNotification.outcome == nil {
+		return
+	}
+	summary := notificationSummary(runNotification.command, *runNotification.outcome, time.Since(runNotification.start), runNotification.changeRef, runNotification.identity, exitCode, err)
+	if sendErr := notify.Send(runNotification.webhook, summary); sendErr != nil {
+		ui.LogWarningf("%v", sendErr)
+	}
+}
+
+// notificationSummary builds the webhook payload for a finished run
+func notificationSummary(command string, outcome processingOutcome, elapsed time.Duration, changeRef string, identity types.RunIdentity, exitCode int, err error) notify.Summary {
+	summary := notify.Summary{
+		Command:         command,
+		Host:            identity.Host,
+		User:            identity.User,
+		ExitCode:        exitCode,
+		ChangeRef:       changeRef,
+		Total:           outcome.Total,
+		Success:         outcome.Success,
+		Skipped:         outcome.Skipped,
+		Failed:          outcome.Error,
+		FailedOrgs:      failedOrganizations(outcome.ProcessingSummary),
+		DurationSeconds: elapsed.Round(time.Millisecond).Seconds(),
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	status := "finished"
+	if exitCode != ExitSuccess {
+		status = fmt.Sprintf("finished with exit code %d", exitCode)
+	}
+	summary.Text = fmt.Sprintf("%s %s: %d succeeded, %d skipped, %d failed of %d organizations in %s",
+		command, status, summary.Success, summary.Skipped, summary.Failed, summary.Total, elapsed.Round(time.Second))
+	if changeRef != "" {
+		summary.Text += fmt.Sprintf(" (change %s)", changeRef)
+	}
+	return summary
+}
+
+// failedOrganizations returns the organizations whose processing failed
+func failedOrganizations(summary types.ProcessingSummary) []string {
+	var orgs []string
+	for _, result := range summary.Results {
+		if result.Error != nil {
+			orgs = append(orgs, result.Organization)
+		}
+	}
+	return orgs
+}
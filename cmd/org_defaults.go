@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var orgDefaultsCmd = &cobra.Command{
+	Use:   "org-defaults",
+	Short: "Report and clear default security configurations per organization",
+	Long:  "Commands to report which security configuration new repositories receive by default in each organization, and to clear those defaults",
+}
+
+var orgDefaultsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the default security configurations of each organization",
+	Long:  "Show, per organization, which security configuration is the default for new public repositories and for new private/internal repositories",
+	RunE:  runOrgDefaultsList,
+}
+
+var orgDefaultsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the default security configurations of each organization",
+	Long:  "Set the default security configuration for new repositories to none in each targeted organization",
+	RunE:  runOrgDefaultsClear,
+}
+
+func init() {
+	orgDefaultsListCmd.Flags().Bool("include-config-id", false, "Add each default configuration's ID next to its name, for scripts that call the API by ID")
+	orgDefaultsListCmd.Flags().Bool("no-request-cache", false, "Send every read request to the API instead of reusing responses already fetched during this run")
+
+	orgDefaultsCmd.AddCommand(orgDefaultsListCmd)
+	orgDefaultsCmd.AddCommand(orgDefaultsClearCmd)
+}
+
+func runOrgDefaultsList(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("GitHub Security Configuration Defaults")
+	pterm.Println()
+
+	includeConfigID, err := cmd.Flags().GetBool("include-config-id")
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveCommandTargets(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "org-defaults list", Orgs: len(targets.Orgs)}); err != nil {
+		return err
+	}
+
+	noRequestCache, err := enableRequestCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	processor := &processors.OrgDefaultsListProcessor{}
+	outcome := processOrganizations(targets.Orgs, targets.Breakdown, processor, targets.CommonFlags)
+
+	utils.PrintCompletionHeader("Default Configuration Report", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showAPICallUsage()
+	showRequestCacheStats()
+	pterm.Println()
+	if err := renderOrgDefaultsTable(processor.Defaults(), includeConfigID); err != nil {
+		return err
+	}
+
+	replicationFlags, err := targetReplicationFlags(cmd, targets)
+	if err != nil {
+		return err
+	}
+	if includeConfigID {
+		replicationFlags["include-config-id"] = true
+	}
+	if noRequestCache {
+		replicationFlags["no-request-cache"] = true
+	}
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("org-defaults list", replicationFlags))
+	showRetryCommand("org-defaults list", replicationFlags, outcome)
+
+	return outcomeError("default configuration report", outcome)
+}
+
+// renderOrgDefaultsTable prints one row per organization, sorted by name. includeConfigID adds
+// each default configuration's ID next to its name.
+func renderOrgDefaultsTable(defaults map[string]types.OrgDefaults, includeConfigID bool) error {
+	if len(defaults) == 0 {
+		ui.LogWarningf("No organization defaults could be read.")
+		return nil
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(orgDefaultsTable(defaults, includeConfigID)).Render()
+}
+
+// orgDefaultsTable returns the rows of the defaults table under a header row
+func orgDefaultsTable(defaults map[string]types.OrgDefaults, includeConfigID bool) pterm.TableData {
+	orgs := make([]string, 0, len(defaults))
+	for org := range defaults {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	tableData := pterm.TableData{{"Organization", "Public repositories", "Private/internal repositories"}}
+	for _, org := range orgs {
+		tableData = append(tableData, []string{
+			org,
+			defaultNameOrNone(defaults[org].Public, defaults[org].PublicID, includeConfigID),
+			defaultNameOrNone(defaults[org].PrivateAndInternal, defaults[org].PrivateAndInternalID, includeConfigID),
+		})
+	}
+	return tableData
+}
+
+// defaultNameOrNone returns the configuration name, followed by its ID when includeConfigID is
+// set, or "none" when no default is set
+func defaultNameOrNone(name string, id int, includeConfigID bool) string {
+	if name == "" {
+		return "none"
+	}
+	if includeConfigID {
+		return fmt.Sprintf("%s (ID %d)", name, id)
+	}
+	return name
+}
+
+func runOrgDefaultsClear(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("GitHub Security Configuration Defaults Cleaner")
+	pterm.Println()
+
+	force, err := extractSkipConfirmationFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveCommandTargets(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRateLimitBudget(cmd, utils.RequestBudgetInput{Command: "org-defaults clear", Orgs: len(targets.Orgs)}); err != nil {
+		return err
+	}
+
+	// Stop before the prompt when the user owns none of the targets
+	if err := requireActionableOrganizations(targets.Orgs, targets.Breakdown, targets.CommonFlags.Concurrency); err != nil {
+		return err
+	}
+
+	identity := resolveRunIdentity()
+	if err := confirmTargetCount(targets.Orgs, targets.Enterprises, targets.Breakdown, targets.CommonFlags, force); err != nil {
+		return err
+	}
+
+	confirmed, err := ui.ConfirmClearDefaultsOperation(identity, targets.Orgs, force)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		ui.ShowOperationCancelled()
+		return types.ErrOperationCancelled
+	}
+
+	outcome := processOrganizations(targets.Orgs, targets.Breakdown, &processors.OrgDefaultsClearProcessor{}, targets.CommonFlags)
+
+	utils.PrintCompletionHeader("Default Configuration Clearing", outcome.Success, outcome.Skipped, outcome.Error)
+	utils.PrintElapsedTime(start)
+	showAPICallUsage()
+
+	replicationFlags, err := targetReplicationFlags(cmd, targets)
+	if err != nil {
+		return err
+	}
+	replicationFlags["skip-confirmation-message"] = fmt.Sprintf("%t", force)
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("org-defaults clear", replicationFlags))
+	showRetryCommand("org-defaults clear", replicationFlags, outcome)
+
+	return outcomeError("default configuration clearing", outcome)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestOrgDefaultsTable_IncludeConfigID(t *testing.T) {
+	defaults := map[string]types.OrgDefaults{"org-a": {Public: "Baseline", PublicID: 17}}
+
+	tests := []struct {
+		name            string
+		includeConfigID bool
+		wantRow         []string
+	}{
+		{name: "names only", wantRow: []string{"org-a", "Baseline", "none"}},
+		{name: "with --include-config-id", includeConfigID: true, wantRow: []string{"org-a", "Baseline (ID 17)", "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := orgDefaultsTable(defaults, tt.includeConfigID)
+			if len(table) != 2 {
+				t.Fatalf("table has %d rows, want a header and one row", len(table))
+			}
+			if !slices.Equal(table[1], tt.wantRow) {
+				t.Errorf("row = %q, want %q", table[1], tt.wantRow)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/pflag"
+
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// outputFlagConflict names two output flags that cannot be used together and explains why.
+// FirstValues limits the conflict to those values of First; without them any value conflicts.
+type outputFlagConflict struct {
+	First       string
+	FirstValues []string
+	Second      string
+	Reason      string
+}
+
+// structuredDriftOutputs are the drift --output formats that write no table
+var structuredDriftOutputs = []string{utils.DriftOutputJSON, utils.DriftOutputYAML}
+
+// outputFlagConflicts lists the output flag combinations rejected before any command runs. A
+// conflict only applies when both flags are defined on the command being run. Commands that add
+// an output flag register its conflicts here, so every command rejects them the same way.
+var outputFlagConflicts = []outputFlagConflict{
+	{First: "color", FirstValues: []string{ui.ColorNever}, Second: "high-contrast", Reason: "the high-contrast palette is a set of colors, which --color never turns off"},
+	{First: "output", FirstValues: structuredDriftOutputs, Second: "include-config-id", Reason: "the column is only added to the table; JSON and YAML always include configuration_id"},
+	{First: "output", FirstValues: structuredDriftOutputs, Second: "no-emoji", Reason: "setting values are only marked with symbols in the table"},
+	{First: "output", FirstValues: structuredDriftOutputs, Second: "high-contrast", Reason: "setting values are only colored in the table"},
+}
+
+// checkOutputFlagConflicts returns an error naming the first pair in conflicts whose flags were
+// both set on the command line
+func checkOutputFlagConflicts(flags *pflag.FlagSet, conflicts []outputFlagConflict) error {
+	for _, conflict := range conflicts {
+		first, second := flags.Lookup(conflict.First), flags.Lookup(conflict.Second)
+		if first == nil || second == nil || !first.Changed || !second.Changed {
+			continue
+		}
+		if len(conflict.FirstValues) == 0 {
+			return fmt.Errorf("--%s and --%s cannot be used together: %s", conflict.First, conflict.Second, conflict.Reason)
+		}
+		if value := first.Value.String(); slices.Contains(conflict.FirstValues, value) {
+			return fmt.Errorf("--%s %s and --%s cannot be used together: %s", conflict.First, value, conflict.Second, conflict.Reason)
+		}
+	}
+	return nil
+}
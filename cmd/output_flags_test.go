@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCheckOutputFlagConflicts(t *testing.T) {
+	conflicts := []outputFlagConflict{
+		{First: "output", Second: "live-view", Reason: "structured output cannot be mixed with the live view"},
+		{First: "quiet", Second: "verbose", Reason: "they set opposite verbosity"},
+		{First: "output", Second: "undefined", Reason: "never applies"},
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no flags"},
+		{name: "one flag of a pair", args: []string{"--output", "json"}},
+		{name: "flags from different pairs", args: []string{"--output", "json", "--quiet"}},
+		{name: "output with live view", args: []string{"--output", "json", "--live-view"}, wantErr: "--output and --live-view cannot be used together"},
+		{name: "quiet with verbose", args: []string{"--verbose", "--quiet"}, wantErr: "--quiet and --verbose cannot be used together"},
+		{name: "flag set to its default still counts", args: []string{"--quiet=false", "--verbose"}, wantErr: "--quiet and --verbose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("output", "", "")
+			flags.Bool("live-view", false, "")
+			flags.Bool("quiet", false, "")
+			flags.Bool("verbose", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkOutputFlagConflicts(flags, conflicts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkOutputFlagConflicts() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkOutputFlagConflicts() = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOutputFlagConflicts_Registered(t *testing.T) {
+	// Every registered flag must exist, or its conflict would never apply
+	for _, conflict := range outputFlagConflicts {
+		for _, name := range []string{conflict.First, conflict.Second} {
+			if driftCmd.Flags().Lookup(name) == nil && rootCmd.PersistentFlags().Lookup(name) == nil {
+				t.Errorf("conflict %s/%s names unknown flag --%s", conflict.First, conflict.Second, name)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "table output with table flags", args: []string{"--output", "table", "--include-config-id", "--no-emoji", "--high-contrast"}},
+		{name: "json output alone", args: []string{"--output", "json"}},
+		{name: "color always with high contrast", args: []string{"--color", "always", "--high-contrast"}},
+		{name: "color never without high contrast", args: []string{"--color", "never", "--no-emoji"}},
+		{name: "color never with high contrast", args: []string{"--color", "never", "--high-contrast"}, wantErr: "--color never and --high-contrast cannot be used together"},
+		{name: "json output with config ID column", args: []string{"--output", "json", "--include-config-id"}, wantErr: "--output json and --include-config-id"},
+		{name: "yaml output with no emoji", args: []string{"--output", "yaml", "--no-emoji"}, wantErr: "--output yaml and --no-emoji"},
+		{name: "yaml output with high contrast", args: []string{"--output", "yaml", "--high-contrast"}, wantErr: "--output yaml and --high-contrast"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A stand-in for drift's flags, so parsing does not change the real commands
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("color", "auto", "")
+			flags.String("output", "table", "")
+			flags.Bool("no-emoji", false, "")
+			flags.Bool("high-contrast", false, "")
+			flags.Bool("include-config-id", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkOutputFlagConflicts(flags, outputFlagConflicts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkOutputFlagConflicts() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkOutputFlagConflicts() = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// probeOrgFlagUsage describes --probe for every command that accepts it
+const probeOrgFlagUsage = "Process this target organization alone first, show its result and API responses, and ask before processing the others; nothing else is touched if it does not succeed"
+
+// extractProbeOrg reads --probe and checks that it names one of orgs, ignoring case as GitHub does.
+// It returns the organization as targeted, or "" when --probe is not set.
+func extractProbeOrg(cmd *cobra.Command, orgs []string) (string, error) {
+	probeOrg, err := cmd.Flags().GetString("probe")
+	if err != nil {
+		return "", err
+	}
+	probeOrg = strings.TrimSpace(probeOrg)
+	if probeOrg == "" {
+		return "", nil
+	}
+	for _, org := range orgs {
+		if strings.EqualFold(org, probeOrg) {
+			return org, nil
+		}
+	}
+	return "", fmt.Errorf("--probe organization '%s' is not one of the %d target organizations", probeOrg, len(orgs))
+}
+
+// processOrganizationsWithProbe processes probeOrg on its own, shows its result and the API
+// responses to its requests, and asks before processing the other organizations with the same
+// processor. When the probe does not succeed, the other organizations are left untouched and the
+// run stops with a types.StopConditionError; declining the prompt stops it with
+// types.ErrOperationCancelled. Either way the organizations not reached are reported as
+// unprocessed, so the retry command covers them. Without a probe organization it is processOrganizations.
+func processOrganizationsWithProbe(probeOrg string, orgs []string, breakdown types.TargetBreakdown, processor processors.OrganizationProcessor, commonFlags *utils.CommonFlags, skipConfirm bool) (processingOutcome, error) {
+	if probeOrg == "" {
+		return processOrganizations(orgs, breakdown, processor, commonFlags), nil
+	}
+
+	var rest []string
+	for _, org := range orgs {
+		if org != probeOrg {
+			rest = append(rest, org)
+		}
+	}
+
+	ui.ShowProbeStart(probeOrg, len(rest))
+	var responses bytes.Buffer
+	api.SetResponseSink(&responses)
+	result := processors.ProcessProbeOrganization(processor, probeOrg, commonFlags.PerOrgTimeout)
+	api.SetResponseSink(nil)
+	ui.ShowProbeResult(result, responses.String())
+
+	var stopErr error
+	if !result.Success {
+		stopErr = &types.StopConditionError{Reason: fmt.Sprintf("the --probe organization '%s' did not succeed, so no other organization was processed", probeOrg)}
+	} else if len(rest) > 0 {
+		confirmed, err := ui.ConfirmContinueAfterProbe(probeOrg, len(rest), skipConfirm)
+		if err != nil {
+			return processingOutcome{}, err
+		}
+		if !confirmed {
+			ui.ShowOperationCancelled()
+			stopErr = types.ErrOperationCancelled
+		}
+	}
+
+	outcome := processingOutcome{StopErr: stopErr}
+	if stopErr == nil && len(rest) > 0 {
+		outcome = dispatchOrganizations(rest, processor, commonFlags)
+	}
+	outcome.Total = len(orgs)
+
+	summary := types.ProcessingSummary{}
+	summary.Add(result)
+	for _, r := range outcome.Results {
+		summary.Add(r)
+	}
+	if stopErr != nil {
+		for _, org := range rest {
+			summary.Add(types.ProcessingResult{Organization: org, Skipped: true, Unprocessed: true, SkipReason: fmt.Sprintf("Skipping organization '%s': processing stopped after the --probe organization", org)})
+		}
+	}
+	outcome.ProcessingSummary = summary
+	return finishProcessing(outcome, orgs, breakdown, commonFlags), nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// recordingProcessor records the organizations it processes and fails those listed in failing
+type recordingProcessor struct {
+	mu        sync.Mutex
+	processed []string
+	failing   map[string]bool
+}
+
+func (p *recordingProcessor) ProcessOrganization(_ context.Context, org string) types.ProcessingResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed = append(p.processed, org)
+	if p.failing[org] {
+		return types.ProcessingResult{Organization: org, Error: errors.New("HTTP 422")}
+	}
+	return types.ProcessingResult{Organization: org, Success: true}
+}
+
+func TestExtractProbeOrg(t *testing.T) {
+	orgs := []string{"org-a", "Sandbox", "org-b"}
+	tests := []struct {
+		name    string
+		probe   string
+		want    string
+		wantErr bool
+	}{
+		{name: "not set", probe: "", want: ""},
+		{name: "target organization", probe: "org-b", want: "org-b"},
+		{name: "matched ignoring case", probe: "sandbox", want: "Sandbox"},
+		{name: "not a target", probe: "elsewhere", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("probe", "", "")
+			if err := cmd.Flags().Set("probe", tt.probe); err != nil {
+				t.Fatal(err)
+			}
+			got, err := extractProbeOrg(cmd, orgs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractProbeOrg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractProbeOrg() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessOrganizationsWithProbe(t *testing.T) {
+	orgs := []string{"org-a", "sandbox", "org-b"}
+	tests := []struct {
+		name          string
+		failing       map[string]bool
+		wantProcessed []string
+		wantSuccess   int
+		wantErrors    int
+		wantStop      bool
+	}{
+		{
+			name:          "probe succeeds and the rest follow",
+			wantProcessed: []string{"sandbox", "org-a", "org-b"},
+			wantSuccess:   3,
+		},
+		{
+			name:          "failed probe leaves the rest untouched",
+			failing:       map[string]bool{"sandbox": true},
+			wantProcessed: []string{"sandbox"},
+			wantErrors:    1,
+			wantStop:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := &recordingProcessor{failing: tt.failing}
+			outcome, err := processOrganizationsWithProbe("sandbox", orgs, types.TargetBreakdown{}, processor, &utils.CommonFlags{Concurrency: 1}, true)
+			if err != nil {
+				t.Fatalf("processOrganizationsWithProbe() error = %v", err)
+			}
+
+			if processor.processed[0] != "sandbox" {
+				t.Errorf("first processed organization = %s, want the probe", processor.processed[0])
+			}
+			if !slices.Equal(processor.processed, tt.wantProcessed) {
+				t.Errorf("processed = %v, want %v", processor.processed, tt.wantProcessed)
+			}
+			if outcome.Total != len(orgs) || len(outcome.Results) != len(orgs) {
+				t.Errorf("outcome covers %d of %d results, total %d; want every target", len(outcome.Results), len(orgs), outcome.Total)
+			}
+			if outcome.Success != tt.wantSuccess || outcome.Error != tt.wantErrors {
+				t.Errorf("outcome = %d succeeded, %d failed; want %d and %d", outcome.Success, outcome.Error, tt.wantSuccess, tt.wantErrors)
+			}
+
+			var stopErr *types.StopConditionError
+			if errors.As(outcomeError("test", outcome), &stopErr) != tt.wantStop {
+				t.Errorf("outcomeError() = %v, want a stop condition: %t", outcomeError("test", outcome), tt.wantStop)
+			}
+			if tt.wantStop && !slices.Equal(retryOrganizations(outcome.ProcessingSummary), orgs) {
+				t.Errorf("retryOrganizations() = %v, want every target", retryOrganizations(outcome.ProcessingSummary))
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/telemetry"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// processingOutcome captures the summary produced by a processor run and the error, if any, that
+// aborted the run before every organization was handled.
+type processingOutcome struct {
+	types.ProcessingSummary
+	Total   int
+	StopErr error
+
+	// --org-list rows skipped for an invalid organization name, and whether they fail the run
+	InvalidOrgList    *types.InvalidOrgListError
+	FailOnInvalidOrgs bool
+
+	// Whether failed --post-hook commands leave the exit code alone
+	IgnoreHookFailures bool
+}
+
+// processOrganizations runs the processor across the given organizations, which target resolution
+// reached as breakdown records. The sequential processor is used when a delay is configured;
+// otherwise the concurrent processor is used, paced by --max-orgs-per-minute when set.
+func processOrganizations(orgs []string, breakdown types.TargetBreakdown, processor processors.OrganizationProcessor, commonFlags *utils.CommonFlags) processingOutcome {
+	return finishProcessing(dispatchOrganizations(orgs, processor, commonFlags), orgs, breakdown, commonFlags)
+}
+
+// dispatchOrganizations hands orgs to the sequential or concurrent processor and returns their results
+func dispatchOrganizations(orgs []string, processor processors.OrganizationProcessor, commonFlags *utils.CommonFlags) processingOutcome {
+	outcome := processingOutcome{Total: len(orgs)}
+	if commonFlags.Delay > 0 {
+		ui.ShowProcessingStartWithDelay(len(orgs), commonFlags.Delay)
+		sequentialProcessor := processors.NewSequentialProcessor(orgs, processor, commonFlags.Delay).WithOrgTimeout(commonFlags.PerOrgTimeout)
+		outcome.ProcessingSummary = sequentialProcessor.Process()
+		outcome.StopErr = sequentialProcessor.StopError()
+	} else {
+		ui.ShowProcessingStart(len(orgs), commonFlags.Concurrency)
+		concurrentProcessor := processors.NewConcurrentProcessor(orgs, processor, commonFlags.Concurrency).WithOrgTimeout(commonFlags.PerOrgTimeout)
+		if commonFlags.MaxOrgsPerMinute > 0 {
+			concurrentProcessor.WithRateLimiter(utils.NewRateLimiter(commonFlags.MaxOrgsPerMinute))
+		}
+		outcome.ProcessingSummary = concurrentProcessor.Process()
+		outcome.StopErr = concurrentProcessor.StopError()
+	}
+	return outcome
+}
+
+// finishProcessing orders the results of a run over orgs and reports what applies to the run as a
+// whole: enterprise totals, SAML single sign-on hints and the --org-list rows breakdown records as skipped
+func finishProcessing(outcome processingOutcome, orgs []string, breakdown types.TargetBreakdown, commonFlags *utils.CommonFlags) processingOutcome {
+	if commonFlags.ReportOrder != utils.ReportOrderCompletion {
+		processors.SortResultsByInput(&outcome.ProcessingSummary, orgs)
+	}
+	if len(breakdown.OrgEnterprises) > 0 {
+		for i := range outcome.Results {
+			outcome.Results[i].Enterprise = breakdown.OrgEnterprises[outcome.Results[i].Organization]
+		}
+		ui.ShowEnterpriseTotals(outcome.ProcessingSummary)
+	}
+	ui.ShowErrorGroups(processors.GroupErrors(outcome.Results))
+	ui.ShowHookFailures(processors.HookFailures(outcome.Results))
+	ui.ShowSSOHint(ssoRequiredOrganizations(outcome.Results), api.CurrentHostName())
+	if invalid := breakdown.InvalidEntries; len(invalid) > 0 {
+		outcome.InvalidOrgList = &types.InvalidOrgListError{Path: commonFlags.OrgListPath, Entries: invalid}
+		outcome.FailOnInvalidOrgs = commonFlags.FailOnInvalidOrgs
+		ui.ShowInvalidOrgEntries(commonFlags.OrgListPath, invalid)
+	}
+	runNotification.outcome = &outcome
+	return outcome
+}
+
+// outcomeError converts a processing outcome into the error returned from a command's RunE so
+// that the process exits with the matching exit code. A nil error means every organization was
+// processed successfully or skipped, no --post-hook command failed unless --ignore-hook-failures
+// is set, and, with --fail-on-invalid-orgs, no --org-list row was skipped.
+func outcomeError(operation string, outcome processingOutcome) error {
+	if outcome.StopErr != nil {
+		return outcome.StopErr
+	}
+	if outcome.Error > 0 {
+		return &types.PartialFailureError{Operation: operation, Failed: outcome.Error, Total: outcome.Total}
+	}
+	if failed := len(processors.HookFailures(outcome.Results)); failed > 0 && !outcome.IgnoreHookFailures {
+		return &types.HookFailureError{Failed: failed, Total: outcome.Total}
+	}
+	if outcome.FailOnInvalidOrgs && outcome.InvalidOrgList != nil {
+		return outcome.InvalidOrgList
+	}
+	return nil
+}
+
+// checkRateLimitBudget compares the estimated request budget of a run against the remaining core
+// rate limit. A run that would exhaust the limit is refused unless --ignore-rate-limit-budget is
+// set, in which case only a warning is shown. Servers with rate limiting disabled always pass.
+func checkRateLimitBudget(cmd *cobra.Command, budget utils.RequestBudgetInput) error {
+	ignoreBudget, err := cmd.Flags().GetBool("ignore-rate-limit-budget")
+	if err != nil {
+		return err
+	}
+
+	rateLimit, err := api.GetRateLimit()
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if rateLimit == nil {
+		return nil
+	}
+
+	estimate := utils.EstimateRequestBudget(budget)
+	if estimate <= rateLimit.Remaining {
+		ui.LogInfof("Estimated API requests: %d (rate limit remaining: %d)", estimate, rateLimit.Remaining)
+		return nil
+	}
+
+	resetIn := time.Until(rateLimit.Reset).Round(time.Second)
+	message := fmt.Sprintf("this run needs up to %d API requests but only %d of %d remain; the rate limit resets at %s (in %s)",
+		estimate, rateLimit.Remaining, rateLimit.Limit, rateLimit.Reset.Format(time.Kitchen), resetIn)
+	if ignoreBudget {
+		ui.LogWarningf("Rate limit budget: %s. Continuing because --ignore-rate-limit-budget is set.", message)
+		return nil
+	}
+	return &types.StopConditionError{Reason: fmt.Sprintf("%s. Wait for the reset, target fewer organizations, or pass --ignore-rate-limit-budget", message)}
+}
+
+// retryOrganizations returns the organizations a retry should target: those that failed and
+// those never reached because processing stopped early
+func retryOrganizations(summary types.ProcessingSummary) []string {
+	var orgs []string
+	for _, result := range summary.Results {
+		if result.Error != nil || result.Unprocessed {
+			orgs = append(orgs, result.Organization)
+		}
+	}
+	return orgs
+}
+
+// ssoRequiredOrganizations returns the organizations that failed because the token is not
+// authorized for their SAML single sign-on
+func ssoRequiredOrganizations(results []types.ProcessingResult) []string {
+	var orgs []string
+	for _, result := range results {
+		var ssoErr *types.SSORequiredError
+		if errors.As(result.Error, &ssoErr) {
+			orgs = append(orgs, result.Organization)
+		}
+	}
+	return orgs
+}
+
+// showRetryCommand writes the organizations that need a retry to a temporary CSV file and prints
+// a command that repeats the run, with the same resolved flags, for just those organizations
+func showRetryCommand(command string, replicationFlags map[string]interface{}, outcome processingOutcome) {
+	orgs := retryOrganizations(outcome.ProcessingSummary)
+	if len(orgs) == 0 {
+		return
+	}
+
+	csvPath, err := utils.WriteOrganizationsToCSV(orgs, "gh-sc-failed-*.csv")
+	if err != nil {
+		ui.LogWarningf("Could not write the failed organizations for a retry command: %v", err)
+		return
+	}
+	utils.ShowRetryCommand(utils.BuildRetryCommand(command, replicationFlags, csvPath), len(orgs))
+}
+
+// resolveTargetOrganizations settles the organization targeting and computes the final target set
+// with api.ResolveTargets, prompting for the targeting only when the run is interactive, and
+// summarizes them. It returns the targets with the breakdown of how they were reached, which the
+// access check and the end of the run report from. Every command calls it once, so all of them
+// stop the same way, with a types.NoTargetsError, when no organizations remain, and do so before
+// prompting for anything else.
+func resolveTargetOrganizations(enterprises []string, commonFlags *utils.CommonFlags, sourceOrg string) ([]string, types.TargetBreakdown, error) {
+	targets, breakdown, err := api.ResolveTargets(commonFlags, api.TargetRequest{
+		Enterprises: enterprises,
+		SourceOrg:   sourceOrg,
+		Interactive: ui.Interactive(),
+		Prompts:     ui.TargetPrompts(),
+	})
+	if err != nil {
+		return nil, breakdown, err
+	}
+	ui.ShowTargetSummary(targets, breakdown)
+	return targets, breakdown, nil
+}
+
+// resolveRunIdentity returns the host and authenticated user the confirmation summary shows, and
+// records them for the --notify-webhook summary. A failed user lookup leaves the user empty rather
+// than stopping the run, as the token may still be able to make the requested changes.
+func resolveRunIdentity() types.RunIdentity {
+	identity := types.RunIdentity{Host: api.CurrentHostName()}
+	user, err := api.AuthenticatedUser()
+	if err != nil {
+		ui.LogInfof("Could not look up the authenticated user: %v", err)
+	}
+	identity.User = user
+	runNotification.identity = identity
+	return identity
+}
+
+// confirmTargetCount shows the --all-orgs target count, per enterprise when breakdown records
+// several, and, for runs above ui.LargeRunThreshold, requires the count to be typed before the
+// operation summary is shown
+func confirmTargetCount(orgs, enterprises []string, breakdown types.TargetBreakdown, commonFlags *utils.CommonFlags, skipConfirm bool) error {
+	confirmed, err := ui.ConfirmEnterpriseTargetCount(len(orgs), enterprises, breakdown.EnterpriseCounts(orgs), commonFlags.AllOrgs, skipConfirm)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		ui.ShowOperationCancelled()
+		return types.ErrOperationCancelled
+	}
+	return nil
+}
+
+// prefetchMemberships looks up the user's membership in each organization ahead of processing. It
+// is a variable so tests do not call the API.
+var prefetchMemberships = api.PrefetchMemberships
+
+// getDependabotAvailability reads whether Dependabot alerts and security updates are available
+// from the --dependabot-*-available flags, prompting for each one not given. Commands call it only
+// after resolveTargetOrganizations, so a run left with no targets stops without asking.
+func getDependabotAvailability(commonFlags *utils.CommonFlags) (bool, bool, error) {
+	alerts, err := ui.GetDependabotAlertsAvailability(commonFlags.DependabotAlertsAvailable)
+	if err != nil {
+		return false, false, err
+	}
+	securityUpdates, err := ui.GetDependabotSecurityUpdatesAvailability(commonFlags.DependabotSecurityUpdatesAvailable)
+	if err != nil {
+		return false, false, err
+	}
+	return alerts, securityUpdates, nil
+}
+
+// requireActionableOrganizations checks the user's membership in every target organization before
+// the confirmation prompt. Commands call it after checkRateLimitBudget, whose estimate counts these
+// lookups: they are cached, so processing does not repeat them. When the user owns none of the
+// organizations, the run stops with a types.NoTargetsError carrying breakdown instead of asking to
+// confirm a run that would skip every organization. Organizations whose membership could not be
+// read count as actionable, so processing reports them.
+func requireActionableOrganizations(orgs []string, breakdown types.TargetBreakdown, concurrency int) error {
+	pterm.Info.Printf("Checking access to %d organization(s)...\n", len(orgs))
+	statuses := prefetchMemberships(orgs, concurrency)
+	notManageable := 0
+	for _, org := range orgs {
+		status, known := statuses[org]
+		if !known || status.Problem() == "" {
+			return nil
+		}
+		notManageable++
+	}
+
+	breakdown.NotManageable = notManageable
+	breakdown.Remaining = 0
+	return &types.NoTargetsError{Breakdown: breakdown}
+}
+
+// warnSettingDependencies warns about each enabled setting whose prerequisite setting is disabled
+func warnSettingDependencies(settings map[string]interface{}) {
+	for _, warning := range utils.SettingDependencyWarnings(settings) {
+		ui.LogWarningf("%s", warning)
+	}
+}
+
+// enableRequestCache turns on the API request cache for a read-only command unless
+// --no-request-cache is set. It returns the flag's value so it can be replicated.
+func enableRequestCache(cmd *cobra.Command) (bool, error) {
+	noRequestCache, err := cmd.Flags().GetBool("no-request-cache")
+	if err != nil {
+		return false, err
+	}
+	if !noRequestCache {
+		api.EnableRequestCache()
+	}
+	return noRequestCache, nil
+}
+
+// withConfigIDColumn appends cell to a table row of a read-only command when --include-config-id is set
+func withConfigIDColumn(row []string, cell string, includeConfigID bool) []string {
+	if !includeConfigID {
+		return row
+	}
+	return append(row, cell)
+}
+
+// configIDCell formats a configuration ID for a table, leaving it blank when the ID is unknown
+func configIDCell(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// recordChangeRef notes the run's change reference, when one was given, in the trace and the
+// --notify-webhook summary so the run can be traced back to its ticket
+func recordChangeRef(changeRef string) {
+	runNotification.changeRef = changeRef
+	if changeRef != "" {
+		telemetry.SetRunAttribute(telemetry.AttrChangeRef, changeRef)
+	}
+}
+
+// showChangeRef prints the run's change reference after the summary, when one was given
+func showChangeRef(changeRef string) {
+	if changeRef != "" {
+		pterm.Info.Printf("Change reference: %s\n", changeRef)
+	}
+}
+
+// showAPICallUsage reports how many API calls the run made, broken down by HTTP method
+func showAPICallUsage() {
+	count, limit := api.APICallCount()
+	if count == 0 {
+		return
+	}
+	pterm.Info.Println(apiCallUsageLine(count, limit, api.APICallCountByMethod()))
+}
+
+// apiCallUsageLine formats the API call summary, e.g.
+// "API calls: 12 (GET 9, PATCH 2, DELETE 1) of 50 allowed by --max-api-calls"
+func apiCallUsageLine(count, limit int, byMethod map[string]int) string {
+	var parts []string
+	for _, method := range []string{"GET", "POST", "PATCH", "PUT", "DELETE"} {
+		if n := byMethod[method]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", method, n))
+		}
+	}
+	line := fmt.Sprintf("API calls: %d (%s)", count, strings.Join(parts, ", "))
+	if limit > 0 {
+		line += fmt.Sprintf(" of %d allowed by --max-api-calls", limit)
+	}
+	return line
+}
+
+// showDefaultCounts reports, when --set-default was given, in how many organizations the run set
+// the configuration as default and in how many it already was
+func showDefaultCounts(results []types.ProcessingResult) {
+	if line := defaultCountsLine(results); line != "" {
+		pterm.Info.Println(line)
+	}
+}
+
+// defaultCountsLine formats the default summary, e.g. "Default configuration: newly set in 3
+// organizations, already default in 5". It is empty when no result records a default.
+func defaultCountsLine(results []types.ProcessingResult) string {
+	newlySet, alreadyDefault := 0, 0
+	for _, result := range results {
+		switch {
+		case result.DefaultSet == nil:
+		case *result.DefaultSet:
+			newlySet++
+		default:
+			alreadyDefault++
+		}
+	}
+	if newlySet+alreadyDefault == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Default configuration: newly set in %d organizations, already default in %d", newlySet, alreadyDefault)
+}
+
+// showCompliantCount prints how many organizations were skipped because they already had the
+// configuration with the requested settings, when any were
+func showCompliantCount(results []types.ProcessingResult) {
+	if line := compliantCountLine(results); line != "" {
+		pterm.Info.Println(line)
+	}
+}
+
+// compliantCountLine formats the compliant count, e.g. "Already compliant: 3 of 5 skipped
+// organizations had the configuration with the requested settings". It is empty when none did.
+func compliantCountLine(results []types.ProcessingResult) string {
+	compliant, skipped := 0, 0
+	for _, result := range results {
+		if result.Skipped {
+			skipped++
+		}
+		if result.AlreadyCompliant {
+			compliant++
+		}
+	}
+	if compliant == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Already compliant: %d of %d skipped organizations had the configuration with the requested settings", compliant, skipped)
+}
+
+// showRequestCacheStats reports, at info level, how many read requests the cache answered
+func showRequestCacheStats() {
+	hits, misses := api.RequestCacheStats()
+	if hits+misses == 0 {
+		return
+	}
+	ui.LogInfof("Request cache: %d of %d read requests served from cache", hits, hits+misses)
+}
+
+// probeInactiveSettings runs the opt-in feature probe in referenceOrg when --probe-feature-support
+// is set. It returns whether the probe was requested, so it can be replicated, and the settings
+// found to be inactive. A failed probe is reported as a warning and leaves the summary unannotated.
+func probeInactiveSettings(cmd *cobra.Command, referenceOrg string, settings map[string]interface{}) (bool, []string, error) {
+	probe, err := cmd.Flags().GetBool("probe-feature-support")
+	if err != nil || !probe {
+		return false, nil, err
+	}
+
+	pterm.Info.Printf("Probing feature support with a scratch configuration in '%s'...\n", referenceOrg)
+	inactive, err := processors.ProbeFeatureSupport(referenceOrg, settings)
+	if err != nil {
+		ui.LogWarningf("Feature probe failed, settings will not be annotated: %v", err)
+		return true, nil, nil
+	}
+	if len(inactive) > 0 {
+		ui.LogWarningf("Settings accepted but inactive on this instance: %s", strings.Join(inactive, ", "))
+	}
+	return true, inactive, nil
+}
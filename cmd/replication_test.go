@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// runTargetingFlags returns the run and targeting replication flags the commands that build their
+// replication flags inline set, each to a value that is printed
+func runTargetingFlags() map[string]interface{} {
+	return map[string]interface{}{
+		"enterprise-slug":              "acme",
+		"github-enterprise-server-url": "github.example.com",
+		"org":                          "octo",
+		"org-list":                     "orgs.csv",
+		"strict-org-list":              true,
+		"fail-on-invalid-orgs":         true,
+		"all-orgs":                     true,
+		"exclude-with-property":        "managed=external",
+		"concurrency":                  4,
+		"delay":                        2,
+		"max-orgs-per-minute":          30,
+		"per-org-timeout":              10 * time.Minute,
+		"report-order":                 utils.ReportOrderCompletion,
+		"log-level":                    "debug",
+	}
+}
+
+// withFlags returns flags with extra added
+func withFlags(flags map[string]interface{}, extra map[string]interface{}) map[string]interface{} {
+	for key, value := range extra {
+		flags[key] = value
+	}
+	return flags
+}
+
+// sharedTargetFlags returns the replication flags targetReplicationFlags builds for cmd from an
+// --org-list run, with the log level set so it is printed
+func sharedTargetFlags(t *testing.T, cmd *cobra.Command) map[string]interface{} {
+	t.Helper()
+	// Merge the root command's persistent flags so --log-level can be read from cmd
+	cmd.InheritedFlags()
+	targets := &commandTargets{
+		Enterprises: []string{"acme"},
+		ServerURL:   "github.example.com",
+		CommonFlags: &utils.CommonFlags{
+			OrgListPath:       "orgs.csv",
+			StrictOrgList:     true,
+			FailOnInvalidOrgs: true,
+			ExcludeProperties: []types.PropertyFilter{{Name: "managed", Value: "external"}},
+			Concurrency:       4,
+			Delay:             2,
+			MaxOrgsPerMinute:  30,
+			PerOrgTimeout:     10 * time.Minute,
+			ReportOrder:       utils.ReportOrderCompletion,
+		},
+	}
+	flags, err := targetReplicationFlags(cmd, targets)
+	if err != nil {
+		t.Fatalf("targetReplicationFlags() error = %v", err)
+	}
+	flags["log-level"] = "debug"
+	return flags
+}
+
+// withPostHook returns flags with the --post-hook flags a run with a hook replicates
+func withPostHook(flags map[string]interface{}) map[string]interface{} {
+	addPostHookReplicationFlags(flags, processors.PostHook{Command: "./notify.sh", Timeout: time.Minute}, true)
+	return flags
+}
+
+// emittedFlags returns the names of the flags in a replication command, without the leading dashes
+func emittedFlags(command string) []string {
+	var names []string
+	for _, field := range strings.Fields(command) {
+		if name, ok := strings.CutPrefix(field, "--"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestReplicationCommandFlagsAreRegistered(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		flags   map[string]interface{}
+	}{
+		{
+			name:    "apply",
+			command: "apply",
+			flags: withPostHook(withFlags(runTargetingFlags(), map[string]interface{}{
+				"template-org":                    "template",
+				"config-name":                     "Baseline",
+				"config-source":                   "organization",
+				"scope":                           "public",
+				"set-as-default":                  "true",
+				"skip-orgs-with-existing-default": true,
+				"exclude-repos":                   "repos.txt",
+				"only-codeql-supported-repos":     true,
+				"codeql-languages":                "go",
+				"sync-settings-from":              "source",
+				"skip-confirmation-message":       "true",
+				"probe":                           "probe-org",
+				"change-ref":                      "CHG-1",
+			})),
+		},
+		{
+			name:    "check",
+			command: "check",
+			flags: withFlags(sharedTargetFlags(t, checkCmd), map[string]interface{}{
+				"policy":            "policy.yml",
+				"config-name":       "Baseline",
+				"include-config-id": true,
+				"no-request-cache":  true,
+			}),
+		},
+		{
+			name:    "delete a configuration",
+			command: "delete",
+			flags: withFlags(runTargetingFlags(), map[string]interface{}{
+				"template-org":              "template",
+				"config-name":               "Baseline",
+				"skip-confirmation-message": "true",
+				"change-ref":                "CHG-1",
+			}),
+		},
+		{
+			name:    "delete all configurations",
+			command: "delete",
+			flags: withFlags(runTargetingFlags(), map[string]interface{}{
+				"all-configs":               true,
+				"managed-prefix":            "sec-",
+				"unattached-only":           true,
+				"older-than":                "30d",
+				"skip-confirmation-message": "true",
+				"change-ref":                "CHG-1",
+			}),
+		},
+		{
+			name:    "drift",
+			command: "drift",
+			flags: withFlags(sharedTargetFlags(t, driftCmd), map[string]interface{}{
+				"baseline":          "template",
+				"config-name":       "Baseline",
+				"csv-report":        "drift.csv",
+				"output":            utils.DriftOutputJSON,
+				"include-config-id": true,
+				"no-request-cache":  true,
+			}),
+		},
+		{
+			name:    "generate a new configuration",
+			command: "generate",
+			flags: withPostHook(withFlags(runTargetingFlags(), map[string]interface{}{
+				"dependabot-alerts-available":           "true",
+				"dependabot-security-updates-available": "true",
+				"config-name":                           "Baseline",
+				"config-description":                    "Baseline",
+				"advanced-security":                     "enabled",
+				"dependabot-alerts":                     "enabled",
+				"dependabot-security-updates":           "enabled",
+				"secret-scanning":                       "enabled",
+				"secret-scanning-push-protection":       "enabled",
+				"secret-scanning-non-provider-patterns": "enabled",
+				"enforcement":                           "enforced",
+				"no-attach":                             true,
+				"set-as-default":                        "true",
+				"apply-to-existing":                     true,
+				"skip-confirmation-message":             "true",
+				"probe":                                 "probe-org",
+				"overwrite":                             "true",
+				"with-audit-copy":                       true,
+				"probe-feature-support":                 true,
+				"skip-orgs-with-existing-default":       true,
+				"exclude-repos":                         "repos.txt",
+				"only-codeql-supported-repos":           true,
+				"codeql-languages":                      "go",
+				"change-ref":                            "CHG-1",
+				"stamp-change-ref":                      true,
+			})),
+		},
+		{
+			name:    "generate a copy",
+			command: "generate",
+			flags: withFlags(runTargetingFlags(), map[string]interface{}{
+				"copy-from-org": "source",
+				"config-name":   "Baseline",
+				"new-name":      "Copied",
+				"name-prefix":   "sec-",
+				"name-suffix":   "-v2",
+				"scope":         "all",
+			}),
+		},
+		{
+			name:    "modify",
+			command: "modify",
+			flags: withFlags(runTargetingFlags(), map[string]interface{}{
+				"template-org":                          "template",
+				"dependabot-alerts-available":           "true",
+				"dependabot-security-updates-available": "true",
+				"config-name":                           "Baseline",
+				"new-name":                              "Renamed",
+				"new-description":                       "Updated",
+				"advanced-security":                     "enabled",
+				"dependabot-alerts":                     "enabled",
+				"dependabot-security-updates":           "enabled",
+				"secret-scanning":                       "enabled",
+				"secret-scanning-push-protection":       "enabled",
+				"secret-scanning-non-provider-patterns": "enabled",
+				"enforcement":                           "enforced",
+				"skip-confirmation-message":             "true",
+				"probe":                                 "probe-org",
+				"force-overwrite":                       true,
+				"probe-feature-support":                 true,
+				"change-ref":                            "CHG-1",
+				"stamp-change-ref":                      true,
+			}),
+		},
+		{
+			name:    "org-defaults list",
+			command: "org-defaults list",
+			flags: withFlags(sharedTargetFlags(t, orgDefaultsListCmd), map[string]interface{}{
+				"include-config-id": true,
+				"no-request-cache":  true,
+			}),
+		},
+		{
+			name:    "org-defaults clear",
+			command: "org-defaults clear",
+			flags: withFlags(sharedTargetFlags(t, orgDefaultsClearCmd), map[string]interface{}{
+				"skip-confirmation-message": "true",
+			}),
+		},
+		{
+			name:    "schema drift",
+			command: "schema drift",
+			flags: map[string]interface{}{
+				"github-enterprise-server-url": "github.example.com",
+				"reference-org":                "reference",
+				"config-name":                  "Baseline",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, _, err := rootCmd.Find(strings.Fields(tt.command))
+			if err != nil || command.CommandPath() != rootCmd.Name()+" "+tt.command {
+				t.Fatalf("no %q command (found %q, error %v)", tt.command, command.CommandPath(), err)
+			}
+
+			line := utils.BuildReplicationCommand(tt.command, tt.flags)
+			emitted := make(map[string]bool)
+			for _, name := range emittedFlags(line) {
+				emitted[name] = true
+				if command.Flag(name) == nil {
+					t.Errorf("%s emits --%s, which the command does not register", line, name)
+				}
+			}
+			for key := range tt.flags {
+				if !emitted[key] {
+					t.Errorf("%s is missing --%s; add it to utils.ReplicationFlagOrder", line, key)
+				}
+			}
+		})
+	}
+}
@@ -1,16 +1,53 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/telemetry"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
+// Exit codes returned by the extension. Automation can rely on these to distinguish
+// failure classes without parsing output.
+const (
+	ExitSuccess        = 0
+	ExitUsageError     = 1
+	ExitPartialFailure = 2
+	ExitAuthFailure    = 3
+	ExitCancelled      = 4
+	ExitStopCondition  = 5
+	ExitNoTargets      = 6
+	ExitPolicyFailure  = 7
+	ExitDrift          = 8
+)
+
+// exitCodeDescriptions documents each exit code, in order, for --explain-exit-codes
+var exitCodeDescriptions = []struct {
+	Code        int
+	Description string
+}{
+	{ExitSuccess, "Success: every targeted organization was processed or skipped"},
+	{ExitUsageError, "Usage or validation error (invalid flags, input, or an unexpected failure)"},
+	{ExitPartialFailure, "Partial failure: one or more organizations failed to process, --org-list rows were skipped with --fail-on-invalid-orgs, a --post-hook command failed, or warnings were raised with --warnings-as-errors"},
+	{ExitAuthFailure, "Authentication or token scope failure"},
+	{ExitCancelled, "Cancelled by the user at the confirmation prompt"},
+	{ExitStopCondition, "Aborted by a stop condition before all organizations were processed"},
+	{ExitNoTargets, "No target organizations remained after the targeting flags and filters were applied"},
+	{ExitPolicyFailure, "The policy check found one or more error-severity violations"},
+	{ExitDrift, "The drift check found configurations that differ from the baseline, or schema drift found fields this extension does not model (in CI)"},
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "security-config",
 	Short: "GitHub Security Configuration Management for Enterprises",
@@ -18,7 +55,20 @@ var rootCmd = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		HiddenDefaultCmd: true,
 	},
+	// Errors are printed by Execute so the exit code can be chosen per failure class
+	SilenceErrors: true,
+	RunE:          runRoot,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFlagConflicts(cmd.Flags(), outputFlagConflicts); err != nil {
+			return err
+		}
+		if err := configureOutput(cmd); err != nil {
+			return err
+		}
+
+		// Flags parsed successfully, so any later error is a runtime failure rather than misuse
+		cmd.SilenceUsage = true
+
 		levelStr, err := cmd.Flags().GetString("log-level")
 		if err != nil {
 			return err
@@ -28,6 +78,56 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 		ui.SetLogLevel(level)
+
+		warningsAsErrors, err = cmd.Flags().GetBool("warnings-as-errors")
+		if err != nil {
+			return err
+		}
+		loglevel.ResetWarningCount()
+
+		treatPendingAsMember, err := cmd.Flags().GetBool("treat-pending-as-member")
+		if err != nil {
+			return err
+		}
+		api.SetTreatPendingAsMember(treatPendingAsMember)
+
+		printRequestBodies, err := cmd.Flags().GetBool("print-request-bodies")
+		if err != nil {
+			return err
+		}
+		var requestBodySink io.Writer
+		if printRequestBodies {
+			requestBodySink = os.Stdout
+		}
+		api.SetRequestBodySink(requestBodySink)
+
+		maxAPICalls, err := cmd.Flags().GetInt("max-api-calls")
+		if err != nil {
+			return err
+		}
+		if maxAPICalls < 0 {
+			return fmt.Errorf("--max-api-calls must be 0 (no limit) or a positive number, got %d", maxAPICalls)
+		}
+		api.ResetAPICallCount()
+		api.SetMaxAPICalls(maxAPICalls)
+
+		// Without --api-version, the version is chosen for the server on the first request
+		api.ResetAPIVersion()
+		if cmd.Flags().Changed("api-version") {
+			apiVersion, err := cmd.Flags().GetString("api-version")
+			if err != nil {
+				return err
+			}
+			if err := api.SetAPIVersion(apiVersion); err != nil {
+				return err
+			}
+		}
+
+		if err := startNotification(cmd); err != nil {
+			return err
+		}
+
+		telemetry.Start(cmd.CommandPath())
 		return nil
 	},
 }
@@ -38,10 +138,16 @@ func init() {
 	rootCmd.PersistentFlags().String("org", "", "Target a single organization by name")
 	rootCmd.PersistentFlags().StringP("org-list", "l", "", "Path to CSV file containing organization names to target (one per line, no header)")
 	rootCmd.PersistentFlags().Bool("all-orgs", false, "Target all organizations in the enterprise")
+	rootCmd.PersistentFlags().Bool("strict-org-list", false, "Refuse to run when any --org-list row has an invalid or empty organization name, listing every such row")
+	rootCmd.PersistentFlags().Bool("fail-on-invalid-orgs", false, "Exit with code 2 after processing when any --org-list row was skipped for an invalid organization name")
+	rootCmd.PersistentFlags().StringSlice("exclude-with-property", nil, "Skip organizations whose custom property has this value, as key=value (e.g. managed=external); repeat it or separate pairs with commas")
 
 	rootCmd.PersistentFlags().IntP("concurrency", "c", 1, "Number of concurrent requests (1-20)")
 	rootCmd.PersistentFlags().IntP("delay", "d", 0, "Delay in seconds between organizations (1-600, mutually exclusive with --concurrency)")
-	rootCmd.PersistentFlags().StringP("enterprise-slug", "e", "", "GitHub Enterprise slug (e.g., github)")
+	rootCmd.PersistentFlags().Int("max-orgs-per-minute", 0, "Maximum number of organizations started per minute, at any concurrency (1-6000, 0 means no limit, mutually exclusive with --delay)")
+	rootCmd.PersistentFlags().Duration("per-org-timeout", 0, "Give up on an organization that takes longer than this, e.g. 10m, and continue with the rest (0 means no limit)")
+	rootCmd.PersistentFlags().String("report-order", utils.ReportOrderInput, fmt.Sprintf("Order of the per-organization results once processing finishes (%s, %s); progress is always logged as organizations finish", utils.ReportOrderInput, utils.ReportOrderCompletion))
+	rootCmd.PersistentFlags().StringSliceP("enterprise-slug", "e", nil, "GitHub Enterprise slug (e.g., github); repeat it or separate slugs with commas to target organizations across several enterprises")
 	rootCmd.PersistentFlags().StringP("github-enterprise-server-url", "u", "", "GitHub Enterprise URL (e.g., github.company.com)")
 	rootCmd.PersistentFlags().StringP("dependabot-alerts-available", "a", "", "Whether Dependabot Alerts are available in your GHES instance (true/false)")
 	rootCmd.PersistentFlags().StringP("dependabot-security-updates-available", "s", "", "Whether Dependabot Security Updates are available in your GHES instance (true/false)")
@@ -49,25 +155,167 @@ func init() {
 	// Flags shared by all subcommands
 	rootCmd.PersistentFlags().StringP("config-name", "n", "", "Name of the security configuration to operate on (replaces the interactive configuration-name prompt for each command)")
 	rootCmd.PersistentFlags().String("skip-confirmation-message", "", "Automatically approve the final confirmation prompt for any command (true/false)")
+	rootCmd.PersistentFlags().Bool("ignore-rate-limit-budget", false, "Proceed even when the estimated API requests exceed the remaining rate limit")
+	rootCmd.PersistentFlags().Int("max-api-calls", 0, "Abort the run once this many API calls have been made (0 means no limit)")
+	rootCmd.PersistentFlags().String("api-version", api.DefaultAPIVersion, "REST API version sent in the X-GitHub-Api-Version header of every request, in place of the version chosen for the detected GHES release")
+	rootCmd.PersistentFlags().Bool("print-request-bodies", false, "Print the JSON body of every create, update, attach and set-default request as it is sent")
+	rootCmd.PersistentFlags().String("notify-webhook", "", "URL to POST a JSON summary of the run to when processing finishes, e.g. a Slack or Teams incoming webhook")
+	rootCmd.PersistentFlags().Bool("treat-pending-as-member", false, "Treat pending organization memberships as active (needed by some enterprise managed user setups)")
+	rootCmd.PersistentFlags().Bool("warnings-as-errors", false, "Exit with code 2 when any warning was raised, such as an invalid --org-list row or a skipped organization, even if --log-level hides it")
 	rootCmd.PersistentFlags().String("log-level", ui.LogLevelDefault, fmt.Sprintf("Minimum log level for output (%s)", strings.Join(ui.LogLevelValues, ", ")))
+	rootCmd.PersistentFlags().String("color", ui.OutputAuto, fmt.Sprintf("When to color output (%s); auto turns color off when NO_COLOR is set or TERM=dumb", strings.Join(ui.ColorValues, ", ")))
+	rootCmd.PersistentFlags().Bool("no-emoji", false, "Mark setting values with the ASCII symbols +, x and - instead of ✓, ✗ and –")
+	rootCmd.PersistentFlags().Bool("high-contrast", false, "Color setting values with a bold blue and yellow palette instead of green and red")
+	rootCmd.PersistentFlags().String("shell", utils.ShellAuto, fmt.Sprintf("Shell to quote the printed replication and retry commands for (%s); auto uses powershell on Windows and bash elsewhere", strings.Join(utils.ShellValues, ", ")))
+	rootCmd.PersistentFlags().String("output-style", ui.OutputAuto, fmt.Sprintf("Output decorations (%s); auto prints plain lines without progress bars when CI is set or TERM=dumb", strings.Join(ui.OutputStyleValues, ", ")))
+
+	// Hidden helper documenting the exit codes for automation authors
+	rootCmd.Flags().Bool("explain-exit-codes", false, "Print the table of exit codes and exit")
+	_ = rootCmd.Flags().MarkHidden("explain-exit-codes")
 
 	// Mark org targeting flags as mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("org", "org-list", "all-orgs")
+	rootCmd.MarkFlagsMutuallyExclusive("strict-org-list", "fail-on-invalid-orgs")
 
 	// Mark concurrency and delay as mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("concurrency", "delay")
+	rootCmd.MarkFlagsMutuallyExclusive("max-orgs-per-minute", "delay")
 
 	// Add subcommands
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(modifyCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(orgDefaultsCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(driftCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(settingsCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(completionCmd)
+
+	registerFlagCompletions()
+}
+
+// configureOutput chooses colors and decorations from the environment and the --color and
+// --output-style flags, the setting value style from --no-emoji and --high-contrast, and the shell
+// printed commands are quoted for from --shell, before the command prints anything
+func configureOutput(cmd *cobra.Command) error {
+	colorFlag, err := cmd.Flags().GetString("color")
+	if err != nil {
+		return err
+	}
+	styleFlag, err := cmd.Flags().GetString("output-style")
+	if err != nil {
+		return err
+	}
+	mode, err := ui.DetectOutputMode(os.Getenv, colorFlag, styleFlag)
+	if err != nil {
+		return err
+	}
+	ui.ApplyOutputMode(mode)
+
+	noEmoji, err := cmd.Flags().GetBool("no-emoji")
+	if err != nil {
+		return err
+	}
+	highContrast, err := cmd.Flags().GetBool("high-contrast")
+	if err != nil {
+		return err
+	}
+	ui.SetValueStyle(ui.ValueStyle{ASCII: noEmoji, HighContrast: highContrast})
+
+	shell, err := cmd.Flags().GetString("shell")
+	if err != nil {
+		return err
+	}
+	return utils.SetReplicationShell(shell)
+}
+
+// runRoot prints the exit code table when --explain-exit-codes is set and shows help otherwise
+func runRoot(cmd *cobra.Command, args []string) error {
+	explain, err := cmd.Flags().GetBool("explain-exit-codes")
+	if err != nil {
+		return err
+	}
+	if !explain {
+		return cmd.Help()
+	}
+
+	tableData := pterm.TableData{{"Code", "Meaning"}}
+	for _, entry := range exitCodeDescriptions {
+		tableData = append(tableData, []string{fmt.Sprintf("%d", entry.Code), entry.Description})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// exitCodeForError maps an error returned by a command to the process exit code
+func exitCodeForError(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+
+	var authErr *types.AuthError
+	var stopErr *types.StopConditionError
+	var partialErr *types.PartialFailureError
+	var noTargetsErr *types.NoTargetsError
+	var policyErr *types.PolicyViolationError
+	var driftErr *types.DriftError
+	var schemaDriftErr *types.SchemaDriftError
+	var invalidOrgsErr *types.InvalidOrgListError
+	var warningsErr *types.WarningsAsErrorsError
+	var hookErr *types.HookFailureError
+	switch {
+	case errors.Is(err, types.ErrOperationCancelled):
+		return ExitCancelled
+	case errors.As(err, &authErr):
+		return ExitAuthFailure
+	case errors.As(err, &stopErr):
+		return ExitStopCondition
+	case errors.As(err, &partialErr), errors.As(err, &invalidOrgsErr), errors.As(err, &warningsErr), errors.As(err, &hookErr):
+		return ExitPartialFailure
+	case errors.As(err, &noTargetsErr):
+		return ExitNoTargets
+	case errors.As(err, &policyErr):
+		return ExitPolicyFailure
+	case errors.As(err, &driftErr), errors.As(err, &schemaDriftErr):
+		return ExitDrift
+	default:
+		return ExitUsageError
+	}
+}
+
+// warningsAsErrors is the value of --warnings-as-errors, read before the command runs
+var warningsAsErrors bool
+
+// failOnWarnings returns a types.WarningsAsErrorsError when a run that otherwise succeeded raised
+// warnings and --warnings-as-errors is set. Any other outcome is returned unchanged, as its own
+// exit code says more than the warnings do.
+func failOnWarnings(err error, enabled bool, warnings int) error {
+	if err != nil || !enabled || warnings == 0 {
+		return err
+	}
+	return &types.WarningsAsErrorsError{Warnings: warnings}
 }
 
 // Execute runs the root command
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := failOnWarnings(rootCmd.Execute(), warningsAsErrors, ui.WarningCount())
+	code := exitCodeForError(err)
+	if traceErr := telemetry.Finish(code, err); traceErr != nil {
+		ui.LogWarningf("%v", traceErr)
+	}
+	sendNotification(code, err)
+	var noTargetsErr *types.NoTargetsError
+	switch {
+	case errors.As(err, &noTargetsErr):
+		// An empty target set is not a failure of the tool, so it is explained rather than printed as an error
+		ui.ShowNoTargetsBreakdown(noTargetsErr.Breakdown)
+	case err != nil && code != ExitCancelled:
+		// Cancellation has already been reported to the user, so it is not printed as an error
 		pterm.Error.Printf("Error: %v\n", err)
-		os.Exit(1)
+	}
+	if code != ExitSuccess {
+		os.Exit(code)
 	}
 }
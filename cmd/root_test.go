@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+func TestExitCodeForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, ExitSuccess},
+		{"validation error", errors.New("invalid value for --scope"), ExitUsageError},
+		{"partial failure", &types.PartialFailureError{Operation: "generate", Failed: 1, Total: 3}, ExitPartialFailure},
+		{"auth failure", &types.AuthError{Message: "bad token"}, ExitAuthFailure},
+		{"wrapped auth failure", fmt.Errorf("fetching orgs: %w", &types.AuthError{Message: "bad token"}), ExitAuthFailure},
+		{"cancelled", types.ErrOperationCancelled, ExitCancelled},
+		{"stop condition", &types.StopConditionError{Reason: "dependabot unavailable"}, ExitStopCondition},
+		{"no targets", &types.NoTargetsError{Breakdown: types.TargetBreakdown{Source: "--org-list"}}, ExitNoTargets},
+		{"policy violations", &types.PolicyViolationError{Violations: 2}, ExitPolicyFailure},
+		{"drift", &types.DriftError{Organizations: 1}, ExitDrift},
+		{"schema drift", &types.SchemaDriftError{Keys: 2}, ExitDrift},
+		{"invalid org list rows", &types.InvalidOrgListError{Path: "orgs.csv", Entries: []types.InvalidOrgEntry{{Line: 2, Name: "bad name"}}}, ExitPartialFailure},
+		{"warnings as errors", &types.WarningsAsErrorsError{Warnings: 3}, ExitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeForError(tt.err); got != tt.want {
+				t.Errorf("exitCodeForError(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailOnWarnings(t *testing.T) {
+	failure := &types.PartialFailureError{Operation: "generate", Failed: 1, Total: 3}
+	tests := []struct {
+		name     string
+		err      error
+		enabled  bool
+		warnings int
+		wantCode int
+	}{
+		{name: "warnings without the flag", warnings: 2, wantCode: ExitSuccess},
+		{name: "flag without warnings", enabled: true, wantCode: ExitSuccess},
+		{name: "warnings with the flag", enabled: true, warnings: 2, wantCode: ExitPartialFailure},
+		{name: "other failures keep their code", err: &types.AuthError{Message: "bad token"}, enabled: true, warnings: 2, wantCode: ExitAuthFailure},
+		{name: "partial failure is unchanged", err: failure, enabled: true, warnings: 1, wantCode: ExitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := failOnWarnings(tt.err, tt.enabled, tt.warnings)
+			if got := exitCodeForError(err); got != tt.wantCode {
+				t.Errorf("exit code = %d (%v), want %d", got, err, tt.wantCode)
+			}
+			if tt.err != nil && err != tt.err {
+				t.Errorf("failOnWarnings() = %v, want the original error", err)
+			}
+		})
+	}
+}
+
+func TestWarningsAreCountedAtEveryLogLevel(t *testing.T) {
+	loglevel.ResetWarningCount()
+	ui.SetLogLevel(ui.LogLevelError)
+	t.Cleanup(func() {
+		ui.SetLogLevel(ui.LogLevelWarning)
+		loglevel.ResetWarningCount()
+	})
+
+	ui.LogWarningf("Skipping organization '%s': not an owner", "octo")
+	ui.LogWarningf("Line %d: Invalid organization name format '%s', skipping", 3, "bad name")
+	if got := ui.WarningCount(); got != 2 {
+		t.Fatalf("WarningCount() = %d, want 2 warnings hidden by --log-level error", got)
+	}
+	if code := exitCodeForError(failOnWarnings(nil, true, ui.WarningCount())); code != ExitPartialFailure {
+		t.Errorf("exit code = %d, want %d", code, ExitPartialFailure)
+	}
+}
+
+func TestOutcomeError(t *testing.T) {
+	invalidOrgList := &types.InvalidOrgListError{Path: "orgs.csv", Entries: []types.InvalidOrgEntry{{Line: 2, Name: "bad name"}}}
+	hookFailed := types.ProcessingSummary{Success: 2, Results: []types.ProcessingResult{
+		{Organization: "a", Success: true},
+		{Organization: "b", Success: true, HookError: errors.New("post hook failed: exit status 1")},
+	}}
+	tests := []struct {
+		name    string
+		outcome processingOutcome
+		want    int
+	}{
+		{"all succeeded", processingOutcome{Total: 2, ProcessingSummary: types.ProcessingSummary{Success: 2}}, ExitSuccess},
+		{"skips only", processingOutcome{Total: 2, ProcessingSummary: types.ProcessingSummary{Success: 1, Skipped: 1}}, ExitSuccess},
+		{"some errors", processingOutcome{Total: 3, ProcessingSummary: types.ProcessingSummary{Success: 2, Error: 1}}, ExitPartialFailure},
+		{"stopped", processingOutcome{Total: 3, ProcessingSummary: types.ProcessingSummary{Error: 1, Skipped: 2}, StopErr: &types.StopConditionError{Reason: "dependabot"}}, ExitStopCondition},
+		{"auth storm", processingOutcome{Total: 5, ProcessingSummary: types.ProcessingSummary{Error: 3, Skipped: 2}, StopErr: &types.AuthError{Message: "token expired"}}, ExitAuthFailure},
+		{"invalid rows without --fail-on-invalid-orgs", processingOutcome{Total: 2, ProcessingSummary: types.ProcessingSummary{Success: 2}, InvalidOrgList: invalidOrgList}, ExitSuccess},
+		{"invalid rows with --fail-on-invalid-orgs", processingOutcome{Total: 2, ProcessingSummary: types.ProcessingSummary{Success: 2}, InvalidOrgList: invalidOrgList, FailOnInvalidOrgs: true}, ExitPartialFailure},
+		{"failures take precedence over invalid rows", processingOutcome{Total: 2, ProcessingSummary: types.ProcessingSummary{Success: 1, Error: 1}, InvalidOrgList: invalidOrgList, FailOnInvalidOrgs: true}, ExitPartialFailure},
+		{"post hook failed", processingOutcome{Total: 2, ProcessingSummary: hookFailed}, ExitPartialFailure},
+		{"post hook failed with --ignore-hook-failures", processingOutcome{Total: 2, ProcessingSummary: hookFailed, IgnoreHookFailures: true}, ExitSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := outcomeError("generate", tt.outcome)
+			if got := exitCodeForError(err); got != tt.want {
+				t.Errorf("exit code for %+v = %d, want %d", tt.outcome, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOrganizations(t *testing.T) {
+	summary := types.ProcessingSummary{Results: []types.ProcessingResult{
+		{Organization: "ok", Success: true},
+		{Organization: "failed", Error: errors.New("boom")},
+		{Organization: "not-owner", Skipped: true},
+		{Organization: "stopped", Skipped: true, Unprocessed: true},
+	}}
+	got := retryOrganizations(summary)
+	if len(got) != 2 || got[0] != "failed" || got[1] != "stopped" {
+		t.Errorf("retryOrganizations() = %v, want [failed stopped]", got)
+	}
+	if got := retryOrganizations(types.ProcessingSummary{Results: summary.Results[:1]}); got != nil {
+		t.Errorf("retryOrganizations() = %v, want nil when nothing failed", got)
+	}
+}
+
+func TestSSORequiredOrganizations(t *testing.T) {
+	results := []types.ProcessingResult{
+		{Organization: "ok", Success: true},
+		{Organization: "saml", Error: fmt.Errorf("failed to get current user: %w", &types.SSORequiredError{Err: errors.New("HTTP 403")})},
+		{Organization: "failed", Error: errors.New("boom")},
+	}
+	if got := ssoRequiredOrganizations(results); !slices.Equal(got, []string{"saml"}) {
+		t.Errorf("ssoRequiredOrganizations() = %v, want [saml]", got)
+	}
+}
+
+func TestResolveTargetOrganizations_SourceOnlyCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.csv")
+	if err := os.WriteFile(path, []byte("source\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commonFlags := &utils.CommonFlags{OrgListPath: path}
+
+	// No enterprise is needed: the CSV is read and filtered without any API call
+	_, breakdown, err := resolveTargetOrganizations(nil, commonFlags, "source")
+	var noTargetsErr *types.NoTargetsError
+	if !errors.As(err, &noTargetsErr) {
+		t.Fatalf("resolveTargetOrganizations() error = %v, want a NoTargetsError", err)
+	}
+	want := types.TargetBreakdown{Source: "--org-list", Found: 1, SourceOrgRemoved: "source"}
+	if !reflect.DeepEqual(noTargetsErr.Breakdown, want) {
+		t.Errorf("breakdown = %+v, want %+v", noTargetsErr.Breakdown, want)
+	}
+	if !reflect.DeepEqual(breakdown, want) {
+		t.Errorf("returned breakdown = %+v, want %+v", breakdown, want)
+	}
+	if exitCodeForError(err) != ExitNoTargets {
+		t.Errorf("exit code = %d, want %d", exitCodeForError(err), ExitNoTargets)
+	}
+}
+
+func TestRequireActionableOrganizations(t *testing.T) {
+	owner := types.MembershipStatus{IsMember: true, IsOwner: true, Role: "admin", State: "active"}
+	member := types.MembershipStatus{IsMember: true, Role: "member", State: "active"}
+	none := types.MembershipStatus{Role: "none", State: "none"}
+
+	tests := []struct {
+		name          string
+		statuses      map[string]types.MembershipStatus
+		wantNoTargets bool
+	}{
+		{name: "one owned organization is enough", statuses: map[string]types.MembershipStatus{"org-a": none, "org-b": owner}},
+		{name: "unknown membership counts as actionable", statuses: map[string]types.MembershipStatus{"org-a": member}},
+		{name: "no owned organizations stops the run", statuses: map[string]types.MembershipStatus{"org-a": member, "org-b": none}, wantNoTargets: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := prefetchMemberships
+			defer func() { prefetchMemberships = orig }()
+			prefetchMemberships = func(orgs []string, concurrency int) map[string]types.MembershipStatus { return tt.statuses }
+
+			breakdown := types.TargetBreakdown{Source: "--org-list", Found: 2, Remaining: 2}
+			err := requireActionableOrganizations([]string{"org-a", "org-b"}, breakdown, 1)
+			if !tt.wantNoTargets {
+				if err != nil {
+					t.Fatalf("requireActionableOrganizations() error = %v", err)
+				}
+				return
+			}
+
+			var noTargetsErr *types.NoTargetsError
+			if !errors.As(err, &noTargetsErr) {
+				t.Fatalf("requireActionableOrganizations() error = %v, want a NoTargetsError", err)
+			}
+			want := types.TargetBreakdown{Source: "--org-list", Found: 2, NotManageable: 2}
+			if !reflect.DeepEqual(noTargetsErr.Breakdown, want) {
+				t.Errorf("breakdown = %+v, want %+v", noTargetsErr.Breakdown, want)
+			}
+			if exitCodeForError(err) != ExitNoTargets {
+				t.Errorf("exit code = %d, want %d", exitCodeForError(err), ExitNoTargets)
+			}
+		})
+	}
+}
+
+func TestAPICallUsageLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		limit    int
+		byMethod map[string]int
+		want     string
+	}{
+		{"reads only", 3, 0, map[string]int{"GET": 3}, "API calls: 3 (GET 3)"},
+		{"methods in a fixed order", 12, 0, map[string]int{"DELETE": 1, "GET": 9, "PATCH": 2}, "API calls: 12 (GET 9, PATCH 2, DELETE 1)"},
+		{"with a budget", 5, 50, map[string]int{"GET": 4, "POST": 1}, "API calls: 5 (GET 4, POST 1) of 50 allowed by --max-api-calls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiCallUsageLine(tt.count, tt.limit, tt.byMethod); got != tt.want {
+				t.Errorf("apiCallUsageLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompliantCountLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []types.ProcessingResult
+		want    string
+	}{
+		{"none compliant", []types.ProcessingResult{{Organization: "a", Success: true}, {Organization: "b", Skipped: true}}, ""},
+		{"mixed", []types.ProcessingResult{
+			{Organization: "a", Success: true},
+			{Organization: "b", Skipped: true, AlreadyCompliant: true},
+			{Organization: "c", Skipped: true, AlreadyCompliant: true},
+			{Organization: "d", Skipped: true},
+		}, "Already compliant: 2 of 3 skipped organizations had the configuration with the requested settings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compliantCountLine(tt.results); got != tt.want {
+				t.Errorf("compliantCountLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultCountsLine(t *testing.T) {
+	set, already := true, false
+	tests := []struct {
+		name    string
+		results []types.ProcessingResult
+		want    string
+	}{
+		{"no default requested", []types.ProcessingResult{{Organization: "a", Success: true}}, ""},
+		{"mixed", []types.ProcessingResult{
+			{Organization: "a", Success: true, DefaultSet: &set},
+			{Organization: "b", Success: true, DefaultSet: &already},
+			{Organization: "c", Success: true, DefaultSet: &already},
+			{Organization: "d", Skipped: true},
+		}, "Default configuration: newly set in 1 organizations, already default in 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultCountsLine(tt.results); got != tt.want {
+				t.Errorf("defaultCountsLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationSummary(t *testing.T) {
+	outcome := processingOutcome{Total: 3}
+	outcome.Add(types.ProcessingResult{Organization: "a", Success: true})
+	outcome.Add(types.ProcessingResult{Organization: "b", Skipped: true})
+	outcome.Add(types.ProcessingResult{Organization: "c", Error: errors.New("boom")})
+	err := &types.PartialFailureError{Operation: "apply", Failed: 1, Total: 3}
+
+	got := notificationSummary("gh-security-config apply", outcome, 90*time.Second, "CHG-1", types.RunIdentity{Host: "github.example.com", User: "svc-security-bot"}, ExitPartialFailure, err)
+	if got.Success != 1 || got.Skipped != 1 || got.Failed != 1 || got.Total != 3 || got.DurationSeconds != 90 {
+		t.Errorf("counts = %+v", got)
+	}
+	if len(got.FailedOrgs) != 1 || got.FailedOrgs[0] != "c" {
+		t.Errorf("FailedOrgs = %v, want [c]", got.FailedOrgs)
+	}
+	if got.Error == "" || got.ExitCode != ExitPartialFailure || got.ChangeRef != "CHG-1" {
+		t.Errorf("summary = %+v, want the error, exit code and change reference", got)
+	}
+	if got.Host != "github.example.com" || got.User != "svc-security-bot" {
+		t.Errorf("Host, User = %q, %q, want the run's host and authenticated user", got.Host, got.User)
+	}
+	want := "gh-security-config apply finished with exit code 2: 1 succeeded, 1 skipped, 1 failed of 3 organizations in 1m30s (change CHG-1)"
+	if got.Text != want {
+		t.Errorf("Text = %q, want %q", got.Text, want)
+	}
+}
+
+// notReplicatedFlags are command flags deliberately left out of replication commands because
+// their value is replicated through another flag
+var notReplicatedFlags = map[string]string{
+	"description-file": "the description read from the file is replicated as --config-description or --new-description",
+	"settings":         "each --settings pair is replicated as its dedicated setting flag",
+	"manifest":         "run prints the replication command of the generate run the manifest describes",
+}
+
+func TestReplicationFlagsMatchCommandFlags(t *testing.T) {
+	defined := make(map[string]bool)
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) { defined[flag.Name] = true })
+		cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) { defined[flag.Name] = true })
+		if cmd != rootCmd && !cmd.Hidden {
+			cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+				if _, skip := notReplicatedFlags[flag.Name]; !skip && flag.Name != "help" && !slices.Contains(utils.ReplicationFlagOrder, flag.Name) {
+					t.Errorf("%s --%s is missing from utils.ReplicationFlagOrder", cmd.CommandPath(), flag.Name)
+				}
+			})
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(rootCmd)
+
+	for _, name := range utils.ReplicationFlagOrder {
+		if !defined[name] {
+			t.Errorf("utils.ReplicationFlagOrder lists --%s, which no command defines", name)
+		}
+	}
+
+	// The template organization is selected the same way wherever a command reads from one
+	for _, cmd := range []*cobra.Command{applyCmd, modifyCmd, deleteCmd} {
+		flag := cmd.Flags().Lookup("template-org")
+		if flag == nil || flag.Shorthand != "t" {
+			t.Errorf("%s does not define --template-org/-t", cmd.Name())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Create and apply a security configuration declared in a manifest",
+	Long: `Read a YAML manifest declaring the enterprise, server URL, target organizations, configuration, settings, attachment scope and default, and run generate with it.
+
+Everything generate would prompt for comes from the manifest, so together with --skip-confirmation-message true the run needs no input.`,
+	RunE: runRun,
+}
+
+func init() {
+	runCmd.Flags().String("manifest", "", "Path to the YAML manifest describing the run (required)")
+	_ = runCmd.MarkFlagRequired("manifest")
+}
+
+// manifestControlledFlags are the flags a manifest sets, which cannot also be given on the command line
+var manifestControlledFlags = []string{
+	"enterprise-slug",
+	"github-enterprise-server-url",
+	"dependabot-alerts-available",
+	"dependabot-security-updates-available",
+	"org",
+	"org-list",
+	"all-orgs",
+	"config-name",
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	manifestPath, err := cmd.Flags().GetString("manifest")
+	if err != nil {
+		return err
+	}
+	for _, name := range manifestControlledFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with --manifest; set it in the manifest instead", name)
+		}
+	}
+
+	manifest, err := utils.ReadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	// The root flags are shared with generate, so flags such as --concurrency and
+	// --skip-confirmation-message given to run carry over
+	if err := generateCmd.ParseFlags(manifestArgs(manifest)); err != nil {
+		return fmt.Errorf("failed to apply manifest %s: %w", manifestPath, err)
+	}
+	return runGenerate(generateCmd, nil)
+}
+
+// manifestArgs returns the generate flags that reproduce a validated manifest
+func manifestArgs(manifest *types.Manifest) []string {
+	args := []string{
+		"--enterprise-slug=" + strings.Join(manifest.Enterprises, ","),
+		"--github-enterprise-server-url=" + manifest.ServerURL,
+		fmt.Sprintf("--dependabot-alerts-available=%t", *manifest.DependabotAlertsAvailable),
+		fmt.Sprintf("--dependabot-security-updates-available=%t", *manifest.DependabotSecurityUpdatesAvailable),
+	}
+
+	switch {
+	case manifest.Targets.Org != "":
+		args = append(args, "--org="+manifest.Targets.Org)
+	case manifest.Targets.OrgList != "":
+		args = append(args, "--org-list="+manifest.Targets.OrgList)
+	case manifest.Targets.AllOrgs:
+		args = append(args, "--all-orgs")
+	}
+
+	args = append(args,
+		"--config-name="+manifest.Configuration.Name,
+		"--config-description="+manifest.Configuration.Description,
+	)
+	for _, key := range types.KnownSettingKeys() {
+		if value, ok := manifest.Configuration.Settings[key]; ok {
+			args = append(args, fmt.Sprintf("--setting=%s=%s", key, value))
+		}
+	}
+
+	args = append(args,
+		"--scope="+manifest.Scope,
+		fmt.Sprintf("--set-as-default=%t", *manifest.SetAsDefault),
+	)
+	if manifest.Overwrite {
+		args = append(args, "--overwrite=true")
+	}
+	if manifest.ChangeRef != "" {
+		args = append(args, "--change-ref="+manifest.ChangeRef)
+	}
+	return args
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestManifestArgs(t *testing.T) {
+	yes, no := true, false
+	manifest := &types.Manifest{
+		Enterprises:                        []string{"acme", "acme-labs"},
+		ServerURL:                          "github.acme.com",
+		DependabotAlertsAvailable:          &yes,
+		DependabotSecurityUpdatesAvailable: &no,
+		Targets:                            types.ManifestTargets{OrgList: "manifests/orgs.csv"},
+		Configuration: types.ManifestConfiguration{
+			Name:        "Baseline",
+			Description: "Managed = baseline",
+			Settings:    map[string]string{"enforcement": "enforced", "advanced_security": "enabled", "dependabot_alerts": "enabled"},
+		},
+		Scope:        "all",
+		SetAsDefault: &yes,
+		Overwrite:    true,
+		ChangeRef:    "CHG-1",
+	}
+
+	want := []string{
+		"--enterprise-slug=acme,acme-labs",
+		"--github-enterprise-server-url=github.acme.com",
+		"--dependabot-alerts-available=true",
+		"--dependabot-security-updates-available=false",
+		"--org-list=manifests/orgs.csv",
+		"--config-name=Baseline",
+		"--config-description=Managed = baseline",
+		"--setting=advanced_security=enabled",
+		"--setting=dependabot_alerts=enabled",
+		"--setting=enforcement=enforced",
+		"--scope=all",
+		"--set-as-default=true",
+		"--overwrite=true",
+		"--change-ref=CHG-1",
+	}
+	got := manifestArgs(manifest)
+	if !slices.Equal(got, want) {
+		t.Fatalf("manifestArgs() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Every flag must exist on generate, locally or inherited from the root command
+	for _, arg := range got {
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if generateCmd.Flags().Lookup(name) == nil && generateCmd.InheritedFlags().Lookup(name) == nil {
+			t.Errorf("manifestArgs() uses --%s, which generate does not define", name)
+		}
+	}
+	for _, name := range manifestControlledFlags {
+		if runCmd.InheritedFlags().Lookup(name) == nil {
+			t.Errorf("manifestControlledFlags lists --%s, which run does not inherit", name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Check the configurations API against the settings this extension knows",
+	Long:  "Commands to compare the fields GitHub's security configurations API returns with the settings this extension models",
+}
+
+var schemaDriftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Report configuration fields this extension does not model",
+	Long:  "Read the organization-level configurations of a reference organization and report every field that is neither a managed setting, the enforcement, nor metadata, with the values observed. Such fields are dropped when configurations are copied, so the extension needs updating. In CI the command exits with code 8 when any are found.",
+	RunE:  runSchemaDrift,
+}
+
+func init() {
+	schemaDriftCmd.Flags().String("reference-org", "", "Organization whose configurations are read (required)")
+	_ = schemaDriftCmd.MarkFlagRequired("reference-org")
+
+	schemaCmd.AddCommand(schemaDriftCmd)
+}
+
+// unmodeledField is a configuration field the extension does not model, as observed in the
+// reference organization
+type unmodeledField struct {
+	Key            string
+	Values         []string // Distinct observed values, JSON-encoded and sorted
+	Configurations []string // Configurations that have the field, in the order they were read
+}
+
+func runSchemaDrift(cmd *cobra.Command, args []string) error {
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("GitHub Security Configuration Schema Drift")
+	pterm.Println()
+
+	referenceOrg, err := cmd.Flags().GetString("reference-org")
+	if err != nil {
+		return err
+	}
+	configName, err := cmd.Flags().GetString("config-name")
+	if err != nil {
+		return err
+	}
+	serverURLFlag, err := cmd.Flags().GetString("github-enterprise-server-url")
+	if err != nil {
+		return err
+	}
+	serverURL, err := ui.GetServerURLInput(serverURLFlag)
+	if err != nil {
+		return err
+	}
+	ui.SetupGitHubHost(serverURL)
+
+	details, err := fetchReferenceConfigurations(referenceOrg, configName)
+	if err != nil {
+		return err
+	}
+	fields := collectUnmodeledFields(details)
+	if err := renderUnmodeledFields(fields, referenceOrg, len(details)); err != nil {
+		return err
+	}
+
+	utils.ShowReplicationCommand(utils.BuildReplicationCommand("schema drift", map[string]interface{}{
+		"github-enterprise-server-url": serverURL,
+		"reference-org":                referenceOrg,
+		"config-name":                  configName,
+	}))
+
+	// Outside CI the fields are reported without failing, as the run itself succeeded
+	if len(fields) > 0 && ui.RunningInCI(os.Getenv) {
+		return &types.SchemaDriftError{Keys: len(fields)}
+	}
+	return nil
+}
+
+// fetchReferenceConfigurations reads the details of the organization-level configurations in org,
+// or only of the one named configName when it is set
+func fetchReferenceConfigurations(org, configName string) ([]*types.SecurityConfigurationDetails, error) {
+	configs, err := api.FetchSecurityConfigurations(context.Background(), org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch configurations from reference organization '%s': %w", org, err)
+	}
+	var details []*types.SecurityConfigurationDetails
+	for _, config := range configs {
+		if config.TargetType == "enterprise" || (configName != "" && config.Name != configName) {
+			continue
+		}
+		configDetails, err := api.GetSecurityConfigurationDetails(context.Background(), org, config.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get details of configuration '%s' in reference organization '%s': %w", config.Name, org, err)
+		}
+		details = append(details, configDetails)
+	}
+	if len(details) == 0 {
+		if configName != "" {
+			return nil, fmt.Errorf("configuration '%s' not found in reference organization '%s'", configName, org)
+		}
+		return nil, fmt.Errorf("reference organization '%s' has no organization-level configurations to read", org)
+	}
+	return details, nil
+}
+
+// collectUnmodeledFields merges the unmodeled fields of every configuration, sorted by key
+func collectUnmodeledFields(details []*types.SecurityConfigurationDetails) []unmodeledField {
+	byKey := make(map[string]*unmodeledField)
+	for _, config := range details {
+		for key, value := range config.UnmodeledSettings {
+			field, ok := byKey[key]
+			if !ok {
+				field = &unmodeledField{Key: key}
+				byKey[key] = field
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				encoded = []byte(fmt.Sprintf("%v", value))
+			}
+			if !slices.Contains(field.Values, string(encoded)) {
+				field.Values = append(field.Values, string(encoded))
+			}
+			field.Configurations = append(field.Configurations, config.Name)
+		}
+	}
+
+	fields := make([]unmodeledField, 0, len(byKey))
+	for _, key := range slices.Sorted(maps.Keys(byKey)) {
+		field := byKey[key]
+		slices.Sort(field.Values)
+		fields = append(fields, *field)
+	}
+	return fields
+}
+
+// renderUnmodeledFields prints the unmodeled fields as a table, or confirms that there are none
+func renderUnmodeledFields(fields []unmodeledField, org string, configCount int) error {
+	if len(fields) == 0 {
+		pterm.Success.Printf("Every field of the %d configuration(s) in '%s' is known to this extension.\n", configCount, org)
+		return nil
+	}
+
+	tableData := pterm.TableData{{"Field", "Observed values", "Configurations"}}
+	for _, field := range fields {
+		tableData = append(tableData, []string{field.Key, strings.Join(field.Values, ", "), strings.Join(field.Configurations, ", ")})
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+		return err
+	}
+	pterm.Println()
+	ui.LogWarningf("%d field(s) in the %d configuration(s) of '%s' are not modeled by this extension and are dropped when configurations are copied", len(fields), configCount, org)
+	return nil
+}
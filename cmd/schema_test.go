@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestCollectUnmodeledFields(t *testing.T) {
+	details := []*types.SecurityConfigurationDetails{
+		{Name: "Baseline", UnmodeledSettings: map[string]interface{}{"zeta_scanning": "enabled", "alpha_options": map[string]interface{}{"level": "high"}}},
+		{Name: "Strict", UnmodeledSettings: map[string]interface{}{"zeta_scanning": "disabled"}},
+		{Name: "Legacy", UnmodeledSettings: map[string]interface{}{"zeta_scanning": "enabled"}},
+		{Name: "Known"},
+	}
+
+	want := []unmodeledField{
+		{Key: "alpha_options", Values: []string{`{"level":"high"}`}, Configurations: []string{"Baseline"}},
+		{Key: "zeta_scanning", Values: []string{`"disabled"`, `"enabled"`}, Configurations: []string{"Baseline", "Strict", "Legacy"}},
+	}
+	if got := collectUnmodeledFields(details); !reflect.DeepEqual(got, want) {
+		t.Errorf("collectUnmodeledFields() = %+v, want %+v", got, want)
+	}
+	if got := collectUnmodeledFields(details[3:]); len(got) != 0 {
+		t.Errorf("collectUnmodeledFields() = %+v, want none", got)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Describe the security settings this extension manages",
+	Long:  "Commands to describe the security settings that can be given with --settings (alias --setting) and the dedicated setting flags",
+}
+
+var settingsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the known setting keys and their allowed values",
+	Long:  "Print every setting key that --settings (alias --setting) accepts, the values allowed for it, its dedicated flag, and the commands that accept it",
+	Args:  cobra.NoArgs,
+	RunE:  runSettingsList,
+}
+
+func init() {
+	settingsCmd.AddCommand(settingsListCmd)
+}
+
+func runSettingsList(cmd *cobra.Command, args []string) error {
+	return pterm.DefaultTable.WithHasHeader().WithData(settingsTable(cmd.Root())).Render()
+}
+
+// settingsTable lists every known setting key in registry order. The commands are those under root
+// that have the --settings flag, and the dedicated flag is shown when those commands define it.
+func settingsTable(root *cobra.Command) pterm.TableData {
+	var accepting []*cobra.Command
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd.Flags().Lookup("settings") != nil {
+			accepting = append(accepting, cmd)
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	tableData := pterm.TableData{{"Key", "Allowed values", "Dedicated flag", "Commands"}}
+	for _, key := range types.KnownSettingKeys() {
+		setting, _ := types.LookupSecuritySetting(key)
+		flag := strings.ReplaceAll(key, "_", "-")
+		var commands []string
+		dedicated := len(accepting) > 0
+		for _, cmd := range accepting {
+			commands = append(commands, strings.TrimPrefix(cmd.CommandPath(), root.Name()+" "))
+			if cmd.Flags().Lookup(flag) == nil {
+				dedicated = false
+			}
+		}
+		dedicatedFlag := "none"
+		if dedicated {
+			dedicatedFlag = "--" + flag
+		}
+		tableData = append(tableData, []string{key, strings.Join(setting.Values, ", "), dedicatedFlag, strings.Join(commands, ", ")})
+	}
+	return tableData
+}
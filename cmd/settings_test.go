@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestSettingsTable(t *testing.T) {
+	table := settingsTable(rootCmd)
+
+	var keys []string
+	for _, row := range table[1:] {
+		keys = append(keys, row[0])
+	}
+	if !slices.Equal(keys, types.KnownSettingKeys()) {
+		t.Fatalf("keys = %v, want %v", keys, types.KnownSettingKeys())
+	}
+
+	for _, row := range table[1:] {
+		if row[3] != "generate, modify" {
+			t.Errorf("%s commands = %q, want %q", row[0], row[3], "generate, modify")
+		}
+	}
+	want := []string{"advanced_security", "enabled, disabled", "--advanced-security", "generate, modify"}
+	if !slices.Equal(table[1], want) {
+		t.Errorf("first row = %v, want %v", table[1], want)
+	}
+	if last := table[len(table)-1]; last[0] != "enforcement" || last[1] != "enforced, unenforced" || last[2] != "--enforcement" {
+		t.Errorf("enforcement row = %v", last)
+	}
+}
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/callmegreg/gh-security-config/internal/processors"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
 	"github.com/callmegreg/gh-security-config/internal/utils"
 )
@@ -28,7 +35,8 @@ var securitySettingFlagNames = struct {
 }
 
 // addSecuritySettingFlags registers the security-setting flags on the given command. It is
-// used by `generate` and `modify` to allow fully non-interactive invocations.
+// used by `generate` and `modify` to allow fully non-interactive invocations. --setting is an
+// alias of --settings, so both feed the same list.
 func addSecuritySettingFlags(cmd *cobra.Command) {
 	cmd.Flags().String(securitySettingFlagNames.AdvancedSecurity, "", "GitHub Advanced Security setting (enabled, disabled)")
 	cmd.Flags().String(securitySettingFlagNames.DependabotAlerts, "", "Dependabot Alerts setting (enabled, disabled, not_set)")
@@ -37,6 +45,137 @@ func addSecuritySettingFlags(cmd *cobra.Command) {
 	cmd.Flags().String(securitySettingFlagNames.SecretScanningPushProtection, "", "Secret Scanning Push Protection setting (enabled, disabled, not_set)")
 	cmd.Flags().String(securitySettingFlagNames.SecretScanningNonProviderPatterns, "", "Secret Scanning Non-Provider Patterns setting (enabled, disabled, not_set)")
 	cmd.Flags().String(securitySettingFlagNames.Enforcement, "", "Enforcement status for the configuration (enforced, unenforced)")
+	cmd.Flags().StringArray("settings", nil, "Security settings as a comma-separated key=value list using the API field names, e.g. advanced_security=enabled,secret_scanning=enabled (repeatable, alias --setting; see 'settings list')")
+	cmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "setting" {
+			name = "settings"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// addChangeRefFlags registers --change-ref on a command that changes configurations, and
+// --stamp-change-ref when the command also writes the configuration description
+func addChangeRefFlags(cmd *cobra.Command, stamp bool) {
+	cmd.Flags().String("change-ref", "", "Change ticket reference for this run, e.g. CHG-12345; shown in the summary and trace")
+	if stamp {
+		cmd.Flags().Bool("stamp-change-ref", false, "Append \"[change: <ref>]\" to the configuration description (requires --change-ref)")
+	}
+}
+
+// extractChangeRefFlags reads --change-ref and, when the command has it, --stamp-change-ref.
+// An explicitly empty --change-ref is rejected, and stamping requires a reference.
+func extractChangeRefFlags(cmd *cobra.Command) (string, bool, error) {
+	changeRef, err := cmd.Flags().GetString("change-ref")
+	if err != nil {
+		return "", false, err
+	}
+	if cmd.Flags().Changed("change-ref") {
+		if err := utils.ValidateChangeRef(changeRef); err != nil {
+			return "", false, err
+		}
+	}
+	stamp := false
+	if cmd.Flags().Lookup("stamp-change-ref") != nil {
+		if stamp, err = cmd.Flags().GetBool("stamp-change-ref"); err != nil {
+			return "", false, err
+		}
+	}
+	if stamp && changeRef == "" {
+		return "", false, fmt.Errorf("--stamp-change-ref requires --change-ref")
+	}
+	return changeRef, stamp, nil
+}
+
+// defaultPostHookTimeout is how long a --post-hook command may run for one organization by default
+const defaultPostHookTimeout = time.Minute
+
+// addPostHookFlags registers --post-hook and its options on a command that applies configurations
+func addPostHookFlags(cmd *cobra.Command) {
+	cmd.Flags().String("post-hook", "", "Command run after each organization that succeeds, with {org} and {config_id} replaced, e.g. \"./register.sh {org} {config_id}\"")
+	cmd.Flags().Duration("post-hook-timeout", defaultPostHookTimeout, "Give up on a --post-hook command that runs longer than this (0 means no limit)")
+	cmd.Flags().Bool("ignore-hook-failures", false, "Exit with code 0 even when a --post-hook command failed; failures are still reported")
+}
+
+// extractPostHookFlags reads --post-hook, --post-hook-timeout and --ignore-hook-failures. The
+// returned hook has an empty command when --post-hook is not set.
+func extractPostHookFlags(cmd *cobra.Command) (processors.PostHook, bool, error) {
+	command, err := cmd.Flags().GetString("post-hook")
+	if err != nil {
+		return processors.PostHook{}, false, err
+	}
+	timeout, err := cmd.Flags().GetDuration("post-hook-timeout")
+	if err != nil {
+		return processors.PostHook{}, false, err
+	}
+	ignoreFailures, err := cmd.Flags().GetBool("ignore-hook-failures")
+	if err != nil {
+		return processors.PostHook{}, false, err
+	}
+
+	command = strings.TrimSpace(command)
+	if cmd.Flags().Changed("post-hook") && command == "" {
+		return processors.PostHook{}, false, fmt.Errorf("--post-hook must not be empty")
+	}
+	if timeout < 0 {
+		return processors.PostHook{}, false, fmt.Errorf("--post-hook-timeout must be 0 (no limit) or a positive duration, got %s", timeout)
+	}
+	if command == "" && (cmd.Flags().Changed("post-hook-timeout") || ignoreFailures) {
+		return processors.PostHook{}, false, fmt.Errorf("--post-hook-timeout and --ignore-hook-failures require --post-hook")
+	}
+	return processors.PostHook{Command: command, Timeout: timeout}, ignoreFailures, nil
+}
+
+// addPostHookReplicationFlags adds the --post-hook flags to a replication command when a hook is set
+func addPostHookReplicationFlags(replicationFlags map[string]interface{}, hook processors.PostHook, ignoreFailures bool) {
+	if hook.Command == "" {
+		return
+	}
+	replicationFlags["post-hook"] = hook.Command
+	if hook.Timeout != defaultPostHookTimeout {
+		replicationFlags["post-hook-timeout"] = hook.Timeout.String()
+	}
+	replicationFlags["ignore-hook-failures"] = ignoreFailures
+}
+
+// addCodeQLRepoFlags registers the flags that limit attachment to repositories CodeQL can analyze
+func addCodeQLRepoFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("only-codeql-supported-repos", false, "Only attach the configuration to repositories whose primary language CodeQL supports")
+	cmd.Flags().String("codeql-languages", "", "Comma-separated primary languages to treat as CodeQL-supported, replacing the built-in list (requires --only-codeql-supported-repos)")
+}
+
+// extractCodeQLRepoFlags reads --only-codeql-supported-repos and --codeql-languages. It returns the
+// raw --codeql-languages value, for replication, and the languages repositories must have; the
+// set is nil when attachment is not limited by language.
+func extractCodeQLRepoFlags(cmd *cobra.Command) (bool, string, types.LanguageSet, error) {
+	onlyCodeQL, err := cmd.Flags().GetBool("only-codeql-supported-repos")
+	if err != nil {
+		return false, "", nil, err
+	}
+	languagesFlag, err := cmd.Flags().GetString("codeql-languages")
+	if err != nil {
+		return false, "", nil, err
+	}
+	if !cmd.Flags().Changed("codeql-languages") {
+		if !onlyCodeQL {
+			return false, "", nil, nil
+		}
+		return true, "", types.NewLanguageSet(types.CodeQLLanguages), nil
+	}
+	if !onlyCodeQL {
+		return false, "", nil, fmt.Errorf("--codeql-languages requires --only-codeql-supported-repos")
+	}
+
+	var languages []string
+	for _, language := range strings.Split(languagesFlag, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			languages = append(languages, language)
+		}
+	}
+	if len(languages) == 0 {
+		return false, "", nil, fmt.Errorf("--codeql-languages must list at least one language")
+	}
+	return true, languagesFlag, types.NewLanguageSet(languages), nil
 }
 
 // extractSkipConfirmationFlag reads the universal --skip-confirmation-message flag. An
@@ -142,5 +281,46 @@ func extractSecuritySettingOverrides(cmd *cobra.Command) (ui.SecuritySettingOver
 	}
 	out.Enforcement = enf
 
+	settingsLists, err := cmd.Flags().GetStringArray("settings")
+	if err != nil {
+		return out, err
+	}
+	settings, err := utils.ParseSettingFlags(settingsLists)
+	if err != nil {
+		return out, err
+	}
+	if err := applySettingFlags(&out, settings); err != nil {
+		return out, err
+	}
+
 	return out, nil
 }
+
+// applySettingFlags merges parsed --settings values into the overrides. A setting
+// given both through them and its dedicated flag is rejected so the effective value is never
+// ambiguous.
+func applySettingFlags(out *ui.SecuritySettingOverrides, settings map[string]string) error {
+	fields := map[string]struct {
+		flag  string
+		value *string
+	}{
+		"advanced_security":                     {securitySettingFlagNames.AdvancedSecurity, &out.AdvancedSecurity},
+		"dependabot_alerts":                     {securitySettingFlagNames.DependabotAlerts, &out.DependabotAlerts},
+		"dependabot_security_updates":           {securitySettingFlagNames.DependabotSecurityUpdates, &out.DependabotSecurityUpdates},
+		"secret_scanning":                       {securitySettingFlagNames.SecretScanning, &out.SecretScanning},
+		"secret_scanning_push_protection":       {securitySettingFlagNames.SecretScanningPushProtection, &out.SecretScanningPushProtection},
+		"secret_scanning_non_provider_patterns": {securitySettingFlagNames.SecretScanningNonProviderPatterns, &out.SecretScanningNonProviderPatterns},
+		"enforcement":                           {securitySettingFlagNames.Enforcement, &out.Enforcement},
+	}
+	for key, value := range settings {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("setting %q cannot be set with --setting or --settings", key)
+		}
+		if *field.value != "" {
+			return fmt.Errorf("setting %s from --setting or --settings cannot be used together with --%s", key, field.flag)
+		}
+		*field.value = value
+	}
+	return nil
+}
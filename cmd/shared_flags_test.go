@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExtractSecuritySettingOverrides_SettingFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"setting only", []string{"--setting", "advanced_security=enabled", "--setting", "enforcement=enforced"}, ""},
+		{"setting with other dedicated flag", []string{"--setting", "advanced_security=enabled", "--enforcement", "enforced"}, ""},
+		{"setting and same dedicated flag", []string{"--setting", "enforcement=enforced", "--enforcement", "enforced"}, "cannot be used together with --enforcement"},
+		{"unknown key", []string{"--setting", "code_scanning=enabled"}, "unknown key 'code_scanning'"},
+		{"settings list", []string{"--settings", "advanced_security=enabled,enforcement=enforced"}, ""},
+		{"settings list and setting", []string{"--settings", "advanced_security=enabled", "--setting", "enforcement=enforced"}, ""},
+		{"settings list and same dedicated flag", []string{"--settings", "advanced_security=enabled,enforcement=enforced", "--enforcement", "enforced"}, "cannot be used together with --enforcement"},
+		{"settings list conflicts with setting", []string{"--settings", "advanced_security=enabled,enforcement=enforced", "--setting", "enforcement=unenforced"}, "--settings[3]: enforcement is already set to 'enforced'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addSecuritySettingFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags: %v", err)
+			}
+			got, err := extractSecuritySettingOverrides(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.AdvancedSecurity != "enabled" || got.Enforcement != "enforced" {
+				t.Errorf("got %+v, want advanced security enabled and enforcement enforced", got)
+			}
+		})
+	}
+}
+
+func TestExtractChangeRefFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		stampFlag bool
+		args      []string
+		wantRef   string
+		wantStamp bool
+		wantErr   string
+	}{
+		{name: "not given", stampFlag: true},
+		{name: "reference only", args: []string{"--change-ref", "CHG-12345"}, wantRef: "CHG-12345"},
+		{name: "reference and stamp", stampFlag: true, args: []string{"--change-ref", "CHG-12345", "--stamp-change-ref"}, wantRef: "CHG-12345", wantStamp: true},
+		{name: "explicitly empty", args: []string{"--change-ref", " "}, wantErr: "cannot be empty"},
+		{name: "stamp without reference", stampFlag: true, args: []string{"--stamp-change-ref"}, wantErr: "requires --change-ref"},
+		{name: "whitespace in reference", args: []string{"--change-ref", "CHG 1"}, wantErr: "must not contain whitespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addChangeRefFlags(cmd, tt.stampFlag)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags: %v", err)
+			}
+			ref, stamp, err := extractChangeRefFlags(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref != tt.wantRef || stamp != tt.wantStamp {
+				t.Errorf("extractChangeRefFlags() = %q, %t; want %q, %t", ref, stamp, tt.wantRef, tt.wantStamp)
+			}
+		})
+	}
+}
+
+func TestExtractCodeQLRepoFlags(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantOnly     bool
+		wantIncluded []string
+		wantExcluded []string
+		wantErr      string
+	}{
+		{name: "not given"},
+		{name: "built-in languages", args: []string{"--only-codeql-supported-repos"}, wantOnly: true, wantIncluded: []string{"Go", "typescript", "C#"}, wantExcluded: []string{"HTML", ""}},
+		{name: "override", args: []string{"--only-codeql-supported-repos", "--codeql-languages", "Go, Rust"}, wantOnly: true, wantIncluded: []string{"go", "Rust"}, wantExcluded: []string{"Python"}},
+		{name: "override without filter", args: []string{"--codeql-languages", "Go"}, wantErr: "requires --only-codeql-supported-repos"},
+		{name: "empty override", args: []string{"--only-codeql-supported-repos", "--codeql-languages", " , "}, wantErr: "at least one language"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addCodeQLRepoFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags: %v", err)
+			}
+			only, _, languages, err := extractCodeQLRepoFlags(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if only != tt.wantOnly || (languages == nil) == tt.wantOnly {
+				t.Fatalf("got only=%t languages=%v, want only=%t", only, languages, tt.wantOnly)
+			}
+			for _, language := range tt.wantIncluded {
+				if !languages.Includes(language) {
+					t.Errorf("%q not included", language)
+				}
+			}
+			for _, language := range tt.wantExcluded {
+				if languages.Includes(language) {
+					t.Errorf("%q included", language)
+				}
+			}
+		})
+	}
+}
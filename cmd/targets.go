@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// commandTargets holds the enterprise and organizations resolved for a command that needs no
+// input beyond the persistent flags
+type commandTargets struct {
+	Enterprises []string
+	ServerURL   string
+	CommonFlags *utils.CommonFlags
+	Orgs        []string
+	Breakdown   types.TargetBreakdown // How the targeting flags were narrowed down to Orgs
+}
+
+// resolveCommandTargets validates the common flags, prompts for any missing enterprise and
+// targeting input, and fetches the organizations to process
+func resolveCommandTargets(cmd *cobra.Command) (*commandTargets, error) {
+	commonFlags, err := utils.ExtractCommonFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateOrgFlagsOptional(commonFlags); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateConcurrency(commonFlags.Concurrency); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateDelay(commonFlags.Delay); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateConcurrencyAndDelay(commonFlags.Concurrency, commonFlags.Delay); err != nil {
+		return nil, err
+	}
+
+	enterpriseFlags, err := cmd.Flags().GetStringSlice("enterprise-slug")
+	if err != nil {
+		return nil, err
+	}
+	serverURLFlag, err := cmd.Flags().GetString("github-enterprise-server-url")
+	if err != nil {
+		return nil, err
+	}
+
+	enterprises, err := resolveEnterprises(enterpriseFlags, commonFlags)
+	if err != nil {
+		return nil, err
+	}
+	serverURL, err := ui.GetServerURLInput(serverURLFlag)
+	if err != nil {
+		return nil, err
+	}
+	ui.SetupGitHubHost(serverURL)
+
+	orgs, breakdown, err := resolveTargetOrganizations(enterprises, commonFlags, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &commandTargets{Enterprises: enterprises, ServerURL: serverURL, CommonFlags: commonFlags, Orgs: orgs, Breakdown: breakdown}, nil
+}
+
+// resolveEnterprises returns the enterprise slugs given with --enterprise-slug, without blanks or
+// repeats, prompting for one when none was given. A run that targets a single organization with
+// --org does not need an enterprise, so owners of that organization who are not enterprise admins
+// can run it: no slug is prompted for, and no slugs means every enterprise call is skipped.
+func resolveEnterprises(enterpriseFlags []string, commonFlags *utils.CommonFlags) ([]string, error) {
+	var enterprises []string
+	for _, slug := range enterpriseFlags {
+		slug = strings.TrimSpace(slug)
+		if slug != "" && !slices.Contains(enterprises, slug) {
+			enterprises = append(enterprises, slug)
+		}
+	}
+	if len(enterprises) > 0 {
+		return enterprises, nil
+	}
+	if commonFlags.Org != "" {
+		pterm.Info.Printf("No enterprise slug given; working on organization '%s' only, without enterprise calls\n", commonFlags.Org)
+		return nil, nil
+	}
+	enterprise, err := ui.GetEnterpriseInput("")
+	if err != nil {
+		return nil, err
+	}
+	return []string{enterprise}, nil
+}
+
+// configEnterprise returns the enterprise whose configurations a command can use. An enterprise
+// configuration belongs to a single enterprise, so there is none when several are targeted.
+func configEnterprise(enterprises []string) string {
+	if len(enterprises) == 1 {
+		return enterprises[0]
+	}
+	if len(enterprises) > 1 {
+		pterm.Info.Println("Several enterprises are targeted; only organization configurations are used")
+	}
+	return ""
+}
+
+// targetReplicationFlags returns the replication flags for the persistent flags resolved into targets
+func targetReplicationFlags(cmd *cobra.Command, targets *commandTargets) (map[string]interface{}, error) {
+	logLevel, err := cmd.Flags().GetString("log-level")
+	if err != nil {
+		return nil, err
+	}
+
+	replicationFlags := map[string]interface{}{
+		"enterprise-slug":              strings.Join(targets.Enterprises, ","),
+		"github-enterprise-server-url": targets.ServerURL,
+		"concurrency":                  targets.CommonFlags.Concurrency,
+		"max-orgs-per-minute":          targets.CommonFlags.MaxOrgsPerMinute,
+		"per-org-timeout":              targets.CommonFlags.PerOrgTimeout,
+		"report-order":                 targets.CommonFlags.ReportOrder,
+		"delay":                        targets.CommonFlags.Delay,
+		"log-level":                    logLevel,
+	}
+	if targets.CommonFlags.Org != "" {
+		replicationFlags["org"] = targets.CommonFlags.Org
+	} else if targets.CommonFlags.OrgListPath != "" {
+		replicationFlags["org-list"] = targets.CommonFlags.OrgListPath
+		replicationFlags["strict-org-list"] = targets.CommonFlags.StrictOrgList
+		replicationFlags["fail-on-invalid-orgs"] = targets.CommonFlags.FailOnInvalidOrgs
+	} else if targets.CommonFlags.AllOrgs {
+		replicationFlags["all-orgs"] = true
+	}
+	replicationFlags["exclude-with-property"] = utils.FormatPropertyFilters(targets.CommonFlags.ExcludeProperties)
+	return replicationFlags, nil
+}
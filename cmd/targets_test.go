@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+func TestResolveEnterprises(t *testing.T) {
+	tests := []struct {
+		name            string
+		enterpriseFlags []string
+		org             string
+		want            []string
+	}{
+		{name: "single org without enterprise", org: "my-org", want: nil},
+		{name: "single org with enterprise", enterpriseFlags: []string{"acme"}, org: "my-org", want: []string{"acme"}},
+		{name: "enterprise given for all orgs", enterpriseFlags: []string{" acme "}, want: []string{"acme"}},
+		{name: "several enterprises keep their order", enterpriseFlags: []string{"globex", "acme", "globex", ""}, want: []string{"globex", "acme"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveEnterprises(tt.enterpriseFlags, &utils.CommonFlags{Org: tt.org})
+			if err != nil {
+				t.Fatalf("resolveEnterprises() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveEnterprises() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigEnterprise(t *testing.T) {
+	tests := []struct {
+		enterprises []string
+		want        string
+	}{
+		{enterprises: nil, want: ""},
+		{enterprises: []string{"acme"}, want: "acme"},
+		{enterprises: []string{"acme", "globex"}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := configEnterprise(tt.enterprises); got != tt.want {
+			t.Errorf("configEnterprise(%q) = %q, want %q", tt.enterprises, got, tt.want)
+		}
+	}
+}
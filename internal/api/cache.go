@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"github.com/cli/go-gh/v2"
+)
+
+// execGH runs a gh CLI command, writing stdin to its standard input unless it is nil. The command
+// is killed when ctx is cancelled. It is a variable so tests can count the requests that reach the
+// API.
+var execGH = execGHCommand
+
+// execGHCommand runs gh as gh.Exec does. gh.Exec leaves standard input unconnected, so a command
+// with input starts the gh executable itself.
+func execGHCommand(ctx context.Context, stdin []byte, args ...string) (stdout, stderr bytes.Buffer, err error) {
+	if stdin == nil {
+		return gh.ExecContext(ctx, args...)
+	}
+	ghExe, err := gh.Path()
+	if err != nil {
+		return stdout, stderr, err
+	}
+	cmd := exec.CommandContext(ctx, ghExe, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout, stderr, fmt.Errorf("gh execution failed: %w", err)
+	}
+	return stdout, stderr, nil
+}
+
+// requestCache holds successful GET responses for the rest of a run, keyed by method and path.
+// It is disabled unless a read-only command enables it, so commands that change configurations
+// always read fresh state.
+type requestCache struct {
+	mu      sync.Mutex
+	enabled bool
+	entries map[string][]byte
+	hits    int
+	misses  int
+}
+
+var cache = &requestCache{}
+
+// EnableRequestCache turns on caching of GET responses for the rest of the run
+func EnableRequestCache() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.enabled = true
+	cache.entries = make(map[string][]byte)
+	cache.hits, cache.misses = 0, 0
+}
+
+// DisableRequestCache turns off caching and discards any cached responses and statistics
+func DisableRequestCache() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.enabled = false
+	cache.entries = nil
+	cache.hits, cache.misses = 0, 0
+}
+
+// RequestCacheStats returns how many GET requests were served from the cache and how many reached
+// the API while the cache was enabled
+func RequestCacheStats() (hits, misses int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.hits, cache.misses
+}
+
+// lookup returns the cached response for key, counting a hit or miss while the cache is enabled
+func (c *requestCache) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled {
+		return nil, false
+	}
+	body, ok := c.entries[key]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return body, ok
+}
+
+// store records a successful response for key while the cache is enabled
+func (c *requestCache) store(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.enabled {
+		c.entries[key] = body
+	}
+}
+
+// restGet sends a GET request for path with the standard REST headers. While the request cache is
+// enabled, a repeated request is answered from memory. Failed requests are never cached.
+func restGet(ctx context.Context, path string) (bytes.Buffer, bytes.Buffer, error) {
+	key := "GET " + path
+	if body, ok := cache.lookup(key); ok {
+		return *bytes.NewBuffer(body), bytes.Buffer{}, nil
+	}
+
+	response, stderr, err := runGH(ctx, restArgs(path)...)
+	if err == nil {
+		cache.store(key, bytes.Clone(response.Bytes()))
+	}
+	return response, stderr, err
+}
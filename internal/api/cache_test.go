@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubExecGH replaces the gh CLI with a function that counts requests per path and fails for
+// paths listed in failing
+func stubExecGH(t *testing.T, failing map[string]bool) map[string]int {
+	t.Helper()
+	calls := make(map[string]int)
+	original := execGH
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		path := args[len(args)-1]
+		calls[path]++
+		if failing[path] {
+			return bytes.Buffer{}, *bytes.NewBufferString("gh: Not Found (HTTP 404)"), errors.New("exit status 1")
+		}
+		return *bytes.NewBufferString(`{"path":"` + path + `"}`), bytes.Buffer{}, nil
+	}
+	t.Cleanup(func() {
+		execGH = original
+		DisableRequestCache()
+		ResetMembershipCache()
+	})
+	return calls
+}
+
+func TestRestGet_CachesRepeatedRequestsWhenEnabled(t *testing.T) {
+	calls := stubExecGH(t, nil)
+	EnableRequestCache()
+
+	for i := 0; i < 3; i++ {
+		response, _, err := restGet(context.Background(), "/orgs/acme/code-security/configurations")
+		if err != nil {
+			t.Fatalf("restGet() error = %v", err)
+		}
+		if got, want := response.String(), `{"path":"/orgs/acme/code-security/configurations"}`; got != want {
+			t.Errorf("restGet() response = %s, want %s", got, want)
+		}
+	}
+	if _, _, err := restGet(context.Background(), "/orgs/other/code-security/configurations"); err != nil {
+		t.Fatalf("restGet() error = %v", err)
+	}
+
+	if calls["/orgs/acme/code-security/configurations"] != 1 {
+		t.Errorf("repeated path sent %d times, want 1", calls["/orgs/acme/code-security/configurations"])
+	}
+	if hits, misses := RequestCacheStats(); hits != 2 || misses != 2 {
+		t.Errorf("RequestCacheStats() = %d hits, %d misses, want 2 hits, 2 misses", hits, misses)
+	}
+}
+
+func TestRestGet_DisabledCacheAlwaysSendsRequests(t *testing.T) {
+	calls := stubExecGH(t, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := restGet(context.Background(), "/meta"); err != nil {
+			t.Fatalf("restGet() error = %v", err)
+		}
+	}
+	if calls["/meta"] != 2 {
+		t.Errorf("path sent %d times, want 2", calls["/meta"])
+	}
+	if hits, misses := RequestCacheStats(); hits != 0 || misses != 0 {
+		t.Errorf("RequestCacheStats() = %d hits, %d misses, want none", hits, misses)
+	}
+}
+
+func TestRestGet_DoesNotCacheFailures(t *testing.T) {
+	calls := stubExecGH(t, map[string]bool{"/orgs/missing/code-security/configurations": true})
+	EnableRequestCache()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := restGet(context.Background(), "/orgs/missing/code-security/configurations"); err == nil {
+			t.Fatal("restGet() expected an error")
+		}
+	}
+	if calls["/orgs/missing/code-security/configurations"] != 2 {
+		t.Errorf("failing path sent %d times, want 2", calls["/orgs/missing/code-security/configurations"])
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/telemetry"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// apiCallCounter counts the requests that reach the gh CLI, in total and by HTTP method, and
+// enforces the optional --max-api-calls budget. It is shared by every organization worker, so it
+// is guarded by a mutex.
+type apiCallCounter struct {
+	mu       sync.Mutex
+	count    int
+	byMethod map[string]int
+	limit    int // 0 means unlimited
+}
+
+var apiCalls = &apiCallCounter{byMethod: make(map[string]int)}
+
+// SetMaxAPICalls sets the most API requests the run may make; 0 removes the limit
+func SetMaxAPICalls(limit int) {
+	apiCalls.mu.Lock()
+	defer apiCalls.mu.Unlock()
+	apiCalls.limit = limit
+}
+
+// APICallCount returns how many API requests the run has made so far and the limit, if any
+func APICallCount() (count, limit int) {
+	apiCalls.mu.Lock()
+	defer apiCalls.mu.Unlock()
+	return apiCalls.count, apiCalls.limit
+}
+
+// APICallCountByMethod returns how many API requests the run has made so far for each HTTP method
+func APICallCountByMethod() map[string]int {
+	apiCalls.mu.Lock()
+	defer apiCalls.mu.Unlock()
+	return maps.Clone(apiCalls.byMethod)
+}
+
+// ResetAPICallCount sets the number of API requests made back to zero
+func ResetAPICallCount() {
+	apiCalls.mu.Lock()
+	defer apiCalls.mu.Unlock()
+	apiCalls.count = 0
+	clear(apiCalls.byMethod)
+}
+
+// reserve counts one request with the given method, or returns a stop condition when the budget
+// is already used up
+func (c *apiCallCounter) reserve(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limit > 0 && c.count >= c.limit {
+		return &types.StopConditionError{Reason: fmt.Sprintf("the budget of %d API calls set by --max-api-calls was reached", c.limit)}
+	}
+	c.count++
+	c.byMethod[method]++
+	return nil
+}
+
+// DefaultAPIVersion is the REST API version requested unless --api-version overrides it or the
+// GHES release needs another one
+const DefaultAPIVersion = "2022-11-28"
+
+// serverAPIVersions maps GHES releases to the REST API version requested from them, newest first.
+// Each entry applies to its release and every later one until the next entry. Releases older than
+// the last entry predate date-based API versions and are sent no version header.
+var serverAPIVersions = []struct {
+	Major, Minor int
+	APIVersion   string
+}{
+	{Major: 3, Minor: 9, APIVersion: "2022-11-28"},
+}
+
+// APIVersionForServer returns the REST API version to request from a GHES release given as
+// "major.minor", such as "3.14". GitHub.com, given as "", and releases that cannot be parsed get
+// DefaultAPIVersion. Releases that predate API versions get "", meaning no header is sent.
+func APIVersionForServer(ghesVersion string) string {
+	major, minor, ok := parseServerVersion(ghesVersion)
+	if !ok {
+		return DefaultAPIVersion
+	}
+	for _, entry := range serverAPIVersions {
+		if major > entry.Major || (major == entry.Major && minor >= entry.Minor) {
+			return entry.APIVersion
+		}
+	}
+	return ""
+}
+
+// parseServerVersion splits a "major.minor" GHES release into its numbers
+func parseServerVersion(version string) (int, int, bool) {
+	majorText, minorText, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(minorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// requestedAPIVersion holds the REST API version sent with every request: the --api-version
+// override when one was set, and otherwise the version chosen for the host requests go to. The
+// mutex guards the fields only; it is never held while the server's release is read.
+var requestedAPIVersion struct {
+	mu       sync.Mutex
+	override string
+	host     string // host version was chosen for; empty until DetectAPIVersion runs
+	version  string
+}
+
+// SetAPIVersion sets the REST API version sent with every REST request for the rest of the run,
+// in place of the version chosen for the server. GitHub names API versions by their release date,
+// so version must be a YYYY-MM-DD date.
+func SetAPIVersion(version string) error {
+	if _, err := time.Parse("2006-01-02", version); err != nil {
+		return fmt.Errorf("invalid value for --api-version: %q (must be a date such as %s)", version, DefaultAPIVersion)
+	}
+	requestedAPIVersion.mu.Lock()
+	defer requestedAPIVersion.mu.Unlock()
+	requestedAPIVersion.override = version
+	return nil
+}
+
+// ResetAPIVersion discards the version set by SetAPIVersion, the one chosen for the server and the
+// server release read by GetGHESVersion, so they are chosen and read again
+func ResetAPIVersion() {
+	requestedAPIVersion.mu.Lock()
+	requestedAPIVersion.override = ""
+	requestedAPIVersion.host = ""
+	requestedAPIVersion.version = ""
+	requestedAPIVersion.mu.Unlock()
+
+	serverRelease.mu.Lock()
+	defer serverRelease.mu.Unlock()
+	serverRelease.host = ""
+	serverRelease.version = ""
+	serverRelease.err = nil
+}
+
+// DetectAPIVersion chooses the REST API version for the current host, unless --api-version set
+// one. Commands run it once the host is known, before processing, through ui.SetupGitHubHost.
+// GitHub.com gets DefaultAPIVersion. For another host the release is read with GetGHESVersion and
+// the version picked from serverAPIVersions, keeping DefaultAPIVersion when it cannot be read.
+func DetectAPIVersion() {
+	host := CurrentHostName()
+	requestedAPIVersion.mu.Lock()
+	settled := requestedAPIVersion.override != "" || requestedAPIVersion.host == host
+	requestedAPIVersion.mu.Unlock()
+	if settled {
+		return
+	}
+
+	version := DefaultAPIVersion
+	if host != "github.com" {
+		version = serverAPIVersion()
+	}
+
+	requestedAPIVersion.mu.Lock()
+	defer requestedAPIVersion.mu.Unlock()
+	requestedAPIVersion.host = host
+	requestedAPIVersion.version = version
+}
+
+// serverAPIVersion returns the API version to request from the current GHES host, read from its
+// release. DefaultAPIVersion is kept when the release cannot be read.
+func serverAPIVersion() string {
+	ghesVersion, err := GetGHESVersion()
+	if err != nil || ghesVersion == "" {
+		return DefaultAPIVersion
+	}
+	version := APIVersionForServer(ghesVersion)
+	if loglevel.InfoEnabled() {
+		if version == "" {
+			pterm.Info.Printf("GHES %s predates REST API versions: sending no X-GitHub-Api-Version header\n", ghesVersion)
+		} else {
+			pterm.Info.Printf("GHES %s: requesting REST API version %s\n", ghesVersion, version)
+		}
+	}
+	return version
+}
+
+// apiVersion returns the REST API version to send: the override, or the version DetectAPIVersion
+// chose for the current host. A request to a host it has not run for yet runs it first.
+func apiVersion() string {
+	DetectAPIVersion()
+	requestedAPIVersion.mu.Lock()
+	defer requestedAPIVersion.mu.Unlock()
+	if requestedAPIVersion.override != "" {
+		return requestedAPIVersion.override
+	}
+	return requestedAPIVersion.version
+}
+
+// commonHeaders returns the gh CLI header arguments sent with every REST request: the media type
+// and, unless the server predates API versions, the API version
+func commonHeaders() []string {
+	headers := []string{"-H", "Accept: application/vnd.github+json"}
+	if version := apiVersion(); version != "" {
+		headers = append(headers, "-H", "X-GitHub-Api-Version: "+version)
+	}
+	return headers
+}
+
+// restArgs builds the gh CLI arguments for a REST request: the options, commonHeaders, and the path
+// last. Every REST request is built here.
+func restArgs(path string, options ...string) []string {
+	args := append([]string{"api"}, options...)
+	args = append(args, commonHeaders()...)
+	return append(args, path)
+}
+
+// runGH runs a gh CLI command that makes an API request, counting it against the budget. Every
+// request goes through this function or runGHWithInput so the count is complete. Once ctx is
+// done, no request is started and one in flight is stopped; the error then wraps ctx.Err().
+func runGH(ctx context.Context, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	return runGHWithInput(ctx, nil, args...)
+}
+
+// runGHWithInput is runGH for a command that reads stdin, such as gh api --input -
+func runGHWithInput(ctx context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	if err := ctx.Err(); err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+	method, endpoint := describeRequest(args)
+	if err := apiCalls.reserve(method); err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+	if !telemetry.Enabled() {
+		return execCancellable(ctx, stdin, args...)
+	}
+
+	attrs := map[string]interface{}{telemetry.AttrHTTPMethod: method, telemetry.AttrEndpoint: endpoint}
+	if org := orgFromEndpoint(endpoint); org != "" {
+		attrs[telemetry.AttrOrg] = org
+	}
+	span := telemetry.StartSpan(telemetry.SpanAPICall, attrs)
+	response, stderr, err := execCancellable(ctx, stdin, args...)
+	span.End(err)
+	return response, stderr, err
+}
+
+// execCancellable runs execGH and, when it fails because ctx was cancelled, wraps ctx.Err() so
+// callers can tell a stopped request from one the API rejected
+func execCancellable(ctx context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	response, stderr, err := execGH(ctx, stdin, args...)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return response, stderr, err
+}
+
+// describeRequest returns the HTTP method and endpoint of a gh api command line
+func describeRequest(args []string) (method, endpoint string) {
+	method = "GET"
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--method" && i+1 < len(args):
+			method = args[i+1]
+			i++
+		case args[i] == "-H" || args[i] == "-f" || args[i] == "--input":
+			i++ // skip the option's value
+		case args[i] == "graphql":
+			method, endpoint = "POST", "/graphql"
+		case strings.HasPrefix(args[i], "/") && endpoint == "":
+			endpoint = args[i]
+		}
+	}
+	return method, endpoint
+}
+
+// orgFromEndpoint returns the organization named in an endpoint containing /orgs/{org}, such as
+// /orgs/{org}/code-security/configurations or /user/memberships/orgs/{org}
+func orgFromEndpoint(endpoint string) string {
+	_, rest, ok := strings.Cut(endpoint, "/orgs/")
+	if !ok {
+		return ""
+	}
+	org, _, _ := strings.Cut(rest, "/")
+	org, _, _ = strings.Cut(org, "?")
+	return org
+}
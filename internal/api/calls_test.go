@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestMaxAPICallsGuard(t *testing.T) {
+	original := execGH
+	t.Cleanup(func() {
+		execGH = original
+		SetMaxAPICalls(0)
+		ResetAPICallCount()
+	})
+	sent := 0
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		sent++
+		return *bytes.NewBufferString(`{}`), bytes.Buffer{}, nil
+	}
+
+	ResetAPICallCount()
+	SetMaxAPICalls(3)
+	for i := 1; i <= 3; i++ {
+		if _, _, err := runGH(context.Background(), "api", "/user"); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+
+	_, _, err := runGH(context.Background(), "api", "/user")
+	var stopErr *types.StopConditionError
+	if !errors.As(err, &stopErr) {
+		t.Fatalf("call past the budget: error = %v, want a StopConditionError", err)
+	}
+	if sent != 3 {
+		t.Errorf("sent %d requests, want the guard to stop at 3", sent)
+	}
+	if count, limit := APICallCount(); count != 3 || limit != 3 {
+		t.Errorf("APICallCount() = %d, %d; want 3, 3", count, limit)
+	}
+
+	// Removing the limit lets requests through again
+	SetMaxAPICalls(0)
+	if _, _, err := runGH(context.Background(), "api", "/user"); err != nil {
+		t.Errorf("unexpected error without a limit: %v", err)
+	}
+}
+
+func TestRunGH_CancelledContextSendsNothing(t *testing.T) {
+	calls := stubExecGH(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := runGH(ctx, "api", "/user")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("API calls = %v, want none", calls)
+	}
+}
+
+func TestAPICallCountByMethod(t *testing.T) {
+	original := execGH
+	t.Cleanup(func() {
+		execGH = original
+		ResetAPICallCount()
+	})
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		return *bytes.NewBufferString(`{}`), bytes.Buffer{}, nil
+	}
+	ResetAPICallCount()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _, _ = runGH(context.Background(), "api", "/orgs/octo")
+		}()
+		go func() {
+			defer wg.Done()
+			_, _, _ = runGH(context.Background(), "api", "--method", "PATCH", "/orgs/octo/code-security/configurations/1")
+		}()
+	}
+	wg.Wait()
+	_, _, _ = runGH(context.Background(), "api", "graphql", "-f", "query=x")
+
+	if count, _ := APICallCount(); count != 21 {
+		t.Errorf("APICallCount() = %d, want 21", count)
+	}
+	want := map[string]int{"GET": 10, "PATCH": 10, "POST": 1}
+	if got := APICallCountByMethod(); !reflect.DeepEqual(got, want) {
+		t.Errorf("APICallCountByMethod() = %v, want %v", got, want)
+	}
+
+	ResetAPICallCount()
+	if got := APICallCountByMethod(); len(got) != 0 {
+		t.Errorf("APICallCountByMethod() after reset = %v, want empty", got)
+	}
+}
+
+func TestMaxAPICallsCachedReadsAreFree(t *testing.T) {
+	calls := stubExecGH(t, nil)
+	t.Cleanup(func() {
+		SetMaxAPICalls(0)
+		ResetAPICallCount()
+	})
+	EnableRequestCache()
+	ResetAPICallCount()
+	SetMaxAPICalls(1)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := restGet(context.Background(), "/orgs/a/code-security/configurations"); err != nil {
+			t.Fatalf("read %d: unexpected error: %v", i, err)
+		}
+	}
+	if calls["/orgs/a/code-security/configurations"] != 1 {
+		t.Errorf("sent %d requests, want 1", calls["/orgs/a/code-security/configurations"])
+	}
+}
+
+func TestDescribeRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantMethod   string
+		wantEndpoint string
+		wantOrg      string
+	}{
+		{"default GET", []string{"api", "/orgs/octo/code-security/configurations", "-H", "Accept: x"}, "GET", "/orgs/octo/code-security/configurations", "octo"},
+		{"explicit method", []string{"api", "--method", "PATCH", "/orgs/octo/code-security/configurations/7", "--input", "-"}, "PATCH", "/orgs/octo/code-security/configurations/7", "octo"},
+		{"membership", []string{"api", "/user/memberships/orgs/octo"}, "GET", "/user/memberships/orgs/octo", "octo"},
+		{"graphql", []string{"api", "graphql", "-f", "query=x"}, "POST", "/graphql", ""},
+		{"enterprise", []string{"api", "/enterprises/acme/code-security/configurations"}, "GET", "/enterprises/acme/code-security/configurations", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, endpoint := describeRequest(tt.args)
+			if method != tt.wantMethod || endpoint != tt.wantEndpoint {
+				t.Errorf("describeRequest() = %q, %q; want %q, %q", method, endpoint, tt.wantMethod, tt.wantEndpoint)
+			}
+			if got := orgFromEndpoint(endpoint); got != tt.wantOrg {
+				t.Errorf("orgFromEndpoint(%q) = %q, want %q", endpoint, got, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestSetAPIVersion_AppliedToEveryRESTRequest(t *testing.T) {
+	original := execGH
+	t.Cleanup(func() {
+		execGH = original
+		ResetAPIVersion()
+		DisableRequestCache()
+	})
+	var headers []string
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		for i, arg := range args {
+			if arg == "-H" && strings.HasPrefix(args[i+1], "X-GitHub-Api-Version:") {
+				headers = append(headers, args[i+1])
+			}
+		}
+		return *bytes.NewBufferString(`{"resources":{"core":{"limit":5000,"remaining":5000,"reset":0}}}`), bytes.Buffer{}, nil
+	}
+
+	for _, version := range []string{"2026-03-10", "next", "2026-13-01", ""} {
+		err := SetAPIVersion(version)
+		if valid := version == "2026-03-10"; (err == nil) != valid {
+			t.Errorf("SetAPIVersion(%q) error = %v, want valid = %t", version, err, valid)
+		}
+	}
+
+	headers = nil
+	_, _ = GetRateLimit()
+	_ = SetConfigurationAsDefault(context.Background(), "org", 7)
+	_ = DeleteSecurityConfiguration(context.Background(), "org", 7)
+	want := []string{"X-GitHub-Api-Version: 2026-03-10", "X-GitHub-Api-Version: 2026-03-10", "X-GitHub-Api-Version: 2026-03-10"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("version headers = %v, want %v", headers, want)
+	}
+}
+
+func TestCommonHeaders(t *testing.T) {
+	t.Cleanup(ResetAPIVersion)
+
+	want := []string{"-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28"}
+	if got := commonHeaders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commonHeaders() = %v, want %v", got, want)
+	}
+
+	if err := SetAPIVersion("2026-03-10"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"api", "--paginate", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2026-03-10", "/organizations"}
+	if got := restArgs("/organizations", "--paginate"); !reflect.DeepEqual(got, want) {
+		t.Errorf("restArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestAPIVersionForServer(t *testing.T) {
+	tests := []struct {
+		ghesVersion string
+		want        string
+	}{
+		{ghesVersion: "", want: DefaultAPIVersion},
+		{ghesVersion: "3.16", want: "2022-11-28"},
+		{ghesVersion: "3.10", want: "2022-11-28"},
+		{ghesVersion: "3.9", want: "2022-11-28"},
+		{ghesVersion: "3.8", want: ""},
+		{ghesVersion: "2.22", want: ""},
+		{ghesVersion: "4.0", want: "2022-11-28"},
+		{ghesVersion: "next", want: DefaultAPIVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ghesVersion, func(t *testing.T) {
+			if got := APIVersionForServer(tt.ghesVersion); got != tt.want {
+				t.Errorf("APIVersionForServer(%q) = %q, want %q", tt.ghesVersion, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIVersion_ChosenForDetectedServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		meta       string
+		override   string
+		wantHeader string // empty when no version header may be sent
+		wantMeta   bool   // whether the server's release is looked up
+	}{
+		{name: "github.com", host: "", wantHeader: "X-GitHub-Api-Version: " + DefaultAPIVersion},
+		{name: "versioned GHES", host: "github.example.com", meta: `{"installed_version":"3.14.2"}`, wantHeader: "X-GitHub-Api-Version: 2022-11-28", wantMeta: true},
+		{name: "GHES before API versions", host: "github.example.com", meta: `{"installed_version":"3.8.1"}`, wantMeta: true},
+		{name: "unreadable release", host: "github.example.com", meta: `not json`, wantHeader: "X-GitHub-Api-Version: " + DefaultAPIVersion, wantMeta: true},
+		{name: "override wins", host: "github.example.com", meta: `{"installed_version":"3.8.1"}`, override: "2026-03-10", wantHeader: "X-GitHub-Api-Version: 2026-03-10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GH_HOST", tt.host)
+			original := execGH
+			t.Cleanup(func() {
+				execGH = original
+				ResetAPIVersion()
+			})
+			ResetAPIVersion()
+			if tt.override != "" {
+				if err := SetAPIVersion(tt.override); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			metaLookups := 0
+			var headers []string
+			execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+				if args[len(args)-1] == "/meta" {
+					metaLookups++
+					if !requestedAPIVersion.mu.TryLock() {
+						t.Errorf("API version lock held while the release is read")
+					} else {
+						requestedAPIVersion.mu.Unlock()
+					}
+					for _, arg := range args {
+						if strings.HasPrefix(arg, "X-GitHub-Api-Version:") {
+							t.Errorf("release lookup sent %q", arg)
+						}
+					}
+					return *bytes.NewBufferString(tt.meta), bytes.Buffer{}, nil
+				}
+				for _, arg := range args {
+					if strings.HasPrefix(arg, "X-GitHub-Api-Version:") {
+						headers = append(headers, arg)
+					}
+				}
+				return bytes.Buffer{}, bytes.Buffer{}, nil
+			}
+
+			_ = SetConfigurationAsDefault(context.Background(), "org", 7)
+			_ = DeleteSecurityConfiguration(context.Background(), "org", 7)
+			if tt.wantMeta {
+				// The release read to choose the version is reused rather than read again
+				_, _ = GetGHESVersion()
+			}
+
+			var want []string
+			if tt.wantHeader != "" {
+				want = []string{tt.wantHeader, tt.wantHeader}
+			}
+			if !reflect.DeepEqual(headers, want) {
+				t.Errorf("version headers = %v, want %v", headers, want)
+			}
+			if wantLookups := map[bool]int{true: 1, false: 0}[tt.wantMeta]; metaLookups != wantLookups {
+				t.Errorf("release lookups = %d, want %d", metaLookups, wantLookups)
+			}
+		})
+	}
+}
@@ -1,46 +1,86 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/cli/go-gh/v2"
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 // FetchSecurityConfigurations retrieves all security configurations for an organization
-func FetchSecurityConfigurations(org string) ([]types.SecurityConfiguration, error) {
-	response, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations", org))
+func FetchSecurityConfigurations(ctx context.Context, org string) ([]types.SecurityConfiguration, error) {
+	response, stderr, err := restGet(ctx, fmt.Sprintf("/orgs/%s/code-security/configurations", org))
 	if err != nil {
-		pterm.Error.Printf("Failed to fetch security configurations for org '%s': %v\n", org, err)
-		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return nil, err
+		classified := classifyReadError(org, stderr.String(), err)
+		// An organization the token cannot read is reported by the caller as a skip, not a failure
+		var noAccessErr *types.NoAccessError
+		if !errors.As(classified, &noAccessErr) {
+			pterm.Error.Printf("Failed to fetch security configurations for org '%s': %v\n", org, err)
+			pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
+		}
+		return nil, classified
 	}
 
 	var configs []types.SecurityConfiguration
 	if err := json.Unmarshal(response.Bytes(), &configs); err != nil {
 		return nil, err
 	}
+	for i := range configs {
+		if configs[i].HTMLURL == "" && configs[i].TargetType != "enterprise" {
+			configs[i].HTMLURL = ConfigurationURL(org, configs[i].ID)
+		}
+	}
 
 	return configs, nil
 }
 
+// ConfigurationURL returns the address of an organization configuration's settings page on the
+// current host, for when the API response does not include html_url
+func ConfigurationURL(org string, configID int) string {
+	return fmt.Sprintf("https://%s/organizations/%s/settings/security_products/configurations/%d", CurrentHostName(), org, configID)
+}
+
+// FetchDefaultConfigurations retrieves the configurations that are defaults for new repositories
+// in an organization
+func FetchDefaultConfigurations(ctx context.Context, org string) ([]types.DefaultConfiguration, error) {
+	response, stderr, err := restGet(ctx, fmt.Sprintf("/orgs/%s/code-security/configurations/defaults", org))
+	if err != nil {
+		return nil, classifyReadError(org, stderr.String(), err)
+	}
+
+	var defaults []types.DefaultConfiguration
+	if err := json.Unmarshal(response.Bytes(), &defaults); err != nil {
+		return nil, err
+	}
+
+	return defaults, nil
+}
+
 // GetSecurityConfigurationDetails retrieves detailed information about a security configuration
-func GetSecurityConfigurationDetails(org string, configID int) (*types.SecurityConfigurationDetails, error) {
-	response, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID))
+func GetSecurityConfigurationDetails(ctx context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+	response, stderr, err := restGet(ctx, fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID))
 	if err != nil {
 		pterm.Error.Printf("Failed to fetch security configuration details for org '%s': %v\n", org, err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return nil, err
+		return nil, classifyExecError(stderr.String(), err)
 	}
 
+	return parseConfigurationDetails(org, response.Bytes())
+}
+
+// parseConfigurationDetails decodes a configuration as returned by the get and update endpoints
+func parseConfigurationDetails(org string, body []byte) (*types.SecurityConfigurationDetails, error) {
 	var configResponse map[string]interface{}
-	if err := json.Unmarshal(response.Bytes(), &configResponse); err != nil {
+	if err := json.Unmarshal(body, &configResponse); err != nil {
 		return nil, err
 	}
 
@@ -65,35 +105,75 @@ func GetSecurityConfigurationDetails(org string, configID int) (*types.SecurityC
 	} else {
 		details.TargetType = "organization"
 	}
-
-	// Extract security settings
-	securitySettings := []string{
-		"advanced_security", "dependabot_alerts", "dependabot_security_updates",
-		"secret_scanning", "secret_scanning_push_protection",
-		"secret_scanning_non_provider_patterns", "enforcement",
+	if updatedAt, ok := configResponse["updated_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+			details.UpdatedAt = parsed
+		}
 	}
 
-	for _, setting := range securitySettings {
-		if val, exists := configResponse[setting]; exists {
-			details.Settings[setting] = val
-		}
+	if htmlURL, ok := configResponse["html_url"].(string); ok {
+		details.HTMLURL = htmlURL
+	} else if details.TargetType != "enterprise" {
+		details.HTMLURL = ConfigurationURL(org, details.ID)
 	}
 
+	extractSettings(configResponse, details)
+
 	return details, nil
 }
 
+// extractSettings copies the managed security settings and the enforcement field of a
+// configuration response into details, and every field the extension does not model into
+// details.UnmodeledSettings
+func extractSettings(configResponse map[string]interface{}, details *types.SecurityConfigurationDetails) {
+	for _, setting := range types.SecuritySettings {
+		val, exists := configResponse[setting.Key]
+		if !exists {
+			continue
+		}
+		// Older GHES versions return null for some unset settings; record those as "not_set" where
+		// the setting accepts it, and as absent otherwise, so they compare equal to an explicit value
+		if val == nil {
+			if !slices.Contains(setting.Values, types.SettingNotSet) {
+				continue
+			}
+			val = types.SettingNotSet
+		}
+		details.Settings[setting.Key] = val
+	}
+	if enforcement, ok := configResponse[types.EnforcementSetting.Key].(string); ok {
+		details.Enforcement = enforcement
+	}
+	for key, val := range configResponse {
+		if types.IsModeledConfigurationKey(key) {
+			continue
+		}
+		if details.UnmodeledSettings == nil {
+			details.UnmodeledSettings = make(map[string]interface{})
+		}
+		details.UnmodeledSettings[key] = val
+	}
+}
+
 // FindConfigurationByName finds a configuration by name and returns its ID
 func FindConfigurationByName(configs []types.SecurityConfiguration, name string) (int, bool) {
+	config, found := FindConfiguration(configs, name)
+	return config.ID, found
+}
+
+// FindConfiguration finds a configuration by name
+func FindConfiguration(configs []types.SecurityConfiguration, name string) (types.SecurityConfiguration, bool) {
 	for _, config := range configs {
 		if config.Name == name {
-			return config.ID, true
+			return config, true
 		}
 	}
-	return 0, false
+	return types.SecurityConfiguration{}, false
 }
 
-// CreateSecurityConfiguration creates a new security configuration in an organization
-func CreateSecurityConfiguration(org, name, description string, settings map[string]interface{}) (int, error) {
+// CreateSecurityConfiguration creates a new security configuration in an organization. An empty
+// enforcement leaves the API default.
+func CreateSecurityConfiguration(ctx context.Context, org, name, description string, settings map[string]interface{}, enforcement string) (int, error) {
 	// Build the request body
 	body := map[string]interface{}{
 		"name":        name,
@@ -104,27 +184,12 @@ func CreateSecurityConfiguration(org, name, description string, settings map[str
 	for key, value := range settings {
 		body[key] = value
 	}
-
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return 0, err
-	}
-
-	// Create temporary file for the JSON body
-	tmpFile, err := os.CreateTemp("", "security-config-*.json")
-	if err != nil {
-		return 0, err
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(bodyBytes); err != nil {
-		return 0, err
+	if enforcement != "" {
+		body[types.EnforcementSetting.Key] = enforcement
 	}
-	tmpFile.Close()
 
 	// Execute the gh API command
-	response, stderr, err := gh.Exec("api", "--method", "POST", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations", org), "--input", tmpFile.Name())
+	response, stderr, err := restSend(ctx, "POST", fmt.Sprintf("/orgs/%s/code-security/configurations", org), body)
 	if err != nil {
 		pterm.Error.Printf("Failed to create security configuration for org '%s': %v\n", org, err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
@@ -134,7 +199,7 @@ func CreateSecurityConfiguration(org, name, description string, settings map[str
 			return 0, apiErr
 		}
 
-		return 0, err
+		return 0, classifyExecError(stderr.String(), err)
 	}
 
 	var config types.SecurityConfiguration
@@ -145,8 +210,10 @@ func CreateSecurityConfiguration(org, name, description string, settings map[str
 	return config.ID, nil
 }
 
-// UpdateSecurityConfiguration updates an existing security configuration
-func UpdateSecurityConfiguration(org string, configID int, name, description string, settings map[string]interface{}) error {
+// UpdateSecurityConfiguration updates an existing security configuration. An empty enforcement
+// leaves the configuration's enforcement unchanged. The configuration returned by the server is
+// decoded, or nil when the response carries none.
+func UpdateSecurityConfiguration(ctx context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
 	// Build the request body for PATCH request
 	body := map[string]interface{}{
 		"name":        name,
@@ -157,102 +224,102 @@ func UpdateSecurityConfiguration(org string, configID int, name, description str
 	for key, value := range settings {
 		body[key] = value
 	}
-
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return err
-	}
-
-	// Create temporary file for the JSON body
-	tmpFile, err := os.CreateTemp("", "update-config-*.json")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(bodyBytes); err != nil {
-		return err
+	if enforcement != "" {
+		body[types.EnforcementSetting.Key] = enforcement
 	}
-	tmpFile.Close()
 
 	// Execute the gh API command with PATCH method
-	_, stderr, err := gh.Exec("api", "--method", "PATCH", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID), "--input", tmpFile.Name())
+	response, stderr, err := restSend(ctx, "PATCH", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID), body)
 	if err != nil {
 		pterm.Error.Printf("Failed to update security configuration %d for org '%s': %v\n", configID, org, err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return err
+		return nil, classifyExecError(stderr.String(), err)
 	}
 
-	return nil
+	// The update has been made, so a body that cannot be decoded only means no snapshot
+	updated, err := parseConfigurationDetails(org, response.Bytes())
+	if err != nil || updated.ID == 0 {
+		return nil, nil
+	}
+	return updated, nil
 }
 
 // DeleteSecurityConfiguration deletes a security configuration from an organization
-func DeleteSecurityConfiguration(org string, configID int) error {
-	_, stderr, err := gh.Exec("api", "--method", "DELETE", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID))
+func DeleteSecurityConfiguration(ctx context.Context, org string, configID int) error {
+	path := fmt.Sprintf("/orgs/%s/code-security/configurations/%d", org, configID)
+	response, stderr, err := runGH(ctx, restArgs(path, "--method", "DELETE")...)
+	printResponse("DELETE", path, response.Bytes(), stderr.String(), err)
 	if err != nil {
 		pterm.Error.Printf("Failed to delete security configuration %d from org '%s': %v\n", configID, org, err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return err
+		return classifyExecError(stderr.String(), err)
 	}
 
 	return nil
 }
 
-// AttachConfigurationToRepos attaches a security configuration to repositories
-func AttachConfigurationToRepos(org string, configID int, scope string) error {
-	body := map[string]interface{}{
+// AttachConfigurationToRepos attaches a security configuration to repositories. GitHub queues the
+// attachment and enables the repositories asynchronously; the fields of its 202 response body are
+// returned, or nil when the body is empty.
+func AttachConfigurationToRepos(ctx context.Context, org string, configID int, scope string) (map[string]interface{}, error) {
+	return attachConfiguration(ctx, org, configID, map[string]interface{}{
 		"scope": scope,
-	}
+	})
+}
 
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return err
-	}
+// AttachConfigurationToRepoIDs attaches a security configuration to the given repositories only.
+// Callers must keep each request within MaxRepositoryIDsPerAttach IDs.
+func AttachConfigurationToRepoIDs(ctx context.Context, org string, configID int, repoIDs []int) error {
+	_, err := attachConfiguration(ctx, org, configID, map[string]interface{}{
+		"scope":                   "selected",
+		"selected_repository_ids": repoIDs,
+	})
+	return err
+}
 
-	// Create temporary file for the JSON body
-	tmpFile, err := os.CreateTemp("", "attach-config-*.json")
+// attachConfiguration sends an attach request with the given body and returns the fields of the
+// 202 response
+func attachConfiguration(ctx context.Context, org string, configID int, body map[string]interface{}) (map[string]interface{}, error) {
+	response, stderr, err := restSend(ctx, "POST", fmt.Sprintf("/orgs/%s/code-security/configurations/%d/attach", org, configID), body)
 	if err != nil {
-		return err
+		return nil, classifyExecError(stderr.String(), err)
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+	return parseAttachResponse(response.Bytes()), nil
+}
 
-	if _, err := tmpFile.Write(bodyBytes); err != nil {
-		return err
+// parseAttachResponse returns the fields of an attach response body, or nil when it is empty. The
+// request has already been accepted, so a body that is not a JSON object is ignored rather than
+// reported as a failure.
+func parseAttachResponse(body []byte) map[string]interface{} {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || len(fields) == 0 {
+		return nil
 	}
-	tmpFile.Close()
+	return fields
+}
 
-	_, _, err = gh.Exec("api", "--method", "POST", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations/%d/attach", org, configID), "--input", tmpFile.Name())
-	return err
+// SetConfigurationAsDefault sets a configuration as default for new repositories
+func SetConfigurationAsDefault(ctx context.Context, org string, configID int) error {
+	return setDefaultForNewRepos(ctx, org, configID, "all")
 }
 
-// SetConfigurationAsDefault sets a security configuration as default for new repositories
-func SetConfigurationAsDefault(org string, configID int) error {
-	body := map[string]interface{}{
-		"default_for_new_repos": "all",
-	}
+// ClearDefaultConfiguration stops a configuration from being the default for new repositories
+func ClearDefaultConfiguration(ctx context.Context, org string, configID int) error {
+	return setDefaultForNewRepos(ctx, org, configID, "none")
+}
 
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return err
+// setDefaultForNewRepos updates which new repositories (all, none, private_and_internal, or public)
+// a configuration is applied to by default
+func setDefaultForNewRepos(ctx context.Context, org string, configID int, defaultForNewRepos string) error {
+	body := map[string]interface{}{
+		"default_for_new_repos": defaultForNewRepos,
 	}
 
-	// Create temporary file for the JSON body
-	tmpFile, err := os.CreateTemp("", "default-config-*.json")
+	_, stderr, err := restSend(ctx, "PUT", fmt.Sprintf("/orgs/%s/code-security/configurations/%d/defaults", org, configID), body)
 	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(bodyBytes); err != nil {
-		return err
+		return classifyExecError(stderr.String(), err)
 	}
-	tmpFile.Close()
-
-	_, _, err = gh.Exec("api", "--method", "PUT", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/code-security/configurations/%d/defaults", org, configID), "--input", tmpFile.Name())
-	return err
+	return nil
 }
 
 // parseAPIError checks for 422 status codes related to Dependabot unavailability
@@ -284,11 +351,11 @@ func parseAPIError(stderr string, org string, settings map[string]interface{}) e
 // FetchEnterpriseSecurityConfigurations retrieves all security configurations for an enterprise
 // This endpoint is available in GHES 3.17+
 func FetchEnterpriseSecurityConfigurations(enterprise string) ([]types.SecurityConfiguration, error) {
-	response, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/enterprises/%s/code-security/configurations", enterprise))
+	response, stderr, err := restGet(context.Background(), fmt.Sprintf("/enterprises/%s/code-security/configurations", enterprise))
 	if err != nil {
 		pterm.Error.Printf("Failed to fetch enterprise security configurations for '%s': %v\n", enterprise, err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return nil, err
+		return nil, classifyExecError(stderr.String(), err)
 	}
 
 	var configs []types.SecurityConfiguration
@@ -306,18 +373,48 @@ func FetchEnterpriseSecurityConfigurations(enterprise string) ([]types.SecurityC
 	return configs, nil
 }
 
+// serverRelease caches the release GetGHESVersion read for a host, so /meta is read once per run
+var serverRelease struct {
+	mu      sync.Mutex
+	host    string // host the release was read for; empty until the first lookup
+	version string
+	err     error
+}
+
 // GetGHESVersion retrieves the GHES version from the /meta endpoint
-// Returns empty string for GitHub.com (GHEC) and the version string for GHES
+// Returns empty string for GitHub.com (GHEC) and the version string for GHES. The result, or the
+// error, is cached for the host, and the mutex is not held during the request.
 func GetGHESVersion() (string, error) {
-	response, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", "/meta")
+	host := CurrentHostName()
+	serverRelease.mu.Lock()
+	if serverRelease.host == host {
+		defer serverRelease.mu.Unlock()
+		return serverRelease.version, serverRelease.err
+	}
+	serverRelease.mu.Unlock()
+
+	// The release decides which API version header may be sent, so it is read without one
+	var version string
+	response, stderr, err := runGH(context.Background(), "api", "-H", "Accept: application/vnd.github+json", "/meta")
 	if err != nil {
-		pterm.Error.Printf("Failed to fetch meta information: %v\n", err)
-		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return "", err
+		err = classifyExecError(stderr.String(), err)
+	} else {
+		version, err = parseInstalledVersion(response.Bytes())
 	}
 
+	serverRelease.mu.Lock()
+	defer serverRelease.mu.Unlock()
+	serverRelease.host = host
+	serverRelease.version = version
+	serverRelease.err = err
+	return version, err
+}
+
+// parseInstalledVersion returns the major.minor release in a /meta response, e.g. "3.16" for an
+// installed_version of "3.16.0", or "" when there is none, as on GitHub.com (GHEC)
+func parseInstalledVersion(body []byte) (string, error) {
 	var metaResponse map[string]interface{}
-	if err := json.Unmarshal(response.Bytes(), &metaResponse); err != nil {
+	if err := json.Unmarshal(body, &metaResponse); err != nil {
 		return "", err
 	}
 
@@ -353,11 +450,11 @@ func SupportsEnterpriseConfigurations(ghesVersion string) bool {
 
 // GetEnterpriseSecurityConfigurationDetails retrieves detailed information about an enterprise security configuration
 func GetEnterpriseSecurityConfigurationDetails(enterprise string, configID int) (*types.SecurityConfigurationDetails, error) {
-	response, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/enterprises/%s/code-security/configurations/%d", enterprise, configID))
+	response, stderr, err := restGet(context.Background(), fmt.Sprintf("/enterprises/%s/code-security/configurations/%d", enterprise, configID))
 	if err != nil {
 		pterm.Error.Printf("Failed to fetch enterprise security configuration details: %v\n", err)
 		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-		return nil, err
+		return nil, classifyExecError(stderr.String(), err)
 	}
 
 	var configResponse map[string]interface{}
@@ -381,18 +478,7 @@ func GetEnterpriseSecurityConfigurationDetails(enterprise string, configID int)
 		details.Description = desc
 	}
 
-	// Extract security settings
-	securitySettings := []string{
-		"advanced_security", "dependabot_alerts", "dependabot_security_updates",
-		"secret_scanning", "secret_scanning_push_protection",
-		"secret_scanning_non_provider_patterns", "enforcement",
-	}
-
-	for _, setting := range securitySettings {
-		if val, exists := configResponse[setting]; exists {
-			details.Settings[setting] = val
-		}
-	}
+	extractSettings(configResponse, details)
 
 	return details, nil
 }
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestExtractSettings(t *testing.T) {
+	response := map[string]interface{}{
+		"id":              float64(7),
+		"secret_scanning": "enabled",
+		"enforcement":     "unenforced",
+		"unmanaged_field": "ignored",
+	}
+	details := &types.SecurityConfigurationDetails{Settings: make(map[string]interface{})}
+	extractSettings(response, details)
+
+	if want := map[string]interface{}{"secret_scanning": "enabled"}; !reflect.DeepEqual(details.Settings, want) {
+		t.Errorf("Settings = %v, want %v without enforcement", details.Settings, want)
+	}
+	if details.Enforcement != "unenforced" {
+		t.Errorf("Enforcement = %q, want unenforced", details.Enforcement)
+	}
+	if want := map[string]interface{}{"unmanaged_field": "ignored"}; !reflect.DeepEqual(details.UnmodeledSettings, want) {
+		t.Errorf("UnmodeledSettings = %v, want %v without metadata or enforcement", details.UnmodeledSettings, want)
+	}
+}
+
+func TestParseAttachResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]interface{}
+	}{
+		{name: "empty body", body: ""},
+		{name: "empty object", body: "{}"},
+		{name: "not an object", body: "accepted"},
+		{name: "identifiers", body: `{"job_id":"abc"}`, want: map[string]interface{}{"job_id": "abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAttachResponse([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAttachResponse(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSecurityConfigurationDetails_NullSettings(t *testing.T) {
+	// A GHES 3.14 response, where settings that were never set come back as null
+	body := `{
+		"id": 12,
+		"name": "legacy",
+		"target_type": "organization",
+		"advanced_security": null,
+		"dependabot_alerts": null,
+		"dependabot_security_updates": "not_set",
+		"secret_scanning": "enabled",
+		"secret_scanning_push_protection": null,
+		"enforcement": "enforced"
+	}`
+	orig := execGH
+	defer func() { execGH = orig }()
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		return *bytes.NewBufferString(body), bytes.Buffer{}, nil
+	}
+
+	details, err := GetSecurityConfigurationDetails(context.Background(), "octo-org", 12)
+	if err != nil {
+		t.Fatalf("GetSecurityConfigurationDetails() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"dependabot_alerts":               "not_set",
+		"dependabot_security_updates":     "not_set",
+		"secret_scanning":                 "enabled",
+		"secret_scanning_push_protection": "not_set",
+	}
+	if !reflect.DeepEqual(details.Settings, want) {
+		t.Errorf("Settings = %v, want %v", details.Settings, want)
+	}
+}
+
+func TestFetchSecurityConfigurations_HTMLURL(t *testing.T) {
+	t.Setenv("GH_HOST", "github.example.com")
+	body := `[
+		{"id": 1, "name": "from-api", "target_type": "organization", "html_url": "https://github.example.com/custom/1"},
+		{"id": 2, "name": "constructed", "target_type": "organization"},
+		{"id": 3, "name": "enterprise", "target_type": "enterprise"}
+	]`
+	orig := execGH
+	defer func() { execGH = orig }()
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		return *bytes.NewBufferString(body), bytes.Buffer{}, nil
+	}
+
+	configs, err := FetchSecurityConfigurations(context.Background(), "octo-org")
+	if err != nil {
+		t.Fatalf("FetchSecurityConfigurations() error = %v", err)
+	}
+	want := []string{
+		"https://github.example.com/custom/1",
+		"https://github.example.com/organizations/octo-org/settings/security_products/configurations/2",
+		"",
+	}
+	for i, config := range configs {
+		if config.HTMLURL != want[i] {
+			t.Errorf("%s: HTMLURL = %q, want %q", config.Name, config.HTMLURL, want[i])
+		}
+	}
+}
+
+func TestUpdateSecurityConfiguration_ReturnsUpdatedConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantName string
+	}{
+		{name: "configuration in response", body: `{"id": 7, "name": "Baseline v2", "secret_scanning": "enabled", "enforcement": "enforced"}`, wantName: "Baseline v2"},
+		{name: "empty response", body: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := execGH
+			defer func() { execGH = orig }()
+			execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+				return *bytes.NewBufferString(tt.body), bytes.Buffer{}, nil
+			}
+
+			updated, err := UpdateSecurityConfiguration(context.Background(), "octo-org", 7, "Baseline v2", "", map[string]interface{}{"secret_scanning": "enabled"}, "enforced")
+			if err != nil {
+				t.Fatalf("UpdateSecurityConfiguration() error = %v", err)
+			}
+			if tt.wantName == "" {
+				if updated != nil {
+					t.Errorf("updated = %+v, want nil for a response without a configuration", updated)
+				}
+				return
+			}
+			if updated == nil || updated.Name != tt.wantName || updated.Enforcement != "enforced" || updated.Settings["secret_scanning"] != "enabled" {
+				t.Errorf("updated = %+v, want the decoded configuration", updated)
+			}
+		})
+	}
+}
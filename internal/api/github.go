@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"sync"
 
-	"github.com/cli/go-gh/v2"
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/loglevel"
@@ -14,29 +17,223 @@ import (
 
 // GetCurrentUser returns the current GitHub user login
 func GetCurrentUser() (string, error) {
-	userResponse, _, err := gh.Exec("api", "user", "-q", ".login")
+	userResponse, stderr, err := restGet(context.Background(), "/user")
+	if err != nil {
+		return "", classifyExecError(stderr.String(), err)
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(userResponse.Bytes(), &user); err != nil {
+		return "", fmt.Errorf("failed to parse current user: %w", err)
+	}
+	return user.Login, nil
+}
+
+// CurrentHost returns the GitHub host every API request is sent to. The gh CLI reads it from
+// GH_HOST, which --github-enterprise-server-url sets for the whole run, and defaults to github.com.
+func CurrentHost() string {
+	if host := os.Getenv("GH_HOST"); host != "" {
+		return host
+	}
+	return "github.com"
+}
+
+// CurrentHostName returns CurrentHost without a scheme or trailing slash, for building web URLs
+func CurrentHostName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(CurrentHost(), "https://"), "http://"), "/")
+}
+
+// OrganizationExists reports whether org exists on the current host. Only a 404 answers false;
+// other failures are returned as errors.
+func OrganizationExists(org string) (bool, error) {
+	_, stderr, err := restGet(context.Background(), fmt.Sprintf("/orgs/%s", org))
+	if err == nil {
+		return true, nil
+	}
+	if strings.Contains(stderr.String(), "HTTP 404") {
+		return false, nil
+	}
+	return false, classifyExecError(stderr.String(), err)
+}
+
+// samlEnforcementMarker is the message GitHub returns with a 403 when an organization enforces SAML
+// single sign-on and the token has not been authorized for it
+const samlEnforcementMarker = "Resource protected by organization SAML enforcement"
+
+// classifyExecError wraps err in a types.AuthError when the gh CLI output shows the token was
+// rejected or lacks a required scope, so commands can exit with a dedicated code. A token not
+// authorized for the organization's SAML single sign-on is wrapped in a types.SSORequiredError.
+// Server errors and dropped connections are wrapped in a types.TransientError so callers can retry them.
+func classifyExecError(stderr string, err error) error {
+	switch {
+	case strings.Contains(stderr, samlEnforcementMarker):
+		return &types.SSORequiredError{Err: err}
+	case strings.Contains(stderr, "HTTP 401"), strings.Contains(stderr, "Bad credentials"):
+		return &types.AuthError{Message: "the GitHub token was rejected (run 'gh auth status' to check it)", Err: err}
+	case strings.Contains(stderr, "INSUFFICIENT_SCOPES"), strings.Contains(stderr, "requires one of the following scopes"):
+		return &types.AuthError{Message: "the GitHub token is missing a required scope (read:enterprise, admin:org)", Err: err}
+	case isTransientStderr(stderr):
+		return &types.TransientError{Err: err}
+	}
+	return err
+}
+
+// classifyReadError is classifyExecError for reads of an organization's security configurations: a
+// 403 or 404 that is not an authentication or SSO failure becomes a types.NoAccessError, so
+// read-only commands can skip the organization without looking up the user's membership first.
+func classifyReadError(org, stderr string, err error) error {
+	classified := classifyExecError(stderr, err)
+	if classified != err {
+		return classified
+	}
+	for _, status := range []int{403, 404} {
+		marker := fmt.Sprintf("(HTTP %d)", status)
+		for _, line := range strings.Split(stderr, "\n") {
+			if before, found := strings.CutSuffix(strings.TrimSpace(line), marker); found {
+				message := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(before), "gh:"))
+				return &types.NoAccessError{OrgName: org, Status: status, Message: message, Err: err}
+			}
+		}
+	}
+	return err
+}
+
+// treatPendingAsMember makes pending memberships count as active ones. Some enterprise managed
+// user setups leave SCIM-provisioned owners pending even though they can manage the organization.
+var treatPendingAsMember bool
+
+// SetTreatPendingAsMember sets whether pending memberships are treated as active for the rest of the run
+func SetTreatPendingAsMember(value bool) {
+	treatPendingAsMember = value
+}
+
+// notAMember is the status reported when the user has no usable membership in an organization
+var notAMember = types.MembershipStatus{IsMember: false, IsOwner: false, Role: "none", State: "none"}
+
+// classifyMembership converts the state and role reported by the memberships API into a
+// MembershipStatus. Only the "admin" role is an owner; other roles, such as "billing_manager",
+// are members that cannot manage security configurations.
+func classifyMembership(state, role string, pendingAsMember bool) types.MembershipStatus {
+	switch state {
+	case "active":
+		return types.MembershipStatus{IsMember: true, IsOwner: role == "admin", Role: role, State: state}
+	case "pending":
+		return types.MembershipStatus{IsMember: pendingAsMember, IsOwner: pendingAsMember && role == "admin", Role: role, State: state}
+	default:
+		return notAMember
+	}
+}
+
+// membershipCache holds the memberships looked up during the command, keyed by organization, so
+// an organization checked before processing, or by an earlier phase of the command, is not looked
+// up again. Failed lookups are not cached.
+var membershipCache sync.Map
+
+// currentUserLogin caches the login returned by GetCurrentUser for membership lookups
+var currentUserLogin struct {
+	mu    sync.Mutex
+	login string
+}
+
+// ResetMembershipCache discards the cached memberships and current user login
+func ResetMembershipCache() {
+	membershipCache.Clear()
+	currentUserLogin.mu.Lock()
+	defer currentUserLogin.mu.Unlock()
+	currentUserLogin.login = ""
+}
+
+// membershipUser returns the current user's login, looking it up only once per command
+func membershipUser() (string, error) {
+	currentUserLogin.mu.Lock()
+	defer currentUserLogin.mu.Unlock()
+	if currentUserLogin.login != "" {
+		return currentUserLogin.login, nil
+	}
+	login, err := GetCurrentUser()
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(userResponse.String()), nil
+	currentUserLogin.login = login
+	return login, nil
 }
 
-// CheckSingleOrganizationMembership checks if the current user has access to an organization
-func CheckSingleOrganizationMembership(org string) (types.MembershipStatus, error) {
-	// Get current user's login first
-	currentUser, err := GetCurrentUser()
+// AuthenticatedUser returns the login of the user the token belongs to. The lookup is shared with
+// the membership checks, so it is made at most once per command.
+func AuthenticatedUser() (string, error) {
+	return membershipUser()
+}
+
+// CheckSingleOrganizationMembership checks if the current user has access to an organization. The
+// answer is cached for the rest of the command.
+func CheckSingleOrganizationMembership(ctx context.Context, org string) (types.MembershipStatus, error) {
+	if status, ok := membershipCache.Load(org); ok {
+		return status.(types.MembershipStatus), nil
+	}
+
+	currentUser, err := membershipUser()
 	if err != nil {
 		return types.MembershipStatus{}, fmt.Errorf("failed to get current user: %w", err)
 	}
+	status, err := checkMembership(ctx, org, currentUser)
+	if err != nil {
+		return types.MembershipStatus{}, err
+	}
+	membershipCache.Store(org, status)
+	return status, nil
+}
 
+// PrefetchMemberships looks up the current user's membership in each organization, running up to
+// concurrency lookups at once, and caches the results for CheckSingleOrganizationMembership.
+// Organizations whose lookup failed are left out of the returned map and are looked up again when
+// they are processed.
+func PrefetchMemberships(orgs []string, concurrency int) map[string]types.MembershipStatus {
+	statuses := make(map[string]types.MembershipStatus, len(orgs))
+	currentUser, err := membershipUser()
+	if err != nil {
+		return statuses
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for _, org := range orgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(org string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status, err := checkMembership(context.Background(), org, currentUser)
+			if err != nil {
+				return
+			}
+			membershipCache.Store(org, status)
+			mu.Lock()
+			statuses[org] = status
+			mu.Unlock()
+		}(org)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// checkMembership looks up user's membership in org with the REST API
+func checkMembership(ctx context.Context, org, user string) (types.MembershipStatus, error) {
 	// Use REST API to check membership and role directly
-	userResponse, stderr, err := gh.Exec("api", "-H", "Accept: application/vnd.github+json", "-H", "X-GitHub-Api-Version: 2022-11-28", fmt.Sprintf("/orgs/%s/memberships/%s", org, currentUser))
+	userResponse, stderr, err := restGet(ctx, fmt.Sprintf("/orgs/%s/memberships/%s", org, user))
 	if err != nil {
-		// If we get a 404 or similar error, the user is likely not a member
-		if strings.Contains(stderr.String(), "404") || strings.Contains(stderr.String(), "Not Found") {
-			return types.MembershipStatus{IsMember: false, IsOwner: false, Role: "none"}, nil
+		// A rejected token is not a membership answer, so surface it instead of reporting "not a member"
+		if classified := classifyExecError(stderr.String(), err); classified != err {
+			return types.MembershipStatus{}, classified
+		}
+		// Nor is a request stopped by ctx, which must not be cached as one
+		if ctx.Err() != nil {
+			return types.MembershipStatus{}, err
 		}
-		return types.MembershipStatus{IsMember: false, IsOwner: false, Role: "none"}, nil
+		// A 404 or similar error means the user is likely not a member
+		return notAMember, nil
 	}
 
 	var membership struct {
@@ -45,37 +242,44 @@ func CheckSingleOrganizationMembership(org string) (types.MembershipStatus, erro
 	}
 
 	if err := json.Unmarshal(userResponse.Bytes(), &membership); err != nil {
+		loglevel.RecordWarning()
 		if loglevel.WarningEnabled() {
 			pterm.Warning.Printf("Failed to parse membership data for organization '%s': %v\n", org, err)
 		}
-		return types.MembershipStatus{IsMember: false, IsOwner: false, Role: "none"}, nil
+		return notAMember, nil
 	}
 
-	// Check if membership is active and determine role
-	if membership.State == "active" {
-		isOwner := membership.Role == "admin"
-		return types.MembershipStatus{
-			IsMember: true,
-			IsOwner:  isOwner,
-			Role:     membership.Role,
-		}, nil
-	}
-
-	// If state is not active, treat as not a member
-	return types.MembershipStatus{IsMember: false, IsOwner: false, Role: "none"}, nil
+	return classifyMembership(membership.State, membership.Role, treatPendingAsMember), nil
 }
 
 // ValidateMembershipAndSkip is a helper function that checks membership and returns appropriate ProcessingResult
-func ValidateMembershipAndSkip(org string) *types.ProcessingResult {
-	status, err := CheckSingleOrganizationMembership(org)
+func ValidateMembershipAndSkip(ctx context.Context, org string) *types.ProcessingResult {
+	status, err := CheckSingleOrganizationMembership(ctx, org)
 	if err != nil {
+		// Authentication failures and stop conditions are reported as errors so processors can
+		// detect an expired token or an exhausted API call budget. A token missing SSO authorization
+		// is an error too, so the organization is counted and retried once the token is authorized.
+		var authErr *types.AuthError
+		var stopErr *types.StopConditionError
+		var ssoErr *types.SSORequiredError
+		if errors.As(err, &authErr) || errors.As(err, &stopErr) || errors.As(err, &ssoErr) {
+			return &types.ProcessingResult{Organization: org, Error: err}
+		}
 		return &types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Failed to check membership for organization '%s': %v, skipping", org, err)}
 	}
-	if !status.IsMember {
-		return &types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Skipping organization '%s': You are not a member", org)}
-	}
-	if !status.IsOwner {
-		return &types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Skipping organization '%s': You are a member but not an owner", org)}
+	if problem := status.Problem(); problem != "" {
+		return &types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Skipping organization '%s': %s", org, problem)}
 	}
 	return nil // No skip needed
 }
+
+// NoAccessSkip returns a skipped result when err is a types.NoAccessError, and nil otherwise.
+// Read-only commands call it on the error of their first read instead of checking membership, as
+// read-only tokens, such as fine-grained tokens without the members permission, cannot look it up.
+func NoAccessSkip(org string, err error) *types.ProcessingResult {
+	var noAccessErr *types.NoAccessError
+	if !errors.As(err, &noAccessErr) {
+		return nil
+	}
+	return &types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Skipping organization '%s': the token cannot read its security configurations (HTTP %d: %s)", org, noAccessErr.Status, noAccessErr.Message)}
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestClassifyExecError(t *testing.T) {
+	base := errors.New("exit status 1")
+	tests := []struct {
+		name          string
+		stderr        string
+		wantAuth      bool
+		wantTransient bool
+	}{
+		{"bad credentials", "gh: Bad credentials (HTTP 401)", true, false},
+		{"missing scope", "INSUFFICIENT_SCOPES: Your token has not been granted the required scopes", true, false},
+		{"not found", "gh: Not Found (HTTP 404)", false, false},
+		{"bad gateway", "gh: Bad Gateway (HTTP 502)", false, true},
+		{"connection reset", "read tcp: connection reset by peer", false, true},
+		{"empty stderr", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyExecError(tt.stderr, base)
+			var authErr *types.AuthError
+			if got := errors.As(err, &authErr); got != tt.wantAuth {
+				t.Errorf("classifyExecError(%q) auth = %v, want %v", tt.stderr, got, tt.wantAuth)
+			}
+			var transientErr *types.TransientError
+			if got := errors.As(err, &transientErr); got != tt.wantTransient {
+				t.Errorf("classifyExecError(%q) transient = %v, want %v", tt.stderr, got, tt.wantTransient)
+			}
+			if !errors.Is(err, base) {
+				t.Errorf("classifyExecError(%q) should wrap the original error", tt.stderr)
+			}
+		})
+	}
+}
+
+func TestValidateMembershipAndSkip_SSORequired(t *testing.T) {
+	orig := execGH
+	defer func() {
+		execGH = orig
+		ResetMembershipCache()
+	}()
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		if strings.Contains(strings.Join(args, " "), "/memberships/") {
+			stderr := bytes.NewBufferString("gh: Resource protected by organization SAML enforcement. You must grant your Personal Access token access to this organization. (HTTP 403)")
+			return bytes.Buffer{}, *stderr, errors.New("exit status 1")
+		}
+		return *bytes.NewBufferString(`{"login": "octocat"}`), bytes.Buffer{}, nil
+	}
+
+	result := ValidateMembershipAndSkip(context.Background(), "saml-org")
+	if result == nil || result.Skipped {
+		t.Fatalf("result = %+v, want an error rather than a skip", result)
+	}
+	var ssoErr *types.SSORequiredError
+	if !errors.As(result.Error, &ssoErr) {
+		t.Errorf("error = %v, want an SSORequiredError", result.Error)
+	}
+	var authErr *types.AuthError
+	if errors.As(result.Error, &authErr) {
+		t.Errorf("SSO errors must not count toward the consecutive authentication failure limit")
+	}
+}
+
+func TestCheckSingleOrganizationMembership_CachesAnswers(t *testing.T) {
+	calls := stubExecGH(t, nil)
+	execStub := execGH
+	execGH = func(ctx context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		path := args[len(args)-1]
+		switch {
+		case path == "/user":
+			calls[path]++
+			return *bytes.NewBufferString(`{"login": "octocat"}`), bytes.Buffer{}, nil
+		case strings.HasPrefix(path, "/orgs/acme/memberships/"):
+			calls[path]++
+			return *bytes.NewBufferString(`{"state": "active", "role": "admin"}`), bytes.Buffer{}, nil
+		}
+		return execStub(ctx, stdin, args...)
+	}
+
+	for i := 0; i < 2; i++ {
+		status, err := CheckSingleOrganizationMembership(context.Background(), "acme")
+		if err != nil {
+			t.Fatalf("CheckSingleOrganizationMembership() error = %v", err)
+		}
+		if !status.IsOwner {
+			t.Errorf("CheckSingleOrganizationMembership() = %+v, want an owner", status)
+		}
+	}
+	if _, err := CheckSingleOrganizationMembership(context.Background(), "other"); err != nil {
+		t.Fatalf("CheckSingleOrganizationMembership() error = %v", err)
+	}
+
+	if got := calls["/orgs/acme/memberships/octocat"]; got != 1 {
+		t.Errorf("membership lookups for acme = %d, want 1 (the second check should be a cache hit)", got)
+	}
+	if got := calls["/user"]; got != 1 {
+		t.Errorf("current user lookups = %d, want 1", got)
+	}
+
+	ResetMembershipCache()
+	if _, err := CheckSingleOrganizationMembership(context.Background(), "acme"); err != nil {
+		t.Fatalf("CheckSingleOrganizationMembership() error = %v", err)
+	}
+	if got := calls["/orgs/acme/memberships/octocat"]; got != 2 {
+		t.Errorf("membership lookups for acme after reset = %d, want 2", got)
+	}
+}
+
+func TestClassifyMembership(t *testing.T) {
+	tests := []struct {
+		state           string
+		role            string
+		pendingAsMember bool
+		wantMember      bool
+		wantOwner       bool
+		wantProblem     string
+	}{
+		{"active", "admin", false, true, true, ""},
+		{"active", "member", false, true, false, "role: member — owner required"},
+		{"active", "billing_manager", false, true, false, "role: billing_manager — owner required"},
+		{"pending", "admin", false, false, false, "membership pending acceptance (role: admin)"},
+		{"pending", "member", false, false, false, "membership pending acceptance (role: member)"},
+		{"pending", "billing_manager", false, false, false, "membership pending acceptance (role: billing_manager)"},
+		{"pending", "admin", true, true, true, ""},
+		{"pending", "member", true, true, false, "role: member — owner required"},
+		{"pending", "billing_manager", true, true, false, "role: billing_manager — owner required"},
+		{"active", "admin", true, true, true, ""},
+		{"", "", false, false, false, "not a member"},
+		{"unknown", "admin", true, false, false, "not a member"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s/pending-as-member=%v", tt.state, tt.role, tt.pendingAsMember), func(t *testing.T) {
+			got := classifyMembership(tt.state, tt.role, tt.pendingAsMember)
+			if got.IsMember != tt.wantMember || got.IsOwner != tt.wantOwner {
+				t.Errorf("classifyMembership() member=%v owner=%v, want member=%v owner=%v", got.IsMember, got.IsOwner, tt.wantMember, tt.wantOwner)
+			}
+			if problem := got.Problem(); problem != tt.wantProblem {
+				t.Errorf("Problem() = %q, want %q", problem, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestClassifyReadError_TokenResponses(t *testing.T) {
+	base := errors.New("exit status 1")
+	tests := []struct {
+		name        string
+		stderr      string
+		wantStatus  int // 0 when the error is not a NoAccessError
+		wantMessage string
+	}{
+		{"fine-grained token without the permission", "gh: Resource not accessible by personal access token (HTTP 403)", 403, "Resource not accessible by personal access token"},
+		{"fine-grained token not granted the organization", "gh: Not Found (HTTP 404)", 404, "Not Found"},
+		{"fine-grained token lifetime policy", "gh: The 'octo' organization forbids access via a fine-grained personal access tokens if the token's lifetime is greater than 366 days. (HTTP 403)", 403, "The 'octo' organization forbids access via a fine-grained personal access tokens if the token's lifetime is greater than 366 days."},
+		{"classic token that is not an owner", "gh: Must be an organization owner. (HTTP 403)\n{\"message\":\"Must be an organization owner.\"}", 403, "Must be an organization owner."},
+		{"classic token missing a scope", "gh: Not Found (HTTP 404)\ngh: This API operation needs the \"read:org\" scope. To request it, run:  gh auth refresh -h github.com -s read:org", 404, "Not Found"},
+		{"SSO is not a lack of access", "gh: Resource protected by organization SAML enforcement. You must grant your Personal Access token access to this organization. (HTTP 403)", 0, ""},
+		{"bad credentials", "gh: Bad credentials (HTTP 401)", 0, ""},
+		{"server error", "gh: Internal Server Error (HTTP 500)", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyReadError("octo", tt.stderr, base)
+			var noAccessErr *types.NoAccessError
+			if !errors.As(err, &noAccessErr) {
+				if tt.wantStatus != 0 {
+					t.Fatalf("classifyReadError() = %v, want a NoAccessError", err)
+				}
+				return
+			}
+			if tt.wantStatus == 0 {
+				t.Fatalf("classifyReadError() = %v, want no NoAccessError", err)
+			}
+			if noAccessErr.Status != tt.wantStatus || noAccessErr.Message != tt.wantMessage || noAccessErr.OrgName != "octo" {
+				t.Errorf("NoAccessError = %+v, want status %d and message %q", noAccessErr, tt.wantStatus, tt.wantMessage)
+			}
+			if !errors.Is(err, base) {
+				t.Error("classifyReadError() should wrap the original error")
+			}
+		})
+	}
+}
+
+func TestFetchSecurityConfigurations_NoAccessSkipsWithoutMembershipLookup(t *testing.T) {
+	orig := execGH
+	t.Cleanup(func() { execGH = orig })
+	var paths []string
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		paths = append(paths, args[len(args)-1])
+		var stderr bytes.Buffer
+		stderr.WriteString("gh: Resource not accessible by personal access token (HTTP 403)")
+		return bytes.Buffer{}, stderr, errors.New("exit status 1")
+	}
+
+	var output bytes.Buffer
+	pterm.SetDefaultOutput(&output)
+	t.Cleanup(func() { pterm.SetDefaultOutput(os.Stdout) })
+
+	_, err := FetchSecurityConfigurations(context.Background(), "octo")
+	if output.Len() != 0 {
+		t.Errorf("output = %q, want nothing printed for an organization that is skipped", output.String())
+	}
+	skip := NoAccessSkip("octo", err)
+	if skip == nil || !skip.Skipped || skip.Error != nil {
+		t.Fatalf("NoAccessSkip() = %+v, want a skipped result", skip)
+	}
+	if !strings.Contains(skip.SkipReason, "HTTP 403: Resource not accessible by personal access token") {
+		t.Errorf("SkipReason = %q, want the status and GitHub's message", skip.SkipReason)
+	}
+	if want := []string{"/orgs/octo/code-security/configurations"}; !slices.Equal(paths, want) {
+		t.Errorf("requests = %v, want only %v", paths, want)
+	}
+	if NoAccessSkip("octo", errors.New("exit status 1")) != nil {
+		t.Error("NoAccessSkip() should return nil for other errors")
+	}
+}
+
+func TestOrganizationExists(t *testing.T) {
+	stubExecGH(t, map[string]bool{"/orgs/elsewhere": true})
+
+	if exists, err := OrganizationExists("octo"); err != nil || !exists {
+		t.Errorf("OrganizationExists(octo) = %v, %v; want true, nil", exists, err)
+	}
+	if exists, err := OrganizationExists("elsewhere"); err != nil || exists {
+		t.Errorf("OrganizationExists(elsewhere) = %v, %v; want false, nil for a 404", exists, err)
+	}
+}
+
+func TestCurrentHost(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	if got := CurrentHost(); got != "github.com" {
+		t.Errorf("CurrentHost() without GH_HOST = %q, want github.com", got)
+	}
+	t.Setenv("GH_HOST", "ghes.example.com")
+	if got := CurrentHost(); got != "ghes.example.com" {
+		t.Errorf("CurrentHost() = %q, want ghes.example.com", got)
+	}
+}
+
+func TestAuthenticatedUser_LooksUpOnce(t *testing.T) {
+	orig := execGH
+	defer func() {
+		execGH = orig
+		ResetMembershipCache()
+	}()
+	lookups := 0
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		lookups++
+		return *bytes.NewBufferString(`{"login": "svc-security-bot"}`), bytes.Buffer{}, nil
+	}
+
+	for range 2 {
+		login, err := AuthenticatedUser()
+		if err != nil || login != "svc-security-bot" {
+			t.Fatalf("AuthenticatedUser() = %q, %v, want svc-security-bot", login, err)
+		}
+	}
+	if _, err := CheckSingleOrganizationMembership(context.Background(), "octo-org"); err != nil {
+		t.Fatalf("CheckSingleOrganizationMembership() error = %v", err)
+	}
+	if lookups != 2 {
+		t.Errorf("requests = %d, want one user lookup shared with the membership check plus the membership request", lookups)
+	}
+}
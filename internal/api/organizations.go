@@ -1,73 +1,346 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 
-	"github.com/cli/go-gh/v2"
 	"github.com/pterm/pterm"
 
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
-// FetchOrganizations fetches all organizations from an enterprise using GraphQL
-func FetchOrganizations(enterprise string) ([]string, error) {
+// organizationsPage is one page of an enterprise's organizations
+type organizationsPage struct {
+	Logins      []string
+	HasNextPage bool
+	EndCursor   string
+}
+
+// fetchOrganizationsPage fetches the page of organizations after cursor. It is a variable so
+// tests can simulate failures part way through pagination.
+var fetchOrganizationsPage = func(enterprise string, cursor *string) (*organizationsPage, error) {
 	const maxPerPage = 100
-	var orgs []string
-	var cursor *string
+	query := fmt.Sprintf(`{
+		enterprise(slug: "%s") {
+			organizations(first: %d, after: %s) {
+				nodes {
+					login
+				}
+				pageInfo {
+					hasNextPage
+					endCursor
+				}
+			}
+		}
+	}`, enterprise, maxPerPage, formatCursor(cursor))
 
-	for {
-		query := fmt.Sprintf(`{
-			enterprise(slug: "%s") {
-				organizations(first: %d, after: %s) {
-					nodes {
-						login
-					}
-					pageInfo {
-						hasNextPage
-						endCursor
+	response, stderr, err := runGH(context.Background(), "api", "graphql", "-f", "query="+query)
+
+	// GraphQL can answer with data and errors together, for example when some organizations cannot
+	// be read. gh then fails, but the organizations that were returned are still usable.
+	var result struct {
+		Data struct {
+			Enterprise struct {
+				Organizations *struct {
+					Nodes []struct {
+						Login string `json:"login"`
 					}
-				}
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"organizations"`
+			} `json:"enterprise"`
+		} `json:"data"`
+		Errors []graphQLError `json:"errors"`
+	}
+	parseErr := json.Unmarshal(response.Bytes(), &result)
+	partial := parseErr == nil && result.Data.Enterprise.Organizations != nil
+
+	if err != nil && !partial && isForbiddenGraphQLError(response.Bytes(), stderr.String()) {
+		return nil, fmt.Errorf("%w: %v", errEnterpriseForbidden, err)
+	}
+	if err != nil && !partial && isSchemaGraphQLError(response.Bytes(), stderr.String()) {
+		return nil, fmt.Errorf("%w: %v", errEnterpriseQueryUnsupported, err)
+	}
+	if err != nil && !partial {
+		pterm.Error.Printf("Failed to fetch organizations for enterprise '%s': %v\n", enterprise, err)
+		pterm.Error.Printf("GraphQL query: %s\n", query)
+		pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
+		return nil, classifyExecError(stderr.String(), err)
+	}
+	if parseErr != nil {
+		pterm.Error.Printf("Failed to parse organizations data for enterprise '%s': %v\n", enterprise, parseErr)
+		return nil, parseErr
+	}
+
+	if len(result.Errors) > 0 {
+		if authErr := graphQLAuthError(result.Errors); authErr != nil {
+			return nil, authErr
+		}
+		loglevel.RecordWarning()
+		if loglevel.WarningEnabled() {
+			pterm.Warning.Printf("GraphQL reported %d errors while listing the organizations of enterprise '%s'; continuing with the organizations it returned:\n", len(result.Errors), enterprise)
+			for _, graphQLErr := range result.Errors {
+				pterm.Printf("  - %s\n", graphQLErr)
+			}
+		}
+	}
+
+	page := &organizationsPage{}
+	if organizations := result.Data.Enterprise.Organizations; organizations != nil {
+		page.HasNextPage = organizations.PageInfo.HasNextPage
+		page.EndCursor = organizations.PageInfo.EndCursor
+		for _, org := range organizations.Nodes {
+			if org.Login != "" {
+				page.Logins = append(page.Logins, org.Login)
+			}
+		}
+	}
+	return page, nil
+}
+
+// graphQLError is one entry of the errors array of a GraphQL response
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+func (e graphQLError) String() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, element := range e.Path {
+		path[i] = fmt.Sprint(element)
+	}
+	return fmt.Sprintf("%s (at %s)", e.Message, strings.Join(path, "."))
+}
+
+// graphQLAuthErrorTypes are the GraphQL error types that mean the token may not read the data. A
+// list missing organizations for these reasons would be silently incomplete, so it is not used.
+var graphQLAuthErrorTypes = []string{"FORBIDDEN", "UNAUTHORIZED", "INSUFFICIENT_SCOPES"}
+
+// graphQLAuthError returns a types.AuthError describing the first authentication or permission
+// error in errs, or nil when there is none
+func graphQLAuthError(errs []graphQLError) error {
+	for _, graphQLErr := range errs {
+		if slices.Contains(graphQLAuthErrorTypes, graphQLErr.Type) {
+			return &types.AuthError{
+				Message: "the GitHub token cannot read every organization of the enterprise",
+				Err:     fmt.Errorf("%s: %s", graphQLErr.Type, graphQLErr),
+			}
+		}
+	}
+	return nil
+}
+
+// errEnterpriseForbidden is returned when the enterprise's organizations cannot be listed because
+// the user is not an enterprise owner
+var errEnterpriseForbidden = errors.New("the enterprise's organizations can only be listed by enterprise owners")
+
+// isForbiddenGraphQLError reports whether a failed GraphQL request was refused with a FORBIDDEN
+// error. gh writes the response, including its errors, to stdout and a summary to stderr.
+func isForbiddenGraphQLError(response []byte, stderr string) bool {
+	var result struct {
+		Errors []struct {
+			Type string `json:"type"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(response, &result) == nil {
+		for _, graphQLErr := range result.Errors {
+			if graphQLErr.Type == "FORBIDDEN" {
+				return true
+			}
+		}
+	}
+	return strings.Contains(stderr, "FORBIDDEN")
+}
+
+// errEnterpriseQueryUnsupported is returned when the server's GraphQL schema has no way to list an
+// enterprise's organizations, as on older GHES versions
+var errEnterpriseQueryUnsupported = errors.New("this server's GraphQL API cannot list an enterprise's organizations")
+
+// graphQLSchemaErrorCodes are the GraphQL error codes for a query the server's schema does not support
+var graphQLSchemaErrorCodes = []string{"undefinedField", "undefinedType", "argumentNotAccepted"}
+
+// isSchemaGraphQLError reports whether a failed GraphQL request was rejected because the query
+// names a field, type or argument the server's schema does not have
+func isSchemaGraphQLError(response []byte, stderr string) bool {
+	var result struct {
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(response, &result) == nil {
+		for _, graphQLErr := range result.Errors {
+			if slices.Contains(graphQLSchemaErrorCodes, graphQLErr.Extensions.Code) {
+				return true
 			}
-		}`, enterprise, maxPerPage, formatCursor(cursor))
+		}
+	}
+	return strings.Contains(stderr, "doesn't exist on type") || strings.Contains(stderr, "doesn't accept argument")
+}
 
-		response, stderr, err := gh.Exec("api", "graphql", "-f", "query="+query)
+// fetchInstanceOrganizations lists every organization on the server with the REST API. It is a
+// variable so tests can supply the organizations.
+var fetchInstanceOrganizations = func() ([]string, error) {
+	response, stderr, err := runGH(context.Background(), restArgs("/organizations?per_page=100", "--paginate")...)
+	if err != nil {
+		return nil, classifyExecError(stderr.String(), err)
+	}
+
+	var logins []string
+	decoder := json.NewDecoder(bytes.NewReader(response.Bytes()))
+	for {
+		var page []struct {
+			Login string `json:"login"`
+		}
+		err := decoder.Decode(&page)
+		if err == io.EOF {
+			return logins, nil
+		}
 		if err != nil {
-			pterm.Error.Printf("Failed to fetch organizations for enterprise '%s': %v\n", enterprise, err)
-			pterm.Error.Printf("GraphQL query: %s\n", query)
-			pterm.Error.Printf("gh CLI stderr: %s\n", stderr.String())
-			return nil, err
+			return nil, fmt.Errorf("failed to parse organizations: %w", err)
+		}
+		for _, org := range page {
+			logins = append(logins, org.Login)
 		}
+	}
+}
 
-		var result struct {
-			Data struct {
-				Enterprise struct {
-					Organizations struct {
-						Nodes []struct {
-							Login string `json:"login"`
-						}
-						PageInfo struct {
-							HasNextPage bool   `json:"hasNextPage"`
-							EndCursor   string `json:"endCursor"`
-						} `json:"pageInfo"`
-					} `json:"organizations"`
-				} `json:"enterprise"`
-			} `json:"data"`
-		}
-
-		if err := json.Unmarshal(response.Bytes(), &result); err != nil {
-			pterm.Error.Printf("Failed to parse organizations data for enterprise '%s': %v\n", enterprise, err)
-			return nil, err
+// fetchInstanceOrganizationsInstead lists the organizations of a GHES instance with the REST API
+// when its GraphQL API cannot list the enterprise's organizations. A GHES instance holds a single
+// enterprise, so every organization on it belongs to the enterprise. On GitHub.com, or when the REST
+// listing fails too, the error explains how to name the organizations instead.
+func fetchInstanceOrganizationsInstead(enterprise string, queryErr error) ([]string, error) {
+	unsupported := fmt.Errorf("your GitHub Enterprise Server version doesn't support listing the organizations of enterprise '%s'; name the organizations with --org-list instead: %w", enterprise, queryErr)
+	if CurrentHost() == "github.com" {
+		return nil, unsupported
+	}
+
+	loglevel.RecordWarning()
+	if loglevel.WarningEnabled() {
+		pterm.Warning.Printf("This GitHub Enterprise Server version cannot list enterprise organizations with GraphQL; listing every organization on %s with the REST API instead\n", CurrentHost())
+	}
+	orgs, err := fetchInstanceOrganizations()
+	if err != nil {
+		return nil, fmt.Errorf("%w (the REST fallback failed too: %v)", unsupported, err)
+	}
+	return orgs, nil
+}
+
+// fetchViewerOrganizationsPage fetches the page, after cursor, of organizations the user belongs to,
+// keeping only those the user can administer. It is a variable so tests can supply pages.
+var fetchViewerOrganizationsPage = func(cursor *string) (*organizationsPage, error) {
+	const maxPerPage = 100
+	query := fmt.Sprintf(`{
+		viewer {
+			organizations(first: %d, after: %s) {
+				nodes {
+					login
+					viewerCanAdminister
+				}
+				pageInfo {
+					hasNextPage
+					endCursor
+				}
+			}
+		}
+	}`, maxPerPage, formatCursor(cursor))
+
+	response, stderr, err := runGH(context.Background(), "api", "graphql", "-f", "query="+query)
+	if err != nil {
+		return nil, classifyExecError(stderr.String(), err)
+	}
+
+	var result struct {
+		Data struct {
+			Viewer struct {
+				Organizations struct {
+					Nodes []struct {
+						Login               string `json:"login"`
+						ViewerCanAdminister bool   `json:"viewerCanAdminister"`
+					}
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"organizations"`
+			} `json:"viewer"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(response.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse your organizations: %w", err)
+	}
+
+	page := &organizationsPage{
+		HasNextPage: result.Data.Viewer.Organizations.PageInfo.HasNextPage,
+		EndCursor:   result.Data.Viewer.Organizations.PageInfo.EndCursor,
+	}
+	for _, org := range result.Data.Viewer.Organizations.Nodes {
+		if org.ViewerCanAdminister {
+			page.Logins = append(page.Logins, org.Login)
 		}
+	}
+	return page, nil
+}
 
-		for _, org := range result.Data.Enterprise.Organizations.Nodes {
-			orgs = append(orgs, org.Login)
+// FetchOrganizations fetches all organizations from an enterprise using GraphQL. A page that
+// fails transiently is retried from the same cursor, so earlier pages are not fetched again. On
+// GHES versions whose GraphQL schema cannot list the organizations, the REST API is used instead.
+func FetchOrganizations(enterprise string) ([]string, error) {
+	orgs, err := fetchAllOrganizationPages(fmt.Sprintf("fetching organizations for enterprise '%s'", enterprise), func(cursor *string) (*organizationsPage, error) {
+		return fetchOrganizationsPage(enterprise, cursor)
+	})
+	if errors.Is(err, errEnterpriseQueryUnsupported) {
+		return fetchInstanceOrganizationsInstead(enterprise, err)
+	}
+	return orgs, err
+}
+
+// FetchViewerOrganizations fetches the organizations the user owns, across every enterprise
+func FetchViewerOrganizations() ([]string, error) {
+	return fetchAllOrganizationPages("fetching your organizations", fetchViewerOrganizationsPage)
+}
+
+// fetchAllOrganizationPages follows pagination from the first page, retrying a page that fails
+// transiently from the same cursor
+func fetchAllOrganizationPages(operation string, fetchPage func(cursor *string) (*organizationsPage, error)) ([]string, error) {
+	var orgs []string
+	var cursor *string
+
+	for {
+		var page *organizationsPage
+		err := withRetry(operation, func() error {
+			var fetchErr error
+			page, fetchErr = fetchPage(cursor)
+			return fetchErr
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		if !result.Data.Enterprise.Organizations.PageInfo.HasNextPage {
+		orgs = append(orgs, page.Logins...)
+
+		if !page.HasNextPage {
 			break
 		}
-		cursor = &result.Data.Enterprise.Organizations.PageInfo.EndCursor
+		endCursor := page.EndCursor
+		cursor = &endCursor
 	}
 
 	return orgs, nil
@@ -76,51 +349,110 @@ func FetchOrganizations(enterprise string) ([]string, error) {
 // GetOrganizations returns organization list from one of three sources:
 // 1) A single org name (--org)
 // 2) A CSV file of org names (--org-list)
-// 3) All orgs in the enterprise (--all-orgs)
-func GetOrganizations(enterprise, org, orgListPath string, allOrgs bool) ([]string, error) {
+// 3) All orgs in the enterprises (--all-orgs)
+// The breakdown records where the organizations came from and how many CSV rows were invalid.
+// Every organization of a CSV file is looked up, concurrency at a time, and those that do not
+// exist are skipped like malformed rows.
+func GetOrganizations(enterprises []string, org, orgListPath string, allOrgs bool, concurrency int) ([]string, types.TargetBreakdown, error) {
 	if org != "" {
 		pterm.Info.Printf("Targeting single organization: %s\n", pterm.Green(org))
 		pterm.Println()
-		return []string{org}, nil
+		return []string{org}, types.TargetBreakdown{Source: "--org", Found: 1}, nil
 	}
 
 	if orgListPath != "" {
 		pterm.Info.Printf("Reading organizations from CSV file: %s\n", orgListPath)
-		csvOrgs, err := utils.ReadOrganizationsFromCSV(orgListPath)
+		csvOrgs, lines, invalid, err := utils.ReadOrganizationsFromCSVWithLines(orgListPath)
 		if err != nil {
-			return nil, err
+			return nil, types.TargetBreakdown{}, err
 		}
+		breakdown := types.TargetBreakdown{Source: "--org-list", Found: len(csvOrgs) + len(invalid), Invalid: len(invalid), InvalidEntries: invalid}
+		csvOrgs = dropMissingOrganizations(csvOrgs, lines, concurrency, &breakdown)
 		if len(csvOrgs) == 0 {
-			return nil, fmt.Errorf("no valid organizations found in CSV file")
+			return nil, breakdown, nil
 		}
 		pterm.Success.Printf("Found %d organizations in CSV file\n", len(csvOrgs))
-
-		// Show the list of organizations that will be targeted
-		if len(csvOrgs) <= 10 {
-			pterm.Info.Println("Organizations to be targeted:")
-			for _, org := range csvOrgs {
-				pterm.Printf("  - %s\n", pterm.Green(org))
-			}
-		} else {
-			pterm.Info.Printf("Organizations to be targeted: %d organizations\n", len(csvOrgs))
-		}
-		pterm.Println()
-
-		return csvOrgs, nil
+		return csvOrgs, breakdown, nil
 	}
 
 	if allOrgs {
+		if len(enterprises) > 1 {
+			return fetchEnterprisesOrganizations(enterprises)
+		}
+		if len(enterprises) == 0 {
+			return nil, types.TargetBreakdown{}, fmt.Errorf("an enterprise slug is required to target all organizations")
+		}
+		enterprise := enterprises[0]
 		// Use existing enterprise API fetching
 		pterm.Info.Println("Fetching all organizations from enterprise...")
 		orgs, err := FetchOrganizations(enterprise)
+		if errors.Is(err, errEnterpriseForbidden) {
+			return fetchOwnedOrganizationsInstead(enterprise)
+		}
 		if err != nil {
-			return nil, err
+			return nil, types.TargetBreakdown{}, err
 		}
 		pterm.Success.Printf("Found %d organizations in enterprise '%s'\n", len(orgs), enterprise)
-		return orgs, nil
+		return orgs, types.TargetBreakdown{Source: "--all-orgs", Found: len(orgs)}, nil
+	}
+
+	return nil, types.TargetBreakdown{}, fmt.Errorf("one of --org, --org-list, or --all-orgs must be specified")
+}
+
+// fetchEnterprisesOrganizations lists the organizations of several enterprises, in the order the
+// enterprises were given, and records which enterprise listed each one. Every enterprise is read
+// from the same host, so an organization listed twice is targeted once, for the first enterprise
+// that lists it; logins are compared ignoring case, as GitHub does. There is no fallback to the
+// organizations the user owns, as that list could not be told apart by enterprise.
+func fetchEnterprisesOrganizations(enterprises []string) ([]string, types.TargetBreakdown, error) {
+	breakdown := types.TargetBreakdown{Source: "--all-orgs", OrgEnterprises: make(map[string]string)}
+	seen := make(map[string]bool)
+	var orgs []string
+	for _, enterprise := range enterprises {
+		pterm.Info.Printf("Fetching all organizations from enterprise '%s'...\n", enterprise)
+		enterpriseOrgs, err := FetchOrganizations(enterprise)
+		if errors.Is(err, errEnterpriseForbidden) {
+			return nil, types.TargetBreakdown{}, fmt.Errorf("only enterprise owners can list the organizations of enterprise '%s'; use --org-list to target organizations across several enterprises without owning them", enterprise)
+		}
+		if err != nil {
+			return nil, types.TargetBreakdown{}, fmt.Errorf("failed to fetch organizations of enterprise '%s': %w", enterprise, err)
+		}
+
+		added := 0
+		for _, org := range enterpriseOrgs {
+			key := strings.ToLower(org)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			orgs = append(orgs, org)
+			breakdown.OrgEnterprises[org] = enterprise
+			added++
+		}
+		if duplicates := len(enterpriseOrgs) - added; duplicates > 0 {
+			pterm.Success.Printf("Found %d organizations in enterprise '%s' (%d already listed by another enterprise)\n", len(enterpriseOrgs), enterprise, duplicates)
+		} else {
+			pterm.Success.Printf("Found %d organizations in enterprise '%s'\n", len(enterpriseOrgs), enterprise)
+		}
 	}
 
-	return nil, fmt.Errorf("one of --org, --org-list, or --all-orgs must be specified")
+	breakdown.Found = len(orgs)
+	return orgs, breakdown, nil
+}
+
+// fetchOwnedOrganizationsInstead targets the organizations the user owns when the enterprise's
+// organizations cannot be listed. Organization owners who are not enterprise owners can then still
+// use --all-orgs. GraphQL does not tell non-owners which enterprise an organization belongs to, so
+// the list is not filtered by enterprise.
+func fetchOwnedOrganizationsInstead(enterprise string) ([]string, types.TargetBreakdown, error) {
+	loglevel.RecordWarning()
+	pterm.Warning.Printf("Only enterprise owners can list the organizations of enterprise '%s'. Targeting the organizations you own instead; this list is not limited to the enterprise.\n", enterprise)
+	orgs, err := FetchViewerOrganizations()
+	if err != nil {
+		return nil, types.TargetBreakdown{}, err
+	}
+	pterm.Success.Printf("Found %d organizations you own\n", len(orgs))
+	return orgs, types.TargetBreakdown{Source: "--all-orgs (organizations you own)", Found: len(orgs)}, nil
 }
 
 // formatCursor formats the cursor for GraphQL pagination
@@ -130,3 +462,45 @@ func formatCursor(cursor *string) string {
 	}
 	return fmt.Sprintf(`"%s"`, *cursor)
 }
+
+// dropMissingOrganizations looks up orgs, listed on the given CSV lines, concurrency at a time,
+// and removes those that do not exist. Each one is warned about and added to the invalid entries
+// of breakdown, in line order. An organization whose lookup fails for another reason is kept, so
+// the failure is reported when it is processed.
+func dropMissingOrganizations(orgs []string, lines []int, concurrency int, breakdown *types.TargetBreakdown) []string {
+	if len(orgs) == 0 {
+		return orgs
+	}
+	pterm.Info.Printf("Checking that %d organization(s) exist...\n", len(orgs))
+	missing := make([]bool, len(orgs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i, org := range orgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, org string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			exists, err := OrganizationExists(org)
+			missing[i] = err == nil && !exists
+		}(i, org)
+	}
+	wg.Wait()
+
+	var kept []string
+	for i, org := range orgs {
+		if !missing[i] {
+			kept = append(kept, org)
+			continue
+		}
+		loglevel.RecordWarning()
+		if loglevel.WarningEnabled() {
+			pterm.Warning.Printf("Line %d: Organization '%s' not found, skipping\n", lines[i], org)
+		}
+		breakdown.Missing++
+		breakdown.InvalidEntries = append(breakdown.InvalidEntries, types.InvalidOrgEntry{Line: lines[i], Name: org, Missing: true})
+	}
+	slices.SortFunc(breakdown.InvalidEntries, func(a, b types.InvalidOrgEntry) int { return a.Line - b.Line })
+	return kept
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func stubOrganizationsPages(t *testing.T, fetch func(enterprise string, cursor *string) (*organizationsPage, error)) {
+	t.Helper()
+	originalFetch, originalDelay := fetchOrganizationsPage, retryBaseDelay
+	fetchOrganizationsPage, retryBaseDelay = fetch, 0
+	t.Cleanup(func() {
+		fetchOrganizationsPage, retryBaseDelay = originalFetch, originalDelay
+	})
+}
+
+func TestFetchOrganizations_RetriesTransientPageFailure(t *testing.T) {
+	const pages = 4
+	var cursors []string
+	failed := false
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		page := 0
+		if cursor != nil {
+			fmt.Sscanf(*cursor, "cursor-%d", &page)
+			cursors = append(cursors, *cursor)
+		} else {
+			cursors = append(cursors, "")
+		}
+		if page == 2 && !failed {
+			failed = true
+			return nil, &types.TransientError{Err: errors.New("HTTP 502")}
+		}
+		return &organizationsPage{
+			Logins:      []string{fmt.Sprintf("org-%d", page+1)},
+			HasNextPage: page+1 < pages,
+			EndCursor:   fmt.Sprintf("cursor-%d", page+1),
+		}, nil
+	})
+
+	orgs, err := FetchOrganizations("acme")
+	if err != nil {
+		t.Fatalf("FetchOrganizations() error = %v", err)
+	}
+	if want := []string{"org-1", "org-2", "org-3", "org-4"}; !reflect.DeepEqual(orgs, want) {
+		t.Errorf("FetchOrganizations() = %v, want %v", orgs, want)
+	}
+	if want := []string{"", "cursor-1", "cursor-2", "cursor-2", "cursor-3"}; !reflect.DeepEqual(cursors, want) {
+		t.Errorf("page cursors = %v, want %v (page 3 should be retried from its own cursor)", cursors, want)
+	}
+}
+
+func TestFetchOrganizations_GivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		calls++
+		return nil, &types.TransientError{Err: errors.New("HTTP 503")}
+	})
+
+	if _, err := FetchOrganizations("acme"); err == nil {
+		t.Fatal("FetchOrganizations() expected an error")
+	}
+	if calls != maxRetryAttempts {
+		t.Errorf("page fetched %d times, want %d", calls, maxRetryAttempts)
+	}
+}
+
+func TestFetchOrganizations_DoesNotRetryPermanentFailure(t *testing.T) {
+	calls := 0
+	authErr := &types.AuthError{Message: "rejected", Err: errors.New("HTTP 401")}
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		calls++
+		return nil, authErr
+	})
+
+	if _, err := FetchOrganizations("acme"); !errors.Is(err, authErr) {
+		t.Fatalf("FetchOrganizations() error = %v, want %v", err, authErr)
+	}
+	if calls != 1 {
+		t.Errorf("page fetched %d times, want 1", calls)
+	}
+}
+
+func TestIsForbiddenGraphQLError(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		stderr   string
+		want     bool
+	}{
+		{name: "forbidden in response", response: `{"data":{"enterprise":null},"errors":[{"type":"FORBIDDEN","path":["enterprise"],"message":"Resource not accessible"}]}`, stderr: "gh: Resource not accessible", want: true},
+		{name: "forbidden in stderr only", stderr: "GraphQL: FORBIDDEN (enterprise)", want: true},
+		{name: "not found", response: `{"errors":[{"type":"NOT_FOUND","path":["enterprise"]}]}`, stderr: "gh: Could not resolve to an Enterprise"},
+		{name: "server error", stderr: "HTTP 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isForbiddenGraphQLError([]byte(tt.response), tt.stderr); got != tt.want {
+				t.Errorf("isForbiddenGraphQLError() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSchemaGraphQLError(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		stderr   string
+		want     bool
+	}{
+		{name: "undefined field in response", response: `{"errors":[{"path":["query","enterprise","organizations"],"extensions":{"code":"undefinedField","typeName":"Enterprise","fieldName":"organizations"},"message":"Field 'organizations' doesn't exist on type 'Enterprise'"}]}`, want: true},
+		{name: "unsupported argument in stderr only", stderr: "gh: Field 'organizations' doesn't accept argument 'after'", want: true},
+		{name: "forbidden", response: `{"errors":[{"type":"FORBIDDEN","path":["enterprise"]}]}`, stderr: "GraphQL: FORBIDDEN (enterprise)"},
+		{name: "server error", stderr: "HTTP 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSchemaGraphQLError([]byte(tt.response), tt.stderr); got != tt.want {
+				t.Errorf("isSchemaGraphQLError() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchOrganizations_UnsupportedEnterpriseQuery(t *testing.T) {
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		return nil, fmt.Errorf("%w: exit status 1", errEnterpriseQueryUnsupported)
+	})
+	originalInstance := fetchInstanceOrganizations
+	t.Cleanup(func() { fetchInstanceOrganizations = originalInstance })
+
+	tests := []struct {
+		name     string
+		host     string
+		instance func() ([]string, error)
+		want     []string
+		wantErr  bool
+	}{
+		{name: "GHES falls back to the REST listing", host: "ghes.example.com", instance: func() ([]string, error) { return []string{"team-a", "team-b"}, nil }, want: []string{"team-a", "team-b"}},
+		{name: "GHES REST listing fails too", host: "ghes.example.com", instance: func() ([]string, error) { return nil, errors.New("HTTP 404") }, wantErr: true},
+		{name: "no fallback on github.com", host: "", instance: func() ([]string, error) { t.Fatal("unexpected REST listing"); return nil, nil }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GH_HOST", tt.host)
+			fetchInstanceOrganizations = tt.instance
+
+			orgs, err := FetchOrganizations("acme")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "--org-list") {
+					t.Fatalf("FetchOrganizations() error = %v, want one suggesting --org-list", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchOrganizations() error = %v", err)
+			}
+			if !reflect.DeepEqual(orgs, tt.want) {
+				t.Errorf("FetchOrganizations() = %v, want %v", orgs, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchOrganizationsPage_PartialResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+		wantAuth bool
+	}{
+		{
+			name:     "errors alongside data keep the returned organizations",
+			response: `{"data":{"enterprise":{"organizations":{"nodes":[{"login":"org-a"},null,{"login":"org-c"}],"pageInfo":{"hasNextPage":false,"endCursor":"c1"}}}},"errors":[{"type":"NOT_FOUND","message":"Could not resolve organization","path":["enterprise","organizations","nodes",1]}]}`,
+			want:     []string{"org-a", "org-c"},
+		},
+		{
+			name:     "permission errors alongside data fail",
+			response: `{"data":{"enterprise":{"organizations":{"nodes":[{"login":"org-a"},null],"pageInfo":{"hasNextPage":false,"endCursor":"c1"}}}},"errors":[{"type":"FORBIDDEN","message":"Resource not accessible by integration","path":["enterprise","organizations","nodes",1]}]}`,
+			wantAuth: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := execGH
+			t.Cleanup(func() { execGH = orig })
+			execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+				return *bytes.NewBufferString(tt.response), *bytes.NewBufferString("gh: Could not resolve organization"), errors.New("exit status 1")
+			}
+
+			page, err := fetchOrganizationsPage("acme", nil)
+			var authErr *types.AuthError
+			if tt.wantAuth {
+				if !errors.As(err, &authErr) {
+					t.Fatalf("fetchOrganizationsPage() error = %v, want an AuthError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchOrganizationsPage() error = %v", err)
+			}
+			if !reflect.DeepEqual(page.Logins, tt.want) {
+				t.Errorf("fetchOrganizationsPage() logins = %v, want %v", page.Logins, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOrganizations_FallsBackToOwnedOrganizations(t *testing.T) {
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		return nil, fmt.Errorf("%w: exit status 1", errEnterpriseForbidden)
+	})
+	originalViewer := fetchViewerOrganizationsPage
+	t.Cleanup(func() { fetchViewerOrganizationsPage = originalViewer })
+	fetchViewerOrganizationsPage = func(cursor *string) (*organizationsPage, error) {
+		if cursor == nil {
+			return &organizationsPage{Logins: []string{"team-a"}, HasNextPage: true, EndCursor: "next"}, nil
+		}
+		return &organizationsPage{Logins: []string{"team-b"}}, nil
+	}
+
+	orgs, breakdown, err := GetOrganizations([]string{"acme"}, "", "", true, 1)
+	if err != nil {
+		t.Fatalf("GetOrganizations() error = %v", err)
+	}
+	if want := []string{"team-a", "team-b"}; !reflect.DeepEqual(orgs, want) {
+		t.Errorf("GetOrganizations() = %v, want %v", orgs, want)
+	}
+	if breakdown.Found != 2 || breakdown.Source != "--all-orgs (organizations you own)" {
+		t.Errorf("breakdown = %+v", breakdown)
+	}
+
+	// Other failures are not hidden by the fallback
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		return nil, errors.New("not found")
+	})
+	if _, _, err := GetOrganizations([]string{"acme"}, "", "", true, 1); err == nil {
+		t.Error("GetOrganizations() expected the enterprise error")
+	}
+}
+
+func TestGetOrganizations_SingleOrgWithoutEnterprise(t *testing.T) {
+	// No enterprise query may be made when a single organization is targeted without an enterprise
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		t.Fatalf("unexpected enterprise query for %q", enterprise)
+		return nil, nil
+	})
+
+	orgs, breakdown, err := GetOrganizations(nil, "my-org", "", false, 1)
+	if err != nil {
+		t.Fatalf("GetOrganizations() error = %v", err)
+	}
+	if !reflect.DeepEqual(orgs, []string{"my-org"}) || breakdown.Source != "--org" {
+		t.Errorf("GetOrganizations() = %v, %+v", orgs, breakdown)
+	}
+}
+
+func TestGetOrganizations_MultipleEnterprises(t *testing.T) {
+	listed := map[string][]string{
+		"acme":   {"shared", "acme-web"},
+		"globex": {"globex-api", "Shared"},
+	}
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		return &organizationsPage{Logins: listed[enterprise]}, nil
+	})
+
+	orgs, breakdown, err := GetOrganizations([]string{"acme", "globex"}, "", "", true, 1)
+	if err != nil {
+		t.Fatalf("GetOrganizations() error = %v", err)
+	}
+	if want := []string{"shared", "acme-web", "globex-api"}; !reflect.DeepEqual(orgs, want) {
+		t.Errorf("GetOrganizations() = %v, want %v", orgs, want)
+	}
+	wantEnterprises := map[string]string{"shared": "acme", "acme-web": "acme", "globex-api": "globex"}
+	if !reflect.DeepEqual(breakdown.OrgEnterprises, wantEnterprises) {
+		t.Errorf("OrgEnterprises = %v, want %v", breakdown.OrgEnterprises, wantEnterprises)
+	}
+	if breakdown.Found != 3 || breakdown.Source != "--all-orgs" {
+		t.Errorf("breakdown = %+v", breakdown)
+	}
+
+	// An enterprise the user cannot list fails the run instead of falling back to owned organizations
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		if enterprise == "globex" {
+			return nil, fmt.Errorf("%w: exit status 1", errEnterpriseForbidden)
+		}
+		return &organizationsPage{Logins: listed[enterprise]}, nil
+	})
+	if _, _, err := GetOrganizations([]string{"acme", "globex"}, "", "", true, 1); err == nil || !strings.Contains(err.Error(), "globex") {
+		t.Errorf("GetOrganizations() error = %v, want one naming globex", err)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// GetOrgCustomProperties returns the custom property values set on org, by property name. A
+// single-select or text value is returned as one element, a multi-select value as one element per
+// selected option, and properties without a value are left out.
+func GetOrgCustomProperties(org string) (map[string][]string, error) {
+	response, stderr, err := restGet(context.Background(), fmt.Sprintf("/organizations/%s/org-properties/values", org))
+	if err != nil {
+		return nil, classifyReadError(org, stderr.String(), err)
+	}
+
+	var values []struct {
+		PropertyName string          `json:"property_name"`
+		Value        json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(response.Bytes(), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse custom properties for organization %s: %w", org, err)
+	}
+
+	properties := make(map[string][]string, len(values))
+	for _, value := range values {
+		if len(value.Value) == 0 || string(value.Value) == "null" {
+			continue
+		}
+		var single string
+		if err := json.Unmarshal(value.Value, &single); err == nil {
+			properties[value.PropertyName] = []string{single}
+			continue
+		}
+		var multiple []string
+		if err := json.Unmarshal(value.Value, &multiple); err == nil && len(multiple) > 0 {
+			properties[value.PropertyName] = multiple
+		}
+	}
+	return properties, nil
+}
+
+// PropertyExclusions is the result of checking organizations against --exclude-with-property
+type PropertyExclusions struct {
+	Kept       []string         // organizations that match no filter, in input order
+	Excluded   []string         // organizations that match a filter
+	Unreadable map[string]error // organizations whose properties could not be read, with the reason
+}
+
+// ExcludeByProperties reads the custom properties of every organization, up to concurrency at a
+// time, and removes those matching any of filters. Organizations whose properties cannot be read
+// are removed too, as it cannot be told whether they were meant to be excluded.
+func ExcludeByProperties(orgs []string, filters []types.PropertyFilter, concurrency int) PropertyExclusions {
+	matched := make([]bool, len(orgs))
+	errs := make([]error, len(orgs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i, org := range orgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, org string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			properties, err := GetOrgCustomProperties(org)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			for _, filter := range filters {
+				if filter.Matches(properties) {
+					matched[i] = true
+					return
+				}
+			}
+		}(i, org)
+	}
+	wg.Wait()
+
+	exclusions := PropertyExclusions{Unreadable: make(map[string]error)}
+	for i, org := range orgs {
+		switch {
+		case errs[i] != nil:
+			exclusions.Unreadable[org] = errs[i]
+		case matched[i]:
+			exclusions.Excluded = append(exclusions.Excluded, org)
+		default:
+			exclusions.Kept = append(exclusions.Kept, org)
+		}
+	}
+	return exclusions
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// stubOrgProperties answers custom property lookups with the JSON in responses, by organization.
+// Organizations without a response fail with a 404.
+func stubOrgProperties(t *testing.T, responses map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	original := execGH
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		path := args[len(args)-1]
+		org := strings.TrimSuffix(strings.TrimPrefix(path, "/organizations/"), "/org-properties/values")
+		mu.Lock()
+		defer mu.Unlock()
+		if body, ok := responses[org]; ok {
+			return *bytes.NewBufferString(body), bytes.Buffer{}, nil
+		}
+		return bytes.Buffer{}, *bytes.NewBufferString("gh: Not Found (HTTP 404)"), errors.New("exit status 1")
+	}
+	t.Cleanup(func() { execGH = original })
+}
+
+func TestGetOrgCustomProperties(t *testing.T) {
+	stubOrgProperties(t, map[string]string{
+		"octo-org": `[
+			{"property_name": "managed", "value": "external"},
+			{"property_name": "regions", "value": ["emea", "apac"]},
+			{"property_name": "owner", "value": null}
+		]`,
+	})
+
+	properties, err := GetOrgCustomProperties("octo-org")
+	if err != nil {
+		t.Fatalf("GetOrgCustomProperties() error = %v", err)
+	}
+	if got := properties["managed"]; !slices.Equal(got, []string{"external"}) {
+		t.Errorf("managed = %v, want [external]", got)
+	}
+	if got := properties["regions"]; !slices.Equal(got, []string{"emea", "apac"}) {
+		t.Errorf("regions = %v, want [emea apac]", got)
+	}
+	if _, ok := properties["owner"]; ok {
+		t.Errorf("owner without a value should be left out, got %v", properties["owner"])
+	}
+}
+
+func TestExcludeByProperties(t *testing.T) {
+	stubOrgProperties(t, map[string]string{
+		"internal-a": `[{"property_name": "managed", "value": "internal"}]`,
+		"external-b": `[{"property_name": "managed", "value": "external"}]`,
+		"vendor-c":   `[{"property_name": "regions", "value": ["emea", "vendor"]}]`,
+		"untagged-d": `[]`,
+	})
+	filters := []types.PropertyFilter{{Name: "managed", Value: "external"}, {Name: "regions", Value: "vendor"}}
+
+	got := ExcludeByProperties([]string{"internal-a", "external-b", "vendor-c", "missing-e", "untagged-d"}, filters, 3)
+
+	if want := []string{"internal-a", "untagged-d"}; !slices.Equal(got.Kept, want) {
+		t.Errorf("Kept = %v, want %v", got.Kept, want)
+	}
+	if want := []string{"external-b", "vendor-c"}; !slices.Equal(got.Excluded, want) {
+		t.Errorf("Excluded = %v, want %v", got.Excluded, want)
+	}
+	var noAccess *types.NoAccessError
+	if len(got.Unreadable) != 1 || !errors.As(got.Unreadable["missing-e"], &noAccess) {
+		t.Errorf("Unreadable = %v, want missing-e with a no-access error", got.Unreadable)
+	}
+}
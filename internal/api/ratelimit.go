@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// GetRateLimit retrieves the core REST API rate limit for the authenticated user. It returns
+// nil (and no error) when the server has rate limiting disabled, as some GHES instances do.
+func GetRateLimit() (*types.RateLimit, error) {
+	response, stderr, err := runGH(context.Background(), restArgs("/rate_limit")...)
+	if err != nil {
+		// GHES responds with 404 "Rate limiting is not enabled" when rate limiting is turned off
+		if strings.Contains(stderr.String(), "404") || strings.Contains(stderr.String(), "not enabled") {
+			return nil, nil
+		}
+		return nil, classifyExecError(stderr.String(), err)
+	}
+	return parseRateLimitResponse(response.Bytes())
+}
+
+// parseRateLimitResponse extracts the core resource from a /rate_limit response body
+func parseRateLimitResponse(body []byte) (*types.RateLimit, error) {
+	var rateLimitResponse struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Used      int   `json:"used"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(body, &rateLimitResponse); err != nil {
+		return nil, err
+	}
+
+	core := rateLimitResponse.Resources.Core
+	return &types.RateLimit{
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Used:      core.Used,
+		Reset:     time.Unix(core.Reset, 0),
+	}, nil
+}
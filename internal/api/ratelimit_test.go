@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitResponse(t *testing.T) {
+	body := []byte(`{"resources":{"core":{"limit":5000,"used":120,"remaining":4880,"reset":1700000000},"graphql":{"limit":5000,"used":0,"remaining":5000,"reset":1700000000}},"rate":{"limit":5000,"used":120,"remaining":4880,"reset":1700000000}}`)
+
+	rateLimit, err := parseRateLimitResponse(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rateLimit.Limit != 5000 || rateLimit.Remaining != 4880 || rateLimit.Used != 120 {
+		t.Errorf("unexpected counts: %+v", rateLimit)
+	}
+	if !rateLimit.Reset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Reset = %v, want %v", rateLimit.Reset, time.Unix(1700000000, 0))
+	}
+
+	if _, err := parseRateLimitResponse([]byte("not json")); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+}
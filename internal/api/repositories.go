@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// MaxRepositoryIDsPerAttach is the most repository IDs sent in a single attach request
+const MaxRepositoryIDsPerAttach = 100
+
+// attachBatchDelay is the pause between consecutive attach batches. It is a variable so tests do
+// not have to wait.
+var attachBatchDelay = 500 * time.Millisecond
+
+// attachRepoIDBatch sends one attach request. It is a variable so tests can fail chosen batches.
+var attachRepoIDBatch = AttachConfigurationToRepoIDs
+
+// ChunkRepositoryIDs splits ids into consecutive batches of at most size IDs
+func ChunkRepositoryIDs(ids []int, size int) [][]int {
+	var batches [][]int
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batches = append(batches, ids[start:end])
+	}
+	return batches
+}
+
+// AttachConfigurationToSelectedRepos attaches a security configuration to the given repositories,
+// sending them in sequential batches of at most MaxRepositoryIDsPerAttach IDs. A failed batch does
+// not stop the later ones, except for an authentication failure or ctx being cancelled, either of
+// which is recorded for every remaining repository. It returns the outcome for each repository ID (nil when attached) and an
+// error summarizing the failed batches, if any.
+func AttachConfigurationToSelectedRepos(ctx context.Context, org string, configID int, repoIDs []int) (map[int]error, error) {
+	outcomes := make(map[int]error, len(repoIDs))
+	batches := ChunkRepositoryIDs(repoIDs, MaxRepositoryIDsPerAttach)
+	var batchErrs []error
+	failed := 0
+	for i, batch := range batches {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(attachBatchDelay):
+			}
+		}
+		err := attachRepoIDBatch(ctx, org, configID, batch)
+		for _, id := range batch {
+			outcomes[id] = err
+		}
+		if err == nil {
+			continue
+		}
+		failed += len(batch)
+		batchErrs = append(batchErrs, fmt.Errorf("batch %d of %d: %w", i+1, len(batches), err))
+
+		var authErr *types.AuthError
+		if errors.As(err, &authErr) || ctx.Err() != nil {
+			for _, rest := range batches[i+1:] {
+				for _, id := range rest {
+					outcomes[id] = err
+				}
+				failed += len(rest)
+			}
+			break
+		}
+	}
+	if failed == 0 {
+		return outcomes, nil
+	}
+	return outcomes, fmt.Errorf("failed to attach %d of %d repositories: %w", failed, len(repoIDs), errors.Join(batchErrs...))
+}
+
+// ListOrganizationRepositories retrieves every repository in an organization, following pagination
+func ListOrganizationRepositories(ctx context.Context, org string) ([]types.Repository, error) {
+	response, stderr, err := runGH(ctx, restArgs(fmt.Sprintf("/orgs/%s/repos?type=all&per_page=100", org), "--paginate")...)
+	if err != nil {
+		return nil, classifyExecError(stderr.String(), err)
+	}
+	return decodeRepositoryPages(response.Bytes())
+}
+
+// decodeRepositoryPages decodes the output of a paginated request, which is one JSON array per page
+// written back to back
+func decodeRepositoryPages(body []byte) ([]types.Repository, error) {
+	var repos []types.Repository
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var page []types.Repository
+		err := decoder.Decode(&page)
+		if err == io.EOF {
+			return repos, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repositories: %w", err)
+		}
+		repos = append(repos, page...)
+	}
+}
+
+// attachedRepositoryStatuses are the attachment statuses that count a repository as attached to a
+// configuration; detached, removed and failed entries do not
+var attachedRepositoryStatuses = []string{"attached", "attaching", "enforced", "updating"}
+
+// configurationRepositoriesPerPage is the most entries GitHub returns in one page of a
+// configuration's repositories
+const configurationRepositoriesPerPage = 100
+
+// ConfigurationRepositoryQuery narrows WalkConfigurationRepositories
+type ConfigurationRepositoryQuery struct {
+	Statuses []string // Attachment statuses to list, filtered by the API; empty lists every status
+	Limit    int      // Most repositories to visit before enumeration stops; 0 for no limit
+}
+
+// WalkConfigurationRepositories calls visit with each repository listed for a configuration,
+// one page of 100 at a time, so memory does not grow with the size of the organization. The
+// endpoint pages with cursors, so pages are fetched in order. It reports whether enumeration
+// stopped at query.Limit with repositories left unvisited. An error from visit stops enumeration
+// and is returned.
+func WalkConfigurationRepositories(ctx context.Context, org string, configID int, query ConfigurationRepositoryQuery, visit func(types.ConfigurationRepository) error) (bool, error) {
+	visited := 0
+	cursor := ""
+	for {
+		response, stderr, err := runGH(ctx, restArgs(configurationRepositoriesPath(org, configID, query.Statuses, cursor), "--include")...)
+		if err != nil {
+			return false, classifyExecError(stderr.String(), err)
+		}
+		page, next, err := decodeConfigurationRepositoriesPage(response.Bytes())
+		if err != nil {
+			return false, err
+		}
+		for _, entry := range page {
+			if query.Limit > 0 && visited == query.Limit {
+				return true, nil
+			}
+			if err := visit(entry); err != nil {
+				return false, err
+			}
+			visited++
+		}
+		if next == "" {
+			return false, nil
+		}
+		cursor = next
+	}
+}
+
+// configurationRepositoriesPath returns the path of one page of a configuration's repositories,
+// listing only statuses when any are given and starting after cursor when it is not empty
+func configurationRepositoriesPath(org string, configID int, statuses []string, cursor string) string {
+	params := url.Values{}
+	params.Set("per_page", strconv.Itoa(configurationRepositoriesPerPage))
+	if len(statuses) > 0 {
+		params.Set("status", strings.Join(statuses, ","))
+	}
+	if cursor != "" {
+		params.Set("after", cursor)
+	}
+	return fmt.Sprintf("/orgs/%s/code-security/configurations/%d/repositories?%s", org, configID, params.Encode())
+}
+
+// decodeConfigurationRepositoriesPage splits the output of gh api --include into the page's
+// entries and the cursor of the next page, read from the Link header. The cursor is empty on the
+// last page.
+func decodeConfigurationRepositoriesPage(output []byte) ([]types.ConfigurationRepository, string, error) {
+	header, body := splitIncludedResponse(output)
+	var page []types.ConfigurationRepository
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, "", fmt.Errorf("failed to parse configuration repositories: %w", err)
+		}
+	}
+	return page, nextPageCursor(header), nil
+}
+
+// splitIncludedResponse splits the output of gh api --include at the blank line that ends the
+// status line and headers
+func splitIncludedResponse(output []byte) (header, body []byte) {
+	for _, separator := range []string{"\r\n\r\n", "\n\n"} {
+		if i := bytes.Index(output, []byte(separator)); i >= 0 {
+			return output[:i], output[i+len(separator):]
+		}
+	}
+	return output, nil
+}
+
+// nextPageCursor returns the "after" cursor of the rel="next" link in a response's Link header, or
+// "" when there is no next page
+func nextPageCursor(header []byte) string {
+	for _, line := range strings.Split(string(header), "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || !strings.EqualFold(name, "Link") {
+			continue
+		}
+		for _, link := range strings.Split(value, ",") {
+			target, rel, _ := strings.Cut(link, ";")
+			if !strings.Contains(rel, `rel="next"`) {
+				continue
+			}
+			parsed, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				return ""
+			}
+			return parsed.Query().Get("after")
+		}
+	}
+	return ""
+}
+
+// CountAttachedRepositories returns how many repositories a configuration is attached to. Only
+// attached statuses are requested from the API. With a limit above 0, counting stops once limit
+// repositories were found, which is enough to tell whether a configuration is attached at all.
+func CountAttachedRepositories(ctx context.Context, org string, configID int, limit int) (int, error) {
+	count := 0
+	query := ConfigurationRepositoryQuery{Statuses: attachedRepositoryStatuses, Limit: limit}
+	_, err := WalkConfigurationRepositories(ctx, org, configID, query, func(types.ConfigurationRepository) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
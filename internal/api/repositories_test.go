@@ -0,0 +1,347 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestDecodeRepositoryPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantIDs []int
+		wantErr bool
+	}{
+		{name: "empty output", body: "", wantIDs: nil},
+		{name: "single page", body: `[{"id":1,"name":"a","visibility":"public"}]`, wantIDs: []int{1}},
+		{name: "pages back to back", body: `[{"id":1,"name":"a"},{"id":2,"name":"b"}][{"id":3,"name":"c"}]`, wantIDs: []int{1, 2, 3}},
+		{name: "pages separated by newlines", body: "[{\"id\":1}]\n[]\n[{\"id\":2}]\n", wantIDs: []int{1, 2}},
+		{name: "malformed", body: `[{"id":1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := decodeRepositoryPages([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRepositoryPages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(repos) != len(tt.wantIDs) {
+				t.Fatalf("got %d repositories, want %d", len(repos), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if repos[i].ID != id {
+					t.Errorf("repos[%d].ID = %d, want %d", i, repos[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeConfigurationRepositoriesPage(t *testing.T) {
+	const nextLink = `Link: <https://api.github.com/orgs/o/code-security/configurations/1/repositories?per_page=100&after=Y3Vyc29y>; rel="next", <https://api.github.com/orgs/o/code-security/configurations/1/repositories?per_page=100>; rel="first"`
+	tests := []struct {
+		name       string
+		output     string
+		wantIDs    []int
+		wantCursor string
+		wantErr    bool
+	}{
+		{name: "empty body", output: "HTTP/2.0 200 OK\r\nContent-Type: application/json\r\n\r\n", wantIDs: nil},
+		{name: "last page", output: "HTTP/2.0 200 OK\r\n\r\n" + `[{"status":"attached","repository":{"id":1}},{"status":"enforced","repository":{"id":2}}]`, wantIDs: []int{1, 2}},
+		{name: "next page", output: "HTTP/2.0 200 OK\r\n" + nextLink + "\r\n\r\n" + `[{"status":"attached","repository":{"id":3}}]`, wantIDs: []int{3}, wantCursor: "Y3Vyc29y"},
+		{name: "newline separated headers", output: "HTTP/2.0 200 OK\n" + strings.ToLower(nextLink) + "\n\n[]", wantCursor: "y3vyc29y"},
+		{name: "malformed", output: "HTTP/2.0 200 OK\r\n\r\n" + `[{"status":"attached"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, cursor, err := decodeConfigurationRepositoriesPage([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeConfigurationRepositoriesPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []int
+			for _, entry := range page {
+				ids = append(ids, entry.Repository.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("repository IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if cursor != tt.wantCursor {
+				t.Errorf("cursor = %q, want %q", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+// stubConfigurationRepositoryPages answers configuration repository requests with pages of
+// perPage attached repositories, total in all, linking each page to the next by its index. It
+// returns the request paths in order.
+func stubConfigurationRepositoryPages(tb testing.TB, total, perPage int) *[]string {
+	tb.Helper()
+	var paths []string
+	entry := []byte(`{"status":"attached","repository":{"id":1,"name":"repo"}}`)
+	page := bytes.Repeat(append(entry, ','), perPage)
+	original := execGH
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		path := args[len(args)-1]
+		paths = append(paths, path)
+		parsed, err := url.Parse(path)
+		if err != nil {
+			tb.Fatalf("parse %q: %v", path, err)
+		}
+		query := parsed.Query()
+		index, _ := strconv.Atoi(query.Get("after"))
+		size := min(perPage, total-index*perPage)
+		var out bytes.Buffer
+		out.WriteString("HTTP/2.0 200 OK\r\n")
+		if (index+1)*perPage < total {
+			query.Set("after", strconv.Itoa(index+1))
+			parsed.RawQuery = query.Encode()
+			fmt.Fprintf(&out, "Link: <https://api.github.com%s>; rel=\"next\"\r\n", parsed)
+		}
+		out.WriteString("\r\n[")
+		if size > 0 {
+			out.Write(page[:size*(len(entry)+1)-1])
+		}
+		out.WriteString("]")
+		return out, bytes.Buffer{}, nil
+	}
+	tb.Cleanup(func() { execGH = original })
+	return &paths
+}
+
+func TestWalkConfigurationRepositories(t *testing.T) {
+	tests := []struct {
+		name          string
+		total         int
+		limit         int
+		wantVisited   int
+		wantTruncated bool
+		wantRequests  int
+	}{
+		{name: "every page", total: 250, wantVisited: 250, wantRequests: 3},
+		{name: "limit stops enumeration", total: 250, limit: 120, wantVisited: 120, wantTruncated: true, wantRequests: 2},
+		{name: "limit equal to total", total: 100, limit: 100, wantVisited: 100, wantRequests: 1},
+		{name: "empty", total: 0, wantVisited: 0, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := stubConfigurationRepositoryPages(t, tt.total, configurationRepositoriesPerPage)
+			visited := 0
+			query := ConfigurationRepositoryQuery{Statuses: []string{"attached", "enforced"}, Limit: tt.limit}
+			truncated, err := WalkConfigurationRepositories(context.Background(), "octo-org", 7, query, func(types.ConfigurationRepository) error {
+				visited++
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkConfigurationRepositories() error = %v", err)
+			}
+			if visited != tt.wantVisited || truncated != tt.wantTruncated {
+				t.Errorf("visited, truncated = %d, %t, want %d, %t", visited, truncated, tt.wantVisited, tt.wantTruncated)
+			}
+			if len(*paths) != tt.wantRequests {
+				t.Errorf("requests = %d, want %d: %v", len(*paths), tt.wantRequests, *paths)
+			}
+			if first := (*paths)[0]; !strings.Contains(first, "per_page=100") || !strings.Contains(first, "status=attached%2Cenforced") {
+				t.Errorf("first request = %q, want per_page=100 and the status filter", first)
+			}
+		})
+	}
+}
+
+func TestWalkConfigurationRepositories_VisitErrorStops(t *testing.T) {
+	paths := stubConfigurationRepositoryPages(t, 300, configurationRepositoriesPerPage)
+	stop := errors.New("stop")
+	_, err := WalkConfigurationRepositories(context.Background(), "octo-org", 7, ConfigurationRepositoryQuery{}, func(types.ConfigurationRepository) error {
+		return stop
+	})
+	if !errors.Is(err, stop) || len(*paths) != 1 {
+		t.Errorf("error = %v after %d requests, want the visit error after the first page", err, len(*paths))
+	}
+}
+
+func TestCountAttachedRepositories_Limit(t *testing.T) {
+	stubConfigurationRepositoryPages(t, 250, configurationRepositoriesPerPage)
+	for _, tt := range []struct{ limit, want int }{{0, 250}, {1, 1}} {
+		if got, err := CountAttachedRepositories(context.Background(), "octo-org", 7, tt.limit); err != nil || got != tt.want {
+			t.Errorf("CountAttachedRepositories(context.Background(), limit %d) = %d, %v, want %d", tt.limit, got, err, tt.want)
+		}
+	}
+}
+
+// BenchmarkWalkConfigurationRepositories walks organizations of increasing size. The bytes
+// allocated per repository stay flat as the organization grows, as each page is decoded and
+// released before the next one is fetched.
+func BenchmarkWalkConfigurationRepositories(b *testing.B) {
+	for _, total := range []int{2_000, 20_000} {
+		b.Run(fmt.Sprintf("%d repositories", total), func(b *testing.B) {
+			stubConfigurationRepositoryPages(b, total, configurationRepositoriesPerPage)
+			b.ReportAllocs()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for range b.N {
+				if _, err := WalkConfigurationRepositories(context.Background(), "octo-org", 7, ConfigurationRepositoryQuery{}, func(types.ConfigurationRepository) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(total*b.N), "B/repo")
+		})
+	}
+}
+
+func TestChunkRepositoryIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		size int
+		want [][]int
+	}{
+		{name: "empty", ids: nil, size: 2, want: nil},
+		{name: "exact multiple", ids: []int{1, 2, 3, 4}, size: 2, want: [][]int{{1, 2}, {3, 4}}},
+		{name: "remainder", ids: []int{1, 2, 3}, size: 2, want: [][]int{{1, 2}, {3}}},
+		{name: "smaller than size", ids: []int{1}, size: 100, want: [][]int{{1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChunkRepositoryIDs(tt.ids, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkRepositoryIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// repoIDRange returns the IDs 1 through n
+func repoIDRange(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+func stubAttachRepoIDBatch(t *testing.T, fn func(org string, configID int, ids []int) error) {
+	t.Helper()
+	origAttach, origDelay := attachRepoIDBatch, attachBatchDelay
+	t.Cleanup(func() { attachRepoIDBatch, attachBatchDelay = origAttach, origDelay })
+	attachRepoIDBatch = func(_ context.Context, org string, configID int, ids []int) error {
+		return fn(org, configID, ids)
+	}
+	attachBatchDelay = 0
+}
+
+func TestAttachConfigurationToSelectedRepos_ChunkBoundaries(t *testing.T) {
+	tests := []struct {
+		count       int
+		wantBatches []int
+	}{
+		{count: 0, wantBatches: nil},
+		{count: 1, wantBatches: []int{1}},
+		{count: MaxRepositoryIDsPerAttach, wantBatches: []int{MaxRepositoryIDsPerAttach}},
+		{count: MaxRepositoryIDsPerAttach + 1, wantBatches: []int{MaxRepositoryIDsPerAttach, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d repositories", tt.count), func(t *testing.T) {
+			var batches []int
+			stubAttachRepoIDBatch(t, func(org string, configID int, ids []int) error {
+				batches = append(batches, len(ids))
+				return nil
+			})
+
+			outcomes, err := AttachConfigurationToSelectedRepos(context.Background(), "org", 7, repoIDRange(tt.count))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("batch sizes = %v, want %v", batches, tt.wantBatches)
+			}
+			if len(outcomes) != tt.count {
+				t.Errorf("got %d outcomes, want %d", len(outcomes), tt.count)
+			}
+			for id, outcome := range outcomes {
+				if outcome != nil {
+					t.Errorf("outcome for %d = %v, want nil", id, outcome)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachConfigurationToSelectedRepos_MidBatchFailure(t *testing.T) {
+	batchErr := errors.New("HTTP 422")
+	call := 0
+	stubAttachRepoIDBatch(t, func(org string, configID int, ids []int) error {
+		call++
+		if call == 2 {
+			return batchErr
+		}
+		return nil
+	})
+
+	total := 2*MaxRepositoryIDsPerAttach + 5
+	outcomes, err := AttachConfigurationToSelectedRepos(context.Background(), "org", 7, repoIDRange(total))
+	if err == nil || !errors.Is(err, batchErr) {
+		t.Fatalf("error = %v, want wrapping %v", err, batchErr)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("%d of %d repositories", MaxRepositoryIDsPerAttach, total)) {
+		t.Errorf("error %q does not report the failed count", err)
+	}
+	if call != 3 {
+		t.Errorf("sent %d batches, want 3; a failed batch must not stop later ones", call)
+	}
+	for id := 1; id <= total; id++ {
+		inFailedBatch := id > MaxRepositoryIDsPerAttach && id <= 2*MaxRepositoryIDsPerAttach
+		if got := outcomes[id]; (got != nil) != inFailedBatch {
+			t.Fatalf("outcome for %d = %v, want failure %t", id, got, inFailedBatch)
+		}
+	}
+}
+
+func TestAttachConfigurationToSelectedRepos_AuthFailureStops(t *testing.T) {
+	call := 0
+	stubAttachRepoIDBatch(t, func(org string, configID int, ids []int) error {
+		call++
+		return &types.AuthError{Message: "token lacks admin:org"}
+	})
+
+	outcomes, err := AttachConfigurationToSelectedRepos(context.Background(), "org", 7, repoIDRange(MaxRepositoryIDsPerAttach+1))
+	var authErr *types.AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("error = %v, want an AuthError", err)
+	}
+	if call != 1 {
+		t.Errorf("sent %d batches, want 1", call)
+	}
+	if outcomes[MaxRepositoryIDsPerAttach+1] == nil {
+		t.Error("repository in the unsent batch should record the authentication failure")
+	}
+}
+
+func TestAttachConfigurationToSelectedRepos_CancelStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	call := 0
+	stubAttachRepoIDBatch(t, func(org string, configID int, ids []int) error {
+		call++
+		cancel()
+		return context.Canceled
+	})
+
+	outcomes, err := AttachConfigurationToSelectedRepos(ctx, "org", 7, repoIDRange(MaxRepositoryIDsPerAttach+1))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if call != 1 {
+		t.Errorf("sent %d batches, want 1", call)
+	}
+	if outcomes[MaxRepositoryIDsPerAttach+1] == nil {
+		t.Error("repository in the unsent batch should record the cancellation")
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// requestBodySink receives a copy of every JSON request body before it is sent. It is nil unless
+// --print-request-bodies is set. responseSink receives the response to each of those requests and
+// to every delete; it is set only while the --probe organization is processed.
+var (
+	requestBodySinkMu sync.Mutex
+	requestBodySink   io.Writer
+	responseSink      io.Writer
+)
+
+// SetRequestBodySink sets where mutating requests print their JSON bodies; nil turns printing off
+func SetRequestBodySink(w io.Writer) {
+	requestBodySinkMu.Lock()
+	defer requestBodySinkMu.Unlock()
+	requestBodySink = w
+}
+
+// printRequestBody writes the method, path and body of a request to the sink, if one is set.
+// Writes are serialized so bodies from concurrent organizations do not interleave.
+func printRequestBody(method, path string, body []byte) {
+	requestBodySinkMu.Lock()
+	defer requestBodySinkMu.Unlock()
+	if requestBodySink == nil {
+		return
+	}
+	fmt.Fprintf(requestBodySink, "%s %s\n%s\n", method, path, body)
+}
+
+// SetResponseSink sets where mutating requests print their responses; nil turns printing off
+func SetResponseSink(w io.Writer) {
+	requestBodySinkMu.Lock()
+	defer requestBodySinkMu.Unlock()
+	responseSink = w
+}
+
+// printResponse writes the method and path of a request with its response, or the gh CLI error
+// output when it failed, to the response sink, if one is set
+func printResponse(method, path string, response []byte, stderr string, err error) {
+	requestBodySinkMu.Lock()
+	defer requestBodySinkMu.Unlock()
+	if responseSink == nil {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(responseSink, "%s %s failed: %s\n", method, path, strings.TrimSpace(stderr))
+		return
+	}
+	body := bytes.TrimSpace(response)
+	if len(body) == 0 {
+		body = []byte("(no content)")
+	}
+	fmt.Fprintf(responseSink, "%s %s\n%s\n", method, path, body)
+}
+
+// restSend sends a request with a JSON body and the standard REST headers. The gh CLI reads the
+// body from standard input, so no temporary file, with its platform-specific path, is involved.
+func restSend(ctx context.Context, method, path string, body map[string]interface{}) (bytes.Buffer, bytes.Buffer, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+	printRequestBody(method, path, bodyBytes)
+
+	response, stderr, err := runGHWithInput(ctx, bodyBytes, restArgs(path, "--method", method, "--input", "-")...)
+	printResponse(method, path, response.Bytes(), stderr.String(), err)
+	return response, stderr, err
+}
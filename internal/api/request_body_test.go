@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRequestBodiesArePrintedAsSent(t *testing.T) {
+	original := execGH
+	t.Cleanup(func() {
+		execGH = original
+		SetRequestBodySink(nil)
+	})
+
+	var sent []string
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		// The body is passed on standard input rather than in a temporary file
+		if !slices.Contains(args, "--input") || args[slices.Index(args, "--input")+1] != "-" {
+			t.Errorf("args = %v, want --input -", args)
+		}
+		method, path := args[2], args[len(args)-1]
+		sent = append(sent, method+" "+path+"\n"+string(stdin)+"\n")
+		return *bytes.NewBufferString(`{"id":7}`), bytes.Buffer{}, nil
+	}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantPath string
+		wantBody []string // fragments that must appear in the body
+	}{
+		{
+			name: "create",
+			call: func() error {
+				_, err := CreateSecurityConfiguration(context.Background(), "org", "Baseline", "desc", map[string]interface{}{"secret_scanning": "enabled"}, "unenforced")
+				return err
+			},
+			wantPath: "POST /orgs/org/code-security/configurations",
+			wantBody: []string{`"name":"Baseline"`, `"secret_scanning":"enabled"`, `"enforcement":"unenforced"`},
+		},
+		{
+			name: "update",
+			call: func() error {
+				_, err := UpdateSecurityConfiguration(context.Background(), "org", 7, "Baseline", "desc", map[string]interface{}{"secret_scanning": "disabled"}, "enforced")
+				return err
+			},
+			wantPath: "PATCH /orgs/org/code-security/configurations/7",
+			wantBody: []string{`"secret_scanning":"disabled"`, `"enforcement":"enforced"`},
+		},
+		{
+			name: "attach by scope",
+			call: func() error {
+				_, err := AttachConfigurationToRepos(context.Background(), "org", 7, "public")
+				return err
+			},
+			wantPath: "POST /orgs/org/code-security/configurations/7/attach",
+			wantBody: []string{`"scope":"public"`},
+		},
+		{
+			name:     "attach by IDs",
+			call:     func() error { return AttachConfigurationToRepoIDs(context.Background(), "org", 7, []int{1, 2}) },
+			wantPath: "POST /orgs/org/code-security/configurations/7/attach",
+			wantBody: []string{`"selected_repository_ids":[1,2]`},
+		},
+		{
+			name:     "set default",
+			call:     func() error { return SetConfigurationAsDefault(context.Background(), "org", 7) },
+			wantPath: "PUT /orgs/org/code-security/configurations/7/defaults",
+			wantBody: []string{`"default_for_new_repos":"all"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var printed bytes.Buffer
+			SetRequestBodySink(&printed)
+			sent = nil
+
+			if err := tt.call(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(sent))
+			}
+			if printed.String() != sent[0] {
+				t.Errorf("printed body %q does not match the request %q", printed.String(), sent[0])
+			}
+			if !strings.HasPrefix(printed.String(), tt.wantPath+"\n") {
+				t.Errorf("printed %q, want it to start with %q", printed.String(), tt.wantPath)
+			}
+			for _, fragment := range tt.wantBody {
+				if !strings.Contains(printed.String(), fragment) {
+					t.Errorf("printed body %q does not contain %s", printed.String(), fragment)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestBodiesNotPrintedWithoutSink(t *testing.T) {
+	original := execGH
+	t.Cleanup(func() { execGH = original })
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		return bytes.Buffer{}, bytes.Buffer{}, nil
+	}
+
+	SetRequestBodySink(nil)
+	// Printing with no sink must be a no-op rather than a nil writer panic
+	if _, err := AttachConfigurationToRepos(context.Background(), "org", 7, "all"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResponsesPrintedToSink(t *testing.T) {
+	original := execGH
+	var responses bytes.Buffer
+	SetResponseSink(&responses)
+	t.Cleanup(func() {
+		execGH = original
+		SetResponseSink(nil)
+	})
+
+	execGH = func(_ context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		if strings.Contains(args[len(args)-1], "/defaults") {
+			return bytes.Buffer{}, *bytes.NewBufferString("gh: Validation Failed (HTTP 422)\n"), errors.New("exit status 1")
+		}
+		if slices.Contains(args, "DELETE") {
+			return bytes.Buffer{}, bytes.Buffer{}, nil
+		}
+		return *bytes.NewBufferString(`{"id":7,"name":"Baseline"}`), bytes.Buffer{}, nil
+	}
+
+	if _, err := CreateSecurityConfiguration(context.Background(), "org", "Baseline", "desc", map[string]interface{}{"secret_scanning": "enabled"}, ""); err != nil {
+		t.Fatalf("CreateSecurityConfiguration() error = %v", err)
+	}
+	_ = SetConfigurationAsDefault(context.Background(), "org", 7)
+	if err := DeleteSecurityConfiguration(context.Background(), "org", 7); err != nil {
+		t.Fatalf("DeleteSecurityConfiguration() error = %v", err)
+	}
+
+	want := "POST /orgs/org/code-security/configurations\n{\"id\":7,\"name\":\"Baseline\"}\n" +
+		"PUT /orgs/org/code-security/configurations/7/defaults failed: gh: Validation Failed (HTTP 422)\n" +
+		"DELETE /orgs/org/code-security/configurations/7\n(no content)\n"
+	if responses.String() != want {
+		t.Errorf("responses =\n%s\nwant\n%s", responses.String(), want)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// maxRetryAttempts is how many times a request is attempted before a transient failure is returned
+const maxRetryAttempts = 3
+
+// retryBaseDelay is the wait before the first retry; it doubles for each later attempt. It is a
+// variable so tests do not have to wait.
+var retryBaseDelay = 2 * time.Second
+
+// transientStderrMarkers are gh CLI error fragments that indicate a failure worth retrying
+var transientStderrMarkers = []string{
+	"HTTP 500",
+	"HTTP 502",
+	"HTTP 503",
+	"HTTP 504",
+	"timeout",
+	"connection reset",
+	"unexpected EOF",
+}
+
+// isTransientStderr reports whether gh CLI error output describes a transient failure
+func isTransientStderr(stderr string) bool {
+	for _, marker := range transientStderrMarkers {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs fn, retrying with exponential backoff while it fails with a types.TransientError.
+// Any other error, or the last transient error, is returned unchanged.
+func withRetry(description string, fn func() error) error {
+	delay := retryBaseDelay
+	var err error
+	for attempt := 1; attempt <= maxRetryAttempts; attempt++ {
+		err = fn()
+		var transientErr *types.TransientError
+		if err == nil || !errors.As(err, &transientErr) || attempt == maxRetryAttempts {
+			return err
+		}
+		loglevel.RecordWarning()
+		pterm.Warning.Printf("Transient failure while %s (attempt %d of %d), retrying in %s: %v\n", description, attempt, maxRetryAttempts, delay, transientErr.Err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return err
+}
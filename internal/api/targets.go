@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// TargetRequest holds what ResolveTargets needs besides the targeting flags
+type TargetRequest struct {
+	Enterprises []string            // Enterprises whose organizations --all-orgs targets
+	SourceOrg   string              // The --copy-from-org source, which is never a target
+	Interactive bool                // Whether the targeting may be prompted for when no targeting flag is set
+	Prompts     utils.TargetPrompts // The prompts asked then
+}
+
+// ResolveTargets is the one place every command settles its organization targeting and computes
+// the organizations it processes. The targeting in flags is settled first: more than one of --org,
+// --org-list and --all-orgs is an error, and when none is set the targeting is prompted for, or,
+// when the run is not interactive, the run stops with an error. The organizations are then read
+// from --org, --org-list or the enterprises, --strict-org-list is checked, organizations matching
+// --exclude-with-property are removed, and so is the --copy-from-org source. The targets keep the
+// order their source lists them in, and the breakdown records how the flags were narrowed down to
+// them. When no organizations remain, the error is a types.NoTargetsError carrying the breakdown.
+func ResolveTargets(flags *utils.CommonFlags, request TargetRequest) ([]string, types.TargetBreakdown, error) {
+	if err := settleTargeting(flags, request.Interactive, request.Prompts); err != nil {
+		return nil, types.TargetBreakdown{}, err
+	}
+
+	orgs, breakdown, err := GetOrganizations(request.Enterprises, flags.Org, flags.OrgListPath, flags.AllOrgs, flags.Concurrency)
+	if err != nil {
+		return nil, breakdown, err
+	}
+	if err := utils.CheckStrictOrgList(flags, breakdown.InvalidEntries); err != nil {
+		return nil, breakdown, err
+	}
+
+	orgs = excludeOrganizationsByProperty(orgs, flags, &breakdown)
+	targets := removeSourceOrganization(orgs, request.SourceOrg, &breakdown)
+
+	breakdown.Remaining = len(targets)
+	if len(targets) == 0 {
+		return nil, breakdown, &types.NoTargetsError{Breakdown: breakdown}
+	}
+	return targets, breakdown, nil
+}
+
+// settleTargeting checks that at most one of --org, --org-list and --all-orgs is set and, when
+// none is, asks for the targeting with prompts, or fails when interactive is false instead of
+// waiting for input. A prompted --org-list file is validated like one given as a flag.
+func settleTargeting(flags *utils.CommonFlags, interactive bool, prompts utils.TargetPrompts) error {
+	var set []string
+	if flags.Org != "" {
+		set = append(set, "--org")
+	}
+	if flags.OrgListPath != "" {
+		set = append(set, "--org-list")
+	}
+	if flags.AllOrgs {
+		set = append(set, "--all-orgs")
+	}
+	if len(set) > 1 {
+		return fmt.Errorf("only one of --org, --org-list or --all-orgs can be used, got %s", strings.Join(set, " and "))
+	}
+	if len(set) == 1 {
+		return nil
+	}
+	if !interactive {
+		return fmt.Errorf("one of --org, --org-list, or --all-orgs must be specified when not running interactively")
+	}
+
+	method, err := prompts.SelectMethod()
+	if err != nil {
+		return err
+	}
+	switch method {
+	case utils.TargetAllOrgs:
+		flags.AllOrgs = true
+	case utils.TargetSingleOrg:
+		org, err := prompts.OrgName()
+		if err != nil {
+			return err
+		}
+		flags.Org = org
+	case utils.TargetOrgList:
+		path, err := prompts.OrgListPath()
+		if err != nil {
+			return err
+		}
+		flags.OrgListPath = path
+	default:
+		return fmt.Errorf("unknown organization targeting method %q", method)
+	}
+	return utils.ValidateOrgFlagsOptional(flags)
+}
+
+// excludeOrganizationsByProperty removes the organizations matching --exclude-with-property and
+// records the removals in breakdown. Without the flag, no custom properties are read.
+func excludeOrganizationsByProperty(orgs []string, flags *utils.CommonFlags, breakdown *types.TargetBreakdown) []string {
+	if len(flags.ExcludeProperties) == 0 || len(orgs) == 0 {
+		return orgs
+	}
+	pterm.Info.Printf("Reading custom properties of %d organization(s)...\n", len(orgs))
+	exclusions := ExcludeByProperties(orgs, flags.ExcludeProperties, flags.Concurrency)
+	for _, org := range slices.Sorted(maps.Keys(exclusions.Unreadable)) {
+		loglevel.RecordWarning()
+		if loglevel.WarningEnabled() {
+			pterm.Warning.Printf("Excluding organization '%s': its custom properties could not be read: %v\n", org, exclusions.Unreadable[org])
+		}
+	}
+	breakdown.PropertyExcluded = len(exclusions.Excluded)
+	breakdown.PropertyUnknown = len(exclusions.Unreadable)
+	return exclusions.Kept
+}
+
+// removeSourceOrganization removes sourceOrg, if any, from orgs and records the removal in
+// breakdown. Logins are compared ignoring case, as GitHub does.
+func removeSourceOrganization(orgs []string, sourceOrg string, breakdown *types.TargetBreakdown) []string {
+	if sourceOrg == "" {
+		return orgs
+	}
+	var targets []string
+	for _, org := range orgs {
+		if !strings.EqualFold(org, sourceOrg) {
+			targets = append(targets, org)
+		}
+	}
+	if len(targets) < len(orgs) {
+		breakdown.SourceOrgRemoved = sourceOrg
+	}
+	return targets
+}
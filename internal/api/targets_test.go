@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+func TestResolveTargets(t *testing.T) {
+	listed := map[string][]string{
+		"acme":   {"shared", "acme-web", "source"},
+		"globex": {"globex-api", "Shared"},
+		"empty":  nil,
+	}
+	stubOrganizationsPages(t, func(enterprise string, cursor *string) (*organizationsPage, error) {
+		return &organizationsPage{Logins: listed[enterprise]}, nil
+	})
+	stubOrgProperties(t, map[string]string{
+		"shared":   `[{"property_name": "managed", "value": "external"}]`,
+		"acme-web": `[]`,
+		"source":   `[]`,
+	})
+	// Every organization exists except ghost
+	properties := execGH
+	execGH = func(ctx context.Context, stdin []byte, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		org, isLookup := strings.CutPrefix(args[len(args)-1], "/orgs/")
+		switch {
+		case !isLookup:
+			return properties(ctx, stdin, args...)
+		case org == "ghost":
+			return bytes.Buffer{}, *bytes.NewBufferString("gh: Not Found (HTTP 404)"), errors.New("exit status 1")
+		}
+		return *bytes.NewBufferString(`{"login":"` + org + `"}`), bytes.Buffer{}, nil
+	}
+
+	writeCSV := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "orgs.csv")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	excludeExternal := []types.PropertyFilter{{Name: "managed", Value: "external"}}
+
+	tests := []struct {
+		name          string
+		enterprises   []string
+		flags         utils.CommonFlags
+		csv           string // written to a file used as --org-list when set
+		sourceOrg     string
+		want          []string
+		wantBreakdown types.TargetBreakdown
+		wantNoTargets bool
+		wantErr       string
+	}{
+		{
+			name:          "single organization",
+			flags:         utils.CommonFlags{Org: "my-org"},
+			want:          []string{"my-org"},
+			wantBreakdown: types.TargetBreakdown{Source: "--org", Found: 1, Remaining: 1},
+		},
+		{
+			name:          "single organization is the copy source",
+			flags:         utils.CommonFlags{Org: "source"},
+			sourceOrg:     "source",
+			wantBreakdown: types.TargetBreakdown{Source: "--org", Found: 1, SourceOrgRemoved: "source"},
+			wantNoTargets: true,
+		},
+		{
+			name:          "CSV keeps file order",
+			csv:           "org-b\norg-a\norg-c\n",
+			want:          []string{"org-b", "org-a", "org-c"},
+			wantBreakdown: types.TargetBreakdown{Source: "--org-list", Found: 3, Remaining: 3},
+		},
+		{
+			name: "CSV invalid rows skipped",
+			csv:  "org-a\nbad name\n",
+			want: []string{"org-a"},
+			wantBreakdown: types.TargetBreakdown{Source: "--org-list", Found: 2, Invalid: 1, Remaining: 1,
+				InvalidEntries: []types.InvalidOrgEntry{{Line: 2, Name: "bad name"}}},
+		},
+		{
+			name:    "CSV invalid rows with --strict-org-list",
+			flags:   utils.CommonFlags{StrictOrgList: true},
+			csv:     "org-a\nbad name\n",
+			wantErr: "--strict-org-list",
+		},
+		{
+			name: "CSV organizations not found skipped and listed with the invalid rows",
+			csv:  "org-a\nghost\nbad name\n",
+			want: []string{"org-a"},
+			wantBreakdown: types.TargetBreakdown{Source: "--org-list", Found: 3, Invalid: 1, Missing: 1, Remaining: 1,
+				InvalidEntries: []types.InvalidOrgEntry{{Line: 2, Name: "ghost", Missing: true}, {Line: 3, Name: "bad name"}}},
+		},
+		{
+			name:    "CSV organizations not found with --strict-org-list",
+			flags:   utils.CommonFlags{StrictOrgList: true},
+			csv:     "org-a\nghost\n",
+			wantErr: "line 2: 'ghost' (not found)",
+		},
+		{
+			name:          "CSV listing only the copy source",
+			csv:           "Source\n",
+			sourceOrg:     "source",
+			wantBreakdown: types.TargetBreakdown{Source: "--org-list", Found: 1, SourceOrgRemoved: "source"},
+			wantNoTargets: true,
+		},
+		{
+			name:          "enterprise without the copy source",
+			enterprises:   []string{"acme"},
+			flags:         utils.CommonFlags{AllOrgs: true},
+			sourceOrg:     "source",
+			want:          []string{"shared", "acme-web"},
+			wantBreakdown: types.TargetBreakdown{Source: "--all-orgs", Found: 3, SourceOrgRemoved: "source", Remaining: 2},
+		},
+		{
+			name:          "copy source outside the enterprise leaves the targets alone",
+			enterprises:   []string{"globex"},
+			flags:         utils.CommonFlags{AllOrgs: true},
+			sourceOrg:     "elsewhere",
+			want:          []string{"globex-api", "Shared"},
+			wantBreakdown: types.TargetBreakdown{Source: "--all-orgs", Found: 2, Remaining: 2},
+		},
+		{
+			name:        "several enterprises list a shared organization once",
+			enterprises: []string{"acme", "globex"},
+			flags:       utils.CommonFlags{AllOrgs: true},
+			want:        []string{"shared", "acme-web", "source", "globex-api"},
+			wantBreakdown: types.TargetBreakdown{Source: "--all-orgs", Found: 4, Remaining: 4,
+				OrgEnterprises: map[string]string{"shared": "acme", "acme-web": "acme", "source": "acme", "globex-api": "globex"}},
+		},
+		{
+			name:          "empty enterprise",
+			enterprises:   []string{"empty"},
+			flags:         utils.CommonFlags{AllOrgs: true},
+			wantBreakdown: types.TargetBreakdown{Source: "--all-orgs"},
+			wantNoTargets: true,
+		},
+		{
+			name:    "all organizations without an enterprise",
+			flags:   utils.CommonFlags{AllOrgs: true},
+			wantErr: "enterprise slug is required",
+		},
+		{
+			name:          "custom property exclusions, with unreadable properties excluded",
+			flags:         utils.CommonFlags{ExcludeProperties: excludeExternal, Concurrency: 2},
+			csv:           "shared\nacme-web\nunknown-org\nsource\n",
+			sourceOrg:     "source",
+			want:          []string{"acme-web"},
+			wantBreakdown: types.TargetBreakdown{Source: "--org-list", Found: 4, PropertyExcluded: 1, PropertyUnknown: 1, SourceOrgRemoved: "source", Remaining: 1},
+		},
+		{
+			name:          "custom property exclusions leave nothing",
+			flags:         utils.CommonFlags{Org: "shared", ExcludeProperties: excludeExternal, Concurrency: 1},
+			wantBreakdown: types.TargetBreakdown{Source: "--org", Found: 1, PropertyExcluded: 1},
+			wantNoTargets: true,
+		},
+		{
+			name:    "no targeting flag when not interactive",
+			wantErr: "when not running interactively",
+		},
+		{
+			name:    "two targeting flags",
+			flags:   utils.CommonFlags{Org: "my-org", AllOrgs: true},
+			wantErr: "got --org and --all-orgs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := tt.flags
+			if tt.csv != "" {
+				flags.OrgListPath = writeCSV(t, tt.csv)
+			}
+
+			got, breakdown, err := ResolveTargets(&flags, TargetRequest{Enterprises: tt.enterprises, SourceOrg: tt.sourceOrg})
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveTargets() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			case tt.wantNoTargets:
+				var noTargetsErr *types.NoTargetsError
+				if !errors.As(err, &noTargetsErr) {
+					t.Fatalf("ResolveTargets() error = %v, want a NoTargetsError", err)
+				}
+				if !reflect.DeepEqual(noTargetsErr.Breakdown, tt.wantBreakdown) {
+					t.Errorf("NoTargetsError breakdown = %+v, want %+v", noTargetsErr.Breakdown, tt.wantBreakdown)
+				}
+			case err != nil:
+				t.Fatalf("ResolveTargets() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveTargets() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(breakdown, tt.wantBreakdown) {
+				t.Errorf("breakdown = %+v, want %+v", breakdown, tt.wantBreakdown)
+			}
+		})
+	}
+}
+
+func TestResolveTargets_NoPropertiesReadWithoutExclusions(t *testing.T) {
+	calls := stubExecGH(t, nil)
+
+	got, _, err := ResolveTargets(&utils.CommonFlags{Org: "my-org"}, TargetRequest{})
+	if err != nil {
+		t.Fatalf("ResolveTargets() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"my-org"}) {
+		t.Errorf("ResolveTargets() = %v, want [my-org]", got)
+	}
+	if len(calls) != 0 {
+		t.Errorf("API calls = %v, want none", calls)
+	}
+}
+
+func TestSettleTargeting(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "orgs.csv")
+	if err := os.WriteFile(csvPath, []byte("org-a\norg-b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	answer := func(value string) func() (string, error) {
+		return func() (string, error) { return value, nil }
+	}
+	notAsked := func() (string, error) {
+		return "", errors.New("prompted unexpectedly")
+	}
+
+	tests := []struct {
+		name        string
+		flags       utils.CommonFlags
+		interactive bool
+		prompts     utils.TargetPrompts
+		want        utils.CommonFlags
+		wantErr     string
+	}{
+		{name: "org", flags: utils.CommonFlags{Org: "octo-org"}, want: utils.CommonFlags{Org: "octo-org"}},
+		{name: "org list", flags: utils.CommonFlags{OrgListPath: csvPath}, want: utils.CommonFlags{OrgListPath: csvPath}},
+		{name: "all orgs", flags: utils.CommonFlags{AllOrgs: true}, want: utils.CommonFlags{AllOrgs: true}},
+		{name: "org and org list", flags: utils.CommonFlags{Org: "octo-org", OrgListPath: csvPath}, wantErr: "got --org and --org-list"},
+		{name: "org and all orgs", flags: utils.CommonFlags{Org: "octo-org", AllOrgs: true}, wantErr: "got --org and --all-orgs"},
+		{name: "org list and all orgs", flags: utils.CommonFlags{OrgListPath: csvPath, AllOrgs: true}, wantErr: "got --org-list and --all-orgs"},
+		{name: "all three", flags: utils.CommonFlags{Org: "octo-org", OrgListPath: csvPath, AllOrgs: true}, wantErr: "got --org and --org-list and --all-orgs"},
+		{name: "none when not interactive", wantErr: "when not running interactively"},
+		{name: "prompted all orgs", interactive: true, prompts: utils.TargetPrompts{SelectMethod: answer(utils.TargetAllOrgs)}, want: utils.CommonFlags{AllOrgs: true}},
+		{name: "prompted org", interactive: true, prompts: utils.TargetPrompts{SelectMethod: answer(utils.TargetSingleOrg), OrgName: answer("octo-org")}, want: utils.CommonFlags{Org: "octo-org"}},
+		{name: "prompted org list", interactive: true, prompts: utils.TargetPrompts{SelectMethod: answer(utils.TargetOrgList), OrgListPath: answer(csvPath)}, want: utils.CommonFlags{OrgListPath: csvPath}},
+		{name: "prompted org list missing", interactive: true, prompts: utils.TargetPrompts{SelectMethod: answer(utils.TargetOrgList), OrgListPath: answer(csvPath + ".missing")}, wantErr: "CSV validation failed"},
+		{name: "flag set skips prompts", flags: utils.CommonFlags{AllOrgs: true}, interactive: true, prompts: utils.TargetPrompts{SelectMethod: notAsked}, want: utils.CommonFlags{AllOrgs: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := tt.flags
+			err := settleTargeting(&flags, tt.interactive, tt.prompts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("settleTargeting() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("settleTargeting() error = %v", err)
+			}
+			if flags.Org != tt.want.Org || flags.OrgListPath != tt.want.OrgListPath || flags.AllOrgs != tt.want.AllOrgs {
+				t.Errorf("targeting = %q, %q, %t, want %q, %q, %t", flags.Org, flags.OrgListPath, flags.AllOrgs, tt.want.Org, tt.want.OrgListPath, tt.want.AllOrgs)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // LogLevel represents the verbosity of output emitted by the extension.
@@ -75,3 +76,22 @@ func WarningEnabled() bool {
 func InfoEnabled() bool {
 	return GetLogLevel() <= LogLevelInfo
 }
+
+// warningCount is the number of warnings raised since the last ResetWarningCount
+var warningCount atomic.Int64
+
+// RecordWarning counts a warning. Code that prints a warning calls it whether or not the current
+// level shows the warning, so --warnings-as-errors also sees warnings hidden by --log-level error.
+func RecordWarning() {
+	warningCount.Add(1)
+}
+
+// WarningCount returns the number of warnings recorded since the last ResetWarningCount
+func WarningCount() int {
+	return int(warningCount.Load())
+}
+
+// ResetWarningCount discards the recorded warnings
+func ResetWarningCount() {
+	warningCount.Store(0)
+}
@@ -63,3 +63,18 @@ func TestInfoEnabled(t *testing.T) {
 		t.Errorf("InfoEnabled() = true, want false for LogLevelError")
 	}
 }
+
+func TestWarningCount(t *testing.T) {
+	ResetWarningCount()
+	t.Cleanup(ResetWarningCount)
+
+	RecordWarning()
+	RecordWarning()
+	if got := WarningCount(); got != 2 {
+		t.Errorf("WarningCount() = %d, want 2", got)
+	}
+	ResetWarningCount()
+	if got := WarningCount(); got != 0 {
+		t.Errorf("WarningCount() after reset = %d, want 0", got)
+	}
+}
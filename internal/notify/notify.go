@@ -0,0 +1,96 @@
+// Package notify posts a JSON summary of a run to a webhook when processing finishes, so
+// unattended runs can report to chat tools such as Slack or Microsoft Teams through their
+// incoming webhooks. It depends only on the standard library.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// sendTimeout bounds how long the end of a run waits for the webhook
+const sendTimeout = 10 * time.Second
+
+// httpClient sends the notification. It is a variable so tests can replace it.
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// Summary is the JSON body posted to the webhook
+type Summary struct {
+	Text            string   `json:"text"` // One-line summary; the field Slack and Teams incoming webhooks display
+	Command         string   `json:"command"`
+	Host            string   `json:"host,omitempty"`
+	User            string   `json:"user,omitempty"` // Login the run was authenticated as
+	ExitCode        int      `json:"exit_code"`
+	Error           string   `json:"error,omitempty"`
+	ChangeRef       string   `json:"change_ref,omitempty"`
+	Total           int      `json:"total"`
+	Success         int      `json:"success"`
+	Skipped         int      `json:"skipped"`
+	Failed          int      `json:"failed"`
+	FailedOrgs      []string `json:"failed_orgs"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+// ValidateURL checks that a --notify-webhook value is an absolute http or https URL. The error
+// shows the value redacted, as webhook URLs carry their secret after the host.
+func ValidateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("--notify-webhook must be an http or https URL, got %q", redactURL(raw))
+	}
+	return nil
+}
+
+// redactURL returns raw with everything after the host replaced by "...". A value without a host
+// is not shown at all, since there is no telling which part of it is secret.
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "(redacted)"
+	}
+	redacted := parsed.Scheme + "://" + parsed.Host
+	if rest := parsed.EscapedPath() + parsed.RawQuery + parsed.Fragment; rest != "" && rest != "/" {
+		redacted += "/..."
+	}
+	return redacted
+}
+
+// Send posts summary to webhookURL as JSON. Any response other than 2xx is an error.
+func Send(webhookURL string, summary Summary) error {
+	if summary.FailedOrgs == nil {
+		summary.FailedOrgs = []string{}
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook notification for %s: %w", redactURL(webhookURL), withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook notification to %s: %w", redactURL(webhookURL), withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send webhook notification: webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// withoutURL returns the cause of a *url.Error, whose message repeats the full URL, and err itself
+// otherwise
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
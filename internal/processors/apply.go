@@ -1,7 +1,9 @@
 package processors
 
 import (
+	"context"
 	"fmt"
+	"slices"
 
 	"github.com/pterm/pterm"
 
@@ -9,6 +11,38 @@ import (
 	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
+// checkOrganizationMembership looks up the user's membership in an organization. It is a variable
+// so tests can supply a membership.
+var checkOrganizationMembership = api.CheckSingleOrganizationMembership
+
+// FetchSourceConfiguration returns the details of the organization-level configuration named
+// configName in sourceOrg, the one organization apply reads a configuration's settings from. It
+// fails when the user cannot manage sourceOrg's configurations or the configuration is not there.
+func FetchSourceConfiguration(sourceOrg, configName string) (*types.SecurityConfigurationDetails, error) {
+	status, err := checkOrganizationMembership(context.Background(), sourceOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership for template organization '%s': %w", sourceOrg, err)
+	}
+	if problem := status.Problem(); problem != "" {
+		return nil, fmt.Errorf("cannot read configurations from template organization '%s': %s", sourceOrg, problem)
+	}
+
+	configs, err := fetchSecurityConfigurations(context.Background(), sourceOrg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch configurations from template organization '%s': %w", sourceOrg, err)
+	}
+	config, found := api.FindConfiguration(configs, configName)
+	if !found || config.TargetType == "enterprise" {
+		return nil, fmt.Errorf("organization configuration '%s' not found in template organization '%s'", configName, sourceOrg)
+	}
+
+	details, err := fetchConfigurationDetails(context.Background(), sourceOrg, config.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configuration details from template organization '%s': %w", sourceOrg, err)
+	}
+	return details, nil
+}
+
 // ApplyProcessor implements OrganizationProcessor for the apply command
 type ApplyProcessor struct {
 	ConfigName         string
@@ -17,26 +51,52 @@ type ApplyProcessor struct {
 	Scope              string
 	SetAsDefault       bool
 	IsEnterpriseConfig bool
+	Exclusions         types.RepositoryExclusions // Repositories that must never be attached
+	Languages          types.LanguageSet          // Primary languages a repository must have to be attached; nil for any
+	SyncSettings       map[string]interface{}     // Settings the configuration is updated to match before attaching; nil to attach as is
+	SyncEnforcement    string                     // Enforcement synced along with SyncSettings; "" leaves it unchanged
+
+	SkipOrgsWithExistingDefault bool // Skip organizations whose default is a different configuration
 }
 
 // ProcessOrganization processes a single organization for the apply command
-func (ap *ApplyProcessor) ProcessOrganization(org string) types.ProcessingResult {
+func (ap *ApplyProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	// Check membership using the shared validation function
-	if skipResult := api.ValidateMembershipAndSkip(org); skipResult != nil {
+	if skipResult := api.ValidateMembershipAndSkip(ctx, org); skipResult != nil {
 		return *skipResult
 	}
 
-	result := ap.processOrganization(org)
+	return ap.applyToOrganization(ctx, org)
+}
+
+// applyToOrganization checks the organization's existing defaults and then applies the
+// configuration. The default is only set when the configuration is not already the default, so
+// re-runs make no needless requests.
+func (ap *ApplyProcessor) applyToOrganization(ctx context.Context, org string) types.ProcessingResult {
+	replacedDefaults, alreadyDefault, guardResult := checkExistingDefaults(ctx, org, ap.ConfigName, ap.SetAsDefault, ap.SkipOrgsWithExistingDefault)
+	if guardResult != nil {
+		return *guardResult
+	}
+
+	setDefault := ap.SetAsDefault && !alreadyDefault
+	result := ap.processOrganization(ctx, org, setDefault)
+	if result.Success {
+		result.ReplacedDefaults = replacedDefaults
+		if ap.SetAsDefault {
+			result.DefaultSet = &setDefault
+		}
+	}
 	return result
 }
 
-// processOrganization handles the core organization processing logic
-func (ap *ApplyProcessor) processOrganization(org string) types.ProcessingResult {
+// processOrganization handles the core organization processing logic, setting the configuration
+// as default when setDefault is true
+func (ap *ApplyProcessor) processOrganization(ctx context.Context, org string, setDefault bool) types.ProcessingResult {
 	// For enterprise configurations, the config exists at enterprise level
 	// and we just need to attach it to repositories in the org
 	if ap.IsEnterpriseConfig {
 		// Check if the enterprise configuration is visible in this org
-		configs, err := api.FetchSecurityConfigurations(org)
+		configs, err := api.FetchSecurityConfigurations(ctx, org)
 		if err != nil {
 			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch security configurations: %w", err)}
 		}
@@ -49,26 +109,27 @@ func (ap *ApplyProcessor) processOrganization(org string) types.ProcessingResult
 		}
 
 		// Attach to repositories if scope is specified
+		var attachment *types.Attachment
 		if ap.Scope != "" {
-			err = api.AttachConfigurationToRepos(org, existingConfigID, ap.Scope)
+			attachment, err = attachRespectingExclusions(ctx, org, existingConfigID, ap.Scope, ap.Exclusions, ap.Languages)
 			if err != nil {
-				return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to attach configuration to repositories: %w", err)}
+				return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to attach configuration to repositories: %w", err), Attachment: attachment}
 			}
 		}
 
 		// Set as default if requested
-		if ap.SetAsDefault {
-			err = api.SetConfigurationAsDefault(org, existingConfigID)
+		if setDefault {
+			err = setConfigurationAsDefault(ctx, org, existingConfigID)
 			if err != nil {
-				return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to set configuration as default: %w", err)}
+				return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to set configuration as default: %w", err), Attachment: attachment}
 			}
 		}
 
-		return types.ProcessingResult{Organization: org, Success: true}
+		return types.ProcessingResult{Organization: org, Success: true, ConfigID: existingConfigID, Attachment: attachment}
 	}
 
 	// For organization-level configurations, check if it exists
-	configs, err := api.FetchSecurityConfigurations(org)
+	configs, err := fetchSecurityConfigurations(ctx, org)
 	if err != nil {
 		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch existing security configurations: %w", err)}
 	}
@@ -82,20 +143,61 @@ func (ap *ApplyProcessor) processOrganization(org string) types.ProcessingResult
 		return types.ProcessingResult{Organization: org, Skipped: true}
 	}
 
+	// Converge the configuration's settings before it is attached
+	var settingsSynced *bool
+	if ap.SyncSettings != nil {
+		synced, err := syncConfigurationSettings(ctx, org, existingConfigID, ap.SyncSettings, ap.SyncEnforcement)
+		if err != nil {
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to sync configuration settings: %w", err)}
+		}
+		settingsSynced = &synced
+	}
+
+	var attachment *types.Attachment
 	if ap.Scope != "" {
-		err = api.AttachConfigurationToRepos(org, existingConfigID, ap.Scope)
+		attachment, err = attachRespectingExclusions(ctx, org, existingConfigID, ap.Scope, ap.Exclusions, ap.Languages)
 		if err != nil {
-			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to attach configuration to repositories: %w", err)}
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to attach configuration to repositories: %w", err), Attachment: attachment, SettingsSynced: settingsSynced}
 		}
 	}
 
 	// Set as default if requested
-	if ap.SetAsDefault {
-		err = api.SetConfigurationAsDefault(org, existingConfigID)
+	if setDefault {
+		err = setConfigurationAsDefault(ctx, org, existingConfigID)
 		if err != nil {
-			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to set configuration as default: %w", err)}
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to set configuration as default: %w", err), Attachment: attachment, SettingsSynced: settingsSynced}
+		}
+	}
+
+	return types.ProcessingResult{Organization: org, Success: true, ConfigID: existingConfigID, Attachment: attachment, SettingsSynced: settingsSynced}
+}
+
+// SettingsDiff returns the keys of template whose value differs from current, sorted
+func SettingsDiff(current, template map[string]interface{}) []string {
+	var changed []string
+	for key := range template {
+		if types.SettingValue(current, key) != types.SettingValue(template, key) {
+			changed = append(changed, key)
 		}
 	}
+	slices.Sort(changed)
+	return changed
+}
 
-	return types.ProcessingResult{Organization: org, Success: true}
+// syncConfigurationSettings updates a configuration so its settings, and its enforcement when one
+// is given, match the template, keeping its name and description. It reports whether an update
+// was needed.
+func syncConfigurationSettings(ctx context.Context, org string, configID int, template map[string]interface{}, enforcement string) (bool, error) {
+	current, err := fetchConfigurationDetails(ctx, org, configID)
+	if err != nil {
+		return false, err
+	}
+	enforcementChanged := enforcement != "" && enforcement != current.Enforcement
+	if len(SettingsDiff(current.Settings, template)) == 0 && !enforcementChanged {
+		return false, nil
+	}
+	if _, err := updateSecurityConfiguration(ctx, org, configID, current.Name, current.Description, template, enforcement); err != nil {
+		return false, err
+	}
+	return true, nil
 }
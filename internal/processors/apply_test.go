@@ -0,0 +1,183 @@
+package processors
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestSettingsDiff(t *testing.T) {
+	template := map[string]interface{}{"secret_scanning": "enabled", "advanced_security": "enabled"}
+
+	tests := []struct {
+		name    string
+		current map[string]interface{}
+		want    []string
+	}{
+		{name: "matching", current: map[string]interface{}{"secret_scanning": "enabled", "advanced_security": "enabled", "dependabot_alerts": "disabled"}},
+		{name: "drifted", current: map[string]interface{}{"secret_scanning": "disabled", "advanced_security": "enabled"}, want: []string{"secret_scanning"}},
+		{name: "missing settings", current: map[string]interface{}{}, want: []string{"advanced_security", "secret_scanning"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SettingsDiff(tt.current, template); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SettingsDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettingsDiff_NotSet(t *testing.T) {
+	template := map[string]interface{}{"dependabot_alerts": "not_set", "secret_scanning": "enabled"}
+
+	// GHES 3.14 reads some unset settings back as null, and a setting may be left out entirely
+	for _, current := range []map[string]interface{}{
+		{"dependabot_alerts": nil, "secret_scanning": "enabled"},
+		{"secret_scanning": "enabled"},
+	} {
+		if got := SettingsDiff(current, template); len(got) != 0 {
+			t.Errorf("SettingsDiff(%v) = %v, want no changes", current, got)
+		}
+	}
+}
+
+func TestSyncConfigurationSettings(t *testing.T) {
+	origDetails, origUpdate := fetchConfigurationDetails, updateSecurityConfiguration
+	t.Cleanup(func() { fetchConfigurationDetails, updateSecurityConfiguration = origDetails, origUpdate })
+
+	template := map[string]interface{}{"secret_scanning": "enabled"}
+	tests := []struct {
+		name               string
+		current            string
+		currentEnforcement string
+		enforcement        string
+		wantSynced         bool
+	}{
+		{name: "in sync", current: "enabled", currentEnforcement: "enforced", enforcement: "enforced", wantSynced: false},
+		{name: "drifted", current: "disabled", currentEnforcement: "enforced", enforcement: "enforced", wantSynced: true},
+		{name: "enforcement drifted", current: "enabled", currentEnforcement: "unenforced", enforcement: "enforced", wantSynced: true},
+		{name: "enforcement not synced", current: "enabled", currentEnforcement: "unenforced", wantSynced: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+				return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline", Description: "Org baseline", Settings: map[string]interface{}{"secret_scanning": tt.current}, Enforcement: tt.currentEnforcement}, nil
+			}
+			var updatedName, updatedDescription string
+			updates := 0
+			updateSecurityConfiguration = func(_ context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
+				updates++
+				updatedName, updatedDescription = name, description
+				if !reflect.DeepEqual(settings, template) || enforcement != tt.enforcement {
+					t.Errorf("updated settings = %v, %q; want %v, %q", settings, enforcement, template, tt.enforcement)
+				}
+				return nil, nil
+			}
+
+			synced, err := syncConfigurationSettings(context.Background(), "org", 5, template, tt.enforcement)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if synced != tt.wantSynced || (updates == 1) != tt.wantSynced {
+				t.Fatalf("synced = %t with %d updates, want %t", synced, updates, tt.wantSynced)
+			}
+			if synced && (updatedName != "Baseline" || updatedDescription != "Org baseline") {
+				t.Errorf("sync changed the name or description: %q, %q", updatedName, updatedDescription)
+			}
+		})
+	}
+}
+
+func TestFetchSourceConfiguration(t *testing.T) {
+	origMembership, origList, origDetails := checkOrganizationMembership, fetchSecurityConfigurations, fetchConfigurationDetails
+	t.Cleanup(func() {
+		checkOrganizationMembership, fetchSecurityConfigurations, fetchConfigurationDetails = origMembership, origList, origDetails
+	})
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{
+			{ID: 5, Name: "Baseline", TargetType: "organization"},
+			{ID: 9, Name: "Enterprise Default", TargetType: "enterprise"},
+		}, nil
+	}
+
+	tests := []struct {
+		name       string
+		status     types.MembershipStatus
+		configName string
+		wantErr    string
+	}{
+		{name: "owner with configuration", status: types.MembershipStatus{IsMember: true, IsOwner: true, Role: "admin", State: "active"}, configName: "Baseline"},
+		{name: "not an owner", status: types.MembershipStatus{IsMember: true, Role: "member", State: "active"}, configName: "Baseline", wantErr: "owner required"},
+		{name: "configuration missing", status: types.MembershipStatus{IsMember: true, IsOwner: true, Role: "admin", State: "active"}, configName: "Strict", wantErr: "not found in template organization"},
+		{name: "enterprise configuration", status: types.MembershipStatus{IsMember: true, IsOwner: true, Role: "admin", State: "active"}, configName: "Enterprise Default", wantErr: "not found in template organization"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOrganizationMembership = func(_ context.Context, org string) (types.MembershipStatus, error) { return tt.status, nil }
+			var fetchedFrom string
+			fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+				fetchedFrom = org
+				return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline"}, nil
+			}
+
+			details, err := FetchSourceConfiguration("template-org", tt.configName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if details.ID != 5 || fetchedFrom != "template-org" {
+				t.Errorf("got configuration %d from %q, want 5 from template-org", details.ID, fetchedFrom)
+			}
+		})
+	}
+}
+
+func TestApplyToOrganization_SetDefault(t *testing.T) {
+	origDefaults, origList, origSet := fetchDefaultConfigurations, fetchSecurityConfigurations, setConfigurationAsDefault
+	t.Cleanup(func() {
+		fetchDefaultConfigurations, fetchSecurityConfigurations, setConfigurationAsDefault = origDefaults, origList, origSet
+	})
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{{ID: 5, Name: "Baseline", TargetType: "organization"}}, nil
+	}
+
+	tests := []struct {
+		name     string
+		defaults []types.DefaultConfiguration
+		wantPut  bool
+	}{
+		{name: "already default skips the request", defaults: []types.DefaultConfiguration{{DefaultForNewRepos: "all", Configuration: types.SecurityConfiguration{ID: 5, Name: "Baseline"}}}},
+		{name: "default for public only is set", defaults: []types.DefaultConfiguration{{DefaultForNewRepos: "public", Configuration: types.SecurityConfiguration{ID: 5, Name: "Baseline"}}}, wantPut: true},
+		{name: "no default is set", wantPut: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchDefaultConfigurations = func(_ context.Context, org string) ([]types.DefaultConfiguration, error) { return tt.defaults, nil }
+			put := false
+			setConfigurationAsDefault = func(_ context.Context, org string, configID int) error {
+				put = true
+				return nil
+			}
+
+			ap := &ApplyProcessor{ConfigName: "Baseline", SetAsDefault: true}
+			result := ap.applyToOrganization(context.Background(), "org")
+			if !result.Success {
+				t.Fatalf("result = %+v, want success", result)
+			}
+			if put != tt.wantPut {
+				t.Errorf("set default called = %t, want %t", put, tt.wantPut)
+			}
+			if result.DefaultSet == nil || *result.DefaultSet != tt.wantPut {
+				t.Errorf("DefaultSet = %v, want %t", result.DefaultSet, tt.wantPut)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// API calls made when attaching around excluded repositories. They are variables so tests can
+// observe which requests a run would make.
+var (
+	listOrganizationRepositories       = api.ListOrganizationRepositories
+	attachConfigurationToSelectedRepos = api.AttachConfigurationToSelectedRepos
+)
+
+// SelectRepositoryIDs returns the IDs of the repositories that match scope, are not excluded and
+// have a primary language in languages, along with how many matching repositories were excluded
+// and how many were left out for their language. A nil languages set includes every language.
+func SelectRepositoryIDs(org string, repos []types.Repository, scope string, exclusions types.RepositoryExclusions, languages types.LanguageSet) ([]int, int, int) {
+	var ids []int
+	excluded, unsupported := 0, 0
+	for _, repo := range repos {
+		if !scopeIncludes(scope, repo.Visibility) {
+			continue
+		}
+		if exclusions.Excludes(org, repo.Name) {
+			excluded++
+			continue
+		}
+		if !languages.Includes(repo.Language) {
+			unsupported++
+			continue
+		}
+		ids = append(ids, repo.ID)
+	}
+	return ids, excluded, unsupported
+}
+
+// scopeIncludes reports whether a repository with the given visibility falls within an
+// attachment scope
+func scopeIncludes(scope, visibility string) bool {
+	switch scope {
+	case "all":
+		return true
+	case "public":
+		return visibility == "public"
+	case "private_or_internal":
+		return visibility == "private" || visibility == "internal"
+	default:
+		return false
+	}
+}
+
+// attachRespectingExclusions attaches a configuration to the repositories in scope. Organizations
+// without exclusions use a single scope-based request unless a languages set is given; otherwise
+// the repositories are enumerated and attached by ID in batches. The returned attachment is never
+// nil; it records the request, how many repositories were excluded and, when filtering by
+// language, how many were included and left out.
+func attachRespectingExclusions(ctx context.Context, org string, configID int, scope string, exclusions types.RepositoryExclusions, languages types.LanguageSet) (*types.Attachment, error) {
+	if !exclusions.HasOrg(org) && languages == nil {
+		attachment := &types.Attachment{Scope: scope}
+		response, err := attachConfigurationToRepos(ctx, org, configID, scope)
+		if err != nil {
+			return attachment, err
+		}
+		attachment.Accepted = true
+		attachment.Response = response
+		return attachment, nil
+	}
+
+	repos, err := listOrganizationRepositories(ctx, org)
+	if err != nil {
+		return &types.Attachment{Scope: scope}, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	ids, excluded, unsupported := SelectRepositoryIDs(org, repos, scope, exclusions, languages)
+	attachment := &types.Attachment{Scope: "selected", Repositories: len(ids), ExcludedRepos: excluded}
+	if languages != nil {
+		attachment.CodeQLRepos = &types.RepositoryCounts{Included: len(ids), Excluded: unsupported}
+	}
+	if _, err := attachConfigurationToSelectedRepos(ctx, org, configID, ids); err != nil {
+		return attachment, err
+	}
+	attachment.Accepted = true
+	return attachment, nil
+}
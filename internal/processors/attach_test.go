@@ -0,0 +1,105 @@
+package processors
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestSelectRepositoryIDs(t *testing.T) {
+	repos := []types.Repository{
+		{ID: 1, Name: "web", Visibility: "public", Language: "TypeScript"},
+		{ID: 2, Name: "mirror", Visibility: "public", Language: "Go"},
+		{ID: 3, Name: "api", Visibility: "private", Language: "Go"},
+		{ID: 4, Name: "frozen", Visibility: "internal"},
+		{ID: 5, Name: "docs", Visibility: "private", Language: "HTML"},
+	}
+	exclusions := make(types.RepositoryExclusions)
+	exclusions.Add("org", "mirror")
+	exclusions.Add("org", "Frozen")
+	exclusions.Add("other-org", "web")
+
+	codeQL := types.NewLanguageSet(types.CodeQLLanguages)
+
+	tests := []struct {
+		name            string
+		scope           string
+		languages       types.LanguageSet
+		wantIDs         []int
+		wantExcluded    int
+		wantUnsupported int
+	}{
+		{name: "all", scope: "all", wantIDs: []int{1, 3, 5}, wantExcluded: 2},
+		{name: "public", scope: "public", wantIDs: []int{1}, wantExcluded: 1},
+		{name: "private_or_internal", scope: "private_or_internal", wantIDs: []int{3, 5}, wantExcluded: 1},
+		{name: "codeql languages", scope: "all", languages: codeQL, wantIDs: []int{1, 3}, wantExcluded: 2, wantUnsupported: 1},
+		{name: "language override", scope: "all", languages: types.NewLanguageSet([]string{"html"}), wantIDs: []int{5}, wantExcluded: 2, wantUnsupported: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, excluded, unsupported := SelectRepositoryIDs("org", repos, tt.scope, exclusions, tt.languages)
+			if !reflect.DeepEqual(ids, tt.wantIDs) || excluded != tt.wantExcluded || unsupported != tt.wantUnsupported {
+				t.Errorf("SelectRepositoryIDs(%q) = %v, %d, %d; want %v, %d, %d", tt.scope, ids, excluded, unsupported, tt.wantIDs, tt.wantExcluded, tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestAttachRespectingExclusions(t *testing.T) {
+	origList, origAttachIDs, origAttach := listOrganizationRepositories, attachConfigurationToSelectedRepos, attachConfigurationToRepos
+	t.Cleanup(func() {
+		listOrganizationRepositories, attachConfigurationToSelectedRepos, attachConfigurationToRepos = origList, origAttachIDs, origAttach
+	})
+
+	var scopeAttaches int
+	var idBatches [][]int
+	listed := false
+	listOrganizationRepositories = func(_ context.Context, org string) ([]types.Repository, error) {
+		listed = true
+		return []types.Repository{{ID: 1, Name: "keep", Visibility: "public", Language: "Go"}, {ID: 2, Name: "skip", Visibility: "public"}}, nil
+	}
+	attachConfigurationToSelectedRepos = func(_ context.Context, org string, configID int, ids []int) (map[int]error, error) {
+		idBatches = append(idBatches, ids)
+		return nil, nil
+	}
+	attachConfigurationToRepos = func(_ context.Context, org string, configID int, scope string) (map[string]interface{}, error) {
+		scopeAttaches++
+		return nil, nil
+	}
+
+	exclusions := make(types.RepositoryExclusions)
+	exclusions.Add("excluded-org", "skip")
+
+	// Organizations without exclusions keep the single scope-based request
+	attachment, err := attachRespectingExclusions(context.Background(), "plain-org", 7, "all", exclusions, nil)
+	if err != nil || scopeAttaches != 1 || listed {
+		t.Fatalf("plain org: err=%v scopeAttaches=%d listed=%t", err, scopeAttaches, listed)
+	}
+	if want := (types.Attachment{Scope: "all", Accepted: true}); !reflect.DeepEqual(*attachment, want) {
+		t.Errorf("plain org: attachment = %+v, want %+v", *attachment, want)
+	}
+
+	attachment, err = attachRespectingExclusions(context.Background(), "excluded-org", 7, "all", exclusions, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scopeAttaches != 1 || !reflect.DeepEqual(idBatches, [][]int{{1}}) {
+		t.Errorf("excluded org: scopeAttaches=%d batches=%v", scopeAttaches, idBatches)
+	}
+	if want := (types.Attachment{Scope: "selected", Repositories: 1, Accepted: true, ExcludedRepos: 1}); !reflect.DeepEqual(*attachment, want) {
+		t.Errorf("excluded org: attachment = %+v, want %+v", *attachment, want)
+	}
+
+	// A language filter enumerates repositories even without exclusions
+	idBatches = nil
+	attachment, err = attachRespectingExclusions(context.Background(), "plain-org", 7, "all", exclusions, types.NewLanguageSet(types.CodeQLLanguages))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counts := attachment.CodeQLRepos
+	if attachment.ExcludedRepos != 0 || counts == nil || *counts != (types.RepositoryCounts{Included: 1, Excluded: 1}) || scopeAttaches != 1 || !reflect.DeepEqual(idBatches, [][]int{{1}}) {
+		t.Errorf("language filter: attachment=%+v scopeAttaches=%d batches=%v", attachment, scopeAttaches, idBatches)
+	}
+}
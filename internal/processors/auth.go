@@ -0,0 +1,36 @@
+package processors
+
+import (
+	"errors"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// DefaultAuthFailureThreshold is the number of consecutive organizations that must fail with an
+// authentication error before processing is aborted. A run of 401s almost always means the token
+// expired or was revoked mid-run rather than that each organization is individually broken.
+const DefaultAuthFailureThreshold = 3
+
+// authFailureTracker counts consecutive organizations whose processing failed with a
+// types.AuthError. Any other outcome resets the count.
+type authFailureTracker struct {
+	threshold   int
+	consecutive int
+}
+
+// record updates the tracker with a processing result and reports whether the number of
+// consecutive authentication failures has reached the threshold.
+func (t *authFailureTracker) record(result types.ProcessingResult) bool {
+	var authErr *types.AuthError
+	if result.Error == nil || !errors.As(result.Error, &authErr) {
+		t.consecutive = 0
+		return false
+	}
+	t.consecutive++
+	return t.threshold > 0 && t.consecutive >= t.threshold
+}
+
+// stopError builds the error reported when the threshold is reached
+func (t *authFailureTracker) stopError() error {
+	return &types.AuthError{Message: "token may be expired or lacks access; aborted after consecutive authentication failures"}
+}
@@ -0,0 +1,129 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// fetchSecurityConfigurations lists an organization's configurations. It is a variable so tests
+// can supply canned configurations.
+var fetchSecurityConfigurations = api.FetchSecurityConfigurations
+
+// fetchConfigurationDetails looks up a configuration's settings. It is a variable so tests can
+// supply canned settings.
+var fetchConfigurationDetails = api.GetSecurityConfigurationDetails
+
+// EvaluatePolicy returns the violations of policy by one organization's configuration. Rules are
+// evaluated in policy order; a setting missing from the configuration is treated as "not_set".
+func EvaluatePolicy(policy *types.Policy, org string, config *types.SecurityConfigurationDetails) []types.PolicyViolation {
+	var violations []types.PolicyViolation
+	for _, rule := range policy.Rules {
+		if !rule.AppliesTo(org) {
+			continue
+		}
+		actual := "not_set"
+		if rule.Setting == types.EnforcementSetting.Key {
+			if config.Enforcement != "" {
+				actual = config.Enforcement
+			}
+		} else {
+			actual = types.SettingValue(config.Settings, rule.Setting)
+		}
+		if rule.Allows(actual) {
+			continue
+		}
+		violations = append(violations, types.PolicyViolation{
+			Organization:     org,
+			Configuration:    config.Name,
+			ConfigurationID:  config.ID,
+			ConfigurationURL: config.HTMLURL,
+			Setting:          rule.Setting,
+			Actual:           actual,
+			Allowed:          rule.Allowed,
+			Severity:         rule.Severity,
+			Description:      rule.Description,
+		})
+	}
+	return violations
+}
+
+// PolicyCheckProcessor implements OrganizationProcessor for the check command. It evaluates the
+// organization-level configurations of each organization, or only ConfigName when set, and records
+// the violations so they can be reported once processing completes.
+type PolicyCheckProcessor struct {
+	Policy     *types.Policy
+	ConfigName string
+
+	mu         sync.Mutex
+	violations []types.PolicyViolation
+}
+
+// ProcessOrganization checks a single organization's configurations against the policy. The
+// organization is skipped when it has no configuration to check, or when the token cannot read its
+// configurations; membership is not looked up, so read-only tokens can run the check.
+func (cp *PolicyCheckProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	configs, err := fetchSecurityConfigurations(ctx, org)
+	if err != nil {
+		if skipResult := api.NoAccessSkip(org, err); skipResult != nil {
+			return *skipResult
+		}
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch security configurations: %w", err)}
+	}
+
+	var violations []types.PolicyViolation
+	checked := 0
+	for _, config := range configs {
+		// Enterprise configurations are shared by every organization and are not the org's to fix
+		if config.TargetType != "" && config.TargetType != "organization" {
+			continue
+		}
+		if cp.ConfigName != "" && config.Name != cp.ConfigName {
+			continue
+		}
+		details, err := fetchConfigurationDetails(ctx, org, config.ID)
+		if err != nil {
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch configuration '%s': %w", config.Name, err)}
+		}
+		if details.ID == 0 {
+			details.ID = config.ID
+		}
+		violations = append(violations, EvaluatePolicy(cp.Policy, org, details)...)
+		checked++
+	}
+
+	if checked == 0 {
+		reason := fmt.Sprintf("Organization '%s' has no organization-level configurations, skipping", org)
+		if cp.ConfigName != "" {
+			reason = fmt.Sprintf("Configuration '%s' not found in organization '%s', skipping", cp.ConfigName, org)
+		}
+		return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: reason}
+	}
+
+	cp.mu.Lock()
+	cp.violations = append(cp.violations, violations...)
+	cp.mu.Unlock()
+
+	return types.ProcessingResult{Organization: org, Success: true}
+}
+
+// Violations returns the recorded violations sorted by organization, configuration, and setting
+func (cp *PolicyCheckProcessor) Violations() []types.PolicyViolation {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	out := append([]types.PolicyViolation(nil), cp.violations...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Organization != out[j].Organization {
+			return out[i].Organization < out[j].Organization
+		}
+		if out[i].Configuration != out[j].Configuration {
+			return out[i].Configuration < out[j].Configuration
+		}
+		return out[i].Setting < out[j].Setting
+	})
+	return out
+}
@@ -0,0 +1,144 @@
+package processors
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	policy := &types.Policy{Rules: []types.PolicyRule{
+		{Setting: "secret_scanning", Allowed: []string{"enabled", "not_set"}, Severity: types.SeverityError},
+		{Setting: "enforcement", Allowed: []string{"enforced"}, Severity: types.SeverityWarning, Orgs: []string{"Prod"}},
+		{Setting: "dependabot_alerts", Allowed: []string{"enabled"}, Severity: types.SeverityError},
+	}}
+
+	tests := []struct {
+		name        string
+		org         string
+		settings    map[string]interface{}
+		enforcement string
+		want        []string // violated settings, in rule order
+	}{
+		{
+			name:        "compliant configuration",
+			org:         "prod",
+			settings:    map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"},
+			enforcement: "enforced",
+		},
+		{
+			name:     "disabled setting violates",
+			org:      "dev",
+			settings: map[string]interface{}{"secret_scanning": "disabled", "dependabot_alerts": "enabled"},
+			want:     []string{"secret_scanning"},
+		},
+		{
+			name:        "org-conditioned rule applies only to listed orgs",
+			org:         "prod",
+			settings:    map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"},
+			enforcement: "unenforced",
+			want:        []string{"enforcement"},
+		},
+		{
+			name:        "org-conditioned rule ignored elsewhere",
+			org:         "dev",
+			settings:    map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"},
+			enforcement: "unenforced",
+		},
+		{
+			name:     "missing setting is treated as not_set",
+			org:      "dev",
+			settings: map[string]interface{}{},
+			want:     []string{"dependabot_alerts"},
+		},
+		{
+			name:     "missing enforcement is treated as not_set",
+			org:      "prod",
+			settings: map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"},
+			want:     []string{"enforcement"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.SecurityConfigurationDetails{Name: "Baseline", Settings: tt.settings, Enforcement: tt.enforcement}
+			var got []string
+			for _, violation := range EvaluatePolicy(policy, tt.org, config) {
+				if violation.Organization != tt.org || violation.Configuration != "Baseline" {
+					t.Errorf("violation not attributed to %s/Baseline: %+v", tt.org, violation)
+				}
+				got = append(got, violation.Setting)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EvaluatePolicy() violated settings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubCheckAPI replaces the configuration lookups used by the check command for the duration of a test
+func stubCheckAPI(t *testing.T, configs []types.SecurityConfiguration, settings map[int]map[string]interface{}) {
+	t.Helper()
+	origList, origDetails := fetchSecurityConfigurations, fetchConfigurationDetails
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return configs, nil
+	}
+	fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+		for _, config := range configs {
+			if config.ID == configID {
+				return &types.SecurityConfigurationDetails{ID: configID, Name: config.Name, Settings: settings[configID]}, nil
+			}
+		}
+		t.Fatalf("unexpected details lookup for configuration %d", configID)
+		return nil, nil
+	}
+	t.Cleanup(func() {
+		fetchSecurityConfigurations, fetchConfigurationDetails = origList, origDetails
+	})
+}
+
+func TestPolicyCheckProcessor_ProcessOrganization(t *testing.T) {
+	policy := &types.Policy{Rules: []types.PolicyRule{
+		{Setting: "secret_scanning", Allowed: []string{"enabled"}, Severity: types.SeverityError},
+	}}
+	configs := []types.SecurityConfiguration{
+		{ID: 1, Name: "Baseline", TargetType: "organization"},
+		{ID: 2, Name: "Legacy", TargetType: "organization"},
+		{ID: 3, Name: "Enterprise", TargetType: "enterprise"},
+	}
+	settings := map[int]map[string]interface{}{
+		1: {"secret_scanning": "enabled"},
+		2: {"secret_scanning": "disabled"},
+	}
+
+	tests := []struct {
+		name           string
+		configName     string
+		wantSkip       bool
+		wantViolations []string // violating configurations
+	}{
+		{name: "checks every organization-level configuration", wantViolations: []string{"Legacy"}},
+		{name: "checks only the named configuration", configName: "Baseline"},
+		{name: "skips when the named configuration is missing", configName: "Missing", wantSkip: true},
+		{name: "enterprise configurations are not checked", configName: "Enterprise", wantSkip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubCheckAPI(t, configs, settings)
+			processor := &PolicyCheckProcessor{Policy: policy, ConfigName: tt.configName}
+
+			result := processor.ProcessOrganization(context.Background(), "org")
+			if result.Skipped != tt.wantSkip || result.Success == tt.wantSkip {
+				t.Fatalf("ProcessOrganization() = %+v, want skip %t", result, tt.wantSkip)
+			}
+			var got []string
+			for _, violation := range processor.Violations() {
+				got = append(got, violation.Configuration)
+			}
+			if !reflect.DeepEqual(got, tt.wantViolations) {
+				t.Errorf("violating configurations = %v, want %v", got, tt.wantViolations)
+			}
+		})
+	}
+}
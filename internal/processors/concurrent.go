@@ -4,11 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
 // ConcurrentProcessor handles concurrent organization processing
@@ -16,13 +18,16 @@ type ConcurrentProcessor struct {
 	organizations []string
 	processor     OrganizationProcessor
 	concurrency   int
-	progressBar   *pterm.ProgressbarPrinter
+	progress      *ui.Progress
 	mu            sync.Mutex
-	successCount  int
-	skippedCount  int
-	errorCount    int
+	summary       types.ProcessingSummary
 	stopSignal    chan struct{}
 	stopped       bool
+	stopErr       error
+	authFailures  authFailureTracker
+	failures      failureLog
+	rateLimiter   *utils.RateLimiter
+	orgTimeout    time.Duration
 }
 
 // NewConcurrentProcessor creates a new concurrent processor
@@ -32,29 +37,47 @@ func NewConcurrentProcessor(organizations []string, processor OrganizationProces
 		processor:     processor,
 		concurrency:   concurrency,
 		stopSignal:    make(chan struct{}),
+		authFailures:  authFailureTracker{threshold: DefaultAuthFailureThreshold},
 	}
 }
 
+// WithRateLimiter caps how fast organizations are handed to workers, independently of the
+// concurrency. A nil limiter dispatches organizations as soon as a worker is free.
+func (cp *ConcurrentProcessor) WithRateLimiter(limiter *utils.RateLimiter) *ConcurrentProcessor {
+	cp.rateLimiter = limiter
+	return cp
+}
+
+// WithOrgTimeout gives up on an organization whose processing takes longer than timeout, recording
+// a types.OrgTimeoutError for it and freeing its worker. A zero timeout waits indefinitely.
+func (cp *ConcurrentProcessor) WithOrgTimeout(timeout time.Duration) *ConcurrentProcessor {
+	cp.orgTimeout = timeout
+	return cp
+}
+
 // Process executes the organization processing with the specified concurrency
-func (cp *ConcurrentProcessor) Process() (successCount, skippedCount, errorCount int) {
+func (cp *ConcurrentProcessor) Process() types.ProcessingSummary {
 	totalOrgs := len(cp.organizations)
 	if totalOrgs == 0 {
-		return 0, 0, 0
+		return types.ProcessingSummary{}
 	}
 
 	// Create progress bar
-	progressBar, _ := pterm.DefaultProgressbar.WithTotal(totalOrgs).WithTitle("Processing organizations").Start()
-	cp.progressBar = progressBar
+	cp.progress = ui.StartProgress(totalOrgs, cp.progressTitle("Processing organizations"))
 
 	// Create channels for work distribution and result collection
 	orgChan := make(chan string, totalOrgs)
 	resultChan := make(chan types.ProcessingResult, totalOrgs)
 
-	// Send all organizations to the work channel
-	for _, org := range cp.organizations {
-		orgChan <- org
+	// Send all organizations to the work channel, paced by the rate limiter when one is set
+	if cp.rateLimiter == nil {
+		for _, org := range cp.organizations {
+			orgChan <- org
+		}
+		close(orgChan)
+	} else {
+		go cp.dispatch(orgChan)
 	}
-	close(orgChan)
 
 	// Start workers
 	var wg sync.WaitGroup
@@ -70,69 +93,108 @@ func (cp *ConcurrentProcessor) Process() (successCount, skippedCount, errorCount
 	}()
 
 	// Collect results and handle special error cases
-	resultsProcessed := 0
 	for result := range resultChan {
 		cp.mu.Lock()
-		resultsProcessed++
-		cp.progressBar.UpdateTitle(fmt.Sprintf("Processed %s", result.Organization))
-		cp.progressBar.Increment()
+		cp.progress.UpdateTitle(cp.progressTitle(fmt.Sprintf("Processed %s", result.Organization)))
+		cp.progress.Increment()
+		result = normalizeResult(result)
+		authLimitReached := cp.authFailures.record(result)
+		ui.LogConfigurationResults(result)
+		cp.summary.Add(result)
 
 		if result.Success {
-			cp.successCount++
 			ui.LogOrgSuccess(result.Organization)
 		} else if result.Skipped {
-			cp.skippedCount++
 			if result.SkipReason != "" {
 				ui.LogWarningf("%s", result.SkipReason)
 			}
 		} else if result.Error != nil {
-			cp.errorCount++
-			// Check if this is a "configuration exists" error
-			var configExistsErr *types.ConfigurationExistsError
-			if errors.As(result.Error, &configExistsErr) {
-				ui.LogWarningf("Configuration '%s' already exists in organization '%s', skipping", configExistsErr.ConfigName, result.Organization)
-				cp.skippedCount++
-				cp.errorCount-- // Don't count this as an error
+			// Check if this is a Dependabot unavailable error (422)
+			var dependabotErr *types.DependabotUnavailableError
+			if errors.As(result.Error, &dependabotErr) {
+				pterm.Error.Printf("Dependabot feature unavailable: %v\n", result.Error)
+				pterm.Error.Println("Stopping processing of remaining organizations due to Dependabot unavailability.")
+				pterm.Error.Println("Please remove Dependabot settings from your configuration or enable Dependabot on your GHES instance.")
+				cp.stop(&types.StopConditionError{Reason: dependabotErr.Error()}, resultChan)
+				break // Exit the result processing loop
+			} else if stopErr := stopConditionOf(result.Error); stopErr != nil {
+				pterm.Error.Printf("Stopping processing of remaining organizations: %s\n", stopErr.Reason)
+				cp.stop(stopErr, resultChan)
+				break // Exit the result processing loop
+			} else if authLimitReached {
+				pterm.Error.Printf("Authentication failed for %d consecutive organizations — the token may be expired or lacks access.\n", cp.authFailures.consecutive)
+				pterm.Error.Println("Stopping processing of remaining organizations. Run 'gh auth status' to check your token.")
+				cp.stop(cp.authFailures.stopError(), resultChan)
+				break // Exit the result processing loop
 			} else {
-				// Check if this is a Dependabot unavailable error (422)
-				var dependabotErr *types.DependabotUnavailableError
-				if errors.As(result.Error, &dependabotErr) {
-					pterm.Error.Printf("Dependabot feature unavailable: %v\n", result.Error)
-					pterm.Error.Println("Stopping processing of remaining organizations due to Dependabot unavailability.")
-					pterm.Error.Println("Please remove Dependabot settings from your configuration or enable Dependabot on your GHES instance.")
-
-					// Signal all workers to stop
-					if !cp.stopped {
-						cp.stopped = true
-						close(cp.stopSignal)
-					}
-
-					// Update progress bar to reflect remaining organizations as skipped
-					remainingOrgs := totalOrgs - resultsProcessed
-					cp.skippedCount += remainingOrgs
-					cp.progressBar.Add(remainingOrgs)
-
-					cp.mu.Unlock()
-
-					// Drain any remaining results to avoid goroutine leaks
-					go func() {
-						for range resultChan {
-							// Just drain the channel
-						}
-					}()
-
-					break // Exit the result processing loop
-				} else {
-					pterm.Error.Printf("Failed to process organization '%s': %v\n", result.Organization, result.Error)
-				}
+				cp.failures.report(result)
 			}
 		}
 
 		cp.mu.Unlock()
 	}
 
-	progressBar.Stop()
-	return cp.successCount, cp.skippedCount, cp.errorCount
+	cp.progress.Stop()
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.summary
+}
+
+// stop signals all workers to exit, records the remaining organizations as skipped, and drains
+// the result channel. It must be called with cp.mu held and releases it.
+func (cp *ConcurrentProcessor) stop(err error, resultChan <-chan types.ProcessingResult) {
+	if !cp.stopped {
+		cp.stopped = true
+		cp.stopErr = err
+		close(cp.stopSignal)
+	}
+
+	// Update progress bar to reflect remaining organizations as skipped
+	remainingOrgs := len(cp.organizations) - len(cp.summary.Results)
+	skipUnprocessed(&cp.summary, cp.organizations)
+	cp.progress.Add(remainingOrgs)
+
+	cp.mu.Unlock()
+
+	// Drain any remaining results to avoid goroutine leaks
+	go func() {
+		for range resultChan {
+			// Just drain the channel
+		}
+	}()
+}
+
+// StopError returns the error that aborted processing before all organizations were handled,
+// or nil if processing ran to completion.
+func (cp *ConcurrentProcessor) StopError() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.stopErr
+}
+
+// progressTitle appends the rate limit, when one is set, to a progress bar title
+func (cp *ConcurrentProcessor) progressTitle(title string) string {
+	if cp.rateLimiter == nil {
+		return title
+	}
+	return fmt.Sprintf("%s (%s)", title, cp.rateLimiter)
+}
+
+// dispatch sends organizations to the work channel no faster than the rate limiter allows and
+// closes it once every organization was sent or processing stopped
+func (cp *ConcurrentProcessor) dispatch(orgChan chan<- string) {
+	defer close(orgChan)
+	for _, org := range cp.organizations {
+		if wait := cp.rateLimiter.Reserve(); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-cp.stopSignal:
+				return
+			}
+		}
+		orgChan <- org
+	}
 }
 
 // worker processes organizations from the channel
@@ -145,7 +207,7 @@ func (cp *ConcurrentProcessor) worker(wg *sync.WaitGroup, orgChan <-chan string,
 			if !ok {
 				return // Channel closed, exit worker
 			}
-			result := cp.processor.ProcessOrganization(org)
+			result := processWithTimeout(cp.processor, org, cp.orgTimeout)
 			resultChan <- result
 		case <-cp.stopSignal:
 			return // Stop signal received, exit worker
@@ -1,13 +1,16 @@
 package processors
 
 import (
+	"context"
 	"errors"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
 // concurrencyTracker records how many concurrent calls happen at once.
@@ -21,7 +24,7 @@ type concurrencyTracker struct {
 	calledSet map[string]bool
 }
 
-func (c *concurrencyTracker) ProcessOrganization(org string) types.ProcessingResult {
+func (c *concurrencyTracker) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	n := atomic.AddInt32(&c.current, 1)
 	defer atomic.AddInt32(&c.current, -1)
 
@@ -52,7 +55,8 @@ func (c *concurrencyTracker) ProcessOrganization(org string) types.ProcessingRes
 
 func TestConcurrentProcessor_EmptyOrgs(t *testing.T) {
 	p := NewConcurrentProcessor(nil, &fakeProcessor{}, 3)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 0 || sk != 0 || e != 0 {
 		t.Errorf("expected all zero counts, got %d/%d/%d", s, sk, e)
 	}
@@ -66,7 +70,8 @@ func TestConcurrentProcessor_CountsSuccessSkipAndError(t *testing.T) {
 		"d": {Error: errors.New("boom")},
 	}}
 	p := NewConcurrentProcessor([]string{"a", "b", "c", "d"}, fp, 2)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 2 || sk != 1 || e != 1 {
 		t.Errorf("counts: success=%d skipped=%d errors=%d; want 2/1/1", s, sk, e)
 	}
@@ -89,13 +94,91 @@ func TestConcurrentProcessor_RespectsConcurrencyLimit(t *testing.T) {
 	}
 }
 
+func TestConcurrentProcessor_RateLimiterPacesDispatch(t *testing.T) {
+	ct := &concurrencyTracker{results: map[string]types.ProcessingResult{}}
+	orgs := []string{"a", "b", "c", "d"}
+	// 1200 per minute is one dispatch every 50ms, so four organizations take at least 150ms
+	p := NewConcurrentProcessor(orgs, ct, 4).WithRateLimiter(utils.NewRateLimiter(1200))
+
+	start := time.Now()
+	summary := p.Process()
+	elapsed := time.Since(start)
+
+	if summary.Success != len(orgs) {
+		t.Errorf("success = %d, want %d", summary.Success, len(orgs))
+	}
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("processing took %s, want at least 150ms with the rate limit", elapsed)
+	}
+	if ct.maxSeen > 1 {
+		t.Errorf("max concurrency observed = %d, want 1 when dispatch is slower than processing", ct.maxSeen)
+	}
+}
+
+// slowProcessor takes the given time for each organization, or no time for unlisted ones, and
+// stops early when ctx is done, as a processor whose requests were cancelled does. It counts the
+// organizations that were stopped.
+type slowProcessor struct {
+	delays  map[string]time.Duration
+	stopped atomic.Int32
+}
+
+func (p *slowProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	select {
+	case <-time.After(p.delays[org]):
+		return types.ProcessingResult{Organization: org, Success: true}
+	case <-ctx.Done():
+		p.stopped.Add(1)
+		return types.ProcessingResult{Organization: org, Error: ctx.Err()}
+	}
+}
+
+func TestProcessors_OrgTimeoutContinuesWithTheRest(t *testing.T) {
+	orgs := []string{"slow", "fast-1", "fast-2"}
+
+	tests := []struct {
+		name    string
+		process func(slow *slowProcessor) types.ProcessingSummary
+	}{
+		{name: "concurrent", process: func(slow *slowProcessor) types.ProcessingSummary {
+			return NewConcurrentProcessor(orgs, slow, 1).WithOrgTimeout(50 * time.Millisecond).Process()
+		}},
+		{name: "sequential", process: func(slow *slowProcessor) types.ProcessingSummary {
+			return NewSequentialProcessor(orgs, slow, 0).WithOrgTimeout(50 * time.Millisecond).Process()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slow := &slowProcessor{delays: map[string]time.Duration{"slow": time.Second}}
+			start := time.Now()
+			summary := tt.process(slow)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("processing took %s, want the slow organization abandoned after its timeout", elapsed)
+			}
+			if got := slow.stopped.Load(); got != 1 {
+				t.Errorf("%d organization(s) were stopped, want the slow one stopped by its timeout", got)
+			}
+			if summary.Success != 2 || summary.Error != 1 {
+				t.Fatalf("success/error = %d/%d, want 2/1", summary.Success, summary.Error)
+			}
+			for _, result := range summary.Results {
+				var timeoutErr *types.OrgTimeoutError
+				if result.Organization == "slow" && !errors.As(result.Error, &timeoutErr) {
+					t.Errorf("slow organization error = %v, want an OrgTimeoutError", result.Error)
+				}
+			}
+		})
+	}
+}
+
 func TestConcurrentProcessor_ConfigurationExistsTreatedAsSkip(t *testing.T) {
 	fp := &fakeProcessor{results: map[string]types.ProcessingResult{
 		"a": {Error: &types.ConfigurationExistsError{ConfigName: "cfg", OrgName: "a"}},
 		"b": {Success: true},
 	}}
 	p := NewConcurrentProcessor([]string{"a", "b"}, fp, 2)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 1 || sk != 1 || e != 0 {
 		t.Errorf("ConfigurationExistsError should be skip; got %d/%d/%d", s, sk, e)
 	}
@@ -109,7 +192,8 @@ func TestConcurrentProcessor_DependabotUnavailableStopsProcessing(t *testing.T)
 		"a": {Error: &types.DependabotUnavailableError{Feature: "alerts", OrgName: "a"}},
 	}}
 	p := NewConcurrentProcessor(orgs, fp, 1)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 
 	total := s + sk + e
 	if total != len(orgs) {
@@ -122,3 +206,109 @@ func TestConcurrentProcessor_DependabotUnavailableStopsProcessing(t *testing.T)
 		t.Errorf("expected remaining orgs to be marked skipped, got %d", sk)
 	}
 }
+
+func TestConcurrentProcessor_AuthFailureStormStopsProcessing(t *testing.T) {
+	orgs := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	results := map[string]types.ProcessingResult{}
+	for _, org := range orgs {
+		results[org] = types.ProcessingResult{Error: &types.AuthError{Message: "Bad credentials (HTTP 401)"}}
+	}
+	fp := &fakeProcessor{results: results}
+	p := NewConcurrentProcessor(orgs, fp, 1)
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
+
+	if total := s + sk + e; total != len(orgs) {
+		t.Errorf("counts should sum to %d orgs, got %d (s=%d, sk=%d, e=%d)", len(orgs), total, s, sk, e)
+	}
+	if e < DefaultAuthFailureThreshold {
+		t.Errorf("errors: got %d, want at least %d", e, DefaultAuthFailureThreshold)
+	}
+	if sk < 1 {
+		t.Errorf("expected remaining orgs to be marked skipped, got %d", sk)
+	}
+	var authErr *types.AuthError
+	if !errors.As(p.StopError(), &authErr) {
+		t.Errorf("StopError() = %v, want an AuthError", p.StopError())
+	}
+}
+
+func TestConcurrentProcessor_SummaryMatchesResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		orgs    []string
+		results map[string]types.ProcessingResult
+	}{
+		{
+			name: "mixed outcomes",
+			orgs: []string{"a", "b", "c", "d", "e"},
+			results: map[string]types.ProcessingResult{
+				"b": {Skipped: true, SkipReason: "not an owner"},
+				"c": {Error: errors.New("boom")},
+				"d": {Error: &types.ConfigurationExistsError{ConfigName: "cfg", OrgName: "d"}},
+			},
+		},
+		{
+			name: "stopped early",
+			orgs: []string{"a", "b", "c", "d", "e"},
+			results: map[string]types.ProcessingResult{
+				"a": {Error: &types.DependabotUnavailableError{Feature: "alerts", OrgName: "a"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewConcurrentProcessor(tt.orgs, &fakeProcessor{results: tt.results}, 3)
+			assertSummaryMatchesResults(t, p.Process(), tt.orgs)
+		})
+	}
+}
+
+// outOfOrderProcessor holds each organization until the one named in waitFor has finished, so
+// organizations complete in a chosen order regardless of dispatch order
+type outOfOrderProcessor struct {
+	waitFor map[string]string
+	done    map[string]chan struct{}
+}
+
+func (p *outOfOrderProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	if prerequisite, ok := p.waitFor[org]; ok {
+		<-p.done[prerequisite]
+	}
+	close(p.done[org])
+	return types.ProcessingResult{Organization: org, Success: true}
+}
+
+func TestConcurrentProcessor_ResultOrder(t *testing.T) {
+	orgs := []string{"a", "b", "c"}
+	newProcessor := func() *outOfOrderProcessor {
+		p := &outOfOrderProcessor{
+			waitFor: map[string]string{"a": "b", "b": "c"},
+			done:    map[string]chan struct{}{},
+		}
+		for _, org := range orgs {
+			p.done[org] = make(chan struct{})
+		}
+		return p
+	}
+	organizations := func(summary types.ProcessingSummary) []string {
+		var names []string
+		for _, result := range summary.Results {
+			names = append(names, result.Organization)
+		}
+		return names
+	}
+
+	summary := NewConcurrentProcessor(orgs, newProcessor(), 3).Process()
+	if got, want := organizations(summary), []string{"c", "b", "a"}; !slices.Equal(got, want) {
+		t.Fatalf("completion order = %v, want %v", got, want)
+	}
+
+	SortResultsByInput(&summary, orgs)
+	if got := organizations(summary); !slices.Equal(got, orgs) {
+		t.Errorf("sorted order = %v, want %v", got, orgs)
+	}
+	if summary.Success != 3 {
+		t.Errorf("Success = %d after sorting, want 3", summary.Success)
+	}
+}
@@ -1,7 +1,11 @@
 package processors
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/types"
@@ -11,16 +15,24 @@ import (
 // DeleteProcessor implements OrganizationProcessor for the delete command
 type DeleteProcessor struct {
 	ConfigName string
+
+	// AllConfigs deletes every configuration in Planned for the organization instead of ConfigName
+	AllConfigs bool
+	Planned    map[string][]types.DeletionCandidate // Configurations confirmed for deletion, keyed by organization
 }
 
 // ProcessOrganization processes a single organization for the delete command
-func (dp *DeleteProcessor) ProcessOrganization(org string) types.ProcessingResult {
+func (dp *DeleteProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	// Check membership using the shared validation function
-	if skipResult := api.ValidateMembershipAndSkip(org); skipResult != nil {
+	if skipResult := api.ValidateMembershipAndSkip(ctx, org); skipResult != nil {
 		return *skipResult
 	}
 
-	deleted, err := dp.deleteConfigurationFromOrg(org)
+	if dp.AllConfigs {
+		return dp.deleteAllConfigurations(ctx, org)
+	}
+
+	deleted, err := dp.deleteConfigurationFromOrg(ctx, org)
 	if err != nil {
 		return types.ProcessingResult{Organization: org, Error: err}
 	}
@@ -33,9 +45,9 @@ func (dp *DeleteProcessor) ProcessOrganization(org string) types.ProcessingResul
 }
 
 // deleteConfigurationFromOrg deletes a configuration from an organization
-func (dp *DeleteProcessor) deleteConfigurationFromOrg(org string) (bool, error) {
+func (dp *DeleteProcessor) deleteConfigurationFromOrg(ctx context.Context, org string) (bool, error) {
 	// First, fetch security configurations for the organization
-	configs, err := api.FetchSecurityConfigurations(org)
+	configs, err := api.FetchSecurityConfigurations(ctx, org)
 	if err != nil {
 		return false, fmt.Errorf("failed to fetch security configurations: %w", err)
 	}
@@ -48,10 +60,139 @@ func (dp *DeleteProcessor) deleteConfigurationFromOrg(org string) (bool, error)
 	}
 
 	// Delete the configuration
-	err = api.DeleteSecurityConfiguration(org, configID)
+	err = api.DeleteSecurityConfiguration(ctx, org, configID)
 	if err != nil {
 		return false, fmt.Errorf("failed to delete security configuration: %w", err)
 	}
 
 	return true, nil
 }
+
+// deleteAllConfigurations deletes the organization's confirmed configurations that still exist,
+// recording an outcome for each one. A failed deletion does not stop the others, but a stop
+// condition such as an exhausted API call budget does.
+func (dp *DeleteProcessor) deleteAllConfigurations(ctx context.Context, org string) types.ProcessingResult {
+	configs, err := fetchSecurityConfigurations(ctx, org)
+	if err != nil {
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch security configurations: %w", err)}
+	}
+
+	// Only configurations that were confirmed, and still exist under the same ID, are deleted
+	existing := make(map[int]bool, len(configs))
+	for _, config := range configs {
+		existing[config.ID] = true
+	}
+	var targets []types.DeletionCandidate
+	for _, config := range dp.Planned[org] {
+		if existing[config.ID] {
+			targets = append(targets, config)
+		}
+	}
+	if len(targets) == 0 {
+		return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Organization '%s' has no configurations to delete, skipping", org)}
+	}
+
+	result := types.ProcessingResult{Organization: org}
+	failed := 0
+	for _, config := range targets {
+		if err := deleteSecurityConfiguration(ctx, org, config.ID); err != nil {
+			result.Configurations = append(result.Configurations, types.ConfigurationResult{Name: config.Name, Error: err})
+			failed++
+			if stopConditionOf(err) != nil {
+				result.Error = err
+				return result
+			}
+			continue
+		}
+		result.Configurations = append(result.Configurations, types.ConfigurationResult{Name: config.Name, Success: true})
+	}
+
+	if failed > 0 {
+		result.Error = fmt.Errorf("failed to delete %d of %d configurations", failed, len(targets))
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// ManagedConfigurations returns the configurations delete --all-configs may remove: the
+// organization's own configurations whose name starts with managedPrefix, sorted by name.
+// GitHub-managed (global) and enterprise configurations are never included.
+func ManagedConfigurations(configs []types.SecurityConfiguration, managedPrefix string) []types.SecurityConfiguration {
+	var managed []types.SecurityConfiguration
+	for _, config := range configs {
+		if config.TargetType != "organization" || !strings.HasPrefix(config.Name, managedPrefix) {
+			continue
+		}
+		managed = append(managed, config)
+	}
+	sort.Slice(managed, func(i, j int) bool { return managed[i].Name < managed[j].Name })
+	return managed
+}
+
+// countAttachedRepositories counts the repositories a configuration is attached to, stopping at a
+// limit above 0. It is a variable so tests can supply canned counts.
+var countAttachedRepositories = api.CountAttachedRepositories
+
+// DeletionFilter narrows the configurations delete --all-configs removes
+type DeletionFilter struct {
+	ManagedPrefix  string
+	OlderThan      time.Duration // Only configurations created at least this long ago; 0 for any age
+	UnattachedOnly bool          // Only configurations attached to no repositories
+	Now            time.Time     // Time ages are measured from
+}
+
+// filtered reports whether the filter goes beyond the managed prefix, so candidates carry their
+// age and attachment count
+func (f DeletionFilter) filtered() bool {
+	return f.OlderThan > 0 || f.UnattachedOnly
+}
+
+// PlanAllConfigsDeletion lists the configurations delete --all-configs would remove from each
+// organization, so they can be confirmed before anything is deleted. With an age or attachment
+// filter, each managed configuration's attached repositories are counted and only those passing
+// the filter are planned. Organizations that cannot be listed are reported as a warning and left
+// out of the plan; processing reports them again.
+func PlanAllConfigsDeletion(orgs []string, filter DeletionFilter) map[string][]types.DeletionCandidate {
+	plan := make(map[string][]types.DeletionCandidate, len(orgs))
+	for _, org := range orgs {
+		configs, err := fetchSecurityConfigurations(context.Background(), org)
+		if err != nil {
+			ui.LogWarningf("Could not list configurations in organization '%s': %v", org, err)
+			continue
+		}
+		var candidates []types.DeletionCandidate
+		for _, config := range ManagedConfigurations(configs, filter.ManagedPrefix) {
+			candidate := types.DeletionCandidate{SecurityConfiguration: config, Repositories: -1}
+			if filter.filtered() {
+				candidate.Age = config.Age(filter.Now)
+				if candidate.Age < filter.OlderThan {
+					continue
+				}
+				// Only whether any repository is attached matters for --unattached-only
+				limit := 0
+				if filter.UnattachedOnly {
+					limit = 1
+				}
+				count, err := countAttachedRepositories(context.Background(), org, config.ID, limit)
+				switch {
+				case err != nil && filter.UnattachedOnly:
+					// A configuration whose attachments are unknown is never deleted as unattached
+					ui.LogWarningf("Could not count repositories attached to '%s' in organization '%s', leaving it out: %v", config.Name, org, err)
+					continue
+				case err != nil:
+					ui.LogWarningf("Could not count repositories attached to '%s' in organization '%s': %v", config.Name, org, err)
+				case filter.UnattachedOnly && count > 0:
+					continue
+				default:
+					candidate.Repositories = count
+				}
+			}
+			candidates = append(candidates, candidate)
+		}
+		if len(candidates) > 0 {
+			plan[org] = candidates
+		}
+	}
+	return plan
+}
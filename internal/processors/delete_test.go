@@ -0,0 +1,167 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestManagedConfigurations(t *testing.T) {
+	configs := []types.SecurityConfiguration{
+		{ID: 1, Name: "GitHub recommended", TargetType: "global"},
+		{ID: 2, Name: "Enterprise Baseline", TargetType: "enterprise"},
+		{ID: 3, Name: "sec-strict", TargetType: "organization"},
+		{ID: 4, Name: "sec-baseline", TargetType: "organization"},
+		{ID: 5, Name: "Team Custom", TargetType: "organization"},
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{name: "every organization configuration", want: []string{"Team Custom", "sec-baseline", "sec-strict"}},
+		{name: "managed prefix", prefix: "sec-", want: []string{"sec-baseline", "sec-strict"}},
+		{name: "prefix matching nothing", prefix: "ops-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ManagedConfigurations(configs, tt.prefix)
+			var names []string
+			for _, config := range got {
+				names = append(names, config.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("ManagedConfigurations() = %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("ManagedConfigurations() = %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteAllConfigurations(t *testing.T) {
+	origList, origDelete := fetchSecurityConfigurations, deleteSecurityConfiguration
+	t.Cleanup(func() { fetchSecurityConfigurations, deleteSecurityConfiguration = origList, origDelete })
+
+	planned := []types.DeletionCandidate{
+		{SecurityConfiguration: types.SecurityConfiguration{ID: 3, Name: "sec-strict"}},
+		{SecurityConfiguration: types.SecurityConfiguration{ID: 4, Name: "sec-baseline"}},
+		{SecurityConfiguration: types.SecurityConfiguration{ID: 6, Name: "sec-gone"}},
+	}
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		// sec-gone was deleted after the plan was confirmed; sec-new was created after it
+		return []types.SecurityConfiguration{{ID: 3, Name: "sec-strict"}, {ID: 4, Name: "sec-baseline"}, {ID: 7, Name: "sec-new"}}, nil
+	}
+
+	tests := []struct {
+		name        string
+		failID      int
+		failErr     error
+		wantSuccess bool
+		wantResults int
+		wantStop    bool
+	}{
+		{name: "deletes only confirmed configurations", wantSuccess: true, wantResults: 2},
+		{name: "continues after a failed deletion", failID: 3, failErr: errors.New("boom"), wantResults: 2},
+		{name: "stops when the budget is exhausted", failID: 3, failErr: &types.StopConditionError{Reason: "budget"}, wantResults: 1, wantStop: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []int
+			deleteSecurityConfiguration = func(_ context.Context, org string, configID int) error {
+				if configID == tt.failID {
+					return tt.failErr
+				}
+				deleted = append(deleted, configID)
+				return nil
+			}
+
+			dp := &DeleteProcessor{AllConfigs: true, Planned: map[string][]types.DeletionCandidate{"org": planned}}
+			result := dp.deleteAllConfigurations(context.Background(), "org")
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Success = %t, want %t (error %v)", result.Success, tt.wantSuccess, result.Error)
+			}
+			if len(result.Configurations) != tt.wantResults {
+				t.Errorf("got %d configuration results, want %d", len(result.Configurations), tt.wantResults)
+			}
+			if (stopConditionOf(result.Error) != nil) != tt.wantStop {
+				t.Errorf("error = %v, want stop condition %t", result.Error, tt.wantStop)
+			}
+			for _, id := range deleted {
+				if id == 6 || id == 7 {
+					t.Errorf("deleted configuration %d, which was not both confirmed and present", id)
+				}
+			}
+		})
+	}
+
+	t.Run("skips organization without planned configurations", func(t *testing.T) {
+		dp := &DeleteProcessor{AllConfigs: true, Planned: map[string][]types.DeletionCandidate{}}
+		if result := dp.deleteAllConfigurations(context.Background(), "org"); !result.Skipped {
+			t.Errorf("result = %+v, want skipped", result)
+		}
+	})
+}
+
+func TestPlanAllConfigsDeletion(t *testing.T) {
+	origList, origCount := fetchSecurityConfigurations, countAttachedRepositories
+	t.Cleanup(func() { fetchSecurityConfigurations, countAttachedRepositories = origList, origCount })
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{
+			{ID: 1, Name: "sec-old-unused", TargetType: "organization", CreatedAt: daysAgo(200)},
+			{ID: 2, Name: "sec-old-used", TargetType: "organization", CreatedAt: daysAgo(150)},
+			{ID: 3, Name: "sec-new-unused", TargetType: "organization", CreatedAt: daysAgo(10)},
+			{ID: 4, Name: "sec-old-uncounted", TargetType: "organization", CreatedAt: daysAgo(120)},
+			{ID: 5, Name: "Team Custom", TargetType: "organization", CreatedAt: daysAgo(400)},
+		}, nil
+	}
+	countAttachedRepositories = func(_ context.Context, org string, configID int, limit int) (int, error) {
+		switch configID {
+		case 2:
+			return 4, nil
+		case 4:
+			return 0, errors.New("boom")
+		}
+		return 0, nil
+	}
+
+	tests := []struct {
+		name   string
+		filter DeletionFilter
+		want   map[string]int // planned configuration name to repository count
+	}{
+		{name: "prefix only", filter: DeletionFilter{ManagedPrefix: "sec-"}, want: map[string]int{"sec-old-unused": -1, "sec-old-used": -1, "sec-new-unused": -1, "sec-old-uncounted": -1}},
+		{name: "older than", filter: DeletionFilter{ManagedPrefix: "sec-", OlderThan: 90 * 24 * time.Hour}, want: map[string]int{"sec-old-unused": 0, "sec-old-used": 4, "sec-old-uncounted": -1}},
+		{name: "unattached only", filter: DeletionFilter{ManagedPrefix: "sec-", UnattachedOnly: true}, want: map[string]int{"sec-old-unused": 0, "sec-new-unused": 0}},
+		{name: "both filters", filter: DeletionFilter{ManagedPrefix: "sec-", OlderThan: 90 * 24 * time.Hour, UnattachedOnly: true}, want: map[string]int{"sec-old-unused": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Now = now
+			plan := PlanAllConfigsDeletion([]string{"org"}, tt.filter)
+			got := make(map[string]int)
+			for _, candidate := range plan["org"] {
+				got[candidate.Name] = candidate.Repositories
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("planned %v, want %v", got, tt.want)
+			}
+			for name, repositories := range tt.want {
+				if count, ok := got[name]; !ok || count != repositories {
+					t.Errorf("planned %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package processors
+
+import (
+	"cmp"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/ui"
+)
+
+// httpStatusPattern finds the status the gh CLI reports for a failed request, e.g. "(HTTP 403)"
+var httpStatusPattern = regexp.MustCompile(`HTTP (\d{3})`)
+
+// idPathSegment matches a numeric path segment, such as a configuration ID, which differs
+// between organizations
+var idPathSegment = regexp.MustCompile(`/\d+\b`)
+
+// errorHints is the remedy shown for each error category. Categories not listed get no hint.
+var errorHints = map[string]string{
+	"authentication":         "The token is invalid or expired. Run 'gh auth status', then 'gh auth refresh' or 'gh auth login'.",
+	"timeout":                "The organizations did not finish within --per-org-timeout. Check their state before retrying, or raise the timeout.",
+	"transient":              "GitHub reported a temporary failure. Retry these organizations with the retry command.",
+	"Dependabot unavailable": "Dependabot is not enabled on this instance. Remove the Dependabot settings, or pass --dependabot-alerts-available false and --dependabot-security-updates-available false.",
+	"HTTP 401":               "The token is invalid or expired. Run 'gh auth status', then 'gh auth refresh' or 'gh auth login'.",
+	"HTTP 403":               "The token lacks permission. Use an organization owner's token with the admin:org scope; a GitHub App needs the organization administration permission.",
+	"HTTP 404":               "The organization or configuration is not visible to the token. Check that you are an owner and that --github-enterprise-server-url points at the right host.",
+	"HTTP 409":               "The request conflicts with the organization's current state, often another change in progress. Retry these organizations.",
+	"HTTP 422":               "GitHub rejected the request. Check that every setting is available on this instance and licensed for these organizations.",
+	"HTTP 429":               "The rate limit was exceeded. Wait for it to reset, or slow the run with --max-orgs-per-minute.",
+	"HTTP 500":               "GitHub returned a server error. Retry these organizations with the retry command.",
+	"HTTP 502":               "GitHub returned a server error. Retry these organizations with the retry command.",
+	"HTTP 503":               "GitHub returned a server error. Retry these organizations with the retry command.",
+	"HTTP 504":               "GitHub returned a server error. Retry these organizations with the retry command.",
+}
+
+// GroupErrors groups the failed results by category and normalized message, so a failure shared
+// by many organizations is reported once. SAML single sign-on failures are left out because they
+// are reported on their own. Groups are ordered by size, largest first, and organizations keep the
+// order of results.
+func GroupErrors(results []types.ProcessingResult) []types.ErrorGroup {
+	var groups []types.ErrorGroup
+	index := make(map[string]int)
+	for _, result := range results {
+		category, message, grouped := errorCause(result)
+		if !grouped {
+			continue
+		}
+		key := category + "\x00" + message
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, types.ErrorGroup{Category: category, Message: message, Hint: errorHints[category]})
+		}
+		groups[i].Organizations = append(groups[i].Organizations, result.Organization)
+	}
+	slices.SortStableFunc(groups, func(a, b types.ErrorGroup) int {
+		return cmp.Compare(len(b.Organizations), len(a.Organizations))
+	})
+	return groups
+}
+
+// errorCause returns the category and normalized message a failed result is grouped under, and
+// false for results that are not grouped: successes, skips and SAML single sign-on failures
+func errorCause(result types.ProcessingResult) (string, string, bool) {
+	if result.Success || result.Skipped || result.Error == nil {
+		return "", "", false
+	}
+	var ssoErr *types.SSORequiredError
+	if errors.As(result.Error, &ssoErr) {
+		return "", "", false
+	}
+	return errorCategory(result.Error), normalizeErrorMessage(result.Error.Error(), result.Organization), true
+}
+
+// failureLog prints failed organizations as they finish. The first failure of each cause is
+// printed in full. Later failures with the same cause are only logged at info level, since
+// ShowErrorGroups reports the cause once, with all its organizations, after the results. The zero
+// value is ready to use.
+type failureLog struct {
+	seen map[string]bool
+}
+
+// report prints the failure of result
+func (l *failureLog) report(result types.ProcessingResult) {
+	if category, message, grouped := errorCause(result); grouped {
+		key := category + "\x00" + message
+		if l.seen[key] {
+			ui.LogInfof("Failed to process organization '%s': %v", result.Organization, result.Error)
+			return
+		}
+		if l.seen == nil {
+			l.seen = make(map[string]bool)
+		}
+		l.seen[key] = true
+	}
+	pterm.Error.Printf("Failed to process organization '%s': %v\n", result.Organization, result.Error)
+}
+
+// errorCategory classifies an error by its type, or by the HTTP status in its message
+func errorCategory(err error) string {
+	var authErr *types.AuthError
+	var timeoutErr *types.OrgTimeoutError
+	var dependabotErr *types.DependabotUnavailableError
+	var transientErr *types.TransientError
+	switch {
+	case errors.As(err, &authErr):
+		return "authentication"
+	case errors.As(err, &timeoutErr):
+		return "timeout"
+	case errors.As(err, &dependabotErr):
+		return "Dependabot unavailable"
+	}
+	if match := httpStatusPattern.FindStringSubmatch(err.Error()); match != nil {
+		return "HTTP " + match[1]
+	}
+	if errors.As(err, &transientErr) {
+		return "transient"
+	}
+	return "other"
+}
+
+// normalizeErrorMessage replaces the organization's name, where it appears quoted or in an API
+// path, and numeric path segments in message, so the same failure reads the same in every
+// organization
+func normalizeErrorMessage(message, org string) string {
+	if org != "" {
+		message = strings.NewReplacer("'"+org+"'", "'<org>'", "/orgs/"+org, "/orgs/<org>").Replace(message)
+	}
+	return strings.TrimSpace(idPathSegment.ReplaceAllString(message, "/<id>"))
+}
@@ -0,0 +1,115 @@
+package processors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestGroupErrors(t *testing.T) {
+	var results []types.ProcessingResult
+	for i := 0; i < 400; i++ {
+		org := fmt.Sprintf("org-%d", i)
+		err := fmt.Errorf("failed to attach configuration to repositories: POST /orgs/%s/code-security/configurations/%d/attach: gh: Resource not accessible by integration (HTTP 403)", org, 100+i)
+		results = append(results, types.ProcessingResult{Organization: org, Error: err})
+	}
+	results = append(results,
+		types.ProcessingResult{Organization: "slow-a", Error: &types.OrgTimeoutError{OrgName: "slow-a", Timeout: time.Minute}},
+		types.ProcessingResult{Organization: "done", Success: true},
+		types.ProcessingResult{Organization: "skipped", Skipped: true},
+		types.ProcessingResult{Organization: "sso", Error: &types.SSORequiredError{Err: errors.New("HTTP 403")}},
+		types.ProcessingResult{Organization: "slow-b", Error: &types.OrgTimeoutError{OrgName: "slow-b", Timeout: time.Minute}},
+		types.ProcessingResult{Organization: "token", Error: &types.AuthError{Message: "Bad credentials (HTTP 401)"}},
+	)
+
+	groups := GroupErrors(results)
+	if len(groups) != 3 {
+		t.Fatalf("GroupErrors() returned %d groups, want 3: %+v", len(groups), groups)
+	}
+
+	forbidden := groups[0]
+	if forbidden.Category != "HTTP 403" || len(forbidden.Organizations) != 400 {
+		t.Errorf("largest group = %s with %d organizations, want HTTP 403 with 400", forbidden.Category, len(forbidden.Organizations))
+	}
+	wantMessage := "failed to attach configuration to repositories: POST /orgs/<org>/code-security/configurations/<id>/attach: gh: Resource not accessible by integration (HTTP 403)"
+	if forbidden.Message != wantMessage {
+		t.Errorf("message = %q, want %q", forbidden.Message, wantMessage)
+	}
+	if forbidden.Hint == "" {
+		t.Error("HTTP 403 group has no hint")
+	}
+
+	timeouts := groups[1]
+	if timeouts.Category != "timeout" || !slices.Equal(timeouts.Organizations, []string{"slow-a", "slow-b"}) {
+		t.Errorf("second group = %s %v, want timeout [slow-a slow-b]", timeouts.Category, timeouts.Organizations)
+	}
+
+	// Authentication errors are classified by type, before the HTTP status in the message
+	if groups[2].Category != "authentication" {
+		t.Errorf("third group category = %q, want authentication", groups[2].Category)
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "HTTP status", err: errors.New("gh: Not Found (HTTP 404)"), want: "HTTP 404"},
+		{name: "wrapped status", err: fmt.Errorf("failed to set configuration as default: %w", errors.New("gh: Validation Failed (HTTP 422)")), want: "HTTP 422"},
+		{name: "transient without status", err: &types.TransientError{Err: errors.New("connection reset by peer")}, want: "transient"},
+		{name: "dependabot", err: &types.DependabotUnavailableError{Feature: "alerts", OrgName: "acme"}, want: "Dependabot unavailable"},
+		{name: "unknown", err: errors.New("something else"), want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCategory(tt.err); got != tt.want {
+				t.Errorf("errorCategory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureLog_PrintsEachCauseOnce(t *testing.T) {
+	var buf bytes.Buffer
+	pterm.SetDefaultOutput(&buf)
+	pterm.EnableOutput()
+	t.Cleanup(func() {
+		pterm.DisableOutput()
+		pterm.SetDefaultOutput(os.Stdout)
+	})
+
+	forbidden := func(org string) types.ProcessingResult {
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("GET /orgs/%s/code-security/configurations: gh: Resource not accessible by integration (HTTP 403)", org)}
+	}
+	sso := func(org string) types.ProcessingResult {
+		return types.ProcessingResult{Organization: org, Error: &types.SSORequiredError{Err: errors.New("HTTP 403")}}
+	}
+
+	var log failureLog
+	for _, result := range []types.ProcessingResult{forbidden("org-a"), forbidden("org-b"), sso("sso-a"), sso("sso-b"), forbidden("org-c")} {
+		log.report(result)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"'org-a'", "'sso-a'", "'sso-b'"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not report %s:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"'org-b'", "'org-c'"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output reports %s, whose cause was already printed:\n%s", unwanted, out)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// CompareToBaseline returns the settings of an organization's configuration that differ from the
+// baseline settings, followed by its enforcement when the baseline sets one. Settings the baseline
+// does not mention are not compared; a setting missing from the configuration is "not_set".
+func CompareToBaseline(org string, config *types.SecurityConfigurationDetails, settings map[string]interface{}, enforcement string) []types.SettingDrift {
+	var drift []types.SettingDrift
+	for _, key := range SettingsDiff(config.Settings, settings) {
+		drift = append(drift, types.SettingDrift{
+			Organization:     org,
+			Configuration:    config.Name,
+			ConfigurationID:  config.ID,
+			ConfigurationURL: config.HTMLURL,
+			Setting:          key,
+			Baseline:         types.SettingValue(settings, key),
+			Actual:           types.SettingValue(config.Settings, key),
+		})
+	}
+	if enforcement != "" && enforcement != config.Enforcement {
+		actual := config.Enforcement
+		if actual == "" {
+			actual = "not_set"
+		}
+		drift = append(drift, types.SettingDrift{
+			Organization:     org,
+			Configuration:    config.Name,
+			ConfigurationID:  config.ID,
+			ConfigurationURL: config.HTMLURL,
+			Setting:          types.EnforcementSetting.Key,
+			Baseline:         enforcement,
+			Actual:           actual,
+		})
+	}
+	return drift
+}
+
+// DriftProcessor implements OrganizationProcessor for the drift command. It compares the
+// organization-level configuration named ConfigName in each organization with the baseline and
+// records every difference so it can be reported once processing completes.
+type DriftProcessor struct {
+	ConfigName  string
+	Settings    map[string]interface{}
+	Enforcement string
+
+	mu      sync.Mutex
+	drift   []types.SettingDrift
+	drifted map[string]bool
+}
+
+// ProcessOrganization compares a single organization's configuration with the baseline. The
+// organization is skipped when it has no configuration with the baseline's name, or when the token
+// cannot read its configurations; membership is not looked up, so read-only tokens can run it.
+func (dp *DriftProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	configs, err := fetchSecurityConfigurations(ctx, org)
+	if err != nil {
+		if skipResult := api.NoAccessSkip(org, err); skipResult != nil {
+			return *skipResult
+		}
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch security configurations: %w", err)}
+	}
+
+	for _, config := range configs {
+		// Enterprise configurations are shared by every organization, so they cannot drift per organization
+		if config.Name != dp.ConfigName || (config.TargetType != "" && config.TargetType != "organization") {
+			continue
+		}
+		details, err := fetchConfigurationDetails(ctx, org, config.ID)
+		if err != nil {
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch configuration '%s': %w", config.Name, err)}
+		}
+		if details.HTMLURL == "" {
+			details.HTMLURL = config.HTMLURL
+		}
+		if details.ID == 0 {
+			details.ID = config.ID
+		}
+		if drift := CompareToBaseline(org, details, dp.Settings, dp.Enforcement); len(drift) > 0 {
+			dp.mu.Lock()
+			dp.drift = append(dp.drift, drift...)
+			if dp.drifted == nil {
+				dp.drifted = make(map[string]bool)
+			}
+			dp.drifted[org] = true
+			dp.mu.Unlock()
+		}
+		return types.ProcessingResult{Organization: org, Success: true}
+	}
+
+	return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Configuration '%s' not found in organization '%s', skipping", dp.ConfigName, org)}
+}
+
+// Drift returns the recorded differences sorted by organization and setting
+func (dp *DriftProcessor) Drift() []types.SettingDrift {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	out := append([]types.SettingDrift(nil), dp.drift...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Organization != out[j].Organization {
+			return out[i].Organization < out[j].Organization
+		}
+		return out[i].Setting < out[j].Setting
+	})
+	return out
+}
+
+// DriftedOrganizations returns how many organizations have at least one difference
+func (dp *DriftProcessor) DriftedOrganizations() int {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	return len(dp.drifted)
+}
@@ -0,0 +1,97 @@
+package processors
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestCompareToBaseline(t *testing.T) {
+	baseline := map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"}
+
+	tests := []struct {
+		name        string
+		settings    map[string]interface{}
+		enforcement string
+		baselineEnf string
+		want        []string // "setting=baseline/actual"
+	}{
+		{
+			name:     "matching configuration has no drift",
+			settings: map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled", "code_scanning_default_setup": "disabled"},
+		},
+		{
+			name:     "differing and missing settings drift",
+			settings: map[string]interface{}{"secret_scanning": "disabled"},
+			want:     []string{"dependabot_alerts=enabled/not_set", "secret_scanning=enabled/disabled"},
+		},
+		{
+			name:        "enforcement is compared when the baseline sets it",
+			settings:    baseline,
+			enforcement: "unenforced",
+			baselineEnf: "enforced",
+			want:        []string{"enforcement=enforced/unenforced"},
+		},
+		{
+			name:        "enforcement is ignored when the baseline leaves it out",
+			settings:    baseline,
+			enforcement: "unenforced",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.SecurityConfigurationDetails{Name: "Baseline", Settings: tt.settings, Enforcement: tt.enforcement}
+			var got []string
+			for _, d := range CompareToBaseline("org", config, baseline, tt.baselineEnf) {
+				got = append(got, d.Setting+"="+d.Baseline+"/"+d.Actual)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareToBaseline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriftProcessor_ProcessOrganization(t *testing.T) {
+	configs := []types.SecurityConfiguration{
+		{ID: 1, Name: "Baseline", TargetType: "organization"},
+		{ID: 2, Name: "Legacy", TargetType: "organization"},
+		{ID: 3, Name: "Enterprise", TargetType: "enterprise"},
+	}
+	settings := map[int]map[string]interface{}{
+		1: {"secret_scanning": "enabled"},
+		2: {"secret_scanning": "disabled"},
+	}
+	baseline := map[string]interface{}{"secret_scanning": "enabled"}
+
+	tests := []struct {
+		name        string
+		configName  string
+		wantSkip    bool
+		wantDrifted int
+	}{
+		{name: "matching configuration", configName: "Baseline"},
+		{name: "drifted configuration", configName: "Legacy", wantDrifted: 1},
+		{name: "missing configuration is skipped", configName: "Missing", wantSkip: true},
+		{name: "enterprise configurations are not compared", configName: "Enterprise", wantSkip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubCheckAPI(t, configs, settings)
+			processor := &DriftProcessor{ConfigName: tt.configName, Settings: baseline}
+
+			result := processor.ProcessOrganization(context.Background(), "org")
+			if result.Skipped != tt.wantSkip || result.Success == tt.wantSkip {
+				t.Fatalf("ProcessOrganization() = %+v, want skip %t", result, tt.wantSkip)
+			}
+			if got := processor.DriftedOrganizations(); got != tt.wantDrifted {
+				t.Errorf("DriftedOrganizations() = %d, want %d", got, tt.wantDrifted)
+			}
+			if got := len(processor.Drift()); got != tt.wantDrifted {
+				t.Errorf("len(Drift()) = %d, want %d", got, tt.wantDrifted)
+			}
+		})
+	}
+}
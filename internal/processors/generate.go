@@ -1,6 +1,7 @@
 package processors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pterm/pterm"
@@ -9,78 +10,200 @@ import (
 	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
+// API calls made while creating configurations. They are variables so tests can observe which
+// requests a run would make.
+var (
+	createSecurityConfiguration = api.CreateSecurityConfiguration
+	deleteSecurityConfiguration = api.DeleteSecurityConfiguration
+	attachConfigurationToRepos  = api.AttachConfigurationToRepos
+	setConfigurationAsDefault   = api.SetConfigurationAsDefault
+)
+
+// AuditCopySuffix is appended to the configuration name to form the companion audit configuration
+const AuditCopySuffix = " (audit)"
+
 // GenerateProcessor implements OrganizationProcessor for the generate command
 type GenerateProcessor struct {
 	ConfigName        string
 	ConfigDescription string
 	Settings          map[string]interface{}
+	Enforcement       string // "enforced" or "unenforced"; kept apart from Settings
 	Scope             string
 	SetAsDefault      bool
+	ApplyToExisting   bool // Set the default before attaching, so new and existing repositories are both covered
 	Overwrite         bool
+	WithAuditCopy     bool
+	NoAttach          bool                       // Never attach the configuration, regardless of Scope
+	Exclusions        types.RepositoryExclusions // Repositories that must never be attached
+	Languages         types.LanguageSet          // Primary languages a repository must have to be attached; nil for any
+
+	SkipOrgsWithExistingDefault bool // Skip organizations whose default is a different configuration
+}
+
+// AuditCopyName returns the name of the companion audit configuration for a configuration name
+func AuditCopyName(configName string) string {
+	return configName + AuditCopySuffix
 }
 
 // ProcessOrganization processes a single organization for the generate command
-func (gp *GenerateProcessor) ProcessOrganization(org string) types.ProcessingResult {
+func (gp *GenerateProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	// Check membership using the shared validation function
-	if skipResult := api.ValidateMembershipAndSkip(org); skipResult != nil {
+	if skipResult := api.ValidateMembershipAndSkip(ctx, org); skipResult != nil {
 		return *skipResult
 	}
 
-	err := gp.processOrganization(org)
-	if err != nil {
-		return types.ProcessingResult{Organization: org, Error: err}
+	// The configuration is created by this run, so it is never already the default
+	replacedDefaults, _, guardResult := checkExistingDefaults(ctx, org, gp.ConfigName, gp.SetAsDefault, gp.SkipOrgsWithExistingDefault)
+	if guardResult != nil {
+		return *guardResult
 	}
 
-	return types.ProcessingResult{Organization: org, Success: true}
+	result := gp.generate(ctx, org)
+	if result.DefaultSet != nil {
+		result.ReplacedDefaults = replacedDefaults
+	}
+	return result
 }
 
-// processOrganization handles the core organization processing logic
-func (gp *GenerateProcessor) processOrganization(org string) error {
+// generate creates the configuration, and its audit copy when requested, in one organization. The
+// result records the default whenever it was set, even when a later step failed.
+func (gp *GenerateProcessor) generate(ctx context.Context, org string) types.ProcessingResult {
 	// Check if a configuration with the same name already exists
-	configs, err := api.FetchSecurityConfigurations(org)
+	configs, err := api.FetchSecurityConfigurations(ctx, org)
 	if err != nil {
-		return fmt.Errorf("failed to fetch existing security configurations: %w", err)
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch existing security configurations: %w", err)}
 	}
 
-	// Check if configuration already exists
-	existingConfigID, exists := api.FindConfigurationByName(configs, gp.ConfigName)
-	if exists {
-		if gp.Overwrite {
-			// Delete the existing configuration
-			pterm.Info.Printf("Overwrite flag enabled: deleting existing configuration '%s' from organization '%s'\n", gp.ConfigName, org)
-			err = api.DeleteSecurityConfiguration(org, existingConfigID)
-			if err != nil {
-				return fmt.Errorf("failed to delete existing security configuration: %w", err)
-			}
-		} else {
-			return &types.ConfigurationExistsError{
-				ConfigName: gp.ConfigName,
-				OrgName:    org,
-			}
-		}
+	configID, attachment, defaultSet, err := gp.processOrganization(ctx, org, configs)
+	var defaultSetResult *bool
+	if defaultSet {
+		defaultSetResult = &defaultSet
+	}
+	if err != nil {
+		return types.ProcessingResult{Organization: org, Error: err, Attachment: attachment, DefaultSet: defaultSetResult}
 	}
 
-	// Create security configuration
-	configID, err := api.CreateSecurityConfiguration(org, gp.ConfigName, gp.ConfigDescription, gp.Settings)
+	if !gp.WithAuditCopy {
+		return types.ProcessingResult{Organization: org, Success: true, ConfigID: configID, Attachment: attachment, DefaultSet: defaultSetResult}
+	}
+
+	// The audit copy is only created once the primary configuration is in place, and is never
+	// attached or set as default
+	result := types.ProcessingResult{
+		Organization:   org,
+		Success:        true,
+		ConfigID:       configID,
+		Attachment:     attachment,
+		DefaultSet:     defaultSetResult,
+		Configurations: []types.ConfigurationResult{{Name: gp.ConfigName, Success: true}},
+	}
+	auditResult := gp.createAuditCopy(ctx, org, configs)
+	result.Configurations = append(result.Configurations, auditResult)
+	if auditResult.Error != nil {
+		result.Success = false
+		result.Error = fmt.Errorf("created configuration '%s' but the audit copy failed: %w", gp.ConfigName, auditResult.Error)
+	}
+	return result
+}
+
+// processOrganization handles the core organization processing logic. It returns the ID of the
+// created configuration, the attach request, or nil when attachment is disabled, and whether the
+// configuration was set as default, which an error does not undo.
+func (gp *GenerateProcessor) processOrganization(ctx context.Context, org string, configs []types.SecurityConfiguration) (int, *types.Attachment, bool, error) {
+	configID, err := gp.createConfiguration(ctx, org, configs, gp.ConfigName, gp.Enforcement)
 	if err != nil {
-		return fmt.Errorf("failed to create security configuration: %w", err)
+		return 0, nil, false, err
+	}
+
+	// With --apply-to-existing the default comes first, so repositories created while the existing
+	// ones are being attached are still covered
+	if gp.ApplyToExisting {
+		if err := setConfigurationAsDefault(ctx, org, configID); err != nil {
+			return configID, nil, false, fmt.Errorf("failed to set configuration as default: %w", err)
+		}
+		if !gp.attachEnabled() {
+			return configID, nil, true, nil
+		}
+		attachment, err := attachRespectingExclusions(ctx, org, configID, gp.Scope, gp.Exclusions, gp.Languages)
+		if err != nil {
+			return configID, attachment, true, fmt.Errorf("set configuration as default but failed to attach it to existing repositories: %w", err)
+		}
+		return configID, attachment, true, nil
 	}
 
-	// Attach configuration to repositories only if scope is not "none"
-	if gp.Scope != "none" {
-		err = api.AttachConfigurationToRepos(org, configID, gp.Scope)
+	// Attach configuration to repositories unless attachment is disabled
+	var attachment *types.Attachment
+	if gp.attachEnabled() {
+		attachment, err = attachRespectingExclusions(ctx, org, configID, gp.Scope, gp.Exclusions, gp.Languages)
 		if err != nil {
-			return fmt.Errorf("failed to attach configuration to repositories: %w", err)
+			return configID, attachment, false, fmt.Errorf("failed to attach configuration to repositories: %w", err)
 		}
 	}
 
 	// Set as default if requested
 	if gp.SetAsDefault {
-		err = api.SetConfigurationAsDefault(org, configID)
+		err = setConfigurationAsDefault(ctx, org, configID)
 		if err != nil {
-			return fmt.Errorf("failed to set configuration as default: %w", err)
+			return configID, attachment, false, fmt.Errorf("failed to set configuration as default: %w", err)
+		}
+	}
+
+	return configID, attachment, gp.SetAsDefault, nil
+}
+
+// alreadyCompliant reports whether the existing configuration has gp.Settings and enforcement.
+// The organization is skipped either way, so a configuration whose details cannot be read is
+// reported as not compliant rather than failing the organization.
+func (gp *GenerateProcessor) alreadyCompliant(ctx context.Context, org string, configID int, enforcement string) bool {
+	current, err := fetchConfigurationDetails(ctx, org, configID)
+	if err != nil {
+		return false
+	}
+	return len(SettingsDiff(current.Settings, gp.Settings)) == 0 && (enforcement == "" || current.Enforcement == enforcement)
+}
+
+// attachEnabled reports whether the configuration should be attached to repositories
+func (gp *GenerateProcessor) attachEnabled() bool {
+	return !gp.NoAttach && gp.Scope != "none"
+}
+
+// createAuditCopy creates the companion configuration in an organization. It has the same settings
+// with enforcement turned off.
+func (gp *GenerateProcessor) createAuditCopy(ctx context.Context, org string, configs []types.SecurityConfiguration) types.ConfigurationResult {
+	auditName := AuditCopyName(gp.ConfigName)
+	_, err := gp.createConfiguration(ctx, org, configs, auditName, types.EnforcementUnenforced)
+	if err != nil {
+		return types.ConfigurationResult{Name: auditName, Error: err}
+	}
+	return types.ConfigurationResult{Name: auditName, Success: true}
+}
+
+// createConfiguration creates a configuration with the given name, gp.Settings and enforcement,
+// replacing an existing configuration of the same name only when overwrite is enabled
+func (gp *GenerateProcessor) createConfiguration(ctx context.Context, org string, configs []types.SecurityConfiguration, name, enforcement string) (int, error) {
+	// Check if configuration already exists
+	existingConfigID, exists := api.FindConfigurationByName(configs, name)
+	if exists {
+		if gp.Overwrite {
+			// Delete the existing configuration
+			pterm.Info.Printf("Overwrite flag enabled: deleting existing configuration '%s' from organization '%s'\n", name, org)
+			err := deleteSecurityConfiguration(ctx, org, existingConfigID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete existing security configuration: %w", err)
+			}
+		} else {
+			return 0, &types.ConfigurationExistsError{
+				ConfigName: name,
+				OrgName:    org,
+				Compliant:  gp.alreadyCompliant(ctx, org, existingConfigID, enforcement),
+			}
 		}
 	}
 
-	return nil
+	// Create security configuration
+	configID, err := createSecurityConfiguration(ctx, org, name, gp.ConfigDescription, gp.Settings, enforcement)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create security configuration: %w", err)
+	}
+	return configID, nil
 }
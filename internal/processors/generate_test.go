@@ -0,0 +1,226 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestAuditCopyName(t *testing.T) {
+	if got := AuditCopyName("Baseline"); got != "Baseline (audit)" {
+		t.Errorf("AuditCopyName() = %q, want %q", got, "Baseline (audit)")
+	}
+}
+
+func TestCreateAuditCopy_TurnsOffEnforcementOnly(t *testing.T) {
+	settings := map[string]interface{}{"advanced_security": "enabled", "secret_scanning": "enabled"}
+	gp := GenerateProcessor{ConfigName: "Baseline", Settings: settings, Enforcement: types.EnforcementEnforced}
+
+	var gotName, gotEnforcement string
+	var gotSettings map[string]interface{}
+	origCreate := createSecurityConfiguration
+	t.Cleanup(func() { createSecurityConfiguration = origCreate })
+	createSecurityConfiguration = func(_ context.Context, org, name, description string, settings map[string]interface{}, enforcement string) (int, error) {
+		gotName, gotSettings, gotEnforcement = name, settings, enforcement
+		return 43, nil
+	}
+
+	if result := gp.createAuditCopy(context.Background(), "org", nil); !result.Success {
+		t.Fatalf("createAuditCopy() = %+v, want success", result)
+	}
+	if gotName != "Baseline (audit)" || gotEnforcement != types.EnforcementUnenforced {
+		t.Errorf("audit copy created as %q with enforcement %q, want Baseline (audit), unenforced", gotName, gotEnforcement)
+	}
+	if gotSettings["advanced_security"] != "enabled" || gotSettings["secret_scanning"] != "enabled" || len(gotSettings) != 2 {
+		t.Errorf("audit copy settings = %v, want the primary settings unchanged", gotSettings)
+	}
+	if gp.Enforcement != types.EnforcementEnforced {
+		t.Errorf("processor enforcement was changed to %q", gp.Enforcement)
+	}
+}
+
+// stubGenerateAPI replaces the generate API calls for the duration of a test and returns the
+// names of the calls that were made
+func stubGenerateAPI(t *testing.T) *[]string {
+	t.Helper()
+	var calls []string
+	origCreate, origDelete, origAttach, origDefault := createSecurityConfiguration, deleteSecurityConfiguration, attachConfigurationToRepos, setConfigurationAsDefault
+	createSecurityConfiguration = func(_ context.Context, org, name, description string, settings map[string]interface{}, enforcement string) (int, error) {
+		calls = append(calls, "create")
+		return 42, nil
+	}
+	deleteSecurityConfiguration = func(_ context.Context, org string, configID int) error {
+		calls = append(calls, "delete")
+		return nil
+	}
+	attachConfigurationToRepos = func(_ context.Context, org string, configID int, scope string) (map[string]interface{}, error) {
+		calls = append(calls, "attach")
+		return nil, nil
+	}
+	setConfigurationAsDefault = func(_ context.Context, org string, configID int) error {
+		calls = append(calls, "default")
+		return nil
+	}
+	t.Cleanup(func() {
+		createSecurityConfiguration, deleteSecurityConfiguration, attachConfigurationToRepos, setConfigurationAsDefault = origCreate, origDelete, origAttach, origDefault
+	})
+	return &calls
+}
+
+func TestGenerateProcessor_ApplyToExisting(t *testing.T) {
+	calls := stubGenerateAPI(t)
+	var attachedScope string
+	attachConfigurationToRepos = func(_ context.Context, org string, configID int, scope string) (map[string]interface{}, error) {
+		*calls = append(*calls, "attach")
+		attachedScope = scope
+		return nil, nil
+	}
+
+	processor := GenerateProcessor{ConfigName: "cfg", Scope: "public", SetAsDefault: true, ApplyToExisting: true}
+	if _, _, _, err := processor.processOrganization(context.Background(), "org", []types.SecurityConfiguration{}); err != nil {
+		t.Fatalf("processOrganization() error = %v", err)
+	}
+	// The default is set before the existing repositories are attached
+	want := []string{"create", "default", "attach"}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+	if attachedScope != "public" {
+		t.Errorf("attached scope = %q, want %q", attachedScope, "public")
+	}
+}
+
+func TestGenerateProcessor_ApplyToExistingRecordsTheDefault(t *testing.T) {
+	tests := []struct {
+		name        string
+		processor   GenerateProcessor
+		attachErr   error
+		wantCalls   []string
+		wantErr     bool
+		wantDefault bool
+	}{
+		{
+			name:        "scope none sets the default without attaching",
+			processor:   GenerateProcessor{ConfigName: "cfg", Scope: "none", SetAsDefault: true, ApplyToExisting: true},
+			wantCalls:   []string{"create", "default"},
+			wantDefault: true,
+		},
+		{
+			name:        "no-attach sets the default without attaching",
+			processor:   GenerateProcessor{ConfigName: "cfg", Scope: "all", NoAttach: true, SetAsDefault: true, ApplyToExisting: true},
+			wantCalls:   []string{"create", "default"},
+			wantDefault: true,
+		},
+		{
+			name:        "failed attach still reports the default as set",
+			processor:   GenerateProcessor{ConfigName: "cfg", Scope: "all", SetAsDefault: true, ApplyToExisting: true},
+			attachErr:   errors.New("HTTP 500"),
+			wantCalls:   []string{"create", "default", "attach"},
+			wantErr:     true,
+			wantDefault: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := stubGenerateAPI(t)
+			attachConfigurationToRepos = func(_ context.Context, org string, configID int, scope string) (map[string]interface{}, error) {
+				*calls = append(*calls, "attach")
+				return nil, tt.attachErr
+			}
+
+			_, _, defaultSet, err := tt.processor.processOrganization(context.Background(), "org", []types.SecurityConfiguration{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processOrganization() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if defaultSet != tt.wantDefault {
+				t.Errorf("default set = %t, want %t", defaultSet, tt.wantDefault)
+			}
+			if !slices.Equal(*calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGenerateProcessor_AttachCalls(t *testing.T) {
+	tests := []struct {
+		name       string
+		processor  GenerateProcessor
+		wantAttach bool
+	}{
+		{name: "scope all attaches", processor: GenerateProcessor{ConfigName: "cfg", Scope: "all"}, wantAttach: true},
+		{name: "scope none skips attach", processor: GenerateProcessor{ConfigName: "cfg", Scope: "none"}},
+		{name: "no-attach overrides scope", processor: GenerateProcessor{ConfigName: "cfg", Scope: "all", NoAttach: true}},
+		{name: "no-attach still sets default", processor: GenerateProcessor{ConfigName: "cfg", Scope: "all", NoAttach: true, SetAsDefault: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := stubGenerateAPI(t)
+			if _, _, _, err := tt.processor.processOrganization(context.Background(), "org", []types.SecurityConfiguration{}); err != nil {
+				t.Fatalf("processOrganization() error = %v", err)
+			}
+			attached, defaulted := false, false
+			for _, call := range *calls {
+				attached = attached || call == "attach"
+				defaulted = defaulted || call == "default"
+			}
+			if attached != tt.wantAttach {
+				t.Errorf("attach called = %t, want %t (calls: %v)", attached, tt.wantAttach, *calls)
+			}
+			if defaulted != tt.processor.SetAsDefault {
+				t.Errorf("set default called = %t, want %t (calls: %v)", defaulted, tt.processor.SetAsDefault, *calls)
+			}
+		})
+	}
+}
+
+func TestGenerateProcessor_ExistingConfigurationCompliance(t *testing.T) {
+	stubGenerateAPI(t)
+	origDetails := fetchConfigurationDetails
+	t.Cleanup(func() { fetchConfigurationDetails = origDetails })
+
+	tests := []struct {
+		name           string
+		current        map[string]interface{}
+		enforcement    string
+		detailsErr     error
+		wantCompliant  bool
+		wantReasonPart string
+	}{
+		{name: "identical settings", current: map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"}, enforcement: types.EnforcementEnforced, wantCompliant: true, wantReasonPart: "already compliant"},
+		{name: "different setting", current: map[string]interface{}{"secret_scanning": "disabled", "dependabot_alerts": "enabled"}, enforcement: types.EnforcementEnforced, wantReasonPart: "with different settings"},
+		{name: "different enforcement", current: map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"}, enforcement: types.EnforcementUnenforced, wantReasonPart: "with different settings"},
+		{name: "details unreadable", detailsErr: errors.New("HTTP 500"), wantReasonPart: "with different settings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+				if tt.detailsErr != nil {
+					return nil, tt.detailsErr
+				}
+				return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline", Settings: tt.current, Enforcement: tt.enforcement}, nil
+			}
+			gp := GenerateProcessor{
+				ConfigName:  "Baseline",
+				Settings:    map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled"},
+				Enforcement: types.EnforcementEnforced,
+			}
+			_, err := gp.createConfiguration(context.Background(), "org", []types.SecurityConfiguration{{ID: 7, Name: "Baseline"}}, gp.ConfigName, gp.Enforcement)
+
+			result := normalizeResult(types.ProcessingResult{Organization: "org", Error: err})
+			if !result.Skipped || result.Error != nil {
+				t.Fatalf("result = %+v, want a skip", result)
+			}
+			if result.AlreadyCompliant != tt.wantCompliant {
+				t.Errorf("AlreadyCompliant = %t, want %t", result.AlreadyCompliant, tt.wantCompliant)
+			}
+			if !strings.Contains(result.SkipReason, tt.wantReasonPart) {
+				t.Errorf("SkipReason = %q, want it to contain %q", result.SkipReason, tt.wantReasonPart)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// PostHook is a command run after each organization that was processed successfully. {org} and
+// {config_id} in Command are replaced with the organization login and the configuration's ID.
+type PostHook struct {
+	Command string
+	Timeout time.Duration // 0 lets the command run for as long as it takes
+}
+
+// hookSafeLogin matches the organization logins GitHub allows. The command line is run by a
+// shell, so only such logins are substituted for {org}; anything else, such as a crafted
+// --org-list row, could run commands of its own.
+var hookSafeLogin = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Expand returns the hook's command line for one organization. An org that is not a valid
+// organization login is refused rather than passed to the shell.
+func (h PostHook) Expand(org string, configID int) (string, error) {
+	if !hookSafeLogin.MatchString(org) {
+		return "", fmt.Errorf("organization %q is not a valid login, so it is not passed to the post hook", org)
+	}
+	return strings.NewReplacer("{org}", org, "{config_id}", strconv.Itoa(configID)).Replace(h.Command), nil
+}
+
+// runHookCommand runs a hook command line and returns its combined output. It is a variable so
+// tests do not start processes.
+var runHookCommand = execHookCommand
+
+// execHookCommand runs command through the platform shell, giving up once timeout has passed or
+// ctx is done
+func execHookCommand(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Processes the shell started may keep its output open after it is killed; stop waiting for them
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return strings.TrimSpace(string(output)), err
+}
+
+// hookProcessor runs a post hook after each organization its processor handles successfully
+type hookProcessor struct {
+	processor OrganizationProcessor
+	hook      PostHook
+}
+
+// WithPostHook wraps processor so that hook runs after every organization it processes
+// successfully. The hook's output is kept in the result for the log, and a failed hook is recorded
+// in HookError without undoing the organization's success. Without a hook command, processor is
+// returned as is.
+func WithPostHook(processor OrganizationProcessor, hook PostHook) OrganizationProcessor {
+	if hook.Command == "" {
+		return processor
+	}
+	return &hookProcessor{processor: processor, hook: hook}
+}
+
+// ProcessOrganization processes org and then runs the hook when processing succeeded
+func (hp *hookProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	result := hp.processor.ProcessOrganization(ctx, org)
+	if !result.Success {
+		return result
+	}
+	command, err := hp.hook.Expand(org, result.ConfigID)
+	if err != nil {
+		result.HookError = fmt.Errorf("post hook not run: %w", err)
+		return result
+	}
+	output, err := runHookCommand(ctx, command, hp.hook.Timeout)
+	result.HookOutput = output
+	if err != nil {
+		result.HookError = fmt.Errorf("post hook failed: %w", err)
+	}
+	return result
+}
+
+// HookFailures returns the organizations whose post hook failed, in result order
+func HookFailures(results []types.ProcessingResult) []types.ProcessingResult {
+	var failed []types.ProcessingResult
+	for _, result := range results {
+		if result.HookError != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
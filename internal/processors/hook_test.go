@@ -0,0 +1,119 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestPostHook_Expand(t *testing.T) {
+	hook := PostHook{Command: "./register.sh {org} {config_id} --org={org}"}
+	tests := []struct {
+		org     string
+		want    string
+		wantErr bool
+	}{
+		{org: "octo", want: "./register.sh octo 42 --org=octo"},
+		{org: "Octo-Org-2", want: "./register.sh Octo-Org-2 42 --org=Octo-Org-2"},
+		{org: "acme;id", wantErr: true},
+		{org: "acme$(id)", wantErr: true},
+		{org: "acme`id`", wantErr: true},
+		{org: "acme&whoami", wantErr: true},
+		{org: "acme|calc", wantErr: true},
+		{org: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := hook.Expand(tt.org, 42)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expand(%q) = %q, want an error", tt.org, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v; want %q", tt.org, got, err, tt.want)
+		}
+	}
+}
+
+func TestWithPostHook_HostileOrganizationNotRun(t *testing.T) {
+	orig := runHookCommand
+	t.Cleanup(func() { runHookCommand = orig })
+	runHookCommand = func(_ context.Context, command string, timeout time.Duration) (string, error) {
+		t.Fatalf("hook run with %q", command)
+		return "", nil
+	}
+
+	fp := &fakeProcessor{results: map[string]types.ProcessingResult{"acme;id": {Success: true, ConfigID: 7}}}
+	result := WithPostHook(fp, PostHook{Command: "register {org}"}).ProcessOrganization(context.Background(), "acme;id")
+	if !result.Success || result.HookError == nil {
+		t.Errorf("result = %+v, want success kept with a hook error", result)
+	}
+}
+
+func TestWithPostHook(t *testing.T) {
+	orig := runHookCommand
+	t.Cleanup(func() { runHookCommand = orig })
+
+	var commands []string
+	runHookCommand = func(_ context.Context, command string, timeout time.Duration) (string, error) {
+		commands = append(commands, command)
+		if strings.Contains(command, "flaky") {
+			return "registry unavailable", errors.New("exit status 1")
+		}
+		return "registered", nil
+	}
+
+	fp := &fakeProcessor{results: map[string]types.ProcessingResult{
+		"ok":      {Success: true, ConfigID: 7},
+		"flaky":   {Success: true, ConfigID: 8},
+		"skipped": {Skipped: true},
+		"failed":  {Error: errors.New("HTTP 422")},
+	}}
+	processor := WithPostHook(fp, PostHook{Command: "register {org} {config_id}", Timeout: time.Minute})
+
+	ok := processor.ProcessOrganization(context.Background(), "ok")
+	if !ok.Success || ok.HookError != nil || ok.HookOutput != "registered" {
+		t.Errorf("ok = %+v, want success with the hook output", ok)
+	}
+	flaky := processor.ProcessOrganization(context.Background(), "flaky")
+	if !flaky.Success || flaky.HookError == nil || flaky.HookOutput != "registry unavailable" {
+		t.Errorf("flaky = %+v, want success kept with a hook error and output", flaky)
+	}
+	for _, org := range []string{"skipped", "failed"} {
+		if result := processor.ProcessOrganization(context.Background(), org); result.HookError != nil || result.HookOutput != "" {
+			t.Errorf("%s = %+v, want the hook not run", org, result)
+		}
+	}
+
+	if want := []string{"register ok 7", "register flaky 8"}; strings.Join(commands, "|") != strings.Join(want, "|") {
+		t.Errorf("hook commands = %v, want %v", commands, want)
+	}
+	if failed := HookFailures([]types.ProcessingResult{ok, flaky}); len(failed) != 1 || failed[0].Organization != "flaky" {
+		t.Errorf("HookFailures() = %+v, want only flaky", failed)
+	}
+	if WithPostHook(fp, PostHook{}) != OrganizationProcessor(fp) {
+		t.Error("WithPostHook() without a command should return the processor unchanged")
+	}
+}
+
+func TestExecHookCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	output, err := execHookCommand(context.Background(), "echo out; echo err >&2", time.Minute)
+	if err != nil || output != "out\nerr" {
+		t.Errorf("execHookCommand() = %q, %v; want both streams captured", output, err)
+	}
+	if _, err := execHookCommand(context.Background(), "exit 3", 0); err == nil {
+		t.Error("execHookCommand() should fail when the command exits non-zero")
+	}
+	if _, err := execHookCommand(context.Background(), "sleep 5", 50*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("execHookCommand() error = %v, want a timeout", err)
+	}
+}
@@ -1,8 +1,13 @@
 package processors
 
-import "github.com/callmegreg/gh-security-config/internal/types"
+import (
+	"context"
 
-// OrganizationProcessor defines the interface for processing organizations
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// OrganizationProcessor defines the interface for processing organizations. Processing stops
+// sending requests once ctx is done.
 type OrganizationProcessor interface {
-	ProcessOrganization(org string) types.ProcessingResult
+	ProcessOrganization(ctx context.Context, org string) types.ProcessingResult
 }
@@ -1,60 +1,129 @@
 package processors
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/types"
 	"github.com/callmegreg/gh-security-config/internal/ui"
 )
 
+// updateSecurityConfiguration sends the modify request. It is a variable so tests can observe
+// whether a write was made.
+var updateSecurityConfiguration = api.UpdateSecurityConfiguration
+
 // ModifyProcessor implements OrganizationProcessor for the modify command
 type ModifyProcessor struct {
 	ConfigName     string
 	NewName        string
 	NewDescription string
 	NewSettings    map[string]interface{}
+	NewEnforcement string // "" leaves the enforcement unchanged
+	// ExpectedUpdatedAt holds the updated_at values recorded when the run was confirmed, keyed by
+	// organization (see SnapshotUpdatedAt). Organizations missing from it use the value from their
+	// configuration list.
+	ExpectedUpdatedAt map[string]time.Time
+	// ForceOverwrite sends the update even if the configuration changed since it was read
+	ForceOverwrite bool
 }
 
 // ProcessOrganization processes a single organization for the modify command
-func (mp *ModifyProcessor) ProcessOrganization(org string) types.ProcessingResult {
+func (mp *ModifyProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	// Check membership using the shared validation function
-	if skipResult := api.ValidateMembershipAndSkip(org); skipResult != nil {
+	if skipResult := api.ValidateMembershipAndSkip(ctx, org); skipResult != nil {
 		return *skipResult
 	}
 
-	updated, err := mp.modifyConfigurationInOrg(org)
-	if err != nil {
-		return types.ProcessingResult{Organization: org, Error: err}
-	}
-	if !updated {
-		// Configuration was not found, already logged as warning in modifyConfigurationInOrg
-		return types.ProcessingResult{Organization: org, Skipped: true}
-	}
-
-	return types.ProcessingResult{Organization: org, Success: true}
+	return mp.modifyConfigurationInOrg(ctx, org)
 }
 
-// modifyConfigurationInOrg updates a configuration in an organization
-func (mp *ModifyProcessor) modifyConfigurationInOrg(org string) (bool, error) {
+// modifyConfigurationInOrg updates a configuration in an organization. The update is sent only if
+// the configuration has not changed since its updated_at was recorded, unless ForceOverwrite is set.
+// The result carries the configuration as read before the update and as returned by it. With
+// ForceOverwrite, a configuration that cannot be read first is still updated, without a before
+// snapshot, as nothing is compared with it.
+func (mp *ModifyProcessor) modifyConfigurationInOrg(ctx context.Context, org string) types.ProcessingResult {
 	// First, fetch security configurations for the organization
-	configs, err := api.FetchSecurityConfigurations(org)
+	configs, err := fetchSecurityConfigurations(ctx, org)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch security configurations: %w", err)
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch security configurations: %w", err)}
 	}
 
 	// Find the configuration by name
-	configID, found := api.FindConfigurationByName(configs, mp.ConfigName)
+	config, found := api.FindConfiguration(configs, mp.ConfigName)
 	if !found {
-		ui.LogWarningf("Configuration '%s' not found in organization '%s', skipping", mp.ConfigName, org)
-		return false, nil // Not an error, just skip this org
+		return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Configuration '%s' not found in organization '%s', skipping", mp.ConfigName, org)}
+	}
+
+	// The current configuration is the before snapshot, and the concurrency check's reference
+	before, err := fetchConfigurationDetails(ctx, org, config.ID)
+	if err != nil {
+		if !mp.ForceOverwrite || ctx.Err() != nil {
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to re-fetch security configuration: %w", err)}
+		}
+		ui.LogWarningf("Could not read configuration '%s' in organization '%s' before updating it, so its changes will not be shown: %v", mp.ConfigName, org, err)
+		before = nil
+	}
+
+	if !mp.ForceOverwrite {
+		expected, recorded := mp.ExpectedUpdatedAt[org]
+		if !recorded {
+			expected = config.UpdatedAt
+		}
+		if modifiedConcurrently(expected, before.UpdatedAt) {
+			return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Skipping organization '%s': configuration '%s' was modified concurrently (updated at %s), re-run to see latest or use --force-overwrite", org, mp.ConfigName, before.UpdatedAt.Format(time.RFC3339)), Before: before}
+		}
 	}
 
 	// Update the configuration
-	err = api.UpdateSecurityConfiguration(org, configID, mp.NewName, mp.NewDescription, mp.NewSettings)
+	after, err := updateSecurityConfiguration(ctx, org, config.ID, mp.NewName, mp.NewDescription, mp.NewSettings, mp.NewEnforcement)
 	if err != nil {
-		return false, fmt.Errorf("failed to update security configuration: %w", err)
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to update security configuration: %w", err), Before: before}
+	}
+
+	return types.ProcessingResult{Organization: org, Success: true, Before: before, After: after}
+}
+
+// SnapshotUpdatedAt records the updated_at of configuration configName in each organization,
+// running up to concurrency lookups at once. Comparing against it lets ModifyProcessor skip a
+// configuration changed after the run was confirmed. Organizations whose configurations could not
+// be listed, or that do not hold configName, are left out.
+func SnapshotUpdatedAt(orgs []string, configName string, concurrency int) map[string]time.Time {
+	snapshot := make(map[string]time.Time, len(orgs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for _, org := range orgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(org string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			configs, err := fetchSecurityConfigurations(context.Background(), org)
+			if err != nil {
+				return
+			}
+			config, found := api.FindConfiguration(configs, configName)
+			if !found {
+				return
+			}
+			mu.Lock()
+			snapshot[org] = config.UpdatedAt
+			mu.Unlock()
+		}(org)
 	}
+	wg.Wait()
+	return snapshot
+}
 
-	return true, nil
+// modifiedConcurrently reports whether a configuration's updated_at moved since it was recorded.
+// A missing timestamp on either side cannot be compared and is not treated as a change.
+func modifiedConcurrently(expected, current time.Time) bool {
+	if expected.IsZero() || current.IsZero() {
+		return false
+	}
+	return !expected.Equal(current)
 }
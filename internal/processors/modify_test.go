@@ -0,0 +1,229 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestModifyConfigurationInOrg_ConcurrentChange(t *testing.T) {
+	origFetch, origDetails, origUpdate := fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration
+	t.Cleanup(func() {
+		fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration = origFetch, origDetails, origUpdate
+	})
+
+	listed := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	later := listed.Add(time.Minute)
+
+	tests := []struct {
+		name        string
+		expected    map[string]time.Time // snapshot recorded before the run
+		current     time.Time            // updated_at returned by the re-fetch
+		force       bool
+		wantUpdated bool
+	}{
+		{name: "unchanged since list", current: listed, wantUpdated: true},
+		{name: "changed since list", current: later, wantUpdated: false},
+		{name: "changed since snapshot", expected: map[string]time.Time{"org": listed.Add(-time.Hour)}, current: listed, wantUpdated: false},
+		{name: "matches snapshot", expected: map[string]time.Time{"org": later}, current: later, wantUpdated: true},
+		{name: "force overwrite ignores change", current: later, force: true, wantUpdated: true},
+		{name: "missing timestamp is not a change", current: time.Time{}, wantUpdated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+				return []types.SecurityConfiguration{{ID: 5, Name: "Baseline", UpdatedAt: listed}}, nil
+			}
+			// Simulates another admin saving the configuration between the list and the write
+			fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+				return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline", UpdatedAt: tt.current}, nil
+			}
+			updated := false
+			updateSecurityConfiguration = func(_ context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
+				updated = true
+				return nil, nil
+			}
+
+			processor := &ModifyProcessor{ConfigName: "Baseline", NewName: "Baseline", ExpectedUpdatedAt: tt.expected, ForceOverwrite: tt.force}
+			result := processor.modifyConfigurationInOrg(context.Background(), "org")
+			if updated != tt.wantUpdated || result.Success != tt.wantUpdated {
+				t.Fatalf("updated = %t, result = %+v; want updated %t", updated, result, tt.wantUpdated)
+			}
+			if !tt.wantUpdated && (!result.Skipped || !strings.Contains(result.SkipReason, "modified concurrently")) {
+				t.Errorf("skip reason = %q, want a concurrent modification skip", result.SkipReason)
+			}
+		})
+	}
+}
+
+func TestModifyConfigurationInOrg_NotFoundSkips(t *testing.T) {
+	origFetch := fetchSecurityConfigurations
+	t.Cleanup(func() { fetchSecurityConfigurations = origFetch })
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{{ID: 1, Name: "Other"}}, nil
+	}
+
+	result := (&ModifyProcessor{ConfigName: "Baseline"}).modifyConfigurationInOrg(context.Background(), "org")
+	if !result.Skipped || !strings.Contains(result.SkipReason, "not found") {
+		t.Errorf("result = %+v, want a not-found skip", result)
+	}
+}
+
+func TestModifyConfigurationInOrg_Snapshots(t *testing.T) {
+	origFetch, origDetails, origUpdate := fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration
+	t.Cleanup(func() {
+		fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration = origFetch, origDetails, origUpdate
+	})
+
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{{ID: 5, Name: "Baseline"}}, nil
+	}
+	fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+		return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline", Settings: map[string]interface{}{"secret_scanning": "disabled"}}, nil
+	}
+
+	tests := []struct {
+		name        string
+		force       bool
+		updateErr   error
+		wantSuccess bool
+		wantAfter   bool
+	}{
+		{name: "update returns the after snapshot", wantSuccess: true, wantAfter: true},
+		{name: "force overwrite still reads the before snapshot", force: true, wantSuccess: true, wantAfter: true},
+		{name: "failed update keeps only the before snapshot", updateErr: errors.New("HTTP 422")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updateSecurityConfiguration = func(_ context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
+				if tt.updateErr != nil {
+					return nil, tt.updateErr
+				}
+				return &types.SecurityConfigurationDetails{ID: configID, Name: name, Settings: settings}, nil
+			}
+
+			processor := &ModifyProcessor{ConfigName: "Baseline", NewName: "Baseline", NewSettings: map[string]interface{}{"secret_scanning": "enabled"}, ForceOverwrite: tt.force}
+			result := processor.modifyConfigurationInOrg(context.Background(), "org")
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("result = %+v, want success %t", result, tt.wantSuccess)
+			}
+			if result.Before == nil || types.SettingValue(result.Before.Settings, "secret_scanning") != "disabled" {
+				t.Errorf("Before = %+v, want the configuration read before the update", result.Before)
+			}
+			if gotAfter := result.After != nil; gotAfter != tt.wantAfter {
+				t.Fatalf("After = %+v, want present %t", result.After, tt.wantAfter)
+			}
+			if tt.wantAfter && types.SettingValue(result.After.Settings, "secret_scanning") != "enabled" {
+				t.Errorf("After = %+v, want the updated configuration", result.After)
+			}
+		})
+	}
+}
+
+func TestModifyConfigurationInOrg_UnreadableBeforeSnapshot(t *testing.T) {
+	origFetch, origDetails, origUpdate := fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration
+	t.Cleanup(func() {
+		fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration = origFetch, origDetails, origUpdate
+	})
+
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		return []types.SecurityConfiguration{{ID: 5, Name: "Baseline"}}, nil
+	}
+	fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+		return nil, errors.New("HTTP 502")
+	}
+
+	tests := []struct {
+		name        string
+		force       bool
+		wantUpdated bool
+	}{
+		{name: "without force overwrite the update is not sent"},
+		{name: "force overwrite sends the update without a before snapshot", force: true, wantUpdated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			updateSecurityConfiguration = func(_ context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
+				updated = true
+				return &types.SecurityConfigurationDetails{ID: configID, Name: name}, nil
+			}
+
+			processor := &ModifyProcessor{ConfigName: "Baseline", NewName: "Baseline", ForceOverwrite: tt.force}
+			result := processor.modifyConfigurationInOrg(context.Background(), "org")
+			if updated != tt.wantUpdated || result.Success != tt.wantUpdated {
+				t.Fatalf("updated = %t, result = %+v; want updated %t", updated, result, tt.wantUpdated)
+			}
+			if result.Before != nil {
+				t.Errorf("Before = %+v, want none", result.Before)
+			}
+			if !tt.wantUpdated && (result.Error == nil || !strings.Contains(result.Error.Error(), "re-fetch")) {
+				t.Errorf("error = %v, want the re-fetch failure", result.Error)
+			}
+		})
+	}
+}
+
+func TestModifyConfigurationInOrg_ChangedAfterSnapshot(t *testing.T) {
+	origFetch, origDetails, origUpdate := fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration
+	t.Cleanup(func() {
+		fetchSecurityConfigurations, fetchConfigurationDetails, updateSecurityConfiguration = origFetch, origDetails, origUpdate
+	})
+
+	confirmed := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	updatedAt := map[string]time.Time{"changed": confirmed, "unchanged": confirmed}
+	current := func(org string) (time.Time, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		at, found := updatedAt[org]
+		return at, found
+	}
+	fetchSecurityConfigurations = func(_ context.Context, org string) ([]types.SecurityConfiguration, error) {
+		at, found := current(org)
+		if !found {
+			return nil, nil
+		}
+		return []types.SecurityConfiguration{{ID: 5, Name: "Baseline", UpdatedAt: at}}, nil
+	}
+	fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+		at, _ := current(org)
+		return &types.SecurityConfigurationDetails{ID: configID, Name: "Baseline", UpdatedAt: at}, nil
+	}
+	var updated []string
+	updateSecurityConfiguration = func(_ context.Context, org string, configID int, name, description string, settings map[string]interface{}, enforcement string) (*types.SecurityConfigurationDetails, error) {
+		updated = append(updated, org)
+		return nil, nil
+	}
+
+	snapshot := SnapshotUpdatedAt([]string{"changed", "unchanged", "missing-config"}, "Baseline", 2)
+	if want := map[string]time.Time{"changed": confirmed, "unchanged": confirmed}; !maps.Equal(snapshot, want) {
+		t.Fatalf("snapshot = %v, want %v", snapshot, want)
+	}
+
+	// Another admin saves the configuration after the run was confirmed but before it reaches the
+	// organization, so the list and the re-read both show the newer updated_at
+	mu.Lock()
+	updatedAt["changed"] = confirmed.Add(time.Minute)
+	mu.Unlock()
+
+	processor := &ModifyProcessor{ConfigName: "Baseline", NewName: "Baseline", ExpectedUpdatedAt: snapshot}
+	result := processor.modifyConfigurationInOrg(context.Background(), "changed")
+	if !result.Skipped || !strings.Contains(result.SkipReason, "modified concurrently") {
+		t.Errorf("changed org result = %+v, want a concurrent modification skip", result)
+	}
+	if result := processor.modifyConfigurationInOrg(context.Background(), "unchanged"); !result.Success {
+		t.Errorf("unchanged org result = %+v, want success", result)
+	}
+	if !slices.Equal(updated, []string{"unchanged"}) {
+		t.Errorf("updated = %v, want only the unchanged organization", updated)
+	}
+}
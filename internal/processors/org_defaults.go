@@ -0,0 +1,167 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// fetchDefaultConfigurations looks up an organization's defaults. It is a variable so tests can
+// supply canned defaults.
+var fetchDefaultConfigurations = api.FetchDefaultConfigurations
+
+// clearDefaultConfiguration removes a configuration as default. It is a variable so tests can
+// observe which configurations a run clears.
+var clearDefaultConfiguration = api.ClearDefaultConfiguration
+
+// SummarizeDefaults reduces an organization's default configurations list to the configuration
+// name and ID used for each repository visibility. A default for "all" applies to both visibilities.
+func SummarizeDefaults(defaults []types.DefaultConfiguration) types.OrgDefaults {
+	var summary types.OrgDefaults
+	for _, entry := range defaults {
+		switch entry.DefaultForNewRepos {
+		case "all":
+			summary.Public, summary.PublicID = entry.Configuration.Name, entry.Configuration.ID
+			summary.PrivateAndInternal, summary.PrivateAndInternalID = entry.Configuration.Name, entry.Configuration.ID
+		case "public":
+			summary.Public, summary.PublicID = entry.Configuration.Name, entry.Configuration.ID
+		case "private_and_internal":
+			summary.PrivateAndInternal, summary.PrivateAndInternalID = entry.Configuration.Name, entry.Configuration.ID
+		}
+	}
+	return summary
+}
+
+// OrgDefaultsListProcessor implements OrganizationProcessor for the org-defaults list command. It
+// records each organization's defaults so they can be reported once processing completes.
+type OrgDefaultsListProcessor struct {
+	mu       sync.Mutex
+	defaults map[string]types.OrgDefaults
+}
+
+// ProcessOrganization records the default configurations for a single organization
+func (lp *OrgDefaultsListProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	// Membership is not looked up, so read-only tokens can list defaults; an organization the token
+	// cannot read is skipped instead
+	defaults, err := fetchDefaultConfigurations(ctx, org)
+	if err != nil {
+		if skipResult := api.NoAccessSkip(org, err); skipResult != nil {
+			return *skipResult
+		}
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch default configurations: %w", err)}
+	}
+
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.defaults == nil {
+		lp.defaults = make(map[string]types.OrgDefaults)
+	}
+	lp.defaults[org] = SummarizeDefaults(defaults)
+
+	return types.ProcessingResult{Organization: org, Success: true}
+}
+
+// Defaults returns the recorded defaults keyed by organization
+func (lp *OrgDefaultsListProcessor) Defaults() map[string]types.OrgDefaults {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	out := make(map[string]types.OrgDefaults, len(lp.defaults))
+	for org, defaults := range lp.defaults {
+		out[org] = defaults
+	}
+	return out
+}
+
+// OrgDefaultsClearProcessor implements OrganizationProcessor for the org-defaults clear command
+type OrgDefaultsClearProcessor struct{}
+
+// ProcessOrganization clears every default configuration in a single organization. Organizations
+// without a default are skipped.
+func (cp *OrgDefaultsClearProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
+	if skipResult := api.ValidateMembershipAndSkip(ctx, org); skipResult != nil {
+		return *skipResult
+	}
+	return cp.clearDefaults(ctx, org)
+}
+
+// clearDefaults clears the default configurations of an organization whose membership has been
+// checked
+func (cp *OrgDefaultsClearProcessor) clearDefaults(ctx context.Context, org string) types.ProcessingResult {
+	defaults, err := fetchDefaultConfigurations(ctx, org)
+	if err != nil {
+		return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch default configurations: %w", err)}
+	}
+	if len(defaults) == 0 {
+		return types.ProcessingResult{Organization: org, Skipped: true, SkipReason: fmt.Sprintf("Organization '%s' has no default configuration, skipping", org)}
+	}
+
+	// A configuration can appear once per visibility, but a single request clears it entirely
+	cleared := make(map[int]bool)
+	for _, entry := range defaults {
+		if cleared[entry.Configuration.ID] {
+			continue
+		}
+		if err := clearDefaultConfiguration(ctx, org, entry.Configuration.ID); err != nil {
+			return types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to clear default configuration '%s': %w", entry.Configuration.Name, err)}
+		}
+		cleared[entry.Configuration.ID] = true
+	}
+
+	return types.ProcessingResult{Organization: org, Success: true}
+}
+
+// OtherDefaultNames returns the sorted, de-duplicated names of default configurations other than
+// configName
+func OtherDefaultNames(defaults []types.DefaultConfiguration, configName string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range defaults {
+		name := entry.Configuration.Name
+		if name == configName || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkExistingDefaults looks up an organization's defaults before a run changes it. When
+// skipOnExisting is set and another configuration is the default, a skip result is returned.
+// Otherwise the names of the defaults that setting configName as default will replace are
+// returned, along with whether configName is already the default for all new repositories. The
+// lookup is only made when it can affect the outcome.
+func checkExistingDefaults(ctx context.Context, org, configName string, setAsDefault, skipOnExisting bool) ([]string, bool, *types.ProcessingResult) {
+	if !setAsDefault && !skipOnExisting {
+		return nil, false, nil
+	}
+
+	defaults, err := fetchDefaultConfigurations(ctx, org)
+	if err != nil {
+		return nil, false, &types.ProcessingResult{Organization: org, Error: fmt.Errorf("failed to fetch default configurations: %w", err)}
+	}
+
+	others := OtherDefaultNames(defaults, configName)
+	if len(others) == 0 {
+		summary := SummarizeDefaults(defaults)
+		alreadyDefault := summary.Public == configName && summary.PrivateAndInternal == configName
+		return nil, setAsDefault && alreadyDefault, nil
+	}
+	if skipOnExisting {
+		return nil, false, &types.ProcessingResult{
+			Organization: org,
+			Skipped:      true,
+			SkipReason:   fmt.Sprintf("Skipping organization '%s': it already has a different default configuration (%s)", org, strings.Join(others, ", ")),
+		}
+	}
+	if !setAsDefault {
+		return nil, false, nil
+	}
+	return others, false, nil
+}
@@ -0,0 +1,188 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestSummarizeDefaults(t *testing.T) {
+	baseline := types.SecurityConfiguration{ID: 1, Name: "Baseline"}
+	strict := types.SecurityConfiguration{ID: 2, Name: "Strict"}
+
+	tests := []struct {
+		name     string
+		defaults []types.DefaultConfiguration
+		want     types.OrgDefaults
+	}{
+		{name: "no defaults", want: types.OrgDefaults{}},
+		{
+			name:     "default for all repositories",
+			defaults: []types.DefaultConfiguration{{DefaultForNewRepos: "all", Configuration: baseline}},
+			want:     types.OrgDefaults{Public: "Baseline", PublicID: 1, PrivateAndInternal: "Baseline", PrivateAndInternalID: 1},
+		},
+		{
+			name: "separate defaults per visibility",
+			defaults: []types.DefaultConfiguration{
+				{DefaultForNewRepos: "public", Configuration: baseline},
+				{DefaultForNewRepos: "private_and_internal", Configuration: strict},
+			},
+			want: types.OrgDefaults{Public: "Baseline", PublicID: 1, PrivateAndInternal: "Strict", PrivateAndInternalID: 2},
+		},
+		{
+			name:     "public only",
+			defaults: []types.DefaultConfiguration{{DefaultForNewRepos: "public", Configuration: strict}},
+			want:     types.OrgDefaults{Public: "Strict", PublicID: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeDefaults(tt.defaults); got != tt.want {
+				t.Errorf("SummarizeDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOtherDefaultNames(t *testing.T) {
+	defaults := []types.DefaultConfiguration{
+		{DefaultForNewRepos: "public", Configuration: types.SecurityConfiguration{ID: 2, Name: "Strict"}},
+		{DefaultForNewRepos: "private_and_internal", Configuration: types.SecurityConfiguration{ID: 1, Name: "Baseline"}},
+		{DefaultForNewRepos: "private_and_internal", Configuration: types.SecurityConfiguration{ID: 2, Name: "Strict"}},
+	}
+	got := OtherDefaultNames(defaults, "Baseline")
+	if len(got) != 1 || got[0] != "Strict" {
+		t.Errorf("OtherDefaultNames() = %v, want [Strict]", got)
+	}
+	if got := OtherDefaultNames(defaults[1:2], "Baseline"); len(got) != 0 {
+		t.Errorf("OtherDefaultNames() = %v, want none when only our configuration is default", got)
+	}
+}
+
+func TestCheckExistingDefaults(t *testing.T) {
+	otherDefault := []types.DefaultConfiguration{{DefaultForNewRepos: "all", Configuration: types.SecurityConfiguration{ID: 9, Name: "Org Custom"}}}
+	ourDefault := []types.DefaultConfiguration{{DefaultForNewRepos: "all", Configuration: types.SecurityConfiguration{ID: 1, Name: "Baseline"}}}
+	ourPublicDefault := []types.DefaultConfiguration{{DefaultForNewRepos: "public", Configuration: types.SecurityConfiguration{ID: 1, Name: "Baseline"}}}
+
+	tests := []struct {
+		name           string
+		defaults       []types.DefaultConfiguration
+		setAsDefault   bool
+		skipOnExisting bool
+		wantFetch      bool
+		wantSkip       bool
+		wantReplaced   []string
+		wantAlready    bool
+	}{
+		{name: "no lookup when neither option is set", defaults: otherDefault},
+		{name: "records replaced default", defaults: otherDefault, setAsDefault: true, wantFetch: true, wantReplaced: []string{"Org Custom"}},
+		{name: "skips org with other default", defaults: otherDefault, setAsDefault: true, skipOnExisting: true, wantFetch: true, wantSkip: true},
+		{name: "skips even when not setting default", defaults: otherDefault, skipOnExisting: true, wantFetch: true, wantSkip: true},
+		{name: "proceeds when our configuration is already default", defaults: ourDefault, setAsDefault: true, skipOnExisting: true, wantFetch: true, wantAlready: true},
+		{name: "already default only for public repositories", defaults: ourPublicDefault, setAsDefault: true, wantFetch: true},
+		{name: "already default is not reported without set-default", defaults: ourDefault, skipOnExisting: true, wantFetch: true},
+		{name: "proceeds when org has no default", setAsDefault: true, skipOnExisting: true, wantFetch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := false
+			original := fetchDefaultConfigurations
+			fetchDefaultConfigurations = func(_ context.Context, org string) ([]types.DefaultConfiguration, error) {
+				fetched = true
+				return tt.defaults, nil
+			}
+			t.Cleanup(func() { fetchDefaultConfigurations = original })
+
+			replaced, already, result := checkExistingDefaults(context.Background(), "org", "Baseline", tt.setAsDefault, tt.skipOnExisting)
+			if fetched != tt.wantFetch {
+				t.Errorf("fetched = %t, want %t", fetched, tt.wantFetch)
+			}
+			if (result != nil && result.Skipped) != tt.wantSkip {
+				t.Errorf("skip result = %+v, want skip %t", result, tt.wantSkip)
+			}
+			if len(replaced) != len(tt.wantReplaced) || (len(replaced) > 0 && replaced[0] != tt.wantReplaced[0]) {
+				t.Errorf("replaced = %v, want %v", replaced, tt.wantReplaced)
+			}
+			if already != tt.wantAlready {
+				t.Errorf("already default = %t, want %t", already, tt.wantAlready)
+			}
+		})
+	}
+}
+
+func TestOrgDefaultsClearProcessor_ClearDefaults(t *testing.T) {
+	origDefaults, origClear := fetchDefaultConfigurations, clearDefaultConfiguration
+	t.Cleanup(func() { fetchDefaultConfigurations, clearDefaultConfiguration = origDefaults, origClear })
+
+	baseline := types.SecurityConfiguration{ID: 1, Name: "Baseline"}
+	strict := types.SecurityConfiguration{ID: 2, Name: "Strict"}
+
+	tests := []struct {
+		name        string
+		defaults    []types.DefaultConfiguration
+		fetchErr    error
+		failID      int
+		wantSuccess bool
+		wantSkipped bool
+		wantCleared []int
+	}{
+		{name: "no default skips the organization", wantSkipped: true},
+		{
+			name: "configuration default for both visibilities is cleared once",
+			defaults: []types.DefaultConfiguration{
+				{DefaultForNewRepos: "public", Configuration: baseline},
+				{DefaultForNewRepos: "private_and_internal", Configuration: baseline},
+			},
+			wantSuccess: true,
+			wantCleared: []int{1},
+		},
+		{
+			name: "each default configuration is cleared",
+			defaults: []types.DefaultConfiguration{
+				{DefaultForNewRepos: "public", Configuration: baseline},
+				{DefaultForNewRepos: "private_and_internal", Configuration: strict},
+			},
+			wantSuccess: true,
+			wantCleared: []int{1, 2},
+		},
+		{
+			name: "failed clear stops the organization",
+			defaults: []types.DefaultConfiguration{
+				{DefaultForNewRepos: "public", Configuration: baseline},
+				{DefaultForNewRepos: "private_and_internal", Configuration: strict},
+			},
+			failID: 1,
+		},
+		{name: "failed lookup clears nothing", fetchErr: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchDefaultConfigurations = func(_ context.Context, org string) ([]types.DefaultConfiguration, error) {
+				return tt.defaults, tt.fetchErr
+			}
+			var cleared []int
+			clearDefaultConfiguration = func(_ context.Context, org string, configID int) error {
+				if configID == tt.failID {
+					return errors.New("boom")
+				}
+				cleared = append(cleared, configID)
+				return nil
+			}
+
+			result := (&OrgDefaultsClearProcessor{}).clearDefaults(context.Background(), "org")
+			if result.Success != tt.wantSuccess || result.Skipped != tt.wantSkipped {
+				t.Errorf("result = %+v, want success %t, skipped %t", result, tt.wantSuccess, tt.wantSkipped)
+			}
+			if wantErr := !tt.wantSuccess && !tt.wantSkipped; (result.Error != nil) != wantErr {
+				t.Errorf("error = %v, want error %t", result.Error, wantErr)
+			}
+			if !slices.Equal(cleared, tt.wantCleared) {
+				t.Errorf("cleared %v, want %v", cleared, tt.wantCleared)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/ui"
+)
+
+// probeConfigurationPrefix starts the name of the scratch configuration created by ProbeFeatureSupport
+const probeConfigurationPrefix = "gh-security-config feature probe"
+
+// ProbeFeatureSupport finds settings the instance accepts but does not apply. It creates a scratch
+// configuration with settings in org, reads it back, and deletes it again. A setting whose read-back
+// value differs from the value sent is inactive on this instance. The returned keys are sorted.
+func ProbeFeatureSupport(org string, settings map[string]interface{}) ([]string, error) {
+	name := fmt.Sprintf("%s %d", probeConfigurationPrefix, time.Now().Unix())
+	configID, err := createSecurityConfiguration(context.Background(), org, name, "Temporary configuration created to probe feature support; safe to delete", settings, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe configuration in '%s': %w", org, err)
+	}
+	defer func() {
+		if err := deleteSecurityConfiguration(context.Background(), org, configID); err != nil {
+			ui.LogWarningf("Could not delete probe configuration '%s' from organization '%s'; delete it manually: %v", name, org, err)
+		}
+	}()
+
+	details, err := fetchConfigurationDetails(context.Background(), org, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back probe configuration in '%s': %w", org, err)
+	}
+
+	return SettingsDiff(details.Settings, settings), nil
+}
@@ -0,0 +1,14 @@
+package processors
+
+import (
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// ProcessProbeOrganization processes org on its own, ahead of the other organizations, for
+// --probe. It goes through the same tracing, timeout and result normalization as an organization
+// handed to the sequential or concurrent processor, so the probe behaves like the run that follows.
+func ProcessProbeOrganization(processor OrganizationProcessor, org string, timeout time.Duration) types.ProcessingResult {
+	return normalizeResult(processWithTimeout(processor, org, timeout))
+}
@@ -0,0 +1,81 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func TestProbeFeatureSupport(t *testing.T) {
+	origCreate, origDelete, origDetails := createSecurityConfiguration, deleteSecurityConfiguration, fetchConfigurationDetails
+	t.Cleanup(func() {
+		createSecurityConfiguration, deleteSecurityConfiguration, fetchConfigurationDetails = origCreate, origDelete, origDetails
+	})
+
+	settings := map[string]interface{}{
+		"secret_scanning":                       "enabled",
+		"secret_scanning_non_provider_patterns": "enabled",
+	}
+
+	tests := []struct {
+		name         string
+		readBack     map[string]interface{}
+		readBackErr  error
+		wantInactive []string
+		wantErr      bool
+	}{
+		{
+			name:     "every setting active",
+			readBack: map[string]interface{}{"secret_scanning": "enabled", "secret_scanning_non_provider_patterns": "enabled"},
+		},
+		{
+			name:         "setting silently dropped",
+			readBack:     map[string]interface{}{"secret_scanning": "enabled", "secret_scanning_non_provider_patterns": "disabled"},
+			wantInactive: []string{"secret_scanning_non_provider_patterns"},
+		},
+		{
+			name:         "setting missing from read-back",
+			readBack:     map[string]interface{}{"secret_scanning": "enabled"},
+			wantInactive: []string{"secret_scanning_non_provider_patterns"},
+		},
+		{
+			name:        "read-back failure still deletes",
+			readBackErr: errors.New("HTTP 500"),
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createSecurityConfiguration = func(_ context.Context, org, name, description string, settings map[string]interface{}, enforcement string) (int, error) {
+				return 42, nil
+			}
+			deleted := 0
+			deleteSecurityConfiguration = func(_ context.Context, org string, configID int) error {
+				if configID == 42 {
+					deleted++
+				}
+				return nil
+			}
+			fetchConfigurationDetails = func(_ context.Context, org string, configID int) (*types.SecurityConfigurationDetails, error) {
+				if tt.readBackErr != nil {
+					return nil, tt.readBackErr
+				}
+				return &types.SecurityConfigurationDetails{ID: configID, Settings: tt.readBack}, nil
+			}
+
+			inactive, err := ProbeFeatureSupport("reference-org", settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(inactive, tt.wantInactive) {
+				t.Errorf("inactive = %v, want %v", inactive, tt.wantInactive)
+			}
+			if deleted != 1 {
+				t.Errorf("probe configuration deleted %d times, want 1", deleted)
+			}
+		})
+	}
+}
@@ -16,10 +16,12 @@ type SequentialProcessor struct {
 	organizations []string
 	processor     OrganizationProcessor
 	delay         int
-	progressBar   *pterm.ProgressbarPrinter
-	successCount  int
-	skippedCount  int
-	errorCount    int
+	progress      *ui.Progress
+	summary       types.ProcessingSummary
+	stopErr       error
+	authFailures  authFailureTracker
+	failures      failureLog
+	orgTimeout    time.Duration
 }
 
 // NewSequentialProcessor creates a new sequential processor with optional delay
@@ -28,79 +30,96 @@ func NewSequentialProcessor(organizations []string, processor OrganizationProces
 		organizations: organizations,
 		processor:     processor,
 		delay:         delay,
+		authFailures:  authFailureTracker{threshold: DefaultAuthFailureThreshold},
 	}
 }
 
+// WithOrgTimeout gives up on an organization whose processing takes longer than timeout, recording
+// a types.OrgTimeoutError for it and moving on to the next one. A zero timeout waits indefinitely.
+func (sp *SequentialProcessor) WithOrgTimeout(timeout time.Duration) *SequentialProcessor {
+	sp.orgTimeout = timeout
+	return sp
+}
+
 // Process executes the organization processing sequentially with optional delay between orgs
-func (sp *SequentialProcessor) Process() (successCount, skippedCount, errorCount int) {
+func (sp *SequentialProcessor) Process() types.ProcessingSummary {
 	totalOrgs := len(sp.organizations)
 	if totalOrgs == 0 {
-		return 0, 0, 0
+		return types.ProcessingSummary{}
 	}
 
 	// Create progress bar
-	progressBar, _ := pterm.DefaultProgressbar.WithTotal(totalOrgs).WithTitle("Processing organizations").Start()
-	sp.progressBar = progressBar
+	sp.progress = ui.StartProgress(totalOrgs, "Processing organizations")
 
 	// Process each organization sequentially
 	for i, org := range sp.organizations {
 		// Add delay between organizations (not before the first one)
 		if i > 0 && sp.delay > 0 {
 			for remaining := sp.delay; remaining > 0; remaining-- {
-				sp.progressBar.UpdateTitle(fmt.Sprintf("Waiting %d seconds before processing next organization...", remaining))
+				sp.progress.UpdateTitle(fmt.Sprintf("Waiting %d seconds before processing next organization...", remaining))
 				time.Sleep(time.Second)
 			}
 		}
 
-		// Increment before processing so the bar shows 1-based progress (e.g. "1/5")
-		sp.progressBar.Increment()
-		sp.progressBar.UpdateTitle(fmt.Sprintf("Processing %s", org))
+		// Increment before processing so the bar shows 1-based progress (e.g. "1/5"), after the
+		// title so plain output names the organization
+		sp.progress.UpdateTitle(fmt.Sprintf("Processing %s", org))
+		sp.progress.Increment()
 
 		// Process the organization
-		result := sp.processor.ProcessOrganization(org)
+		result := normalizeResult(processWithTimeout(sp.processor, org, sp.orgTimeout))
+		authLimitReached := sp.authFailures.record(result)
+		ui.LogConfigurationResults(result)
+		sp.summary.Add(result)
 
 		if result.Success {
-			sp.successCount++
-			sp.progressBar.UpdateTitle(fmt.Sprintf("Processed %s", result.Organization))
+			sp.progress.UpdateTitle(fmt.Sprintf("Processed %s", result.Organization))
 			ui.LogOrgSuccess(result.Organization)
 		} else if result.Skipped {
-			sp.skippedCount++
-			sp.progressBar.UpdateTitle(fmt.Sprintf("Skipped %s", result.Organization))
+			sp.progress.UpdateTitle(fmt.Sprintf("Skipped %s", result.Organization))
 			if result.SkipReason != "" {
 				ui.LogWarningf("%s", result.SkipReason)
 			}
 		} else if result.Error != nil {
-			sp.errorCount++
-			sp.progressBar.UpdateTitle(fmt.Sprintf("Processed %s", result.Organization))
-			// Check if this is a "configuration exists" error
-			var configExistsErr *types.ConfigurationExistsError
-			if errors.As(result.Error, &configExistsErr) {
-				ui.LogWarningf("Configuration '%s' already exists in organization '%s', skipping", configExistsErr.ConfigName, result.Organization)
-				sp.skippedCount++
-				sp.errorCount-- // Don't count this as an error
+			sp.progress.UpdateTitle(fmt.Sprintf("Processed %s", result.Organization))
+			// Check if this is a Dependabot unavailable error (422)
+			var dependabotErr *types.DependabotUnavailableError
+			if errors.As(result.Error, &dependabotErr) {
+				pterm.Error.Printf("Dependabot feature unavailable: %v\n", result.Error)
+				pterm.Error.Println("Stopping processing of remaining organizations due to Dependabot unavailability.")
+				pterm.Error.Println("Please remove Dependabot settings from your configuration or enable Dependabot on your GHES instance.")
+				sp.stop(&types.StopConditionError{Reason: dependabotErr.Error()}, sp.organizations[i+1:])
+				return sp.summary
+			} else if stopErr := stopConditionOf(result.Error); stopErr != nil {
+				pterm.Error.Printf("Stopping processing of remaining organizations: %s\n", stopErr.Reason)
+				sp.stop(stopErr, sp.organizations[i+1:])
+				return sp.summary
+			} else if authLimitReached {
+				pterm.Error.Printf("Authentication failed for %d consecutive organizations — the token may be expired or lacks access.\n", sp.authFailures.consecutive)
+				pterm.Error.Println("Stopping processing of remaining organizations. Run 'gh auth status' to check your token.")
+				sp.stop(sp.authFailures.stopError(), sp.organizations[i+1:])
+				return sp.summary
 			} else {
-				// Check if this is a Dependabot unavailable error (422)
-				var dependabotErr *types.DependabotUnavailableError
-				if errors.As(result.Error, &dependabotErr) {
-					pterm.Error.Printf("Dependabot feature unavailable: %v\n", result.Error)
-					pterm.Error.Println("Stopping processing of remaining organizations due to Dependabot unavailability.")
-					pterm.Error.Println("Please remove Dependabot settings from your configuration or enable Dependabot on your GHES instance.")
-
-					// Add remaining orgs as skipped
-					remainingOrgs := totalOrgs - (i + 1)
-					sp.skippedCount += remainingOrgs
-					sp.progressBar.Add(remainingOrgs)
-					sp.progressBar.Stop()
-
-					return sp.successCount, sp.skippedCount, sp.errorCount
-				} else {
-					pterm.Error.Printf("Failed to process organization '%s': %v\n", result.Organization, result.Error)
-				}
+				sp.failures.report(result)
 			}
 		}
 
 	}
 
-	progressBar.Stop()
-	return sp.successCount, sp.skippedCount, sp.errorCount
+	sp.progress.Stop()
+	return sp.summary
+}
+
+// stop records why processing was aborted and marks the remaining organizations as skipped
+func (sp *SequentialProcessor) stop(err error, remainingOrgs []string) {
+	sp.stopErr = err
+	skipUnprocessed(&sp.summary, remainingOrgs)
+	sp.progress.Add(len(remainingOrgs))
+	sp.progress.Stop()
+}
+
+// StopError returns the error that aborted processing before all organizations were handled,
+// or nil if processing ran to completion.
+func (sp *SequentialProcessor) StopError() error {
+	return sp.stopErr
 }
@@ -1,7 +1,9 @@
 package processors
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -25,7 +27,7 @@ type fakeProcessor struct {
 	calls []string
 }
 
-func (f *fakeProcessor) ProcessOrganization(org string) types.ProcessingResult {
+func (f *fakeProcessor) ProcessOrganization(ctx context.Context, org string) types.ProcessingResult {
 	f.mu.Lock()
 	f.calls = append(f.calls, org)
 	f.mu.Unlock()
@@ -46,7 +48,8 @@ func (f *fakeProcessor) callsSnapshot() []string {
 
 func TestSequentialProcessor_EmptyOrgs(t *testing.T) {
 	p := NewSequentialProcessor(nil, &fakeProcessor{}, 0)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 0 || sk != 0 || e != 0 {
 		t.Errorf("expected all zero counts, got success=%d skipped=%d errors=%d", s, sk, e)
 	}
@@ -59,7 +62,8 @@ func TestSequentialProcessor_CountsSuccessSkipAndError(t *testing.T) {
 		"c": {Error: errors.New("boom")},
 	}}
 	p := NewSequentialProcessor([]string{"a", "b", "c"}, fp, 0)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 1 || sk != 1 || e != 1 {
 		t.Errorf("counts: success=%d skipped=%d errors=%d; want 1/1/1", s, sk, e)
 	}
@@ -74,7 +78,8 @@ func TestSequentialProcessor_ConfigurationExistsTreatedAsSkip(t *testing.T) {
 		"b": {Success: true},
 	}}
 	p := NewSequentialProcessor([]string{"a", "b"}, fp, 0)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 1 || sk != 1 || e != 0 {
 		t.Errorf("ConfigurationExistsError should be counted as skip; got success=%d skipped=%d errors=%d", s, sk, e)
 	}
@@ -87,7 +92,8 @@ func TestSequentialProcessor_DependabotUnavailableStopsProcessing(t *testing.T)
 		// c and d should not be called but are recorded as skipped.
 	}}
 	p := NewSequentialProcessor([]string{"a", "b", "c", "d"}, fp, 0)
-	s, sk, e := p.Process()
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
 	if s != 1 {
 		t.Errorf("success: got %d, want 1", s)
 	}
@@ -106,6 +112,26 @@ func TestSequentialProcessor_DependabotUnavailableStopsProcessing(t *testing.T)
 	}
 }
 
+func TestSequentialProcessor_StopConditionStopsProcessing(t *testing.T) {
+	budgetErr := &types.StopConditionError{Reason: "the budget of 10 API calls set by --max-api-calls was reached"}
+	fp := &fakeProcessor{results: map[string]types.ProcessingResult{
+		"b": {Error: fmt.Errorf("failed to fetch security configurations: %w", budgetErr)},
+	}}
+	p := NewSequentialProcessor([]string{"a", "b", "c"}, fp, 0)
+	summary := p.Process()
+	if summary.Success != 1 || summary.Error != 1 || summary.Skipped != 1 {
+		t.Errorf("got success=%d errors=%d skipped=%d, want 1, 1, 1", summary.Success, summary.Error, summary.Skipped)
+	}
+	if !errors.Is(p.StopError(), budgetErr) {
+		t.Errorf("StopError() = %v, want the budget stop condition", p.StopError())
+	}
+	for _, called := range fp.callsSnapshot() {
+		if called == "c" {
+			t.Error("processor should not have been called after the stop condition")
+		}
+	}
+}
+
 func TestSequentialProcessor_DelayBetweenOrgs(t *testing.T) {
 	fp := &fakeProcessor{}
 	// 1-second delay between 2 orgs -> expect at least ~1s elapsed.
@@ -117,3 +143,110 @@ func TestSequentialProcessor_DelayBetweenOrgs(t *testing.T) {
 		t.Errorf("expected delay between orgs to take ~1s, got %s", elapsed)
 	}
 }
+
+func TestSequentialProcessor_AuthFailureStormStopsProcessing(t *testing.T) {
+	authErr := &types.AuthError{Message: "Bad credentials (HTTP 401)"}
+	fp := &fakeProcessor{results: map[string]types.ProcessingResult{
+		"a": {Success: true},
+		"b": {Error: authErr},
+		"c": {Error: authErr},
+		"d": {Error: authErr},
+		// e and f come after the token expired and must not be called.
+	}}
+	p := NewSequentialProcessor([]string{"a", "b", "c", "d", "e", "f"}, fp, 0)
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
+	if s != 1 || e != DefaultAuthFailureThreshold || sk != 2 {
+		t.Errorf("counts: success=%d skipped=%d errors=%d; want 1/2/%d", s, sk, e, DefaultAuthFailureThreshold)
+	}
+	var stopAuthErr *types.AuthError
+	if !errors.As(p.StopError(), &stopAuthErr) {
+		t.Errorf("StopError() = %v, want an AuthError", p.StopError())
+	}
+	for _, called := range fp.callsSnapshot() {
+		if called == "e" || called == "f" {
+			t.Errorf("processor should not have been called for %q after the auth failure storm", called)
+		}
+	}
+}
+
+func TestSequentialProcessor_NonConsecutiveAuthFailuresContinue(t *testing.T) {
+	authErr := &types.AuthError{Message: "Bad credentials (HTTP 401)"}
+	fp := &fakeProcessor{results: map[string]types.ProcessingResult{
+		"a": {Error: authErr},
+		"b": {Error: authErr},
+		"c": {Success: true},
+		"d": {Error: authErr},
+		"e": {Error: authErr},
+	}}
+	p := NewSequentialProcessor([]string{"a", "b", "c", "d", "e"}, fp, 0)
+	summary := p.Process()
+	s, sk, e := summary.Success, summary.Skipped, summary.Error
+	if s != 1 || sk != 0 || e != 4 {
+		t.Errorf("counts: success=%d skipped=%d errors=%d; want 1/0/4", s, sk, e)
+	}
+	if p.StopError() != nil {
+		t.Errorf("StopError() = %v, want nil when failures are not consecutive", p.StopError())
+	}
+}
+
+// assertSummaryMatchesResults checks that the summary counts agree with its results slice and
+// that every organization has exactly one result.
+func assertSummaryMatchesResults(t *testing.T, summary types.ProcessingSummary, orgs []string) {
+	t.Helper()
+	var success, skipped, errored int
+	seen := make(map[string]int)
+	for _, r := range summary.Results {
+		seen[r.Organization]++
+		switch {
+		case r.Success:
+			success++
+		case r.Skipped:
+			skipped++
+		case r.Error != nil:
+			errored++
+		}
+	}
+	if success != summary.Success || skipped != summary.Skipped || errored != summary.Error {
+		t.Errorf("summary counts %d/%d/%d do not match results %d/%d/%d", summary.Success, summary.Skipped, summary.Error, success, skipped, errored)
+	}
+	if len(summary.Results) != len(orgs) {
+		t.Errorf("got %d results, want one per organization (%d)", len(summary.Results), len(orgs))
+	}
+	for _, org := range orgs {
+		if seen[org] != 1 {
+			t.Errorf("organization %q has %d results, want 1", org, seen[org])
+		}
+	}
+}
+
+func TestSequentialProcessor_SummaryMatchesResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		orgs    []string
+		results map[string]types.ProcessingResult
+	}{
+		{
+			name: "mixed outcomes",
+			orgs: []string{"a", "b", "c", "d"},
+			results: map[string]types.ProcessingResult{
+				"b": {Skipped: true, SkipReason: "not an owner"},
+				"c": {Error: errors.New("boom")},
+				"d": {Error: &types.ConfigurationExistsError{ConfigName: "cfg", OrgName: "d"}},
+			},
+		},
+		{
+			name: "stopped early",
+			orgs: []string{"a", "b", "c", "d"},
+			results: map[string]types.ProcessingResult{
+				"b": {Error: &types.DependabotUnavailableError{Feature: "alerts", OrgName: "b"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSequentialProcessor(tt.orgs, &fakeProcessor{results: tt.results}, 0)
+			assertSummaryMatchesResults(t, p.Process(), tt.orgs)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package processors
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/callmegreg/gh-security-config/internal/telemetry"
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// stoppedSkipReason is recorded for organizations that were never processed because the run
+// stopped early
+const stoppedSkipReason = "Skipping organization '%s': processing stopped before it was reached"
+
+// normalizeResult converts a "configuration exists" error into a skip so that the result's
+// outcome matches how it is counted. A configuration that already has the requested settings is
+// marked as already compliant, so it can be told apart from one that would need changes.
+func normalizeResult(result types.ProcessingResult) types.ProcessingResult {
+	var configExistsErr *types.ConfigurationExistsError
+	if errors.As(result.Error, &configExistsErr) {
+		result.Skipped = true
+		result.AlreadyCompliant = configExistsErr.Compliant
+		if configExistsErr.Compliant {
+			result.SkipReason = fmt.Sprintf("Configuration '%s' already exists in organization '%s' with the requested settings (already compliant), skipping", configExistsErr.ConfigName, result.Organization)
+		} else {
+			result.SkipReason = fmt.Sprintf("Configuration '%s' already exists in organization '%s' with different settings, skipping", configExistsErr.ConfigName, result.Organization)
+		}
+		result.Error = nil
+	}
+	return result
+}
+
+// skipUnprocessed records a skipped result for every organization that has no result yet
+func skipUnprocessed(summary *types.ProcessingSummary, organizations []string) {
+	processed := make(map[string]bool, len(summary.Results))
+	for _, result := range summary.Results {
+		processed[result.Organization] = true
+	}
+	for _, org := range organizations {
+		if !processed[org] {
+			summary.Add(types.ProcessingResult{Organization: org, Skipped: true, Unprocessed: true, SkipReason: fmt.Sprintf(stoppedSkipReason, org)})
+		}
+	}
+}
+
+// SortResultsByInput reorders summary.Results to follow organizations, the order the organizations
+// were targeted in. Organizations finish in any order when processed concurrently; this restores
+// the targeted order for everything read from the results afterwards. Results for organizations
+// not in the list keep their relative order after the others.
+func SortResultsByInput(summary *types.ProcessingSummary, organizations []string) {
+	position := make(map[string]int, len(organizations))
+	for i, org := range organizations {
+		if _, seen := position[org]; !seen {
+			position[org] = i
+		}
+	}
+	rank := func(org string) int {
+		if i, ok := position[org]; ok {
+			return i
+		}
+		return len(organizations)
+	}
+	slices.SortStableFunc(summary.Results, func(a, b types.ProcessingResult) int {
+		return cmp.Compare(rank(a.Organization), rank(b.Organization))
+	})
+}
+
+// stopConditionOf returns the stop condition carried by a processing error, such as an exhausted
+// API call budget, or nil if the error should not stop the run
+func stopConditionOf(err error) *types.StopConditionError {
+	var stopErr *types.StopConditionError
+	if errors.As(err, &stopErr) {
+		return stopErr
+	}
+	return nil
+}
+
+// processTraced runs processor for one organization inside an organization span when the run is
+// being traced
+func processTraced(ctx context.Context, processor OrganizationProcessor, org string) types.ProcessingResult {
+	span := telemetry.StartSpan(telemetry.SpanOrganization, map[string]interface{}{telemetry.AttrOrg: org})
+	result := processor.ProcessOrganization(ctx, org)
+	switch {
+	case result.Success:
+		span.SetAttribute(telemetry.AttrOutcome, "success")
+	case result.Skipped:
+		span.SetAttribute(telemetry.AttrOutcome, "skipped")
+	default:
+		span.SetAttribute(telemetry.AttrOutcome, "error")
+	}
+	span.End(result.Error)
+	return result
+}
+
+// processWithTimeout runs processTraced with a context that is cancelled once timeout has passed.
+// From then on no further request is sent for the organization and the one in flight is stopped,
+// so the organization's processing returns and is reported as timed out. A zero timeout waits for
+// as long as processing takes.
+func processWithTimeout(processor OrganizationProcessor, org string, timeout time.Duration) types.ProcessingResult {
+	if timeout <= 0 {
+		return processTraced(context.Background(), processor, org)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result := processTraced(ctx, processor, org)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return types.ProcessingResult{Organization: org, Error: &types.OrgTimeoutError{OrgName: org, Timeout: timeout}}
+	}
+	return result
+}
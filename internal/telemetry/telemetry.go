@@ -0,0 +1,329 @@
+// Package telemetry records an OpenTelemetry trace of a run and exports it over OTLP/HTTP (JSON)
+// when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. Without either
+// variable every function is a no-op. It depends only on the standard library, so api and
+// processors can use it without import cycles.
+//
+// A run produces one root span per command. Organization and API call spans are children of the
+// root span; API call spans carry the organization as an attribute.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span and attribute names, documented in the README
+const (
+	AttrCommand    = "gh_security_config.command"
+	AttrChangeRef  = "gh_security_config.change_ref"
+	AttrExitCode   = "gh_security_config.exit_code"
+	AttrOrg        = "gh_security_config.org"
+	AttrOutcome    = "gh_security_config.outcome"
+	AttrHTTPMethod = "http.request.method"
+	AttrEndpoint   = "url.path"
+
+	SpanOrganization = "organization"
+	SpanAPICall      = "api_call"
+)
+
+// exportTimeout bounds how long the end of a run waits for the collector
+const exportTimeout = 10 * time.Second
+
+// Span is one timed operation in the trace. A nil *Span is valid and ignores every call, which
+// is what StartSpan returns when tracing is off.
+type Span struct {
+	mu       sync.Mutex
+	name     string
+	spanID   string
+	parentID string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	errMsg   string
+}
+
+// SetAttribute records a string, bool or integer attribute on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+// End finishes the span, marking it as failed when err is non-nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end = time.Now()
+	if err != nil {
+		s.errMsg = err.Error()
+	}
+}
+
+// tracer holds the spans of the current run
+type tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	headers  map[string]string
+	service  string
+	traceID  string
+	root     *Span
+	spans    []*Span
+}
+
+var current *tracer
+
+// httpClient sends the export request. It is a variable so tests can point it at a test server.
+var httpClient = &http.Client{Timeout: exportTimeout}
+
+// Start begins a trace for command when an OTLP endpoint is configured. It must be called before
+// any other function in the package for spans to be recorded.
+func Start(command string) {
+	endpoint := tracesEndpoint()
+	if endpoint == "" {
+		current = nil
+		return
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "gh-security-config"
+	}
+	t := &tracer{
+		endpoint: endpoint,
+		headers:  parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		service:  service,
+		traceID:  randomHex(16),
+	}
+	t.root = t.newSpan(command, "")
+	t.root.attrs[AttrCommand] = command
+	current = t
+}
+
+// Enabled reports whether the current run is being traced
+func Enabled() bool {
+	return current != nil
+}
+
+// SetRunAttribute records an attribute on the command's root span. It does nothing when tracing is off.
+func SetRunAttribute(key string, value interface{}) {
+	if t := current; t != nil {
+		t.root.SetAttribute(key, value)
+	}
+}
+
+// StartSpan starts a child of the command's root span. It returns nil when tracing is off.
+func StartSpan(name string, attrs map[string]interface{}) *Span {
+	t := current
+	if t == nil {
+		return nil
+	}
+	span := t.newSpan(name, t.root.spanID)
+	for key, value := range attrs {
+		span.attrs[key] = value
+	}
+	return span
+}
+
+// Finish ends the root span with the run's exit code and exports the trace. Export failures are
+// returned so the caller can report them; they never change the run's outcome.
+func Finish(exitCode int, err error) error {
+	t := current
+	if t == nil {
+		return nil
+	}
+	current = nil
+	t.root.SetAttribute(AttrExitCode, exitCode)
+	t.root.End(err)
+	return t.export()
+}
+
+func (t *tracer) newSpan(name, parentID string) *Span {
+	span := &Span{
+		name:     name,
+		spanID:   randomHex(8),
+		parentID: parentID,
+		start:    time.Now(),
+		attrs:    make(map[string]interface{}),
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return span
+}
+
+// tracesEndpoint returns the URL traces are posted to, following the OTLP exporter conventions:
+// the signal-specific variable is used as is, and the generic one gets /v1/traces appended
+func tracesEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimRight(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// parseHeaders parses OTEL_EXPORTER_OTLP_HEADERS, a comma-separated list of key=value pairs
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// export posts the recorded spans to the collector as an OTLP/HTTP JSON request
+func (t *tracer) export() error {
+	body, err := json.Marshal(t.payload())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build trace export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export trace: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export trace: collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of the trace, limited to the fields this package sets
+type (
+	otlpPayload struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusOK         = 1
+	statusError      = 2
+)
+
+func (t *tracer) payload() otlpPayload {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, span := range t.spans {
+		span.mu.Lock()
+		end := span.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		kind := spanKindInternal
+		if span.name == SpanAPICall {
+			kind = spanKindClient
+		}
+		status := otlpStatus{Code: statusOK}
+		if span.errMsg != "" {
+			status = otlpStatus{Code: statusError, Message: span.errMsg}
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              kind,
+			StartTimeUnixNano: fmt.Sprintf("%d", span.start.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprintf("%d", end.UnixNano()),
+			Attributes:        encodeAttributes(span.attrs),
+			Status:            status,
+		})
+		span.mu.Unlock()
+	}
+
+	return otlpPayload{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes(map[string]interface{}{"service.name": t.service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/callmegreg/gh-security-config"}, Spans: spans}},
+	}}}
+}
+
+// encodeAttributes converts attributes to OTLP key/value pairs, sorted by key
+func encodeAttributes(attrs map[string]interface{}) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		var value map[string]interface{}
+		switch v := attrs[key].(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			// OTLP JSON encodes 64-bit integers as strings
+			value = map[string]interface{}{"intValue": fmt.Sprintf("%d", v)}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+		}
+		out = append(out, otlpAttribute{Key: key, Value: value})
+	}
+	return out
+}
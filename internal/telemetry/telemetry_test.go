@@ -0,0 +1,118 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceExport(t *testing.T) {
+	var received otlpPayload
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("export path = %q, want /v1/traces", r.URL.Path)
+		}
+		gotHeader = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid OTLP JSON: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer token")
+
+	Start("gh-security-config apply")
+	if !Enabled() {
+		t.Fatal("Enabled() = false with an endpoint configured")
+	}
+	orgSpan := StartSpan(SpanOrganization, map[string]interface{}{AttrOrg: "octo"})
+	apiSpan := StartSpan(SpanAPICall, map[string]interface{}{AttrHTTPMethod: "GET", AttrEndpoint: "/orgs/octo"})
+	apiSpan.End(errors.New("HTTP 404"))
+	orgSpan.SetAttribute(AttrOutcome, "skipped")
+	orgSpan.End(nil)
+
+	if err := Finish(2, errors.New("some organizations failed")); err != nil {
+		t.Fatalf("Finish() error: %v", err)
+	}
+	if Enabled() {
+		t.Error("Enabled() = true after Finish")
+	}
+	if gotHeader != "Bearer token" {
+		t.Errorf("Authorization header = %q, want the OTEL_EXPORTER_OTLP_HEADERS value", gotHeader)
+	}
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+	root, org, call := spans[0], spans[1], spans[2]
+	if root.ParentSpanID != "" || root.Status.Code != statusError {
+		t.Errorf("root span = %+v, want no parent and an error status", root)
+	}
+	if attr(root, AttrExitCode) != "2" || attr(root, AttrCommand) != "gh-security-config apply" {
+		t.Errorf("root attributes = %+v", root.Attributes)
+	}
+	for _, span := range []otlpSpan{org, call} {
+		if span.ParentSpanID != root.SpanID || span.TraceID != root.TraceID {
+			t.Errorf("span %q is not a child of the root span", span.Name)
+		}
+	}
+	if attr(org, AttrOrg) != "octo" || attr(org, AttrOutcome) != "skipped" || org.Status.Code != statusOK {
+		t.Errorf("organization span = %+v", org)
+	}
+	if call.Kind != spanKindClient || call.Status.Message != "HTTP 404" || attr(call, AttrHTTPMethod) != "GET" {
+		t.Errorf("API call span = %+v", call)
+	}
+}
+
+func TestTracingDisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	original := httpClient
+	t.Cleanup(func() { httpClient = original })
+	httpClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("unexpected export request with tracing off")
+		return nil, errors.New("unexpected request")
+	})}
+
+	Start("gh-security-config generate")
+	if Enabled() {
+		t.Fatal("Enabled() = true without an endpoint")
+	}
+	span := StartSpan(SpanOrganization, nil)
+	span.SetAttribute(AttrOrg, "octo")
+	span.End(nil)
+	if err := Finish(0, nil); err != nil {
+		t.Errorf("Finish() error: %v", err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := parseHeaders("api-key=abc, x-team = sec ,malformed,=empty")
+	if len(got) != 2 || got["api-key"] != "abc" || got["x-team"] != "sec" {
+		t.Errorf("parseHeaders() = %v", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// attr returns the exported value of an attribute, or "" when absent
+func attr(span otlpSpan, key string) string {
+	for _, a := range span.Attributes {
+		if a.Key == key {
+			for _, v := range a.Value {
+				return fmt.Sprint(v)
+			}
+		}
+	}
+	return ""
+}
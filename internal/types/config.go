@@ -1,11 +1,33 @@
 package types
 
+import "time"
+
 // SecurityConfiguration represents a GitHub security configuration
 type SecurityConfiguration struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	TargetType  string `json:"target_type"` // "enterprise" or "organization"
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TargetType  string    `json:"target_type"` // "enterprise" or "organization"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	HTMLURL     string    `json:"html_url"` // Settings page of the configuration; empty for enterprise configurations
+}
+
+// Age returns how long ago the configuration was created, as of now. Configurations without a
+// creation time fall back to their last update.
+func (c SecurityConfiguration) Age(now time.Time) time.Duration {
+	if !c.CreatedAt.IsZero() {
+		return now.Sub(c.CreatedAt)
+	}
+	return now.Sub(c.UpdatedAt)
+}
+
+// DeletionCandidate is a configuration planned for deletion by delete --all-configs, with the
+// details that qualified it when filters were applied
+type DeletionCandidate struct {
+	SecurityConfiguration
+	Age          time.Duration
+	Repositories int // Repositories the configuration is attached to; -1 when not looked up
 }
 
 // SecurityConfigurationDetails represents detailed security configuration information
@@ -14,14 +36,98 @@ type SecurityConfigurationDetails struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	TargetType  string                 `json:"target_type"` // "enterprise" or "organization"
-	Settings    map[string]interface{} `json:"-"`           // Will be populated separately
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Enforcement string                 `json:"enforcement"` // "enforced" or "unenforced"
+	HTMLURL     string                 `json:"html_url"`    // Settings page of the configuration; empty for enterprise configurations
+	Settings    map[string]interface{} `json:"-"`           // Feature toggles, populated separately; excludes enforcement
+
+	// Fields of the API response that the extension does not model, with their values. They are
+	// not copied, compared or displayed; schema drift reports them.
+	UnmodeledSettings map[string]interface{} `json:"-"`
+}
+
+// ConfigurationResult records the outcome for one configuration when an organization's
+// processing touches more than one configuration
+type ConfigurationResult struct {
+	Name    string
+	Success bool
+	Skipped bool
+	Error   error
 }
 
 // ProcessingResult represents the result of processing a single organization
 type ProcessingResult struct {
-	Organization string
-	Success      bool
-	Skipped      bool
-	SkipReason   string
-	Error        error
+	Organization     string
+	Enterprise       string // Enterprise that listed the organization, set only when the run spans several enterprises
+	Success          bool
+	Skipped          bool
+	SkipReason       string
+	Unprocessed      bool // Skipped because processing stopped before the organization was reached
+	AlreadyCompliant bool // Skipped because the configuration already existed with the requested settings
+	Error            error
+	ConfigID         int                           // ID of the configuration created or applied in the organization; 0 when unknown
+	Configurations   []ConfigurationResult         // Per-configuration outcomes, set only when more than one configuration is involved
+	ReplacedDefaults []string                      // Other configurations that were the default for new repositories before this run set its own
+	DefaultSet       *bool                         // Whether the run set the configuration as default; false when it already was, nil when no default was requested
+	Attachment       *Attachment                   // The attach request for the organization; nil when nothing was attached
+	SettingsSynced   *bool                         // Whether apply updated the configuration's settings first; nil when no sync was requested
+	Before           *SecurityConfigurationDetails // The organization's configuration as read before modify updated it; nil for other commands
+	After            *SecurityConfigurationDetails // The configuration returned by modify's update; nil when not updated or the response carried none
+	HookOutput       string                        // Combined output of the --post-hook command
+	HookError        error                         // Why the --post-hook command failed; the organization still counts as a success
+}
+
+// ProcessingSummary aggregates the per-organization results of a processing run. The counts
+// always agree with Results, so commands can derive any report from this single value.
+type ProcessingSummary struct {
+	Results []ProcessingResult
+	Success int
+	Skipped int
+	Error   int
+}
+
+// Add records a result and increments the count matching its outcome
+func (s *ProcessingSummary) Add(result ProcessingResult) {
+	s.Results = append(s.Results, result)
+	switch {
+	case result.Success:
+		s.Success++
+	case result.Skipped:
+		s.Skipped++
+	case result.Error != nil:
+		s.Error++
+	}
+}
+
+// ErrorGroup is a set of organizations that failed with the same error. Organization-specific
+// parts of the message, such as the organization name and configuration IDs, are replaced so that
+// the same failure in every organization has one message.
+type ErrorGroup struct {
+	Category      string // What kind of failure it is, e.g. "HTTP 403" or "authentication"
+	Message       string
+	Hint          string // How to fix it; empty when there is no known remedy
+	Organizations []string
+}
+
+// RateLimit represents the core REST API rate limit for the authenticated user
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+// DefaultConfiguration represents an entry from an organization's default configurations list
+type DefaultConfiguration struct {
+	DefaultForNewRepos string                `json:"default_for_new_repos"` // "all", "public", or "private_and_internal"
+	Configuration      SecurityConfiguration `json:"configuration"`
+}
+
+// OrgDefaults summarizes which configuration new repositories receive by default in an
+// organization. An empty name, and a zero ID, means no default is set for that visibility.
+type OrgDefaults struct {
+	Public               string
+	PublicID             int
+	PrivateAndInternal   string
+	PrivateAndInternalID int
 }
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProcessingSummary_Add(t *testing.T) {
+	var summary ProcessingSummary
+	summary.Add(ProcessingResult{Organization: "a", Success: true})
+	summary.Add(ProcessingResult{Organization: "b", Skipped: true})
+	summary.Add(ProcessingResult{Organization: "c", Error: errors.New("boom")})
+	summary.Add(ProcessingResult{Organization: "d", Success: true})
+
+	if summary.Success != 2 || summary.Skipped != 1 || summary.Error != 1 {
+		t.Errorf("counts = %d/%d/%d, want 2/1/1", summary.Success, summary.Skipped, summary.Error)
+	}
+	if len(summary.Results) != 4 {
+		t.Fatalf("got %d results, want 4", len(summary.Results))
+	}
+	if summary.Results[2].Organization != "c" {
+		t.Errorf("results should keep insertion order, got %q at index 2", summary.Results[2].Organization)
+	}
+}
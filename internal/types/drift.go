@@ -0,0 +1,13 @@
+package types
+
+// SettingDrift records a setting of an organization's configuration whose value differs from the
+// drift baseline
+type SettingDrift struct {
+	Organization     string `json:"organization"`
+	Configuration    string `json:"configuration"`
+	ConfigurationID  int    `json:"configuration_id"`
+	ConfigurationURL string `json:"configuration_url,omitempty"` // Settings page of the configuration; empty when unknown
+	Setting          string `json:"setting"`
+	Baseline         string `json:"baseline"`
+	Actual           string `json:"actual"`
+}
@@ -1,11 +1,17 @@
 package types
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ConfigurationExistsError represents an error when a security configuration already exists
 type ConfigurationExistsError struct {
 	ConfigName string
 	OrgName    string
+	Compliant  bool // The existing configuration already has the requested settings and enforcement
 }
 
 func (e *ConfigurationExistsError) Error() string {
@@ -21,3 +27,169 @@ type DependabotUnavailableError struct {
 func (e *DependabotUnavailableError) Error() string {
 	return fmt.Sprintf("Dependabot %s is not available for organization '%s'. This feature may not be enabled on your GitHub Enterprise Server instance", e.Feature, e.OrgName)
 }
+
+// ErrOperationCancelled is returned when the user declines the final confirmation prompt
+var ErrOperationCancelled = errors.New("operation cancelled by user")
+
+// ErrOrganizationNotFound is wrapped by errors for an organization that does not exist on the
+// run's host, which usually means it lives on another host
+var ErrOrganizationNotFound = errors.New("was not found")
+
+// ErrNoConfigurations is wrapped by errors for an organization that holds no security
+// configurations for an operation to pick from
+var ErrNoConfigurations = errors.New("has no security configurations")
+
+// PartialFailureError represents a run that completed but failed for one or more organizations
+type PartialFailureError struct {
+	Operation string
+	Failed    int
+	Total     int
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%s failed for %d of %d organization(s)", e.Operation, e.Failed, e.Total)
+}
+
+// HookFailureError represents a run in which the --post-hook command failed for one or more
+// organizations that were otherwise processed successfully
+type HookFailureError struct {
+	Failed int
+	Total  int
+}
+
+func (e *HookFailureError) Error() string {
+	return fmt.Sprintf("the post hook failed for %d of %d organization(s)", e.Failed, e.Total)
+}
+
+// WarningsAsErrorsError represents a run that otherwise succeeded but raised warnings while
+// --warnings-as-errors was set
+type WarningsAsErrorsError struct {
+	Warnings int
+}
+
+func (e *WarningsAsErrorsError) Error() string {
+	return fmt.Sprintf("%d warning(s) were raised and --warnings-as-errors is set", e.Warnings)
+}
+
+// AuthError represents an authentication or token scope failure reported by the GitHub API
+type AuthError struct {
+	Message string
+	Err     error
+}
+
+func (e *AuthError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("authentication failed: %s: %v", e.Message, e.Err)
+	}
+	return fmt.Sprintf("authentication failed: %s", e.Message)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// SSORequiredError represents a request refused because the organization enforces SAML single
+// sign-on and the token has not been authorized for it. Authorizing the token fixes it, so it is
+// reported apart from permission failures.
+type SSORequiredError struct {
+	Err error
+}
+
+func (e *SSORequiredError) Error() string {
+	return fmt.Sprintf("the organization enforces SAML single sign-on and the token is not authorized for it: %v", e.Err)
+}
+
+func (e *SSORequiredError) Unwrap() error {
+	return e.Err
+}
+
+// NoAccessError represents a read of an organization's security configurations refused with HTTP
+// 403 or 404 because the token cannot see them, as with a fine-grained token that was not granted
+// the organization or lacks the administration permission. Read-only commands skip the organization.
+type NoAccessError struct {
+	OrgName string
+	Status  int
+	Message string // GitHub's explanation, e.g. "Resource not accessible by personal access token"
+	Err     error
+}
+
+func (e *NoAccessError) Error() string {
+	return fmt.Sprintf("the token cannot read the security configurations of organization '%s' (HTTP %d: %s)", e.OrgName, e.Status, e.Message)
+}
+
+func (e *NoAccessError) Unwrap() error {
+	return e.Err
+}
+
+// StopConditionError represents a run that was aborted before all organizations were processed
+type StopConditionError struct {
+	Reason string
+}
+
+func (e *StopConditionError) Error() string {
+	return fmt.Sprintf("processing aborted before all organizations were processed: %s", e.Reason)
+}
+
+// OrgTimeoutError represents an organization whose processing did not finish within the per-org
+// timeout. Its remaining requests were not sent, but one stopped in flight may have been applied,
+// so the organization's outcome is unknown.
+type OrgTimeoutError struct {
+	OrgName string
+	Timeout time.Duration
+}
+
+func (e *OrgTimeoutError) Error() string {
+	return fmt.Sprintf("processing organization '%s' did not finish within %s; its outcome is unknown, check it before retrying", e.OrgName, e.Timeout)
+}
+
+// TransientError represents a failure that is likely to succeed if the request is retried, such as
+// a server error or a dropped connection
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("transient failure: %v", e.Err)
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// NoTargetsError represents a run with no organizations left to process once the targeting flags
+// and filters were applied
+type NoTargetsError struct {
+	Breakdown TargetBreakdown
+}
+
+func (e *NoTargetsError) Error() string {
+	return fmt.Sprintf("no target organizations remain (%s)", strings.Join(e.Breakdown.Reasons(), ", "))
+}
+
+// PolicyViolationError represents a policy check that found error-severity violations
+type PolicyViolationError struct {
+	Violations int
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("policy check found %d error-severity violation(s)", e.Violations)
+}
+
+// DriftError represents a drift check that found configurations differing from the baseline
+type DriftError struct {
+	Organizations int
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("drift check found %d organization(s) whose configuration differs from the baseline", e.Organizations)
+}
+
+// SchemaDriftError represents a schema drift check that found configuration fields the extension
+// does not model. It is returned only in CI, so a scheduled job notices when GitHub adds settings.
+type SchemaDriftError struct {
+	Keys int
+}
+
+func (e *SchemaDriftError) Error() string {
+	return fmt.Sprintf("schema drift check found %d configuration field(s) this extension does not model", e.Keys)
+}
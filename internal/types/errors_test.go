@@ -48,3 +48,13 @@ func TestDependabotUnavailableError_ErrorsAs(t *testing.T) {
 		t.Errorf("unexpected feature: %q", target.Feature)
 	}
 }
+
+func TestNoTargetsError_MessageListsReasons(t *testing.T) {
+	err := &NoTargetsError{Breakdown: TargetBreakdown{Source: "--org-list", Found: 3, Invalid: 2, SourceOrgRemoved: "source"}}
+	msg := err.Error()
+	for _, want := range []string{"3 organization(s) found via --org-list", "2 invalid", "source organization 'source' removed"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error message %q missing %q", msg, want)
+		}
+	}
+}
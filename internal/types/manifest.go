@@ -0,0 +1,32 @@
+package types
+
+// Manifest declares everything a non-interactive run needs: where the organizations are, which of
+// them to target, and the configuration to create in each of them. It is read from YAML by the run
+// command.
+type Manifest struct {
+	Enterprises                        []string              `yaml:"enterprises" json:"enterprises"`
+	ServerURL                          string                `yaml:"server_url" json:"server_url"`
+	DependabotAlertsAvailable          *bool                 `yaml:"dependabot_alerts_available" json:"dependabot_alerts_available"`
+	DependabotSecurityUpdatesAvailable *bool                 `yaml:"dependabot_security_updates_available" json:"dependabot_security_updates_available"`
+	Targets                            ManifestTargets       `yaml:"targets" json:"targets"`
+	Configuration                      ManifestConfiguration `yaml:"configuration" json:"configuration"`
+	Scope                              string                `yaml:"scope" json:"scope"`
+	SetAsDefault                       *bool                 `yaml:"set_as_default" json:"set_as_default"`
+	Overwrite                          bool                  `yaml:"overwrite" json:"overwrite"`
+	ChangeRef                          string                `yaml:"change_ref" json:"change_ref,omitempty"`
+}
+
+// ManifestTargets selects the organizations a manifest applies to. Exactly one field is set.
+type ManifestTargets struct {
+	Org     string `yaml:"org" json:"org,omitempty"`
+	OrgList string `yaml:"org_list" json:"org_list,omitempty"` // CSV file, relative to the manifest
+	AllOrgs bool   `yaml:"all_orgs" json:"all_orgs,omitempty"`
+}
+
+// ManifestConfiguration is the security configuration a manifest creates. Settings are keyed by
+// API field name and include enforcement.
+type ManifestConfiguration struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Settings    map[string]string `yaml:"settings" json:"settings"`
+}
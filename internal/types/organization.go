@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 // Organization represents a GitHub organization
 type Organization struct {
 	Login string `json:"login"`
@@ -9,5 +11,28 @@ type Organization struct {
 type MembershipStatus struct {
 	IsMember bool
 	IsOwner  bool
-	Role     string
+	Role     string // role reported by the memberships API, e.g. "admin", "member", "billing_manager"
+	State    string // "active", "pending", or "none"
+}
+
+// Problem describes why the membership does not allow managing security configurations, or
+// returns an empty string for an owner
+func (s MembershipStatus) Problem() string {
+	switch {
+	case s.IsOwner:
+		return ""
+	case s.State == "pending" && !s.IsMember:
+		return fmt.Sprintf("membership pending acceptance (role: %s)", s.Role)
+	case !s.IsMember:
+		return "not a member"
+	default:
+		return fmt.Sprintf("role: %s — owner required", s.Role)
+	}
+}
+
+// RunIdentity is the host and account a run sends its API requests to and as, shown before
+// prompting so changes are not made on the wrong host or as the wrong user
+type RunIdentity struct {
+	Host string
+	User string // empty when the authenticated user could not be looked up
 }
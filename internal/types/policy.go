@@ -0,0 +1,60 @@
+package types
+
+import "strings"
+
+// Policy violation severities. Error-severity violations fail the check command.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Policy is a set of rules that organization security configurations must satisfy
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+}
+
+// PolicyRule restricts one setting to a set of allowed values. A rule with Orgs applies only to
+// those organizations; otherwise it applies to every organization checked.
+type PolicyRule struct {
+	Setting     string   `yaml:"setting" json:"setting"`
+	Allowed     []string `yaml:"allowed" json:"allowed"`
+	Severity    string   `yaml:"severity,omitempty" json:"severity,omitempty"` // "error" (the default) or "warning"
+	Orgs        []string `yaml:"orgs,omitempty" json:"orgs,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// AppliesTo reports whether the rule applies to org. Organization names are compared case-insensitively.
+func (r PolicyRule) AppliesTo(org string) bool {
+	if len(r.Orgs) == 0 {
+		return true
+	}
+	for _, candidate := range r.Orgs {
+		if strings.EqualFold(candidate, org) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether value is one of the rule's allowed values
+func (r PolicyRule) Allows(value string) bool {
+	for _, allowed := range r.Allowed {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyViolation records a configuration setting whose value a policy rule does not allow
+type PolicyViolation struct {
+	Organization     string
+	Configuration    string
+	ConfigurationID  int
+	ConfigurationURL string // Settings page of the configuration; empty when unknown
+	Setting          string
+	Actual           string
+	Allowed          []string
+	Severity         string
+	Description      string
+}
@@ -0,0 +1,82 @@
+package types
+
+import "strings"
+
+// Repository represents the repository fields needed to target attachments
+type Repository struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"` // "public", "private", or "internal"
+	Language   string `json:"language"`   // Primary language detected by GitHub; empty when none was detected
+}
+
+// ConfigurationRepository is one entry in the list of repositories a configuration applies to
+type ConfigurationRepository struct {
+	Status     string     `json:"status"` // Attachment status, e.g. "attached", "enforced" or "failed"
+	Repository Repository `json:"repository"`
+}
+
+// RepositoryExclusions holds the repositories that must never have a configuration attached,
+// keyed by lowercase organization and then lowercase repository name
+type RepositoryExclusions map[string]map[string]bool
+
+// Add records org/repo as excluded
+func (e RepositoryExclusions) Add(org, repo string) {
+	org = strings.ToLower(org)
+	if e[org] == nil {
+		e[org] = make(map[string]bool)
+	}
+	e[org][strings.ToLower(repo)] = true
+}
+
+// HasOrg reports whether any repository in org is excluded
+func (e RepositoryExclusions) HasOrg(org string) bool {
+	return len(e[strings.ToLower(org)]) > 0
+}
+
+// Excludes reports whether org/repo is excluded
+func (e RepositoryExclusions) Excludes(org, repo string) bool {
+	return e[strings.ToLower(org)][strings.ToLower(repo)]
+}
+
+// CodeQLLanguages lists the primary languages, as GitHub names them, that CodeQL can analyze.
+// Keep it in step with https://codeql.github.com/docs/codeql-overview/supported-languages-and-frameworks/
+var CodeQLLanguages = []string{"C", "C#", "C++", "Go", "Java", "JavaScript", "Kotlin", "Python", "Ruby", "Swift", "TypeScript"}
+
+// LanguageSet holds primary languages keyed by lowercase name. A nil set includes every repository.
+type LanguageSet map[string]bool
+
+// NewLanguageSet returns a set holding languages
+func NewLanguageSet(languages []string) LanguageSet {
+	set := make(LanguageSet, len(languages))
+	for _, language := range languages {
+		set[strings.ToLower(language)] = true
+	}
+	return set
+}
+
+// Includes reports whether a repository with the given primary language belongs to the set
+func (s LanguageSet) Includes(language string) bool {
+	if s == nil {
+		return true
+	}
+	return s[strings.ToLower(language)]
+}
+
+// RepositoryCounts splits the repositories in scope into those a filter included and excluded
+type RepositoryCounts struct {
+	Included int
+	Excluded int
+}
+
+// Attachment records an attach request for one organization. GitHub answers the request with a
+// 202 once it is queued and enables the repositories afterwards, so an accepted request does not
+// mean the repositories are enabled yet.
+type Attachment struct {
+	Scope         string                 // Scope of the request; "selected" when repositories were listed individually
+	Repositories  int                    // Repositories listed in a "selected" request
+	Accepted      bool                   // Whether GitHub accepted the request
+	Response      map[string]interface{} // Fields of the 202 response body; nil when it had none
+	ExcludedRepos int                    // Repositories in scope that were not attached because they are excluded
+	CodeQLRepos   *RepositoryCounts      // Repositories in scope with and without a CodeQL-supported primary language; nil when not filtering
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SecuritySetting describes one setting of a security configuration that the extension manages
+type SecuritySetting struct {
+	Key    string   // API field name, e.g. "secret_scanning"
+	Label  string   // Human-readable name
+	Values []string // Values the API accepts for the setting
+}
+
+// SecuritySettings is the registry of managed settings, in display order
+var SecuritySettings = []SecuritySetting{
+	{"advanced_security", "GitHub Advanced Security", []string{"enabled", "disabled"}},
+	{"dependabot_alerts", "Dependabot Alerts", []string{"enabled", "disabled", "not_set"}},
+	{"dependabot_security_updates", "Dependabot Security Updates", []string{"enabled", "disabled", "not_set"}},
+	{"secret_scanning", "Secret Scanning", []string{"enabled", "disabled", "not_set"}},
+	{"secret_scanning_push_protection", "Secret Scanning Push Protection", []string{"enabled", "disabled", "not_set"}},
+	{"secret_scanning_non_provider_patterns", "Secret Scanning Non-Provider Patterns", []string{"enabled", "disabled", "not_set"}},
+}
+
+// ConfigurationMetadataKeys are the fields of a configuration response that describe the
+// configuration rather than set a feature
+var ConfigurationMetadataKeys = []string{"id", "name", "description", "target_type", "url", "html_url", "created_at", "updated_at"}
+
+// IsModeledConfigurationKey reports whether the extension knows a field of a configuration
+// response: a managed setting, the enforcement field, or metadata
+func IsModeledConfigurationKey(key string) bool {
+	if _, ok := LookupSecuritySetting(key); ok {
+		return true
+	}
+	return slices.Contains(ConfigurationMetadataKeys, key)
+}
+
+// Enforcement values accepted by the API
+const (
+	EnforcementEnforced   = "enforced"
+	EnforcementUnenforced = "unenforced"
+)
+
+// EnforcementSetting describes the enforcement field. It governs whether repository owners can
+// override the configuration's settings rather than toggling a feature, so it is not part of
+// SecuritySettings and is carried separately from the settings map.
+var EnforcementSetting = SecuritySetting{"enforcement", "Enforcement Status", []string{EnforcementEnforced, EnforcementUnenforced}}
+
+// DescribeEnforcement explains what an enforcement value means for repository owners
+func DescribeEnforcement(enforcement string) string {
+	switch enforcement {
+	case EnforcementEnforced:
+		return "enforced — repo owners cannot override"
+	case EnforcementUnenforced:
+		return "unenforced — repo owners can override"
+	case "":
+		return "not set"
+	default:
+		return enforcement
+	}
+}
+
+// SplitEnforcement returns a copy of settings without the enforcement field, and that field's
+// value ("" when absent). Use it on settings maps read from a file or another source that may mix
+// the two.
+func SplitEnforcement(settings map[string]interface{}) (map[string]interface{}, string) {
+	rest := make(map[string]interface{}, len(settings))
+	enforcement := ""
+	for key, value := range settings {
+		if key == EnforcementSetting.Key {
+			if value != nil {
+				enforcement = fmt.Sprintf("%v", value)
+			}
+			continue
+		}
+		rest[key] = value
+	}
+	return rest, enforcement
+}
+
+// LookupSecuritySetting returns the registry entry for key. The enforcement field is found too, so
+// it can be named wherever a setting key is accepted (e.g., --setting and policy rules).
+func LookupSecuritySetting(key string) (SecuritySetting, bool) {
+	if key == EnforcementSetting.Key {
+		return EnforcementSetting, true
+	}
+	for _, setting := range SecuritySettings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return SecuritySetting{}, false
+}
+
+// SecuritySettingKeys returns the API field names of every managed setting, in display order.
+// The enforcement field is not included.
+func SecuritySettingKeys() []string {
+	keys := make([]string, len(SecuritySettings))
+	for i, setting := range SecuritySettings {
+		keys[i] = setting.Key
+	}
+	return keys
+}
+
+// KnownSettingKeys returns the keys LookupSecuritySetting accepts: every managed setting followed
+// by the enforcement field
+func KnownSettingKeys() []string {
+	return append(SecuritySettingKeys(), EnforcementSetting.Key)
+}
+
+// SettingNotSet is the value of a setting left to repository owners
+const SettingNotSet = "not_set"
+
+// SettingValue returns the value of key in settings as a string. A missing or null value reads as
+// "not_set", which is how older GHES versions report some unset settings.
+func SettingValue(settings map[string]interface{}, key string) string {
+	value, ok := settings[key]
+	if !ok || value == nil {
+		return SettingNotSet
+	}
+	return fmt.Sprintf("%v", value)
+}
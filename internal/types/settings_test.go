@@ -0,0 +1,85 @@
+package types
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestEnforcementIsNotAManagedSetting(t *testing.T) {
+	if slices.Contains(SecuritySettingKeys(), EnforcementSetting.Key) {
+		t.Error("SecuritySettingKeys() should not include enforcement")
+	}
+	if setting, ok := LookupSecuritySetting("enforcement"); !ok || setting.Key != "enforcement" {
+		t.Errorf("LookupSecuritySetting(enforcement) = %+v, %t; want the enforcement entry", setting, ok)
+	}
+	if keys := KnownSettingKeys(); keys[len(keys)-1] != "enforcement" {
+		t.Errorf("KnownSettingKeys() = %v, want enforcement last", keys)
+	}
+}
+
+func TestSplitEnforcement(t *testing.T) {
+	tests := []struct {
+		name            string
+		settings        map[string]interface{}
+		wantSettings    map[string]interface{}
+		wantEnforcement string
+	}{
+		{
+			name:            "mixed",
+			settings:        map[string]interface{}{"secret_scanning": "enabled", "enforcement": "unenforced"},
+			wantSettings:    map[string]interface{}{"secret_scanning": "enabled"},
+			wantEnforcement: "unenforced",
+		},
+		{
+			name:         "no enforcement",
+			settings:     map[string]interface{}{"secret_scanning": "enabled"},
+			wantSettings: map[string]interface{}{"secret_scanning": "enabled"},
+		},
+		{
+			name:         "null enforcement",
+			settings:     map[string]interface{}{"enforcement": nil},
+			wantSettings: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := maps.Clone(tt.settings)
+			settings, enforcement := SplitEnforcement(tt.settings)
+			if !reflect.DeepEqual(settings, tt.wantSettings) || enforcement != tt.wantEnforcement {
+				t.Errorf("SplitEnforcement() = %v, %q; want %v, %q", settings, enforcement, tt.wantSettings, tt.wantEnforcement)
+			}
+			if !reflect.DeepEqual(tt.settings, input) {
+				t.Errorf("SplitEnforcement() modified its input to %v, want %v", tt.settings, input)
+			}
+		})
+	}
+}
+
+func TestSettingValue(t *testing.T) {
+	settings := map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": nil}
+	tests := map[string]string{
+		"secret_scanning":   "enabled",
+		"dependabot_alerts": "not_set",
+		"advanced_security": "not_set",
+	}
+	for key, want := range tests {
+		if got := SettingValue(settings, key); got != want {
+			t.Errorf("SettingValue(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDescribeEnforcement(t *testing.T) {
+	tests := map[string]string{
+		"enforced":   "enforced — repo owners cannot override",
+		"unenforced": "unenforced — repo owners can override",
+		"":           "not set",
+	}
+	for value, want := range tests {
+		if got := DescribeEnforcement(value); got != want {
+			t.Errorf("DescribeEnforcement(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TargetBreakdown records how the organizations named by the targeting flags were narrowed down
+// to the organizations a command will process
+type TargetBreakdown struct {
+	Source           string            // targeting flag the organizations came from, e.g. "--org-list"
+	Found            int               // organizations named by the targeting source, including invalid ones
+	Invalid          int               // --org-list rows skipped because the organization name is malformed
+	Missing          int               // --org-list rows skipped because no such organization exists
+	InvalidEntries   []InvalidOrgEntry // the skipped --org-list rows, malformed or missing, in file order
+	SourceOrgRemoved string            // --copy-from-org organization removed from the targets
+	NotManageable    int               // organizations whose membership does not allow managing security configurations
+	PropertyExcluded int               // organizations excluded by --exclude-with-property
+	PropertyUnknown  int               // organizations excluded because their custom properties could not be read
+	Remaining        int               // organizations left to process
+
+	// Enterprise each organization was listed by, set only when --all-orgs spans several enterprises
+	OrgEnterprises map[string]string
+}
+
+// Reasons describes each step that removed organizations, in the order they were applied
+func (b TargetBreakdown) Reasons() []string {
+	reasons := []string{fmt.Sprintf("%d organization(s) found via %s", b.Found, b.Source)}
+	if b.Invalid > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d invalid organization name(s) skipped", b.Invalid))
+	}
+	if b.Missing > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d organization(s) not found", b.Missing))
+	}
+	if b.SourceOrgRemoved != "" {
+		reasons = append(reasons, fmt.Sprintf("source organization '%s' removed from the targets", b.SourceOrgRemoved))
+	}
+	if b.PropertyExcluded > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d organization(s) excluded by custom property", b.PropertyExcluded))
+	}
+	if b.PropertyUnknown > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d organization(s) excluded because their custom properties could not be read", b.PropertyUnknown))
+	}
+	if b.NotManageable > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d organization(s) where you are not an owner", b.NotManageable))
+	}
+	return reasons
+}
+
+// EnterpriseCounts returns how many of orgs each enterprise listed, or nil when the organizations
+// were not listed by several enterprises
+func (b TargetBreakdown) EnterpriseCounts(orgs []string) map[string]int {
+	if len(b.OrgEnterprises) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, org := range orgs {
+		if enterprise := b.OrgEnterprises[org]; enterprise != "" {
+			counts[enterprise]++
+		}
+	}
+	return counts
+}
+
+// PropertyFilter is an --exclude-with-property key=value pair. An organization matches when its
+// custom property Name has Value, or includes it for a multi-select property.
+type PropertyFilter struct {
+	Name  string
+	Value string
+}
+
+func (f PropertyFilter) String() string {
+	return f.Name + "=" + f.Value
+}
+
+// Matches reports whether properties, an organization's custom property values by name, match the filter
+func (f PropertyFilter) Matches(properties map[string][]string) bool {
+	return slices.Contains(properties[f.Name], f.Value)
+}
+
+// InvalidOrgEntry is an --org-list row skipped because its organization name is malformed, or,
+// with --strict-org-list, empty, or because no organization has that name
+type InvalidOrgEntry struct {
+	Line    int    // 1-based line in the CSV file
+	Name    string // the value as written, trimmed; empty for a row with no organization
+	Missing bool   // the name is well formed but no such organization exists
+}
+
+func (e InvalidOrgEntry) String() string {
+	switch {
+	case e.Name == "":
+		return fmt.Sprintf("line %d: (empty)", e.Line)
+	case e.Missing:
+		return fmt.Sprintf("line %d: '%s' (not found)", e.Line, e.Name)
+	}
+	return fmt.Sprintf("line %d: '%s'", e.Line, e.Name)
+}
+
+// InvalidOrgListError reports --org-list rows that were skipped, returned after processing when
+// --fail-on-invalid-orgs is set so the exit code reflects them
+type InvalidOrgListError struct {
+	Path    string
+	Entries []InvalidOrgEntry
+}
+
+func (e *InvalidOrgListError) Error() string {
+	return fmt.Sprintf("%d row(s) of %s were not processed: %s", len(e.Entries), e.Path, DescribeInvalidOrgEntries(e.Entries))
+}
+
+// DescribeInvalidOrgEntries lists skipped --org-list rows on one line, e.g. "line 3: 'a b', line 7: 'x/y'"
+func DescribeInvalidOrgEntries(entries []InvalidOrgEntry) string {
+	described := make([]string, len(entries))
+	for i, entry := range entries {
+		described[i] = entry.String()
+	}
+	return strings.Join(described, ", ")
+}
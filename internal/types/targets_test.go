@@ -0,0 +1,32 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTargetBreakdown_EnterpriseCounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		breakdown TargetBreakdown
+		orgs      []string
+		want      map[string]int
+	}{
+		{name: "single source", breakdown: TargetBreakdown{Source: "--org-list"}, orgs: []string{"org-a"}, want: nil},
+		{
+			name: "counts only the remaining targets",
+			breakdown: TargetBreakdown{Source: "--all-orgs", OrgEnterprises: map[string]string{
+				"acme-web": "acme", "acme-api": "acme", "acme-old": "acme", "globex-api": "globex",
+			}},
+			orgs: []string{"acme-web", "globex-api", "acme-api"},
+			want: map[string]int{"acme": 2, "globex": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.breakdown.EnterpriseCounts(tt.orgs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnterpriseCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
 // GetSecurityConfigInput prompts for security configuration name and description.
@@ -28,7 +35,7 @@ func GetSecurityConfigInput(nameOverride, descriptionOverride string) (string, s
 	if strings.TrimSpace(descriptionOverride) != "" {
 		description = strings.TrimSpace(descriptionOverride)
 	} else {
-		d, err := pterm.DefaultInteractiveTextInput.WithDefaultText("Security configuration applied across enterprise organizations").WithMultiLine(false).Show("Enter security configuration description")
+		d, err := promptDescription("Enter security configuration description", "Security configuration applied across enterprise organizations")
 		if err != nil {
 			return "", "", err
 		}
@@ -37,12 +44,15 @@ func GetSecurityConfigInput(nameOverride, descriptionOverride string) (string, s
 	if description == "" {
 		return "", "", fmt.Errorf("configuration description is required")
 	}
+	if err := utils.ValidateDescriptionLength(description); err != nil {
+		return "", "", err
+	}
 
 	return name, description, nil
 }
 
-// SecuritySettingOverrides holds optional pre-supplied values for security settings.
-// Any field left empty will fall back to interactive prompting.
+// SecuritySettingOverrides holds optional pre-supplied values for security settings and
+// enforcement. Any field left empty will fall back to interactive prompting.
 type SecuritySettingOverrides struct {
 	AdvancedSecurity                  string
 	DependabotAlerts                  string
@@ -78,8 +88,7 @@ func GetSecuritySettings(overrides SecuritySettingOverrides, dependabotAlertsAva
 		(dependabotSecurityUpdatesAvailable && overrides.DependabotSecurityUpdates == "") ||
 		overrides.SecretScanning == "" ||
 		overrides.SecretScanningPushProtection == "" ||
-		overrides.SecretScanningNonProviderPatterns == "" ||
-		overrides.Enforcement == ""
+		overrides.SecretScanningNonProviderPatterns == ""
 	if needsPrompt {
 		pterm.Info.Println("Configure security settings:")
 	}
@@ -130,14 +139,31 @@ func GetSecuritySettings(overrides SecuritySettingOverrides, dependabotAlertsAva
 	}
 	settings["secret_scanning_non_provider_patterns"] = nonProviderPatterns
 
-	// Enforcement
-	enforcement, err := selectWithOverride("Enforcement Status", overrides.Enforcement, []string{"enforced", "unenforced"}, "enforced")
+	return settings, nil
+}
+
+// GetEnforcement prompts for whether repository owners can override the configuration's settings.
+// If override is non-empty, it is validated and used directly.
+func GetEnforcement(override string) (string, error) {
+	return selectWithOverride(types.EnforcementSetting.Label, override, types.EnforcementSetting.Values, types.EnforcementEnforced)
+}
+
+// GetEnforcementForUpdate prompts for a configuration's new enforcement, offering to keep the
+// current value. If override is non-empty, it is validated and used directly.
+func GetEnforcementForUpdate(current, override string) (string, error) {
+	if override != "" {
+		return selectWithOverride(types.EnforcementSetting.Label, override, types.EnforcementSetting.Values, "")
+	}
+	keep := fmt.Sprintf("Keep current (%s)", types.DescribeEnforcement(current))
+	options := append([]string{keep}, types.EnforcementSetting.Values...)
+	selection, err := pterm.DefaultInteractiveSelect.WithOptions(options).WithDefaultOption(keep).Show(types.EnforcementSetting.Label)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	settings["enforcement"] = enforcement
-
-	return settings, nil
+	if selection == keep {
+		return current, nil
+	}
+	return selection, nil
 }
 
 // GetAttachmentScope prompts for repository attachment scope. If override is non-empty,
@@ -202,14 +228,54 @@ func GetConfigNameForModification() (string, error) {
 	return strings.TrimSpace(configName), nil
 }
 
-// GetUpdatedName prompts for updated configuration name. If override is non-empty, it is used.
+// GetCopiedConfigName prompts for the name the copied configuration will have in the target
+// organizations, offering the source configuration's name as the default. If override is
+// non-empty, it is used directly without prompting.
+func GetCopiedConfigName(sourceName, override string) (string, error) {
+	if strings.TrimSpace(override) != "" {
+		return strings.TrimSpace(override), nil
+	}
+	name, err := textInput("Enter a name for the copied configuration (press Enter to keep the source name)", sourceName)
+	if err != nil {
+		return "", err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("configuration name is required")
+	}
+
+	return name, nil
+}
+
+// validateCopiedConfigName checks the name chosen for a copied configuration. The name must be
+// non-empty and, when it differs from the source name, must not match another configuration
+// visible in the source organization (which includes enterprise configurations) unless
+// allowExisting is set.
+func validateCopiedConfigName(sourceName, newName string, configs []types.SecurityConfiguration, allowExisting bool) error {
+	if strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("configuration name is required")
+	}
+	if newName == sourceName || allowExisting {
+		return nil
+	}
+	for _, config := range configs {
+		if config.Name == newName {
+			return fmt.Errorf("a %s configuration named %q already exists; choose a different name or pass --overwrite true", config.TargetType, newName)
+		}
+	}
+	return nil
+}
+
+// GetUpdatedName prompts for updated configuration name, offering the name of the configuration
+// located in the template organization as the default. If override is non-empty, it is used.
 // To explicitly mean "keep current", pass currentName as the override (the caller handles that).
 func GetUpdatedName(currentName, override string) (string, error) {
 	if strings.TrimSpace(override) != "" {
 		newName := strings.TrimSpace(override)
 		return newName, nil
 	}
-	newName, err := pterm.DefaultInteractiveTextInput.WithDefaultText(currentName).WithMultiLine(false).Show("Enter updated security configuration name")
+	newName, err := textInput("Enter updated security configuration name (press Enter to keep the current name)", currentName)
 	if err != nil {
 		return "", err
 	}
@@ -229,12 +295,38 @@ func GetUpdatedDescription(currentDescription, override string) (string, error)
 	if strings.TrimSpace(override) != "" {
 		return strings.TrimSpace(override), nil
 	}
-	newDescription, err := pterm.DefaultInteractiveTextInput.WithDefaultText(currentDescription).WithMultiLine(false).Show("Enter updated security configuration description")
+	newDescription, err := promptDescription("Enter updated security configuration description", currentDescription)
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(newDescription), nil
+	newDescription = strings.TrimSpace(newDescription)
+	if err := utils.ValidateDescriptionLength(newDescription); err != nil {
+		return "", err
+	}
+	return newDescription, nil
+}
+
+// promptDescription offers to read a long description from a file before falling back to the
+// single-line text prompt pre-filled with defaultText
+func promptDescription(prompt, defaultText string) (string, error) {
+	fromFile, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Read the description from a file?").WithDefaultValue(false).Show()
+	if err != nil {
+		return "", err
+	}
+	if !fromFile {
+		return textInput(prompt, defaultText)
+	}
+
+	path, err := textInput("Enter the path to the description file", "")
+	if err != nil {
+		return "", err
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("description file path is required")
+	}
+	return utils.ReadDescription(path, os.Stdin)
 }
 
 // GetSecuritySettingsForUpdate prompts for updated security settings. Any non-empty override
@@ -258,7 +350,6 @@ func GetSecuritySettingsForUpdate(currentSettings map[string]interface{}, overri
 		{"secret_scanning", "Secret Scanning", []string{"enabled", "disabled", "not_set"}, "enabled", overrides.SecretScanning, false, false},
 		{"secret_scanning_push_protection", "Secret Scanning Push Protection", []string{"enabled", "disabled", "not_set"}, "enabled", overrides.SecretScanningPushProtection, false, false},
 		{"secret_scanning_non_provider_patterns", "Secret Scanning Non-Provider Patterns", []string{"enabled", "disabled", "not_set"}, "not_set", overrides.SecretScanningNonProviderPatterns, false, false},
-		{"enforcement", "Enforcement Status", []string{"enforced", "unenforced"}, "enforced", overrides.Enforcement, false, false},
 	}
 
 	// Determine if we will prompt for anything (to decide whether to show the header)
@@ -288,10 +379,7 @@ func GetSecuritySettingsForUpdate(currentSettings map[string]interface{}, overri
 			continue
 		}
 
-		currentValue := "not_set"
-		if val, exists := currentSettings[config.key]; exists {
-			currentValue = fmt.Sprintf("%v", val)
-		}
+		currentValue := types.SettingValue(currentSettings, config.key)
 
 		// If an override is provided via flag, validate and use it
 		if config.override != "" {
@@ -354,34 +442,109 @@ func SelectConfigurationFromList(orgConfigs, enterpriseConfigs []string, overrid
 	return selectConfiguration(orgConfigs, enterpriseConfigs, "Select a security configuration to apply")
 }
 
-// SelectConfigurationForDeletion prompts user to select a configuration to delete.
-// If override is non-empty and matches one of the configs, it is returned directly.
-// Returns the configuration name
-func SelectConfigurationForDeletion(orgConfigs []string, override string) (string, error) {
-	if override != "" {
-		return resolveNameOverride(orgConfigs, override, "delete")
+// The API calls FetchOrgConfigurations makes. They are variables so tests do not call the API.
+var (
+	checkOrgMembership = func(org string) (types.MembershipStatus, error) {
+		return api.CheckSingleOrganizationMembership(context.Background(), org)
+	}
+	organizationExists    = api.OrganizationExists
+	listOrgConfigurations = func(org string) ([]types.SecurityConfiguration, error) {
+		return api.FetchSecurityConfigurations(context.Background(), org)
+	}
+)
+
+// FetchOrgConfigurations lists the security configurations in org that an operation picks from,
+// once the user is confirmed as an owner of org. role names the part org plays in the run (e.g.,
+// "template") in messages. Enterprise configurations shown at the organization level are left
+// out unless includeEnterprise is set. An organization missing from the host fails with an error
+// wrapping types.ErrOrganizationNotFound, and one with nothing to pick from with an error wrapping
+// types.ErrNoConfigurations.
+func FetchOrgConfigurations(org, role string, includeEnterprise bool) ([]types.SecurityConfiguration, error) {
+	pterm.Info.Printf("Fetching security configurations from %s organization '%s'...\n", role, org)
+
+	status, err := checkOrgMembership(org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership for %s organization '%s': %w", role, org, err)
+	}
+	if problem := status.Problem(); problem != "" {
+		// A missing organization usually means it lives on another host than the one this run targets
+		if !status.IsMember && status.State != "pending" {
+			exists, err := organizationExists(org)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up %s organization '%s': %w", role, org, err)
+			}
+			if !exists {
+				return nil, fmt.Errorf("%s organization '%s' %w on %s", role, org, types.ErrOrganizationNotFound, api.CurrentHost())
+			}
+		}
+		return nil, fmt.Errorf("cannot read configurations from %s organization '%s': %s", role, org, problem)
+	}
+
+	configs, err := listOrgConfigurations(org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch security configurations from %s organization '%s': %w", role, org, err)
+	}
+	if !includeEnterprise {
+		configs = slices.DeleteFunc(configs, func(config types.SecurityConfiguration) bool {
+			return config.TargetType == "enterprise"
+		})
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%s organization '%s' %w", role, org, types.ErrNoConfigurations)
 	}
-	return selectFromList(orgConfigs, "Select a security configuration to delete")
+
+	pterm.Success.Printf("Found %d security configuration(s) in %s organization '%s'\n", len(configs), role, org)
+	return configs, nil
 }
 
-// SelectConfigurationForModification prompts user to select a configuration to modify.
-// If override is non-empty and matches one of the configs, it is returned directly.
-// Returns the configuration name
-func SelectConfigurationForModification(orgConfigs []string, override string) (string, error) {
+// SelectConfiguration returns the configuration named override when it is non-empty, and
+// otherwise asks the user to pick one of configs. Each option shows the configuration's name and
+// description, and the choice is mapped back to the configuration by its position in the list.
+// verb names the operation in the prompt and errors (e.g., "delete").
+func SelectConfiguration(configs []types.SecurityConfiguration, override, verb string) (types.SecurityConfiguration, error) {
 	if override != "" {
-		return resolveNameOverride(orgConfigs, override, "modify")
+		for _, config := range configs {
+			if config.Name == override {
+				return config, nil
+			}
+		}
+		return types.SecurityConfiguration{}, fmt.Errorf("configuration %q not found in the list of configurations available to %s", override, verb)
 	}
-	return selectFromList(orgConfigs, "Select a security configuration to modify")
+	if len(configs) == 0 {
+		return types.SecurityConfiguration{}, fmt.Errorf("no configurations available")
+	}
+
+	options := configurationOptions(configs)
+	selection, err := selectInput(fmt.Sprintf("Select a security configuration to %s", verb), options)
+	if err != nil {
+		return types.SecurityConfiguration{}, err
+	}
+	index := slices.Index(options, selection)
+	if index < 0 {
+		return types.SecurityConfiguration{}, fmt.Errorf("unknown configuration selection %q", selection)
+	}
+	return configs[index], nil
 }
 
-// resolveNameOverride validates that the supplied override matches one of the available configs.
-func resolveNameOverride(configs []string, override, verb string) (string, error) {
-	for _, c := range configs {
-		if c == override {
-			return override, nil
+// configurationOptions returns the select option shown for each configuration, in order. Options
+// that would read the same (same name and description) get the configuration ID appended, so
+// every option maps back to exactly one configuration.
+func configurationOptions(configs []types.SecurityConfiguration) []string {
+	options := make([]string, len(configs))
+	seen := make(map[string]int)
+	for i, config := range configs {
+		options[i] = config.Name
+		if config.Description != "" {
+			options[i] = fmt.Sprintf("%s - %s", config.Name, config.Description)
+		}
+		seen[options[i]]++
+	}
+	for i, config := range configs {
+		if seen[options[i]] > 1 {
+			options[i] = fmt.Sprintf("%s (ID %d)", options[i], config.ID)
 		}
 	}
-	return "", fmt.Errorf("configuration %q not found in the list of configurations available to %s", override, verb)
+	return options
 }
 
 // resolveConfigOverride disambiguates between org and enterprise configs given an override name
@@ -426,20 +589,6 @@ func contains(list []string, target string) bool {
 	return false
 }
 
-// selectFromList is a shared helper for single-list configuration selection prompts
-func selectFromList(configs []string, prompt string) (string, error) {
-	if len(configs) == 0 {
-		return "", fmt.Errorf("no configurations available")
-	}
-
-	selection, err := pterm.DefaultInteractiveSelect.WithOptions(configs).Show(prompt)
-	if err != nil {
-		return "", err
-	}
-
-	return selection, nil
-}
-
 // selectConfiguration is a shared helper for configuration selection prompts
 func selectConfiguration(orgConfigs, enterpriseConfigs []string, prompt string) (string, string, error) {
 	if len(orgConfigs) == 0 && len(enterpriseConfigs) == 0 {
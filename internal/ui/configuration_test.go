@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// stubTextInput replaces the interactive text prompt for the duration of a test. The stub
+// records the default text it was offered and returns answer.
+func stubTextInput(t *testing.T, answer string, err error) *string {
+	t.Helper()
+	var offeredDefault string
+	original := textInput
+	textInput = func(prompt, defaultText string) (string, error) {
+		offeredDefault = defaultText
+		return answer, err
+	}
+	t.Cleanup(func() { textInput = original })
+	return &offeredDefault
+}
+
+func TestGetCopiedConfigName(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		answer   string
+		want     string
+		wantErr  bool
+	}{
+		{name: "keep source name", answer: "Baseline", want: "Baseline"},
+		{name: "rename on copy", answer: "  Baseline v2  ", want: "Baseline v2"},
+		{name: "override skips prompt", override: "Flag Name", answer: "ignored", want: "Flag Name"},
+		{name: "empty answer rejected", answer: "   ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offered := stubTextInput(t, tt.answer, nil)
+			got, err := GetCopiedConfigName("Baseline", tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCopiedConfigName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetCopiedConfigName() = %q, want %q", got, tt.want)
+			}
+			if tt.override == "" && *offered != "Baseline" {
+				t.Errorf("prompt default = %q, want the source name", *offered)
+			}
+		})
+	}
+}
+
+func TestGetCopiedConfigName_PromptError(t *testing.T) {
+	stubTextInput(t, "", errors.New("interrupted"))
+	if _, err := GetCopiedConfigName("Baseline", ""); err == nil {
+		t.Fatal("expected prompt error to be returned")
+	}
+}
+
+func TestGetUpdatedName_OffersLocatedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		answer   string
+		want     string
+		wantErr  bool
+	}{
+		{name: "keep located name", answer: "Baseline", want: "Baseline"},
+		{name: "rename", answer: " Baseline v2 ", want: "Baseline v2"},
+		{name: "override skips prompt", override: "Flag Name", answer: "ignored", want: "Flag Name"},
+		{name: "empty answer rejected", answer: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offered := stubTextInput(t, tt.answer, nil)
+			got, err := GetUpdatedName("Baseline", tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetUpdatedName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetUpdatedName() = %q, want %q", got, tt.want)
+			}
+			if tt.override == "" && *offered != "Baseline" {
+				t.Errorf("prompt default = %q, want the located name", *offered)
+			}
+		})
+	}
+}
+
+func TestValidateCopiedConfigName(t *testing.T) {
+	configs := []types.SecurityConfiguration{
+		{ID: 1, Name: "Baseline", TargetType: "organization"},
+		{ID: 2, Name: "Strict", TargetType: "organization"},
+		{ID: 3, Name: "Enterprise Default", TargetType: "enterprise"},
+	}
+
+	tests := []struct {
+		name          string
+		newName       string
+		allowExisting bool
+		wantErr       bool
+	}{
+		{name: "keep source name", newName: "Baseline"},
+		{name: "rename to unused name", newName: "Baseline v2"},
+		{name: "empty name rejected", newName: "  ", wantErr: true},
+		{name: "collides with organization configuration", newName: "Strict", wantErr: true},
+		{name: "collides with enterprise configuration", newName: "Enterprise Default", wantErr: true},
+		{name: "collision allowed with overwrite", newName: "Strict", allowExisting: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCopiedConfigName("Baseline", tt.newName, configs, tt.allowExisting)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateCopiedConfigName(%q) error = %v, wantErr %v", tt.newName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectConfiguration(t *testing.T) {
+	configs := []types.SecurityConfiguration{
+		{ID: 1, Name: "Baseline", Description: "Default settings"},
+		{ID: 2, Name: "Strict"},
+		{ID: 3, Name: "Legacy", Description: "Old repos"},
+	}
+
+	original := selectInput
+	t.Cleanup(func() { selectInput = original })
+	var offered []string
+	selectInput = func(prompt string, options []string) (string, error) {
+		offered = options
+		return options[1], nil
+	}
+
+	got, err := SelectConfiguration(configs, "", "delete")
+	if err != nil {
+		t.Fatalf("SelectConfiguration() error = %v", err)
+	}
+	if got.ID != 2 {
+		t.Errorf("selected ID %d, want 2", got.ID)
+	}
+	if want := []string{"Baseline - Default settings", "Strict", "Legacy - Old repos"}; !reflect.DeepEqual(offered, want) {
+		t.Errorf("options = %v, want %v", offered, want)
+	}
+
+	got, err = SelectConfiguration(configs, "Legacy", "delete")
+	if err != nil || got.ID != 3 {
+		t.Errorf("override selected %+v, %v; want ID 3", got, err)
+	}
+	if _, err := SelectConfiguration(configs, "Missing", "delete"); err == nil {
+		t.Error("expected an error for an override not in the list")
+	}
+}
+
+func TestSelectConfiguration_SameNameAndDescription(t *testing.T) {
+	configs := []types.SecurityConfiguration{
+		{ID: 10, Name: "Baseline", Description: "Default settings"},
+		{ID: 11, Name: "Baseline", Description: "Default settings"},
+	}
+
+	original := selectInput
+	t.Cleanup(func() { selectInput = original })
+	var offered []string
+	selectInput = func(prompt string, options []string) (string, error) {
+		offered = options
+		return options[1], nil
+	}
+
+	got, err := SelectConfiguration(configs, "", "copy")
+	if err != nil {
+		t.Fatalf("SelectConfiguration() error = %v", err)
+	}
+	if got.ID != 11 {
+		t.Errorf("selected ID %d, want 11", got.ID)
+	}
+	if want := []string{"Baseline - Default settings (ID 10)", "Baseline - Default settings (ID 11)"}; !reflect.DeepEqual(offered, want) {
+		t.Errorf("options = %v, want %v", offered, want)
+	}
+}
+
+func TestFetchOrgConfigurations(t *testing.T) {
+	listed := []types.SecurityConfiguration{
+		{ID: 1, Name: "Baseline", TargetType: "organization"},
+		{ID: 2, Name: "Enterprise default", TargetType: "enterprise"},
+	}
+	owner := types.MembershipStatus{IsMember: true, IsOwner: true, Role: "admin"}
+
+	tests := []struct {
+		name              string
+		status            types.MembershipStatus
+		exists            bool
+		configs           []types.SecurityConfiguration
+		includeEnterprise bool
+		wantIDs           []int
+		wantErr           error  // matched with errors.Is
+		wantMsg           string // matched as a substring when wantErr is nil
+	}{
+		{name: "organization-level only", status: owner, configs: listed, wantIDs: []int{1}},
+		{name: "enterprise included", status: owner, configs: listed, includeEnterprise: true, wantIDs: []int{1, 2}},
+		{name: "only enterprise configurations", status: owner, configs: listed[1:], wantErr: types.ErrNoConfigurations},
+		{name: "no configurations", status: owner, includeEnterprise: true, wantErr: types.ErrNoConfigurations},
+		{name: "missing from host", status: types.MembershipStatus{}, wantErr: types.ErrOrganizationNotFound},
+		{name: "not a member", status: types.MembershipStatus{}, exists: true, wantMsg: "not a member"},
+		{name: "not an owner", status: types.MembershipStatus{IsMember: true, Role: "member"}, wantMsg: "owner required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origMembership, origExists, origList := checkOrgMembership, organizationExists, listOrgConfigurations
+			t.Cleanup(func() {
+				checkOrgMembership, organizationExists, listOrgConfigurations = origMembership, origExists, origList
+			})
+			checkOrgMembership = func(string) (types.MembershipStatus, error) { return tt.status, nil }
+			organizationExists = func(string) (bool, error) { return tt.exists, nil }
+			listOrgConfigurations = func(string) ([]types.SecurityConfiguration, error) {
+				return slices.Clone(tt.configs), nil
+			}
+
+			got, err := FetchOrgConfigurations("template-org", "template", tt.includeEnterprise)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want one wrapping %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantMsg)
+				}
+				return
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []int
+			for _, config := range got {
+				ids = append(ids, config.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -1,47 +1,87 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/api"
 	"github.com/callmegreg/gh-security-config/internal/types"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
-// ConfirmOperation shows operation summary and asks for confirmation. If skipConfirm is true,
-// the summary is shown and true is returned without prompting.
-func ConfirmOperation(orgs []string, configName, configDescription string, settings map[string]interface{}, scope string, setAsDefault bool, skipConfirm bool) (bool, error) {
+// descriptionPreviewLength is how many characters of a description the confirmation summaries show
+const descriptionPreviewLength = 80
+
+// inactiveSettingNote is shown next to settings that a feature probe found to be accepted but not applied
+const inactiveSettingNote = "(accepted but inactive on this instance)"
+
+// inactiveAnnotation returns the note to print after a setting, or "" when the setting is active
+func inactiveAnnotation(key string, inactiveSettings []string) string {
+	if slices.Contains(inactiveSettings, key) {
+		return " " + pterm.Gray(inactiveSettingNote)
+	}
+	return ""
+}
+
+// showRunIdentity prints the host and user the operation will run against
+func showRunIdentity(identity types.RunIdentity) {
+	user := pterm.Gray("unknown")
+	if identity.User != "" {
+		user = pterm.Cyan(identity.User)
+	}
+	pterm.Printf("Host: %s\n", pterm.Cyan(identity.Host))
+	pterm.Printf("Authenticated as: %s\n", user)
+}
+
+// ConfirmOperation shows operation summary and asks for confirmation. A sourceName that differs
+// from configName, as when a copy is renamed, is shown next to it. If auditCopyName is non-empty,
+// the companion audit configuration is included in the summary. Settings listed in inactiveSettings
+// are annotated as inactive on this instance. applyToExisting shows that the default is set before
+// the existing repositories in scope are attached. If skipConfirm is true, the summary is shown and
+// true is returned without prompting.
+func ConfirmOperation(identity types.RunIdentity, orgs []string, sourceName, configName, configDescription string, settings map[string]interface{}, enforcement string, inactiveSettings []string, scope string, setAsDefault, applyToExisting bool, auditCopyName string, skipConfirm bool) (bool, error) {
 	pterm.Println()
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("Operation Summary")
 
+	showRunIdentity(identity)
+
 	pterm.Printf("Organizations: %d\n", len(orgs))
-	pterm.Printf("Configuration Name: %s\n", pterm.Yellow(configName))
-	pterm.Printf("Configuration Description: %s\n", pterm.Yellow(configDescription))
+	if sourceName != "" && sourceName != configName {
+		pterm.Printf("Configuration Name: %s → %s\n", pterm.Gray(sourceName), pterm.Yellow(configName))
+	} else {
+		pterm.Printf("Configuration Name: %s\n", pterm.Yellow(configName))
+	}
+	pterm.Printf("Configuration Description: %s\n", pterm.Yellow(utils.TruncateDescription(configDescription, descriptionPreviewLength)))
 	pterm.Println()
 
 	pterm.Info.Println("Security Settings:")
 	for key, value := range settings {
-		valueStr := fmt.Sprintf("%v", value)
-		var coloredValue string
-
-		switch valueStr {
-		case "enabled", "enforced":
-			coloredValue = pterm.Green(valueStr)
-		case "disabled", "unenforced":
-			coloredValue = pterm.Red(valueStr)
-		case "not_set":
-			coloredValue = pterm.Yellow(valueStr)
-		default:
-			coloredValue = pterm.Yellow(valueStr)
-		}
-
-		pterm.Printf("  %s: %s\n", pterm.Cyan(key), coloredValue)
+		pterm.Printf("  %s: %s%s\n", pterm.Cyan(key), RenderSettingValue(value), inactiveAnnotation(key, inactiveSettings))
 	}
+	pterm.Printf("Enforcement: %s\n", coloredEnforcement(enforcement))
 	pterm.Println()
 
-	pterm.Printf("Attachment Scope: %s\n", pterm.Magenta(scope))
-	pterm.Printf("Set as Default: %s\n", pterm.Cyan(fmt.Sprintf("%t", setAsDefault)))
+	if applyToExisting {
+		pterm.Printf("Set as Default: %s\n", pterm.Cyan("true (new repositories)"))
+		pterm.Printf("Then Attach to Existing Repositories: %s\n", pterm.Magenta(scope))
+	} else {
+		if scope == "none" {
+			pterm.Printf("Attachment: %s\n", pterm.Gray("skipped"))
+		} else {
+			pterm.Printf("Attachment Scope: %s\n", pterm.Magenta(scope))
+		}
+		pterm.Printf("Set as Default: %s\n", pterm.Cyan(fmt.Sprintf("%t", setAsDefault)))
+	}
+	if auditCopyName != "" {
+		pterm.Printf("Audit Copy: %s %s\n", pterm.Yellow(auditCopyName), pterm.Gray("(unenforced, not attached, not default)"))
+	}
 	pterm.Println()
 
 	if skipConfirm {
@@ -59,10 +99,12 @@ func ConfirmOperation(orgs []string, configName, configDescription string, setti
 
 // ConfirmDeleteOperation shows delete summary and asks for confirmation. If skipConfirm is true,
 // the summary is shown and true is returned without prompting.
-func ConfirmDeleteOperation(orgs []string, configName string, skipConfirm bool) (bool, error) {
+func ConfirmDeleteOperation(identity types.RunIdentity, orgs []string, configName string, skipConfirm bool) (bool, error) {
 	pterm.Println()
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgRed)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("DELETE OPERATION SUMMARY")
 
+	showRunIdentity(identity)
+
 	pterm.Printf("Organizations: %d\n", len(orgs))
 	pterm.Printf("Configuration to Delete: %s\n", pterm.Red(configName))
 	pterm.Println()
@@ -84,12 +126,181 @@ func ConfirmDeleteOperation(orgs []string, configName string, skipConfirm bool)
 	return confirmed, nil
 }
 
-// ConfirmModifyOperation shows modify summary and asks for confirmation. If skipConfirm is true,
-// the summary is shown and true is returned without prompting.
-func ConfirmModifyOperation(orgs []string, configName, newName, currentDescription, newDescription string, currentSettings, newSettings map[string]interface{}, skipConfirm bool) (bool, error) {
+// ConfirmDeleteAllOperation lists every configuration delete --all-configs will remove, grouped by
+// organization, and asks the user to type a phrase naming how many will be deleted. Each of
+// filters, such as "Older Than: 90d", is listed with the managed prefix. If skipConfirm is true,
+// the list is shown and true is returned without prompting.
+func ConfirmDeleteAllOperation(identity types.RunIdentity, plan map[string][]types.DeletionCandidate, managedPrefix string, filters []string, skipConfirm bool) (bool, error) {
+	pterm.Println()
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgRed)).WithTextStyle(pterm.NewStyle(pterm.FgWhite)).Println("DELETE ALL CONFIGURATIONS SUMMARY")
+
+	showRunIdentity(identity)
+	pterm.Println()
+
+	total := 0
+	for _, org := range slices.Sorted(maps.Keys(plan)) {
+		pterm.Printf("%s:\n", org)
+		for _, config := range plan[org] {
+			if details := DeletionCandidateDetails(config); details != "" {
+				pterm.Printf("  - %s %s\n", Hyperlink(config.HTMLURL, pterm.Red(config.Name)), pterm.Gray("("+details+")"))
+			} else {
+				pterm.Printf("  - %s\n", Hyperlink(config.HTMLURL, pterm.Red(config.Name)))
+			}
+		}
+		total += len(plan[org])
+	}
+	pterm.Println()
+	pterm.Printf("Organizations: %d\n", len(plan))
+	pterm.Printf("Configurations to Delete: %s\n", pterm.Red(total))
+	if managedPrefix != "" {
+		pterm.Printf("Managed Prefix: %s\n", managedPrefix)
+	}
+	for _, filter := range filters {
+		pterm.Println(filter)
+	}
+	pterm.Println()
+
+	pterm.Warning.Println("WARNING: This operation will delete every configuration listed above.")
+	pterm.Warning.Println("This action cannot be undone. Repositories will retain their settings but will no longer be associated with the configurations.")
+	pterm.Println()
+
+	if skipConfirm {
+		pterm.Info.Println("--skip-confirmation-message=true provided: skipping confirmation prompt.")
+		return true, nil
+	}
+
+	return confirmTyped(deleteAllConfirmationPhrase(total))
+}
+
+// DeletionCandidateDetails describes the age and attachment count of a configuration planned for
+// deletion, e.g. "age 120d, 0 repositories". It is empty when neither was looked up.
+func DeletionCandidateDetails(candidate types.DeletionCandidate) string {
+	if candidate.Age == 0 && candidate.Repositories < 0 {
+		return ""
+	}
+	repositories := "repositories unknown"
+	if candidate.Repositories >= 0 {
+		repositories = fmt.Sprintf("%d repositories", candidate.Repositories)
+	}
+	return fmt.Sprintf("age %s, %s", utils.FormatAge(candidate.Age), repositories)
+}
+
+// deleteAllConfirmationPhrase returns the phrase that must be typed to confirm deleting count configurations
+func deleteAllConfirmationPhrase(count int) string {
+	return fmt.Sprintf("delete %d configurations", count)
+}
+
+// confirmTyped asks the user to type phrase and reports whether the input matches it exactly,
+// ignoring surrounding whitespace
+func confirmTyped(phrase string) (bool, error) {
+	input, err := pterm.DefaultInteractiveTextInput.WithDefaultText("").WithMultiLine(false).Show(fmt.Sprintf("Type '%s' to proceed", phrase))
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(input) == phrase, nil
+}
+
+// LargeRunThreshold is the number of organizations above which an --all-orgs run must be confirmed
+// by typing the organization count
+const LargeRunThreshold = 50
+
+// requiresTypedCount reports whether a run targeting orgCount organizations must be confirmed by
+// typing the count. Only --all-orgs runs are guarded, as explicit organization lists are chosen by hand.
+func requiresTypedCount(allOrgs bool, orgCount int) bool {
+	return allOrgs && orgCount > LargeRunThreshold
+}
+
+// enterpriseTargetHeadline returns the line announcing how many organizations a run targets, e.g.
+// "This will target 120 organizations across enterprise acme". When counts gives the targets of
+// each enterprise, they follow its name, e.g. "across enterprises acme (80), globex (40)".
+func enterpriseTargetHeadline(count string, enterprises []string, counts map[string]int) string {
+	label := "enterprise"
+	if len(enterprises) > 1 {
+		label = "enterprises"
+	}
+	names := make([]string, len(enterprises))
+	for i, enterprise := range enterprises {
+		names[i] = enterprise
+		if counts != nil {
+			names[i] = fmt.Sprintf("%s (%d)", enterprise, counts[enterprise])
+		}
+	}
+	return fmt.Sprintf("This will target %s organizations across %s %s", count, label, strings.Join(names, ", "))
+}
+
+// ConfirmEnterpriseTargetCount shows how many organizations an --all-orgs run targets, with the
+// count of each enterprise when enterpriseCounts is set, and, when the total is above
+// LargeRunThreshold, asks the user to type it before the operation summary. Runs that do not use
+// --all-orgs return true without output. If skipConfirm is true, the headline is shown and true is
+// returned without prompting.
+func ConfirmEnterpriseTargetCount(orgCount int, enterprises []string, enterpriseCounts map[string]int, allOrgs, skipConfirm bool) (bool, error) {
+	if !allOrgs {
+		return true, nil
+	}
+
+	pterm.Println()
+	pterm.DefaultBox.Println(enterpriseTargetHeadline(pterm.Bold.Sprint(pterm.Red(orgCount)), enterprises, enterpriseCounts))
+
+	if !requiresTypedCount(allOrgs, orgCount) {
+		return true, nil
+	}
+	if skipConfirm {
+		pterm.Info.Printf("--skip-confirmation-message=true provided: skipping the organization count confirmation.\n")
+		return true, nil
+	}
+
+	pterm.Warning.Printf("More than %d organizations are targeted. Type the organization count to continue.\n", LargeRunThreshold)
+	return confirmTyped(strconv.Itoa(orgCount))
+}
+
+// ConfirmContinueAfterProbe asks whether to process the remaining organizations once the --probe
+// organization succeeded. If skipConfirm is true, true is returned without prompting.
+func ConfirmContinueAfterProbe(probeOrg string, remaining int, skipConfirm bool) (bool, error) {
+	if skipConfirm {
+		pterm.Info.Printf("--skip-confirmation-message=true provided: continuing with the remaining %d organization(s).\n", remaining)
+		return true, nil
+	}
+
+	return pterm.DefaultInteractiveConfirm.WithDefaultText(fmt.Sprintf("Organization '%s' succeeded. Continue with the remaining %d organization(s)?", probeOrg, remaining)).WithDefaultValue(false).Show()
+}
+
+// ConfirmClearDefaultsOperation shows the org-defaults clear summary and asks for confirmation. If
+// skipConfirm is true, the summary is shown and true is returned without prompting.
+func ConfirmClearDefaultsOperation(identity types.RunIdentity, orgs []string, skipConfirm bool) (bool, error) {
+	pterm.Println()
+	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("CLEAR DEFAULTS OPERATION SUMMARY")
+
+	showRunIdentity(identity)
+
+	pterm.Printf("Organizations: %d\n", len(orgs))
+	pterm.Println()
+
+	pterm.Warning.Println("New repositories in these organizations will no longer receive a security configuration by default.")
+	pterm.Warning.Println("Existing repositories keep their current configuration attachments.")
+	pterm.Println()
+
+	if skipConfirm {
+		pterm.Info.Println("--skip-confirmation-message=true provided: skipping confirmation prompt.")
+		return true, nil
+	}
+
+	confirmed, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Do you want to clear the default configurations?").WithDefaultValue(false).Show()
+	if err != nil {
+		return false, err
+	}
+
+	return confirmed, nil
+}
+
+// ConfirmModifyOperation shows modify summary and asks for confirmation. Settings listed in
+// inactiveSettings are annotated as inactive on this instance. If skipConfirm is true, the summary
+// is shown and true is returned without prompting.
+func ConfirmModifyOperation(identity types.RunIdentity, orgs []string, configName, newName, currentDescription, newDescription string, currentSettings, newSettings map[string]interface{}, currentEnforcement, newEnforcement string, inactiveSettings []string, skipConfirm bool) (bool, error) {
 	pterm.Println()
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("MODIFY OPERATION SUMMARY")
 
+	showRunIdentity(identity)
+
 	pterm.Printf("Organizations: %d\n", len(orgs))
 	pterm.Printf("Configuration to Modify: %s\n", pterm.Magenta(configName))
 	pterm.Println()
@@ -106,23 +317,30 @@ func ConfirmModifyOperation(orgs []string, configName, newName, currentDescripti
 
 	// Description changes
 	if currentDescription != newDescription {
-		pterm.Printf("  Description: %s → %s\n", pterm.Red(currentDescription), pterm.Green(newDescription))
+		pterm.Printf("  Description: %s → %s\n", pterm.Red(utils.TruncateDescription(currentDescription, descriptionPreviewLength)), pterm.Green(utils.TruncateDescription(newDescription, descriptionPreviewLength)))
 	} else {
-		pterm.Printf("  Description: %s (no change)\n", pterm.Yellow(currentDescription))
+		pterm.Printf("  Description: %s (no change)\n", pterm.Yellow(utils.TruncateDescription(currentDescription, descriptionPreviewLength)))
 	}
 
 	// Setting changes
-	for key, newValue := range newSettings {
-		currentValue := fmt.Sprintf("%v", currentSettings[key])
-		newValueStr := fmt.Sprintf("%v", newValue)
+	for key := range newSettings {
+		currentValue := types.SettingValue(currentSettings, key)
+		newValueStr := types.SettingValue(newSettings, key)
 
 		if currentValue != newValueStr {
-			pterm.Printf("  %s: %s → %s\n", pterm.Cyan(key), pterm.Red(currentValue), pterm.Green(newValueStr))
+			pterm.Printf("  %s: %s → %s%s\n", pterm.Cyan(key), RenderSettingValue(currentValue), RenderSettingValue(newValueStr), inactiveAnnotation(key, inactiveSettings))
 		} else {
-			pterm.Printf("  %s: %s (no change)\n", pterm.Cyan(key), pterm.Yellow(currentValue))
+			pterm.Printf("  %s: %s (no change)%s\n", pterm.Cyan(key), RenderSettingValue(currentValue), inactiveAnnotation(key, inactiveSettings))
 		}
 	}
 
+	// Enforcement changes
+	if newEnforcement != "" && newEnforcement != currentEnforcement {
+		pterm.Printf("  Enforcement: %s → %s\n", pterm.Red(types.DescribeEnforcement(currentEnforcement)), pterm.Green(types.DescribeEnforcement(newEnforcement)))
+	} else {
+		pterm.Printf("  Enforcement: %s (no change)\n", pterm.Yellow(types.DescribeEnforcement(currentEnforcement)))
+	}
+
 	pterm.Println()
 
 	if skipConfirm {
@@ -140,126 +358,125 @@ func ConfirmModifyOperation(orgs []string, configName, newName, currentDescripti
 
 // CopyFromOrgOverrides holds optional pre-supplied values for the copy-from-org flow.
 type CopyFromOrgOverrides struct {
-	ConfigName   string // Name of the source configuration to copy
-	Scope        string // Attachment scope override
-	SetAsDefault *bool  // Set-as-default override
+	ConfigName    string // Name of the source configuration to copy
+	NewName       string // Name for the copied configuration in the target organizations
+	NamePrefix    string // Added before the copy's name once it is resolved
+	NameSuffix    string // Added after the copy's name once it is resolved
+	Scope         string // Attachment scope override
+	SetAsDefault  *bool  // Set-as-default override
+	AllowExisting bool   // Allow the new name to match another configuration (generate --overwrite)
 }
 
-// HandleCopyFromOrg handles the copy-from-org functionality. Any non-empty fields on overrides
-// are used instead of prompting the user.
-func HandleCopyFromOrg(copyFromOrg string, overrides CopyFromOrgOverrides) (string, string, map[string]interface{}, string, bool, error) {
-	pterm.Info.Printf("Fetching security configurations from organization '%s'...\n", copyFromOrg)
+// CopiedConfiguration holds everything needed to recreate a source configuration in the target
+// organizations
+type CopiedConfiguration struct {
+	SourceName   string // Name of the configuration in the source organization
+	BaseName     string // Name chosen for the copy before the name prefix and suffix are added
+	Name         string // Name the copy will have in the target organizations
+	Description  string
+	Settings     map[string]interface{}
+	Enforcement  string
+	Scope        string
+	SetAsDefault bool
+}
 
-	// Check if user has access to the source organization
-	status, err := api.CheckSingleOrganizationMembership(copyFromOrg)
-	if err != nil {
-		return "", "", nil, "", false, fmt.Errorf("failed to check membership for organization '%s': %w", copyFromOrg, err)
-	}
-	if !status.IsMember {
-		return "", "", nil, "", false, fmt.Errorf("you are not a member of organization '%s'", copyFromOrg)
-	}
-	if !status.IsOwner {
-		return "", "", nil, "", false, fmt.Errorf("you are a member but not an owner of organization '%s'", copyFromOrg)
+// HandleCopyFromOrg handles the copy-from-org functionality. Any non-empty fields on overrides
+// are used instead of prompting the user. The copy's name defaults to the source configuration's
+// name, and the name prefix and suffix are added to whichever name is chosen.
+func HandleCopyFromOrg(copyFromOrg string, overrides CopyFromOrgOverrides) (*CopiedConfiguration, error) {
+	configs, err := FetchOrgConfigurations(copyFromOrg, "source", true)
+	if errors.Is(err, types.ErrOrganizationNotFound) {
+		return nil, utils.CopySourceHostError(copyFromOrg, "was not found", api.CurrentHost())
 	}
-
-	// Fetch security configurations from the source organization
-	configs, err := api.FetchSecurityConfigurations(copyFromOrg)
 	if err != nil {
-		return "", "", nil, "", false, fmt.Errorf("failed to fetch security configurations from organization '%s': %w", copyFromOrg, err)
-	}
-
-	if len(configs) == 0 {
-		return "", "", nil, "", false, fmt.Errorf("no security configurations found in organization '%s'", copyFromOrg)
+		return nil, err
 	}
 
 	// Select configuration: via override or interactively
-	var selectedConfigData types.SecurityConfiguration
-	if overrides.ConfigName != "" {
-		found := false
-		for _, config := range configs {
-			if config.Name == overrides.ConfigName {
-				selectedConfigData = config
-				found = true
-				break
-			}
-		}
-		if !found {
-			return "", "", nil, "", false, fmt.Errorf("configuration %q not found in organization '%s'", overrides.ConfigName, copyFromOrg)
-		}
-	} else {
-		// Present configurations for selection
-		var configOptions []string
-		configMap := make(map[string]types.SecurityConfiguration)
-		for _, config := range configs {
-			displayName := fmt.Sprintf("%s - %s", config.Name, config.Description)
-			configOptions = append(configOptions, displayName)
-			configMap[displayName] = config
-		}
-
-		selectedConfig, err := pterm.DefaultInteractiveSelect.WithOptions(configOptions).Show("Select a configuration to copy")
-		if err != nil {
-			return "", "", nil, "", false, err
-		}
-		selectedConfigData = configMap[selectedConfig]
+	selectedConfigData, err := SelectConfiguration(configs, overrides.ConfigName, "copy")
+	if err != nil {
+		return nil, err
 	}
 
 	// Get detailed configuration including settings
-	configDetails, err := api.GetSecurityConfigurationDetails(copyFromOrg, selectedConfigData.ID)
+	configDetails, err := api.GetSecurityConfigurationDetails(context.Background(), copyFromOrg, selectedConfigData.ID)
 	if err != nil {
-		return "", "", nil, "", false, fmt.Errorf("failed to fetch configuration details: %w", err)
+		return nil, fmt.Errorf("failed to fetch configuration details: %w", err)
 	}
 
 	pterm.Success.Printf("Selected configuration '%s' from organization '%s'\n", selectedConfigData.Name, copyFromOrg)
+	if unmodeled := configDetails.UnmodeledSettings; len(unmodeled) > 0 {
+		LogWarningf("%d setting(s) of '%s' are not known to this extension and will not be copied: %s (run 'gh security-config schema drift' for details)", len(unmodeled), selectedConfigData.Name, strings.Join(slices.Sorted(maps.Keys(unmodeled)), ", "))
+	}
 
 	// Display current settings
 	pterm.Info.Println("Configuration details that will be copied:")
-	DisplayCurrentSettings(configDetails.Settings, configDetails.Description)
+	DisplayCurrentSettings(configDetails.Settings, configDetails.Enforcement, configDetails.Description)
 	pterm.Println()
 
+	// Offer to rename the copy. When the source configuration was chosen non-interactively the
+	// source name is kept unless a new name is also supplied, so scripted runs never prompt.
+	newName := selectedConfigData.Name
+	if overrides.ConfigName == "" || overrides.NewName != "" {
+		newName, err = GetCopiedConfigName(selectedConfigData.Name, overrides.NewName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	baseName := newName
+	newName, err = utils.ApplyNameAffixes(baseName, overrides.NamePrefix, overrides.NameSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCopiedConfigName(selectedConfigData.Name, newName, configs, overrides.AllowExisting); err != nil {
+		return nil, err
+	}
+	if newName != selectedConfigData.Name {
+		pterm.Info.Printf("The copied configuration will be created as '%s' (source '%s')\n", newName, selectedConfigData.Name)
+	}
+
 	// Ask for attachment scope (this might be different for target organizations)
 	scope, err := GetAttachmentScope(overrides.Scope)
 	if err != nil {
-		return "", "", nil, "", false, err
+		return nil, err
 	}
 
 	// Ask about setting as default (this might be different for target organizations)
 	setAsDefault, err := GetDefaultSetting(overrides.SetAsDefault)
 	if err != nil {
-		return "", "", nil, "", false, err
+		return nil, err
 	}
 
-	return selectedConfigData.Name, configDetails.Description, configDetails.Settings, scope, setAsDefault, nil
+	return &CopiedConfiguration{
+		SourceName:   selectedConfigData.Name,
+		BaseName:     baseName,
+		Name:         newName,
+		Description:  configDetails.Description,
+		Settings:     configDetails.Settings,
+		Enforcement:  configDetails.Enforcement,
+		Scope:        scope,
+		SetAsDefault: setAsDefault,
+	}, nil
 }
 
 // ConfirmApplyOperation shows operation summary and asks for confirmation for apply command.
 // If skipConfirm is true, the summary is shown and true is returned without prompting.
-func ConfirmApplyOperation(orgs []string, configName, configDescription string, settings map[string]interface{}, scope string, setAsDefault bool, skipConfirm bool) (bool, error) {
+func ConfirmApplyOperation(identity types.RunIdentity, orgs []string, configName, configDescription string, settings map[string]interface{}, enforcement string, scope string, setAsDefault bool, skipConfirm bool) (bool, error) {
 	pterm.Println()
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("Apply Operation Summary")
 
+	showRunIdentity(identity)
+
 	pterm.Printf("Organizations: %d\n", len(orgs))
 	pterm.Printf("Configuration Name: %s\n", pterm.Yellow(configName))
-	pterm.Printf("Configuration Description: %s\n", pterm.Yellow(configDescription))
+	pterm.Printf("Configuration Description: %s\n", pterm.Yellow(utils.TruncateDescription(configDescription, descriptionPreviewLength)))
 	pterm.Println()
 
 	pterm.Info.Println("Security Settings:")
 	for key, value := range settings {
-		valueStr := fmt.Sprintf("%v", value)
-		var coloredValue string
-
-		switch valueStr {
-		case "enabled", "enforced":
-			coloredValue = pterm.Green(valueStr)
-		case "disabled", "unenforced":
-			coloredValue = pterm.Red(valueStr)
-		case "not_set":
-			coloredValue = pterm.Yellow(valueStr)
-		default:
-			coloredValue = pterm.Yellow(valueStr)
-		}
-
-		pterm.Printf("  %s: %s\n", pterm.Cyan(key), coloredValue)
+		pterm.Printf("  %s: %s\n", pterm.Cyan(key), RenderSettingValue(value))
 	}
+	pterm.Printf("Enforcement: %s\n", coloredEnforcement(enforcement))
 	pterm.Println()
 
 	pterm.Printf("Attachment Scope: %s\n", pterm.Magenta(scope))
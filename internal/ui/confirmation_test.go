@@ -0,0 +1,73 @@
+package ui
+
+import "testing"
+
+func TestRequiresTypedCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		allOrgs  bool
+		orgCount int
+		want     bool
+	}{
+		{name: "all orgs above threshold", allOrgs: true, orgCount: LargeRunThreshold + 1, want: true},
+		{name: "all orgs at threshold", allOrgs: true, orgCount: LargeRunThreshold, want: false},
+		{name: "all orgs small run", allOrgs: true, orgCount: 3, want: false},
+		{name: "org list above threshold", allOrgs: false, orgCount: LargeRunThreshold * 2, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requiresTypedCount(tt.allOrgs, tt.orgCount); got != tt.want {
+				t.Errorf("requiresTypedCount(%t, %d) = %t, want %t", tt.allOrgs, tt.orgCount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnterpriseTargetHeadline(t *testing.T) {
+	tests := []struct {
+		name        string
+		enterprises []string
+		counts      map[string]int
+		want        string
+	}{
+		{name: "single enterprise", enterprises: []string{"acme"}, want: "This will target 120 organizations across enterprise acme"},
+		{name: "several enterprises", enterprises: []string{"acme", "globex"}, want: "This will target 120 organizations across enterprises acme, globex"},
+		{
+			name:        "several enterprises with counts",
+			enterprises: []string{"acme", "globex", "initech"},
+			counts:      map[string]int{"acme": 80, "globex": 40},
+			want:        "This will target 120 organizations across enterprises acme (80), globex (40), initech (0)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enterpriseTargetHeadline("120", tt.enterprises, tt.counts); got != tt.want {
+				t.Errorf("enterpriseTargetHeadline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmEnterpriseTargetCount_SkipsPrompt(t *testing.T) {
+	tests := []struct {
+		name        string
+		orgCount    int
+		allOrgs     bool
+		skipConfirm bool
+	}{
+		{name: "not all orgs", orgCount: 500, allOrgs: false},
+		{name: "below threshold", orgCount: LargeRunThreshold, allOrgs: true},
+		{name: "skip confirmation", orgCount: 500, allOrgs: true, skipConfirm: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmed, err := ConfirmEnterpriseTargetCount(tt.orgCount, []string{"acme"}, nil, tt.allOrgs, tt.skipConfirm)
+			if err != nil || !confirmed {
+				t.Errorf("ConfirmEnterpriseTargetCount() = %t, %v, want true without prompting", confirmed, err)
+			}
+		})
+	}
+}
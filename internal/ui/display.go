@@ -2,42 +2,89 @@ package ui
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/pterm/pterm"
 
-	"github.com/callmegreg/gh-security-config/internal/utils"
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
-// DisplayCurrentSettings shows current configuration settings with colored output
-func DisplayCurrentSettings(settings map[string]interface{}, description string) {
+// DisplayCurrentSettings shows current configuration settings, rendered by RenderSettingValue,
+// followed by the configuration's enforcement
+func DisplayCurrentSettings(settings map[string]interface{}, enforcement, description string) {
 	pterm.Printf("  Description: %s\n", pterm.Yellow(description))
 	for key, value := range settings {
-		valueStr := fmt.Sprintf("%v", value)
-		var coloredValue string
-
-		switch valueStr {
-		case "enabled", "enforced":
-			coloredValue = pterm.Green(valueStr)
-		case "disabled", "unenforced":
-			coloredValue = pterm.Red(valueStr)
-		case "not_set":
-			coloredValue = pterm.Yellow(valueStr)
-		default:
-			coloredValue = pterm.Yellow(valueStr)
-		}
+		pterm.Printf("  %s: %s\n", pterm.Cyan(key), RenderSettingValue(value))
+	}
+	pterm.Printf("  Enforcement: %s\n", coloredEnforcement(enforcement))
+}
+
+// coloredEnforcement describes an enforcement value, e.g. "enforced — repo owners cannot
+// override", colored green when enforced and yellow otherwise
+func coloredEnforcement(enforcement string) string {
+	description := types.DescribeEnforcement(enforcement)
+	if enforcement == types.EnforcementEnforced {
+		return pterm.Green(description)
+	}
+	return pterm.Yellow(description)
+}
+
+// hyperlinksSupported reports whether terminal output may contain OSC 8 hyperlinks: output must
+// not be plain, and stdout must be a terminal that is not "dumb". It is a variable so tests can
+// choose either rendering.
+var hyperlinksSupported = func() bool {
+	if PlainOutput() || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Hyperlink renders text as a clickable OSC 8 link to url when the terminal supports it, and as
+// "text (url)" otherwise so the address survives redirection to a file. An empty url leaves text as is.
+func Hyperlink(url, text string) string {
+	if url == "" {
+		return text
+	}
+	if !hyperlinksSupported() {
+		return fmt.Sprintf("%s (%s)", text, url)
+	}
+	return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", url, text)
+}
 
-		pterm.Printf("  %s: %s\n", pterm.Cyan(key), coloredValue)
+// ShowTargetSummary prints the final target organizations once every target filter has been
+// applied, with the steps that removed any. Organizations are listed by name when there are at
+// most 10. A single --org target was already announced when it was read, so nothing is printed.
+func ShowTargetSummary(orgs []string, breakdown types.TargetBreakdown) {
+	if breakdown.Source == "--org" {
+		return
 	}
+	reasons := breakdown.Reasons()
+	if len(reasons) > 1 {
+		pterm.Info.Printf("Targeting %d organization(s): %s\n", len(orgs), strings.Join(reasons, ", "))
+	} else {
+		pterm.Info.Printf("Targeting %d organization(s)\n", len(orgs))
+	}
+	if len(orgs) <= 10 {
+		for _, org := range orgs {
+			pterm.Printf("  - %s\n", pterm.Green(org))
+		}
+	}
+	pterm.Println()
 }
 
-// ShowNoOrganizationsWarning displays appropriate warning based on org targeting mode
-func ShowNoOrganizationsWarning(flags *utils.CommonFlags) {
-	if flags.Org != "" {
-		LogWarningf("Organization '%s' was not found or is not accessible.", flags.Org)
-	} else if flags.OrgListPath != "" {
-		LogWarningf("No valid organizations found in the CSV file.")
-	} else if flags.AllOrgs {
-		LogWarningf("No organizations found in the enterprise.")
+// ShowNoTargetsBreakdown explains why no organizations were left to process
+func ShowNoTargetsBreakdown(breakdown types.TargetBreakdown) {
+	if !WarningEnabled() {
+		return
+	}
+	LogWarningf("No target organizations remain. Nothing to do.")
+	for _, reason := range breakdown.Reasons() {
+		pterm.Printf("  - %s\n", reason)
 	}
 }
 
@@ -55,3 +102,135 @@ func ShowProcessingStart(orgCount, concurrency int) {
 func ShowProcessingStartWithDelay(orgCount, delay int) {
 	pterm.Info.Printf("Processing %d organizations sequentially with %d second delay between organizations...\n", orgCount, delay)
 }
+
+// ShowProbeStart announces that the --probe organization is processed before the others
+func ShowProbeStart(probeOrg string, remaining int) {
+	pterm.Info.Printf("Probing organization '%s' before the other %d organization(s)...\n", probeOrg, remaining)
+}
+
+// ShowProbeResult prints the outcome of the --probe organization followed by the responses to the
+// requests it sent, so they can be checked before the other organizations are processed
+func ShowProbeResult(result types.ProcessingResult, responses string) {
+	pterm.Println()
+	pterm.DefaultSection.Println("Probe Result")
+	switch {
+	case result.Success:
+		pterm.Success.Printf("Organization '%s' was processed successfully\n", result.Organization)
+	case result.Skipped:
+		loglevel.RecordWarning()
+		pterm.Warning.Printf("Organization '%s' was skipped: %s\n", result.Organization, result.SkipReason)
+	default:
+		pterm.Error.Printf("Organization '%s' failed: %v\n", result.Organization, result.Error)
+	}
+	LogConfigurationResults(result)
+
+	if responses == "" {
+		pterm.Info.Println("No create, update, attach or default requests were sent.")
+	} else {
+		pterm.Info.Println("API responses:")
+		pterm.Println(responses)
+	}
+	pterm.Println()
+}
+
+// ShowEnterpriseTotals prints the outcome counts for each enterprise when the results span more
+// than one enterprise
+func ShowEnterpriseTotals(summary types.ProcessingSummary) {
+	totals := make(map[string]*types.ProcessingSummary)
+	for _, result := range summary.Results {
+		if result.Enterprise == "" {
+			continue
+		}
+		if totals[result.Enterprise] == nil {
+			totals[result.Enterprise] = &types.ProcessingSummary{}
+		}
+		totals[result.Enterprise].Add(result)
+	}
+	if len(totals) < 2 {
+		return
+	}
+
+	pterm.Println()
+	pterm.Info.Println("Results by enterprise:")
+	for _, enterprise := range slices.Sorted(maps.Keys(totals)) {
+		total := totals[enterprise]
+		pterm.Printf("  %s: %s succeeded, %s skipped, %s failed\n", pterm.Cyan(enterprise),
+			pterm.Green(total.Success), pterm.Yellow(total.Skipped), pterm.Red(total.Error))
+	}
+}
+
+// ShowSSOHint lists the organizations that refused the token because it is not authorized for their
+// SAML single sign-on, and explains how to authorize it on host. It is a warning, so it is counted
+// at every level but printed only when warnings are.
+func ShowSSOHint(orgs []string, host string) {
+	if len(orgs) == 0 {
+		return
+	}
+	loglevel.RecordWarning()
+	if !WarningEnabled() {
+		return
+	}
+	pterm.Println()
+	pterm.Warning.Printf("%d organization(s) failed because the token is not authorized for their SAML single sign-on:\n", len(orgs))
+	for _, org := range orgs {
+		pterm.Printf("  - %s\n", org)
+	}
+	pterm.Printf("Authorize a personal access token under https://%s/settings/tokens (Configure SSO), or sign in at https://%s/orgs/<org>/sso and run 'gh auth refresh' for the gh token, then retry these organizations.\n", host, host)
+}
+
+// ShowHookFailures lists the organizations that were processed successfully but whose --post-hook
+// command failed
+func ShowHookFailures(results []types.ProcessingResult) {
+	if len(results) == 0 {
+		return
+	}
+	pterm.Println()
+	loglevel.RecordWarning()
+	pterm.Warning.Printf("The post hook failed for %d organization(s); their configuration changes were kept:\n", len(results))
+	for _, result := range results {
+		pterm.Printf("  - %s: %v\n", result.Organization, result.HookError)
+	}
+}
+
+// errorGroupOrgLimit is how many organizations each error group names before truncating the list
+const errorGroupOrgLimit = 5
+
+// ShowErrorGroups prints each group of organizations that failed with the same error once, with
+// its remedy, so a failure shared by hundreds of organizations does not bury the summary. The
+// organization lists are truncated. Only the first failure of each cause is printed as it
+// happens; with --log-level info, every later one is logged as well.
+func ShowErrorGroups(groups []types.ErrorGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	pterm.Println()
+	pterm.Error.Printf("Failures by cause (%d):\n", len(groups))
+	for _, group := range groups {
+		pterm.Printf("  %s — %d organization(s): %s\n", pterm.Red(group.Category), len(group.Organizations), group.Message)
+		orgs := group.Organizations
+		list := strings.Join(orgs[:min(len(orgs), errorGroupOrgLimit)], ", ")
+		if len(orgs) > errorGroupOrgLimit {
+			list += fmt.Sprintf(" and %d more [list truncated, --log-level info prints every failure]", len(orgs)-errorGroupOrgLimit)
+		}
+		pterm.Printf("    Organizations: %s\n", list)
+		if group.Hint != "" {
+			pterm.Printf("    Hint: %s\n", group.Hint)
+		}
+	}
+}
+
+// ShowInvalidOrgEntries repeats, after the results, the --org-list rows that were skipped because
+// the organization name is invalid or no such organization exists, in one list, so they are not
+// lost in the output printed before processing
+func ShowInvalidOrgEntries(path string, entries []types.InvalidOrgEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	pterm.Println()
+	loglevel.RecordWarning()
+	pterm.Warning.Printf("%d row(s) of %s were not processed because the organization name is invalid or the organization was not found:\n", len(entries), path)
+	for _, entry := range entries {
+		pterm.Printf("  - %s\n", entry)
+	}
+	pterm.Println("Fix these rows and rerun for them, or use --strict-org-list to stop before confirmation when a row is invalid.")
+}
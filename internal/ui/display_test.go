@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/loglevel"
+)
+
+func TestHyperlink(t *testing.T) {
+	const url = "https://github.com/organizations/octo-org/settings/security_products/configurations/7"
+	tests := []struct {
+		name      string
+		supported bool
+		url       string
+		want      string
+	}{
+		{name: "terminal", supported: true, url: url, want: "\x1b]8;;" + url + "\x1b\\baseline\x1b]8;;\x1b\\"},
+		{name: "plain text fallback", supported: false, url: url, want: "baseline (" + url + ")"},
+		{name: "no url", supported: true, url: "", want: "baseline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := hyperlinksSupported
+			defer func() { hyperlinksSupported = orig }()
+			hyperlinksSupported = func() bool { return tt.supported }
+
+			if got := Hyperlink(tt.url, "baseline"); got != tt.want {
+				t.Errorf("Hyperlink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShowSSOHint_FollowsLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	pterm.SetDefaultOutput(&buf)
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		SetLogLevel(LogLevelWarning)
+		loglevel.ResetWarningCount()
+	})
+
+	for _, tt := range []struct {
+		level     LogLevel
+		wantPrint bool
+	}{
+		{level: LogLevelWarning, wantPrint: true},
+		{level: LogLevelError, wantPrint: false},
+	} {
+		buf.Reset()
+		loglevel.ResetWarningCount()
+		SetLogLevel(tt.level)
+		ShowSSOHint([]string{"saml-org"}, "github.com")
+		if printed := strings.Contains(buf.String(), "saml-org"); printed != tt.wantPrint {
+			t.Errorf("level %v: hint printed = %v, want %v", tt.level, printed, tt.wantPrint)
+		}
+		if WarningCount() != 1 {
+			t.Errorf("level %v: WarningCount() = %d, want 1", tt.level, WarningCount())
+		}
+	}
+}
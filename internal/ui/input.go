@@ -6,8 +6,23 @@ import (
 	"strings"
 
 	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/api"
+	"github.com/callmegreg/gh-security-config/internal/utils"
 )
 
+// textInput shows a single-line interactive text prompt with a pre-filled default. It is a
+// variable so tests can substitute canned answers for the prompt.
+var textInput = func(prompt, defaultText string) (string, error) {
+	return pterm.DefaultInteractiveTextInput.WithDefaultText(defaultText).WithMultiLine(false).Show(prompt)
+}
+
+// selectInput shows an interactive select and returns the chosen option. It is a variable so
+// tests can substitute canned answers for the prompt.
+var selectInput = func(prompt string, options []string) (string, error) {
+	return pterm.DefaultInteractiveSelect.WithOptions(options).Show(prompt)
+}
+
 // GetEnterpriseInput prompts for enterprise slug or uses provided value
 func GetEnterpriseInput(enterpriseFlag string) (string, error) {
 	// If enterprise slug is provided via flag, use it
@@ -86,25 +101,42 @@ func GetDependabotSecurityUpdatesAvailability(dependabotSecurityUpdatesAvailable
 	return isAvailable, nil
 }
 
-// SetupGitHubHost sets the GH_HOST environment variable if using GitHub Enterprise Server
+// SetupGitHubHost sets the GH_HOST environment variable if using GitHub Enterprise Server, then
+// chooses the REST API version for the host, so the server release is read once, before processing
 func SetupGitHubHost(serverURL string) {
 	if serverURL != "" {
 		os.Setenv("GH_HOST", serverURL)
 		pterm.Info.Printf("Using GitHub Enterprise Server: %s\n", serverURL)
 	}
+	api.DetectAPIVersion()
+}
+
+// Interactive reports whether the run can prompt for input: stdin must be a terminal and the run
+// must not be in CI
+func Interactive() bool {
+	if RunningInCI(os.Getenv) {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// TargetPrompts returns the prompts api.ResolveTargets asks for organization targeting with
+func TargetPrompts() utils.TargetPrompts {
+	return utils.TargetPrompts{SelectMethod: SelectOrgTargetingMethod, OrgName: GetSingleOrgName, OrgListPath: GetOrgListPath}
 }
 
 // SelectOrgTargetingMethod prompts user to select an org targeting method
 func SelectOrgTargetingMethod() (string, error) {
 	options := []string{
-		"all-orgs",
-		"single-org",
-		"org-list",
+		utils.TargetAllOrgs,
+		utils.TargetSingleOrg,
+		utils.TargetOrgList,
 	}
 
 	selection, err := pterm.DefaultInteractiveSelect.
 		WithOptions(options).
-		WithDefaultOption("all-orgs").
+		WithDefaultOption(utils.TargetAllOrgs).
 		Show("Select organization targeting method")
 	if err != nil {
 		return "", err
@@ -2,11 +2,14 @@ package ui
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 // LogLevel is an alias for loglevel.LogLevel so existing callers compile unchanged.
@@ -40,11 +43,15 @@ func WarningEnabled() bool { return loglevel.WarningEnabled() }
 // InfoEnabled reports whether informational messages should be emitted.
 func InfoEnabled() bool { return loglevel.InfoEnabled() }
 
+// WarningCount delegates to loglevel.WarningCount.
+func WarningCount() int { return loglevel.WarningCount() }
+
 // LogWarningf prints a warning message using pterm.Warning only when the
 // current log level is `warning` or lower. The format string and arguments
 // follow the usual fmt.Printf conventions and a trailing newline is appended
-// when absent.
+// when absent. The warning is counted at every level.
 func LogWarningf(format string, args ...interface{}) {
+	loglevel.RecordWarning()
 	if !WarningEnabled() {
 		return
 	}
@@ -77,3 +84,105 @@ func LogOrgSuccess(org string) {
 	}
 	pterm.Success.Printf("Successfully processed organization '%s'\n", org)
 }
+
+// AttachmentSummary describes an accepted attach request. GitHub enables the repositories after
+// accepting the request, so the summary says the attachment is asynchronous rather than done.
+// Any identifiers from the response body are listed, sorted by field name.
+func AttachmentSummary(attachment *types.Attachment) string {
+	var summary string
+	if attachment.Scope == "selected" {
+		summary = fmt.Sprintf("Attach accepted for %d repositories (async)", attachment.Repositories)
+	} else {
+		summary = fmt.Sprintf("Attach accepted for scope '%s' (async)", attachment.Scope)
+	}
+	if len(attachment.Response) > 0 {
+		var fields []string
+		for _, key := range slices.Sorted(maps.Keys(attachment.Response)) {
+			fields = append(fields, fmt.Sprintf("%s=%v", key, attachment.Response[key]))
+		}
+		summary += "; response: " + strings.Join(fields, ", ")
+	}
+	return summary
+}
+
+// LogConfigurationResults prints the outcome of each configuration processed in an organization
+// when its processing involved more than one configuration. Successes are informational;
+// skips, failures, and replaced default configurations are warnings. Accepted attach requests,
+// excluded repositories, and settings syncs are reported as information. The output of a
+// --post-hook command is information too, and its failure a warning.
+func LogConfigurationResults(result types.ProcessingResult) {
+	for _, config := range result.Configurations {
+		switch {
+		case config.Success:
+			LogInfof("Configuration '%s' processed in organization '%s'", config.Name, result.Organization)
+		case config.Skipped:
+			LogWarningf("Configuration '%s' skipped in organization '%s'", config.Name, result.Organization)
+		case config.Error != nil:
+			LogWarningf("Configuration '%s' failed in organization '%s': %v", config.Name, result.Organization, config.Error)
+		}
+	}
+	if result.SettingsSynced != nil {
+		if *result.SettingsSynced {
+			LogInfof("Synced configuration settings to the template in organization '%s'", result.Organization)
+		} else {
+			LogInfof("Configuration settings already match the template in organization '%s'", result.Organization)
+		}
+	}
+	if attachment := result.Attachment; attachment != nil {
+		if attachment.Accepted {
+			LogInfof("%s in organization '%s'", AttachmentSummary(attachment), result.Organization)
+		}
+		if attachment.ExcludedRepos > 0 {
+			LogInfof("Excluded %d repositories from attachment in organization '%s'", attachment.ExcludedRepos, result.Organization)
+		}
+		if attachment.CodeQLRepos != nil {
+			LogInfof("Attached %d repositories with a CodeQL-supported language in organization '%s'; left out %d without one", attachment.CodeQLRepos.Included, result.Organization, attachment.CodeQLRepos.Excluded)
+		}
+	}
+	if result.HookOutput != "" {
+		LogInfof("Post hook output for organization '%s':\n%s", result.Organization, result.HookOutput)
+	}
+	if result.HookError != nil {
+		LogWarningf("Post hook failed in organization '%s': %v", result.Organization, result.HookError)
+	}
+	if result.DefaultSet != nil && !*result.DefaultSet {
+		LogInfof("Configuration is already the default in organization '%s', left unchanged", result.Organization)
+	}
+	if len(result.ReplacedDefaults) > 0 {
+		LogWarningf("Replaced the existing default configuration in organization '%s': %s", result.Organization, strings.Join(result.ReplacedDefaults, ", "))
+	}
+	if result.Before != nil && result.After != nil {
+		if changes := SnapshotChanges(result.Before, result.After); len(changes) > 0 {
+			LogInfof("Changed in organization '%s': %s", result.Organization, strings.Join(changes, "; "))
+		} else {
+			LogInfof("Configuration in organization '%s' already matched the update", result.Organization)
+		}
+	}
+}
+
+// SnapshotChanges lists what differs between two snapshots of a configuration, e.g.
+// "secret_scanning: disabled → enabled": the name, description and enforcement, then each setting
+// sorted by key
+func SnapshotChanges(before, after *types.SecurityConfigurationDetails) []string {
+	var changes []string
+	addChange := func(key, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", key, from, to))
+		}
+	}
+	addChange("name", before.Name, after.Name)
+	addChange("description", before.Description, after.Description)
+	addChange(types.EnforcementSetting.Key, before.Enforcement, after.Enforcement)
+
+	keys := slices.Collect(maps.Keys(before.Settings))
+	for key := range after.Settings {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		addChange(key, types.SettingValue(before.Settings, key), types.SettingValue(after.Settings, key))
+	}
+	return changes
+}
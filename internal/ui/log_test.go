@@ -1,6 +1,11 @@
 package ui
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
 
 func TestParseLogLevel(t *testing.T) {
 	tests := []struct {
@@ -63,3 +68,51 @@ func TestInfoEnabled(t *testing.T) {
 		t.Errorf("InfoEnabled() = true, want false for LogLevelError")
 	}
 }
+
+func TestAttachmentSummary(t *testing.T) {
+	tests := []struct {
+		name       string
+		attachment types.Attachment
+		want       string
+	}{
+		{name: "scope", attachment: types.Attachment{Scope: "all", Accepted: true}, want: "Attach accepted for scope 'all' (async)"},
+		{name: "selected", attachment: types.Attachment{Scope: "selected", Repositories: 12, Accepted: true}, want: "Attach accepted for 12 repositories (async)"},
+		{
+			name:       "response identifiers",
+			attachment: types.Attachment{Scope: "public", Accepted: true, Response: map[string]interface{}{"status": "queued", "job_id": "abc"}},
+			want:       "Attach accepted for scope 'public' (async); response: job_id=abc, status=queued",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AttachmentSummary(&tt.attachment); got != tt.want {
+				t.Errorf("AttachmentSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotChanges(t *testing.T) {
+	before := &types.SecurityConfigurationDetails{
+		Name:        "Baseline",
+		Enforcement: "unenforced",
+		Settings:    map[string]interface{}{"secret_scanning": "disabled", "dependabot_alerts": "enabled"},
+	}
+	after := &types.SecurityConfigurationDetails{
+		Name:        "Baseline v2",
+		Enforcement: "unenforced",
+		Settings:    map[string]interface{}{"secret_scanning": "enabled", "dependabot_alerts": "enabled", "code_scanning_default_setup": "enabled"},
+	}
+
+	want := []string{
+		"name: Baseline → Baseline v2",
+		"code_scanning_default_setup: not_set → enabled",
+		"secret_scanning: disabled → enabled",
+	}
+	if got := SnapshotChanges(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("SnapshotChanges() = %q, want %q", got, want)
+	}
+	if got := SnapshotChanges(before, before); len(got) != 0 {
+		t.Errorf("SnapshotChanges() of identical snapshots = %q, want none", got)
+	}
+}
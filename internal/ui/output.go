@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"github.com/pterm/pterm"
+
+	"github.com/callmegreg/gh-security-config/internal/utils"
+)
+
+// Values accepted by --color and --output-style. "auto" follows the environment.
+const (
+	OutputAuto  = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+	OutputRich  = "rich"
+	OutputPlain = "plain"
+)
+
+// ColorValues and OutputStyleValues list the accepted values for --color and --output-style
+var (
+	ColorValues       = []string{OutputAuto, ColorAlways, ColorNever}
+	OutputStyleValues = []string{OutputAuto, OutputRich, OutputPlain}
+)
+
+// OutputMode is how the extension writes to the terminal
+type OutputMode struct {
+	// Color enables ANSI colors
+	Color bool
+	// Plain drops pterm decorations and animations: messages are printed as "INFO: ..." lines,
+	// headers as plain text, and progress as one line per organization instead of a bar
+	Plain bool
+}
+
+// outputMode is the mode applied by ApplyOutputMode
+var outputMode = OutputMode{Color: true}
+
+// DetectOutputMode chooses the output mode from the environment, following the usual conventions:
+// NO_COLOR (any non-empty value) turns color off, CI (any value other than "false" or "0") switches
+// to plain output for log collectors, and TERM=dumb does both. colorFlag and styleFlag override the
+// environment unless they are "auto" or empty.
+func DetectOutputMode(getenv func(string) string, colorFlag, styleFlag string) (OutputMode, error) {
+	if err := utils.ValidateEnumValue("color", colorFlag, ColorValues); err != nil {
+		return OutputMode{}, err
+	}
+	if err := utils.ValidateEnumValue("output-style", styleFlag, OutputStyleValues); err != nil {
+		return OutputMode{}, err
+	}
+
+	dumb := getenv("TERM") == "dumb"
+	mode := OutputMode{
+		Color: getenv("NO_COLOR") == "" && !dumb,
+		Plain: dumb || RunningInCI(getenv),
+	}
+
+	switch styleFlag {
+	case OutputRich:
+		mode.Plain = false
+	case OutputPlain:
+		mode.Plain = true
+	}
+	switch colorFlag {
+	case ColorAlways:
+		mode.Color = true
+	case ColorNever:
+		mode.Color = false
+	}
+	return mode, nil
+}
+
+// RunningInCI reports whether the CI environment variable is set to anything other than "false" or
+// "0", as CI services do
+func RunningInCI(getenv func(string) string) bool {
+	ci := getenv("CI")
+	return ci != "" && ci != "false" && ci != "0"
+}
+
+// ApplyOutputMode configures pterm for mode. It must run before anything is printed.
+func ApplyOutputMode(mode OutputMode) {
+	outputMode = mode
+	if mode.Plain {
+		// Raw output also strips color from pterm's printers; explicitly colored text keeps its
+		// color only when asked for
+		pterm.DisableStyling()
+		if mode.Color {
+			pterm.EnableColor()
+		}
+		return
+	}
+	pterm.EnableStyling()
+	if !mode.Color {
+		pterm.DisableColor()
+	}
+}
+
+// PlainOutput reports whether plain, line-per-event output is in effect
+func PlainOutput() bool {
+	return outputMode.Plain
+}
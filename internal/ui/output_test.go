@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+func TestDetectOutputMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		color   string
+		style   string
+		want    OutputMode
+		wantErr bool
+	}{
+		{name: "interactive terminal", env: map[string]string{"TERM": "xterm-256color"}, want: OutputMode{Color: true}},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1"}, want: OutputMode{}},
+		{name: "empty NO_COLOR is ignored", env: map[string]string{"NO_COLOR": ""}, want: OutputMode{Color: true}},
+		{name: "CI", env: map[string]string{"CI": "true"}, want: OutputMode{Color: true, Plain: true}},
+		{name: "CI set to false", env: map[string]string{"CI": "false"}, want: OutputMode{Color: true}},
+		{name: "CI set to 0", env: map[string]string{"CI": "0"}, want: OutputMode{Color: true}},
+		{name: "CI and NO_COLOR", env: map[string]string{"CI": "true", "NO_COLOR": "1"}, want: OutputMode{Plain: true}},
+		{name: "dumb terminal", env: map[string]string{"TERM": "dumb"}, want: OutputMode{Plain: true}},
+		{name: "auto flags follow the environment", env: map[string]string{"CI": "1"}, color: OutputAuto, style: OutputAuto, want: OutputMode{Color: true, Plain: true}},
+		{name: "color always overrides NO_COLOR", env: map[string]string{"NO_COLOR": "1"}, color: ColorAlways, want: OutputMode{Color: true}},
+		{name: "color never", color: ColorNever, want: OutputMode{}},
+		{name: "rich overrides CI", env: map[string]string{"CI": "true"}, style: OutputRich, want: OutputMode{Color: true}},
+		{name: "rich on a dumb terminal stays uncolored", env: map[string]string{"TERM": "dumb"}, style: OutputRich, want: OutputMode{}},
+		{name: "plain without CI", style: OutputPlain, want: OutputMode{Color: true, Plain: true}},
+		{name: "invalid color", color: "sometimes", wantErr: true},
+		{name: "invalid style", style: "fancy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			got, err := DetectOutputMode(getenv, tt.color, tt.style)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectOutputMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectOutputMode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgress_PlainOutput(t *testing.T) {
+	var buf bytes.Buffer
+	pterm.SetDefaultOutput(&buf)
+	ApplyOutputMode(OutputMode{Plain: true})
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		ApplyOutputMode(OutputMode{Color: true})
+	})
+
+	progress := StartProgress(2, "Processing organizations")
+	progress.UpdateTitle("Processing org-a")
+	progress.Increment()
+	progress.UpdateTitle("Waiting 5 seconds before processing next organization...")
+	progress.UpdateTitle("Processing org-b")
+	progress.Increment()
+	progress.Stop()
+
+	want := "[1/2] Processing org-a\n[2/2] Processing org-b\n"
+	if buf.String() != want {
+		t.Errorf("plain progress output = %q, want %q", buf.String(), want)
+	}
+}
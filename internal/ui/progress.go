@@ -0,0 +1,55 @@
+package ui
+
+import "github.com/pterm/pterm"
+
+// Progress shows how many organizations have been processed. With styled output it is a pterm
+// progress bar; with plain output each step is printed as a "[current/total] title" line, since
+// log collectors cannot redraw a bar.
+type Progress struct {
+	bar     *pterm.ProgressbarPrinter
+	title   string
+	current int
+	total   int
+}
+
+// StartProgress starts tracking progress through total organizations
+func StartProgress(total int, title string) *Progress {
+	p := &Progress{title: title, total: total}
+	if !PlainOutput() {
+		p.bar, _ = pterm.DefaultProgressbar.WithTotal(total).WithTitle(title).Start()
+	}
+	return p
+}
+
+// UpdateTitle replaces the title shown with the next step
+func (p *Progress) UpdateTitle(title string) {
+	p.title = title
+	if p.bar != nil {
+		p.bar.UpdateTitle(title)
+	}
+}
+
+// Increment records one more organization as processed
+func (p *Progress) Increment() {
+	p.current++
+	if p.bar != nil {
+		p.bar.Increment()
+		return
+	}
+	pterm.Printf("[%d/%d] %s\n", p.current, p.total, p.title)
+}
+
+// Add records count organizations as done at once, e.g. those skipped when processing stops
+func (p *Progress) Add(count int) {
+	p.current += count
+	if p.bar != nil {
+		p.bar.Add(count)
+	}
+}
+
+// Stop removes the progress bar from the screen
+func (p *Progress) Stop() {
+	if p.bar != nil {
+		p.bar.Stop()
+	}
+}
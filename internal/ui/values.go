@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+)
+
+// ValueStyle controls how setting values are rendered. Every value carries a symbol as well as a
+// color, so the state can be read without telling red from green.
+type ValueStyle struct {
+	// ASCII replaces the ✓, ✗ and – symbols with +, x and -
+	ASCII bool
+	// HighContrast uses a bold blue and yellow palette, which stays distinct for red-green color
+	// blindness, instead of green and red
+	HighContrast bool
+}
+
+// valueStyle is the style applied by SetValueStyle
+var valueStyle ValueStyle
+
+// SetValueStyle sets how setting values are rendered for the rest of the run
+func SetValueStyle(style ValueStyle) {
+	valueStyle = style
+}
+
+// valuePalette holds the styles for enabled, disabled and any other value
+type valuePalette struct {
+	enabled, disabled, other *pterm.Style
+}
+
+var (
+	defaultPalette      = valuePalette{enabled: pterm.NewStyle(pterm.FgGreen), disabled: pterm.NewStyle(pterm.FgRed), other: pterm.NewStyle(pterm.FgYellow)}
+	highContrastPalette = valuePalette{enabled: pterm.NewStyle(pterm.FgLightBlue, pterm.Bold), disabled: pterm.NewStyle(pterm.FgLightYellow, pterm.Bold), other: pterm.NewStyle(pterm.FgLightWhite)}
+)
+
+// RenderSettingValue renders a feature setting's value with its symbol and color, e.g. "✓ enabled".
+// Values other than enabled, disabled and not_set, such as an enforcement, are colored but get no
+// symbol. Every summary, diff and table showing setting values goes through it.
+func RenderSettingValue(value interface{}) string {
+	valueStr := fmt.Sprintf("%v", value)
+	palette := defaultPalette
+	if valueStyle.HighContrast {
+		palette = highContrastPalette
+	}
+
+	switch valueStr {
+	case "enabled":
+		return palette.enabled.Sprint(valueSymbol("✓", "+") + " " + valueStr)
+	case "disabled":
+		return palette.disabled.Sprint(valueSymbol("✗", "x") + " " + valueStr)
+	case "not_set":
+		return palette.other.Sprint(valueSymbol("–", "-") + " " + valueStr)
+	default:
+		return palette.other.Sprint(valueStr)
+	}
+}
+
+// valueSymbol returns symbol, or its ASCII fallback when the ASCII style is in effect
+func valueSymbol(symbol, ascii string) string {
+	if valueStyle.ASCII {
+		return ascii
+	}
+	return symbol
+}
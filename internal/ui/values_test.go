@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestRenderSettingValue_Golden renders every kind of setting value in each value style, with and
+// without color, and compares the output with testdata/values/<style>.golden. Run with -update to
+// rewrite the golden files after an intended change.
+func TestRenderSettingValue_Golden(t *testing.T) {
+	values := []interface{}{"enabled", "disabled", "not_set", "enforced", "unenforced"}
+	styles := []struct {
+		name  string
+		style ValueStyle
+	}{
+		{name: "default", style: ValueStyle{}},
+		{name: "no_emoji", style: ValueStyle{ASCII: true}},
+		{name: "high_contrast", style: ValueStyle{HighContrast: true}},
+		{name: "high_contrast_no_emoji", style: ValueStyle{ASCII: true, HighContrast: true}},
+	}
+	t.Cleanup(func() {
+		SetValueStyle(ValueStyle{})
+		ApplyOutputMode(OutputMode{Color: true})
+	})
+
+	for _, tt := range styles {
+		t.Run(tt.name, func(t *testing.T) {
+			SetValueStyle(tt.style)
+			var got strings.Builder
+			for _, mode := range []OutputMode{{Color: true}, {}} {
+				ApplyOutputMode(mode)
+				fmt.Fprintf(&got, "color=%t\n", mode.Color)
+				for _, value := range values {
+					fmt.Fprintf(&got, "  %v: %q\n", value, RenderSettingValue(value))
+				}
+			}
+
+			goldenPath := filepath.Join("testdata", "values", tt.name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, []byte(got.String()), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("RenderSettingValue() mismatch\ngot:\n%s\nwant:\n%s", got.String(), want)
+			}
+		})
+	}
+}
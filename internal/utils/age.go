@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Day is the length of a day in ages given to --older-than. Weeks are 7 days and months 30 days.
+const Day = 24 * time.Hour
+
+// ageUnits maps the suffixes accepted by ParseAge to their length
+var ageUnits = map[string]time.Duration{
+	"d": Day,
+	"w": 7 * Day,
+	"m": 30 * Day,
+}
+
+// ParseAge parses an age such as "90d", "2w" or "3m": a positive whole number followed by d
+// (days), w (weeks) or m (30-day months)
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid age %q: expected a number followed by d, w or m, e.g. 90d", value)
+	}
+	unit, ok := ageUnits[value[len(value)-1:]]
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q: the unit must be d (days), w (weeks) or m (30-day months)", value)
+	}
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid age %q: expected a positive whole number before the unit", value)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// FormatAge renders an age in whole days, e.g. "120d"
+func FormatAge(age time.Duration) string {
+	return fmt.Sprintf("%dd", int(age/Day))
+}
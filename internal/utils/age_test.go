@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "90d", want: 90 * Day},
+		{value: "2w", want: 14 * Day},
+		{value: "3m", want: 90 * Day},
+		{value: " 1D ", want: Day},
+		{value: "", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "90", wantErr: true},
+		{value: "90h", wantErr: true},
+		{value: "0d", wantErr: true},
+		{value: "-5d", wantErr: true},
+		{value: "1.5w", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAge(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAge(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	if got := FormatAge(120*Day + 5*time.Hour); got != "120d" {
+		t.Errorf("FormatAge() = %q, want 120d", got)
+	}
+}
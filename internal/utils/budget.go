@@ -0,0 +1,88 @@
+package utils
+
+// RequestBudgetInput describes the shape of a run for estimating its REST API request budget
+type RequestBudgetInput struct {
+	Command      string // generate, apply, modify, delete, check, org-defaults list, or org-defaults clear
+	Orgs         int    // Number of target organizations
+	Attach       bool   // Whether the configuration is attached to repositories
+	SetAsDefault bool   // Whether the configuration is set as default for new repositories
+	AuditCopy    bool   // Whether generate also creates an audit copy
+	Overwrite    bool   // Whether generate may delete an existing configuration before recreating it
+	CheckDefault bool   // Whether each organization's existing default configuration is looked up first
+	SyncSettings bool   // Whether apply converges each configuration's settings before attaching
+	Deletes      int    // Configurations delete --all-configs removes across all organizations; 0 for one per organization
+
+	// Configurations searched for by name once for the run, in the template, --copy-from-org or
+	// reference organization rather than in each target
+	ConfigLookups int
+}
+
+// membershipCallsPerOrg is the number of requests made by the per-organization membership
+// check (the current user lookup and the membership lookup). The check runs after the budget
+// check, before the confirmation prompt, and processing reuses its results.
+const membershipCallsPerOrg = 2
+
+// configLookupCalls is the number of requests made to search for a configuration by name in one
+// organization (the configurations list) and read it (the configuration's details)
+const configLookupCalls = 2
+
+// EstimateRequestBudget estimates how many core REST API requests a run will make. The estimate
+// assumes every organization passes the membership check, so it is an upper bound. It includes
+// the configuration lookups made once for the run, although most are made before the budget is
+// checked, which keeps it an upper bound too.
+func EstimateRequestBudget(in RequestBudgetInput) int {
+	if in.Orgs <= 0 {
+		return 0
+	}
+
+	// Every command lists the organization's configurations (or its defaults) after the membership check
+	perOrg := membershipCallsPerOrg + 1
+	switch in.Command {
+	case "generate":
+		perOrg++ // create
+		if in.Overwrite {
+			perOrg++
+		}
+		if in.AuditCopy {
+			perOrg++
+		}
+		if in.Attach {
+			perOrg++
+		}
+		if in.SetAsDefault {
+			perOrg++
+		}
+	case "apply":
+		if in.SyncSettings {
+			perOrg += 2 // details, and an update when the settings differ
+		}
+		if in.Attach {
+			perOrg++
+		}
+		if in.SetAsDefault {
+			perOrg++
+		}
+	case "modify", "delete":
+		if in.Deletes > 0 {
+			break // counted across all organizations below
+		}
+		perOrg++ // update or delete
+		if in.Command == "modify" {
+			perOrg += 2 // configurations listed when the run is confirmed, and details read before the update
+		}
+	case "check":
+		perOrg++ // details of the checked configuration, assuming one per organization
+	case "drift":
+		perOrg++ // details of the configuration compared with the baseline
+	case "org-defaults list":
+		// Only the defaults lookup replaces the configurations list
+	case "org-defaults clear":
+		perOrg += 2 // at most one default per repository visibility
+	}
+
+	if in.CheckDefault {
+		perOrg++
+	}
+
+	return perOrg*in.Orgs + in.Deletes + in.ConfigLookups*configLookupCalls
+}
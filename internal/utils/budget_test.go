@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestEstimateRequestBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		in   RequestBudgetInput
+		want int
+	}{
+		{"no organizations", RequestBudgetInput{Command: "generate", Orgs: 0, Attach: true}, 0},
+		{"generate without attach", RequestBudgetInput{Command: "generate", Orgs: 10}, 40},
+		{"generate with attach and default", RequestBudgetInput{Command: "generate", Orgs: 10, Attach: true, SetAsDefault: true}, 60},
+		{"generate with every option", RequestBudgetInput{Command: "generate", Orgs: 2, Attach: true, SetAsDefault: true, AuditCopy: true, Overwrite: true}, 16},
+		{"apply attach only", RequestBudgetInput{Command: "apply", Orgs: 5, Attach: true}, 20},
+		{"apply attach and default", RequestBudgetInput{Command: "apply", Orgs: 5, Attach: true, SetAsDefault: true}, 25},
+		{"apply with settings sync", RequestBudgetInput{Command: "apply", Orgs: 5, Attach: true, SyncSettings: true}, 30},
+		{"apply with default check", RequestBudgetInput{Command: "apply", Orgs: 5, Attach: true, SetAsDefault: true, CheckDefault: true}, 30},
+		{"modify", RequestBudgetInput{Command: "modify", Orgs: 3}, 18},
+		{"delete", RequestBudgetInput{Command: "delete", Orgs: 3}, 12},
+		{"delete all configurations", RequestBudgetInput{Command: "delete", Orgs: 3, Deletes: 7}, 16},
+		{"check", RequestBudgetInput{Command: "check", Orgs: 4}, 16},
+		{"org-defaults list", RequestBudgetInput{Command: "org-defaults list", Orgs: 4}, 12},
+		{"org-defaults clear", RequestBudgetInput{Command: "org-defaults clear", Orgs: 4}, 20},
+		{"modify with template lookup", RequestBudgetInput{Command: "modify", Orgs: 3, ConfigLookups: 1}, 20},
+		{"apply with template and reference lookups", RequestBudgetInput{Command: "apply", Orgs: 5, Attach: true, ConfigLookups: 2}, 24},
+		{"config lookups without organizations", RequestBudgetInput{Command: "modify", ConfigLookups: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateRequestBudget(tt.in); got != tt.want {
+				t.Errorf("EstimateRequestBudget(%+v) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// CompletionCacheTTL is how long completion candidates fetched from the API are reused. Every tab
+// press starts a new process, so without a cache each one would call the API again.
+const CompletionCacheTTL = 10 * time.Minute
+
+// userCacheDir returns the base cache directory. It is a variable so tests can use a temp dir.
+var userCacheDir = os.UserCacheDir
+
+var unsafeCacheKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// completionCachePath returns the file that holds the completion candidates for key
+func completionCachePath(key string) (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-security-config", "completion", unsafeCacheKeyChars.ReplaceAllString(key, "_")+".json"), nil
+}
+
+// ReadCompletionCache returns the values cached under key if they were written less than ttl ago
+func ReadCompletionCache(key string, ttl time.Duration) ([]string, bool) {
+	path, err := completionCachePath(key)
+	if err != nil {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var values []string
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+// WriteCompletionCache stores values under key. Errors are ignored because the cache only saves
+// API calls; completion still works without it.
+func WriteCompletionCache(key string, values []string) {
+	path, err := completionCachePath(key)
+	if err != nil {
+		return
+	}
+	content, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, content, 0o600)
+}
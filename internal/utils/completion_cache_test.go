@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func useTempCacheDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := userCacheDir
+	userCacheDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { userCacheDir = original })
+	return dir
+}
+
+func TestCompletionCache_RoundTrip(t *testing.T) {
+	useTempCacheDir(t)
+
+	if _, ok := ReadCompletionCache("orgs-github.com-acme", time.Minute); ok {
+		t.Fatal("ReadCompletionCache() found values before any were written")
+	}
+
+	want := []string{"org-a", "org-b"}
+	WriteCompletionCache("orgs-github.com-acme", want)
+	got, ok := ReadCompletionCache("orgs-github.com-acme", time.Minute)
+	if !ok {
+		t.Fatal("ReadCompletionCache() missed freshly written values")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCompletionCache() = %v, want %v", got, want)
+	}
+}
+
+func TestCompletionCache_ExpiresAfterTTL(t *testing.T) {
+	useTempCacheDir(t)
+	WriteCompletionCache("orgs-acme", []string{"org-a"})
+
+	path, err := completionCachePath("orgs-acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ReadCompletionCache("orgs-acme", time.Minute); ok {
+		t.Error("ReadCompletionCache() returned values older than the TTL")
+	}
+}
+
+func TestCompletionCachePath_SanitizesKey(t *testing.T) {
+	dir := useTempCacheDir(t)
+	path, err := completionCachePath("orgs-https://ghes.example.com/acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "gh-security-config", "completion", "orgs-https___ghes.example.com_acme.json")
+	if path != want {
+		t.Errorf("completionCachePath() = %q, want %q", path, want)
+	}
+}
@@ -2,47 +2,134 @@ package utils
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/pterm/pterm"
 
 	"github.com/callmegreg/gh-security-config/internal/loglevel"
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 // ReadOrganizationsFromCSV reads organization names from a CSV file
 func ReadOrganizationsFromCSV(filePath string) ([]string, error) {
+	orgs, _, err := ReadOrganizationsFromCSVWithInvalid(filePath)
+	return orgs, err
+}
+
+// ReadOrganizationsFromCSVWithInvalid reads organization names from a CSV file and also returns
+// the rows that were skipped because their organization name is malformed
+func ReadOrganizationsFromCSVWithInvalid(filePath string) ([]string, []types.InvalidOrgEntry, error) {
+	orgs, _, invalid, err := readOrganizationsCSV(filePath, false)
+	return orgs, invalid, err
+}
+
+// ReadOrganizationsFromCSVWithLines is ReadOrganizationsFromCSVWithInvalid that also returns the
+// line of each organization, so a row found wrong later can still be pointed at
+func ReadOrganizationsFromCSVWithLines(filePath string) ([]string, []int, []types.InvalidOrgEntry, error) {
+	return readOrganizationsCSV(filePath, false)
+}
+
+// readOrganizationsCSV reads organization names from a CSV file with their lines, returning the
+// rows skipped as invalid. With strict, as for --strict-org-list, rows whose organization is empty once trimmed,
+// such as a line of spaces, are invalid rather than ignored, and nothing is printed, as the caller
+// rejects the file.
+func readOrganizationsCSV(filePath string, strict bool) ([]string, []int, []types.InvalidOrgEntry, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %w", err)
-	}
-
 	var orgs []string
-	for i, record := range records {
+	var lines []int
+	var invalid []types.InvalidOrgEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
 		if len(record) == 0 {
 			continue // Skip empty lines
 		}
+		// The reader skips blank lines, so the line number comes from the reader rather than a count
+		line, _ := reader.FieldPos(0)
 		orgName := strings.TrimSpace(record[0])
 		if orgName == "" {
+			if strict {
+				invalid = append(invalid, types.InvalidOrgEntry{Line: line})
+			}
 			continue // Skip empty organization names
 		}
 		// Basic validation for organization name format
 		if strings.Contains(orgName, " ") || strings.Contains(orgName, "/") {
-			if loglevel.WarningEnabled() {
-				pterm.Warning.Printf("Line %d: Invalid organization name format '%s', skipping\n", i+1, orgName)
+			if !strict {
+				loglevel.RecordWarning()
+				if loglevel.WarningEnabled() {
+					pterm.Warning.Printf("Line %d: Invalid organization name format '%s', skipping\n", line, orgName)
+				}
 			}
+			invalid = append(invalid, types.InvalidOrgEntry{Line: line, Name: orgName})
 			continue
 		}
 		orgs = append(orgs, orgName)
+		lines = append(lines, line)
+	}
+
+	return orgs, lines, invalid, nil
+}
+
+// WriteOrganizationsToCSV writes organization names, one per line, to a new temporary CSV file
+// that --org-list can read back. It returns the file's path.
+func WriteOrganizationsToCSV(orgs []string, pattern string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	for _, org := range orgs {
+		if err := writer.Write([]string{org}); err != nil {
+			return "", fmt.Errorf("failed to write CSV file: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// ReadRepositoryExclusions reads repositories to exclude from attachment from a file with one
+// "org/repo" entry per line. Blank lines and lines starting with "#" are ignored.
+func ReadRepositoryExclusions(filePath string) (types.RepositoryExclusions, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exclusions file: %w", err)
+	}
+
+	exclusions := make(types.RepositoryExclusions)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		org, repo, found := strings.Cut(line, "/")
+		org, repo = strings.TrimSpace(org), strings.TrimSpace(repo)
+		if !found || org == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("line %d of exclusions file: expected org/repo, got %q", i+1, line)
+		}
+		exclusions.Add(org, repo)
 	}
 
-	return orgs, nil
+	return exclusions, nil
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 func writeTempCSV(t *testing.T, content string) string {
@@ -54,6 +56,21 @@ func TestReadOrganizationsFromCSV_SkipsBlankAndInvalid(t *testing.T) {
 	}
 }
 
+func TestReadOrganizationsFromCSVWithInvalid_ReportsInvalidRows(t *testing.T) {
+	path := writeTempCSV(t, "org-one\n\nbad name\nbad/name\norg-two\n")
+	got, invalid, err := ReadOrganizationsFromCSVWithInvalid(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"org-one", "org-two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	want := []types.InvalidOrgEntry{{Line: 3, Name: "bad name"}, {Line: 4, Name: "bad/name"}}
+	if !reflect.DeepEqual(invalid, want) {
+		t.Errorf("invalid = %v, want %v", invalid, want)
+	}
+}
+
 func TestReadOrganizationsFromCSV_MultiColumnUsesFirst(t *testing.T) {
 	// CSV reader requires consistent field counts, so each row must have the same
 	// number of columns. Only the first column should be used as the org name.
@@ -85,3 +102,59 @@ func TestReadOrganizationsFromCSV_EmptyFile(t *testing.T) {
 		t.Errorf("expected empty slice, got %v", got)
 	}
 }
+
+func TestWriteOrganizationsToCSV_RoundTrip(t *testing.T) {
+	orgs := []string{"first-org", "second-org"}
+	path, err := WriteOrganizationsToCSV(orgs, "gh-sc-test-*.csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+
+	got, err := ReadOrganizationsFromCSV(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading back: %v", err)
+	}
+	if !reflect.DeepEqual(got, orgs) {
+		t.Errorf("round trip = %v, want %v", got, orgs)
+	}
+}
+
+func TestReadRepositoryExclusions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		excluded [][2]string
+		allowed  [][2]string
+		wantErr  bool
+	}{
+		{
+			name:     "entries, comments, and blank lines",
+			content:  "# frozen for audit\nfirst-org/mirror\n\n  first-org/Legacy-App  \nsecond-org/archive\n",
+			excluded: [][2]string{{"first-org", "mirror"}, {"first-org", "legacy-app"}, {"Second-Org", "ARCHIVE"}},
+			allowed:  [][2]string{{"first-org", "app"}, {"third-org", "mirror"}},
+		},
+		{name: "missing repo", content: "first-org/\n", wantErr: true},
+		{name: "missing slash", content: "first-org\n", wantErr: true},
+		{name: "too many segments", content: "first-org/repo/extra\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempCSV(t, tt.content)
+			exclusions, err := ReadRepositoryExclusions(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRepositoryExclusions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, e := range tt.excluded {
+				if !exclusions.Excludes(e[0], e[1]) {
+					t.Errorf("%s/%s should be excluded", e[0], e[1])
+				}
+			}
+			for _, a := range tt.allowed {
+				if exclusions.Excludes(a[0], a[1]) {
+					t.Errorf("%s/%s should not be excluded", a[0], a[1])
+				}
+			}
+		})
+	}
+}
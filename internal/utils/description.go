@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDescriptionLength is the longest description the security configurations API accepts
+const MaxDescriptionLength = 255
+
+// ReadDescription reads a configuration description from a file, or from stdin when path is "-".
+// Line breaks are folded into single spaces because the API stores a single-line string, and the
+// result is checked against MaxDescriptionLength.
+func ReadDescription(path string, stdin io.Reader) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read description from %s: %w", describeSource(path), err)
+	}
+
+	description := NormalizeDescription(string(content))
+	if description == "" {
+		return "", fmt.Errorf("description read from %s is empty", describeSource(path))
+	}
+	if err := ValidateDescriptionLength(description); err != nil {
+		return "", err
+	}
+	return description, nil
+}
+
+// NormalizeDescription joins the non-blank lines of a multi-line description with single spaces
+func NormalizeDescription(raw string) string {
+	var parts []string
+	for _, line := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ValidateDescriptionLength returns an error naming the overflowing text when description is
+// longer than MaxDescriptionLength characters
+func ValidateDescriptionLength(description string) error {
+	length := utf8.RuneCountInString(description)
+	if length <= MaxDescriptionLength {
+		return nil
+	}
+	overflow := string([]rune(description)[MaxDescriptionLength:])
+	return fmt.Errorf("description is %d characters, %d over the %d-character limit; the text past the limit is: %q", length, length-MaxDescriptionLength, MaxDescriptionLength, overflow)
+}
+
+// TruncateDescription shortens description to at most maxLength characters for previews,
+// marking the cut with an ellipsis
+func TruncateDescription(description string, maxLength int) string {
+	runes := []rune(description)
+	if len(runes) <= maxLength {
+		return description
+	}
+	return string(runes[:maxLength-1]) + "…"
+}
+
+// describeSource names the description source for error messages
+func describeSource(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return fmt.Sprintf("file '%s'", path)
+}
+
+// changeRefStamp matches a change reference stamp previously appended by StampChangeRef
+var changeRefStamp = regexp.MustCompile(`\s*\[change: [^\]]*\]$`)
+
+// StampChangeRef appends "[change: ref]" to description so the configuration shows which change
+// last touched it. An existing stamp is replaced rather than repeated.
+func StampChangeRef(description, ref string) string {
+	base := changeRefStamp.ReplaceAllString(description, "")
+	if base == "" {
+		return fmt.Sprintf("[change: %s]", ref)
+	}
+	return fmt.Sprintf("%s [change: %s]", base, ref)
+}
+
+// ValidateChangeRef checks a --change-ref value. It must be non-empty and, so it can be stamped
+// into a description, contain no whitespace or square brackets.
+func ValidateChangeRef(ref string) error {
+	if strings.TrimSpace(ref) == "" {
+		return fmt.Errorf("--change-ref cannot be empty")
+	}
+	if strings.ContainsAny(ref, " \t\n[]") {
+		return fmt.Errorf("invalid value for --change-ref: %q (must not contain whitespace or square brackets)", ref)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"single line", "Baseline configuration", "Baseline configuration"},
+		{"newlines become spaces", "Owner: appsec\nTicket: SEC-1\n", "Owner: appsec Ticket: SEC-1"},
+		{"windows line endings", "Owner: appsec\r\nTicket: SEC-1\r\n", "Owner: appsec Ticket: SEC-1"},
+		{"blank lines and indentation dropped", "\n  Owner: appsec\n\n\n  Review: 2025-01\n", "Owner: appsec Review: 2025-01"},
+		{"whitespace only", " \n\t\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeDescription(tt.raw); got != tt.want {
+				t.Errorf("NormalizeDescription(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDescriptionLength(t *testing.T) {
+	if err := ValidateDescriptionLength(strings.Repeat("a", MaxDescriptionLength)); err != nil {
+		t.Errorf("description at the limit should be valid, got %v", err)
+	}
+
+	err := ValidateDescriptionLength(strings.Repeat("a", MaxDescriptionLength) + "overflow")
+	if err == nil {
+		t.Fatal("expected an error for a description over the limit")
+	}
+	if !strings.Contains(err.Error(), "8 over") || !strings.Contains(err.Error(), `"overflow"`) {
+		t.Errorf("error should report the overflow count and text, got %q", err.Error())
+	}
+}
+
+func TestReadDescription(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "description.txt")
+	if err := os.WriteFile(path, []byte("Owner: appsec\nTicket: SEC-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(emptyPath, []byte("\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "from file", path: path, want: "Owner: appsec Ticket: SEC-1"},
+		{name: "from stdin", path: "-", stdin: "Line one\nLine two", want: "Line one Line two"},
+		{name: "missing file", path: filepath.Join(dir, "missing.txt"), wantErr: true},
+		{name: "empty file", path: emptyPath, wantErr: true},
+		{name: "too long", path: "-", stdin: strings.Repeat("x", MaxDescriptionLength+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadDescription(tt.path, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadDescription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ReadDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	if got := TruncateDescription("short", 10); got != "short" {
+		t.Errorf("short description should be unchanged, got %q", got)
+	}
+	if got := TruncateDescription("abcdefghijkl", 5); got != "abcd…" {
+		t.Errorf("TruncateDescription() = %q, want %q", got, "abcd…")
+	}
+}
+
+func TestStampChangeRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{"appends", "Org baseline", "Org baseline [change: CHG-2]"},
+		{"replaces an earlier stamp", "Org baseline [change: CHG-1]", "Org baseline [change: CHG-2]"},
+		{"keeps other brackets", "Org baseline [prod]", "Org baseline [prod] [change: CHG-2]"},
+		{"empty description", "", "[change: CHG-2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StampChangeRef(tt.description, "CHG-2"); got != tt.want {
+				t.Errorf("StampChangeRef(%q) = %q, want %q", tt.description, got, tt.want)
+			}
+		})
+	}
+}
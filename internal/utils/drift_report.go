@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// Drift output formats accepted by drift --output
+const (
+	DriftOutputTable = "table"
+	DriftOutputJSON  = "json"
+	DriftOutputYAML  = "yaml"
+)
+
+// driftCSVHeader names the columns of a drift CSV report
+var driftCSVHeader = []string{"organization", "configuration", "setting", "baseline", "actual", "configuration_url", "configuration_id"}
+
+// WriteDriftJSON writes the differences as an indented JSON array. No differences are written as
+// an empty array, not null, so consumers can always iterate the result.
+func WriteDriftJSON(w io.Writer, drift []types.SettingDrift) error {
+	if drift == nil {
+		drift = []types.SettingDrift{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(drift); err != nil {
+		return fmt.Errorf("failed to write drift JSON: %w", err)
+	}
+	return nil
+}
+
+// WriteDriftYAML writes the differences as a YAML sequence with the same field names as the JSON
+// output. Keys are sorted so the output diffs cleanly between runs. No differences are written as
+// an empty sequence.
+func WriteDriftYAML(w io.Writer, drift []types.SettingDrift) error {
+	if drift == nil {
+		drift = []types.SettingDrift{}
+	}
+	// Going through JSON keeps the field names and omissions of the JSON output, and decodes every
+	// record into a map, which the YAML encoder writes with sorted keys
+	content, err := json.Marshal(drift)
+	if err != nil {
+		return fmt.Errorf("failed to write drift YAML: %w", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(content, &records); err != nil {
+		return fmt.Errorf("failed to write drift YAML: %w", err)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write drift YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to write drift YAML: %w", err)
+	}
+	return nil
+}
+
+// WriteDriftCSV writes the differences, one row per setting under a header row, to the file at path
+func WriteDriftCSV(path string, drift []types.SettingDrift) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create drift report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(driftCSVHeader); err != nil {
+		return fmt.Errorf("failed to write drift report: %w", err)
+	}
+	for _, d := range drift {
+		if err := writer.Write([]string{d.Organization, d.Configuration, d.Setting, d.Baseline, d.Actual, d.ConfigurationURL, strconv.Itoa(d.ConfigurationID)}); err != nil {
+			return fmt.Errorf("failed to write drift report: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write drift report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+var testDrift = []types.SettingDrift{
+	{Organization: "org-a", Configuration: "Baseline", ConfigurationID: 1, Setting: "secret_scanning", Baseline: "enabled", Actual: "disabled", ConfigurationURL: "https://github.com/organizations/org-a/settings/security_products/configurations/1"},
+}
+
+func TestWriteDriftJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDriftJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("no drift = %q, want an empty array", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteDriftJSON(&buf, testDrift); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"organization": "org-a"`, `"setting": "secret_scanning"`, `"baseline": "enabled"`, `"actual": "disabled"`, `"configuration_id": 1`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("JSON %s does not contain %s", buf.String(), want)
+		}
+	}
+}
+
+// TestWriteDriftYAML compares the YAML output with the golden files in testdata/drift. Run with
+// -update to rewrite them after an intended change.
+func TestWriteDriftYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		drift []types.SettingDrift
+	}{
+		{name: "empty", drift: nil},
+		{name: "drift", drift: append(slices.Clone(testDrift),
+			types.SettingDrift{Organization: "org-b", Configuration: "Baseline", ConfigurationID: 42, Setting: "enforcement", Baseline: "enforced", Actual: "unenforced"},
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteDriftYAML(&buf, tt.drift); err != nil {
+				t.Fatal(err)
+			}
+
+			goldenPath := filepath.Join("testdata", "drift", tt.name+".yaml")
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if buf.String() != string(want) {
+				t.Errorf("WriteDriftYAML() mismatch\ngot:\n%s\nwant:\n%s", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestWriteDriftCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift.csv")
+	if err := WriteDriftCSV(path, testDrift); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "organization,configuration,setting,baseline,actual,configuration_url,configuration_id\n" +
+		"org-a,Baseline,secret_scanning,enabled,disabled,https://github.com/organizations/org-a/settings/security_products/configurations/1,1\n"
+	if string(content) != want {
+		t.Errorf("CSV = %q, want %q", content, want)
+	}
+}
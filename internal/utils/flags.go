@@ -3,9 +3,12 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 // GetCommonFlags extracts common flags used across all commands
@@ -15,10 +18,23 @@ type CommonFlags struct {
 	AllOrgs                            bool
 	Concurrency                        int
 	Delay                              int
+	MaxOrgsPerMinute                   int           // Cap on organizations dispatched per minute; 0 means no cap
+	PerOrgTimeout                      time.Duration // Time after which an organization is given up on; 0 means no limit
+	ReportOrder                        string        // Order of the final results: ReportOrderInput or ReportOrderCompletion
 	DependabotAlertsAvailable          *bool
 	DependabotSecurityUpdatesAvailable *bool
+	StrictOrgList                      bool                   // Any invalid or empty --org-list row stops the run before anything else
+	FailOnInvalidOrgs                  bool                   // Invalid --org-list rows make the run exit with a partial failure
+	ExcludeProperties                  []types.PropertyFilter // Organizations with any of these custom property values are not targeted
 }
 
+// Orders accepted by --report-order. With concurrency, organizations finish in any order; the
+// final results follow the targeted order by default, or the order the organizations finished in.
+const (
+	ReportOrderInput      = "input"
+	ReportOrderCompletion = "completion"
+)
+
 // ExtractCommonFlags gets org targeting, concurrency, and delay flags from command
 func ExtractCommonFlags(cmd *cobra.Command) (*CommonFlags, error) {
 	org, err := cmd.Flags().GetString("org")
@@ -36,6 +52,25 @@ func ExtractCommonFlags(cmd *cobra.Command) (*CommonFlags, error) {
 		return nil, err
 	}
 
+	strictOrgList, err := cmd.Flags().GetBool("strict-org-list")
+	if err != nil {
+		return nil, err
+	}
+
+	failOnInvalidOrgs, err := cmd.Flags().GetBool("fail-on-invalid-orgs")
+	if err != nil {
+		return nil, err
+	}
+
+	excludeWithProperty, err := cmd.Flags().GetStringSlice("exclude-with-property")
+	if err != nil {
+		return nil, err
+	}
+	excludeProperties, err := ParsePropertyFilters(excludeWithProperty)
+	if err != nil {
+		return nil, err
+	}
+
 	concurrency, err := cmd.Flags().GetInt("concurrency")
 	if err != nil {
 		return nil, err
@@ -46,6 +81,30 @@ func ExtractCommonFlags(cmd *cobra.Command) (*CommonFlags, error) {
 		return nil, err
 	}
 
+	maxOrgsPerMinute, err := cmd.Flags().GetInt("max-orgs-per-minute")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateMaxOrgsPerMinute(maxOrgsPerMinute); err != nil {
+		return nil, err
+	}
+
+	perOrgTimeout, err := cmd.Flags().GetDuration("per-org-timeout")
+	if err != nil {
+		return nil, err
+	}
+	if perOrgTimeout < 0 {
+		return nil, fmt.Errorf("per-org-timeout must not be negative, got %s", perOrgTimeout)
+	}
+
+	reportOrder, err := cmd.Flags().GetString("report-order")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateEnumValue("report-order", reportOrder, []string{ReportOrderInput, ReportOrderCompletion}); err != nil {
+		return nil, err
+	}
+
 	dependabotAlertsAvailableFlag, err := cmd.Flags().GetString("dependabot-alerts-available")
 	if err != nil {
 		return nil, err
@@ -86,8 +145,14 @@ func ExtractCommonFlags(cmd *cobra.Command) (*CommonFlags, error) {
 		Org:                                org,
 		OrgListPath:                        orgListPath,
 		AllOrgs:                            allOrgs,
+		StrictOrgList:                      strictOrgList,
+		FailOnInvalidOrgs:                  failOnInvalidOrgs,
+		ExcludeProperties:                  excludeProperties,
 		Concurrency:                        concurrency,
 		Delay:                              delay,
+		MaxOrgsPerMinute:                   maxOrgsPerMinute,
+		PerOrgTimeout:                      perOrgTimeout,
+		ReportOrder:                        reportOrder,
 		DependabotAlertsAvailable:          dependabotAlertsAvailable,
 		DependabotSecurityUpdatesAvailable: dependabotSecurityUpdatesAvailable,
 	}, nil
@@ -102,12 +167,8 @@ func ValidateOrgFlags(flags *CommonFlags) error {
 
 	// Validate CSV file early if provided
 	if flags.OrgListPath != "" {
-		orgs, err := ReadOrganizationsFromCSV(flags.OrgListPath)
-		if err != nil {
-			return fmt.Errorf("CSV validation failed: %w", err)
-		}
-		if len(orgs) == 0 {
-			return fmt.Errorf("CSV file contains no valid organizations")
+		if err := validateOrgList(flags); err != nil {
+			return err
 		}
 	}
 
@@ -125,12 +186,8 @@ func ValidateOrgFlags(flags *CommonFlags) error {
 func ValidateOrgFlagsOptional(flags *CommonFlags) error {
 	// Validate CSV file early if provided
 	if flags.OrgListPath != "" {
-		orgs, err := ReadOrganizationsFromCSV(flags.OrgListPath)
-		if err != nil {
-			return fmt.Errorf("CSV validation failed: %w", err)
-		}
-		if len(orgs) == 0 {
-			return fmt.Errorf("CSV file contains no valid organizations")
+		if err := validateOrgList(flags); err != nil {
+			return err
 		}
 	}
 
@@ -144,6 +201,67 @@ func ValidateOrgFlagsOptional(flags *CommonFlags) error {
 	return nil
 }
 
+// validateOrgList reads the --org-list file once, failing when it cannot be read, when
+// --strict-org-list rejects any of its rows, or when no valid organization remains
+func validateOrgList(flags *CommonFlags) error {
+	orgs, _, invalid, err := readOrganizationsCSV(flags.OrgListPath, flags.StrictOrgList)
+	if err != nil {
+		return fmt.Errorf("CSV validation failed: %w", err)
+	}
+	if err := CheckStrictOrgList(flags, invalid); err != nil {
+		return err
+	}
+	if len(orgs) == 0 {
+		return &types.NoTargetsError{Breakdown: types.TargetBreakdown{Source: "--org-list", Found: len(invalid), Invalid: len(invalid), InvalidEntries: invalid}}
+	}
+	return nil
+}
+
+// CheckStrictOrgList fails with every invalid --org-list row when --strict-org-list is set, so a
+// mistyped organization stops the run instead of silently receiving nothing. The commands check
+// the file before anything else, where rows with an empty organization are invalid as well.
+func CheckStrictOrgList(flags *CommonFlags, invalid []types.InvalidOrgEntry) error {
+	if !flags.StrictOrgList || len(invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("--strict-org-list: %d invalid row(s) in %s: %s", len(invalid), flags.OrgListPath, types.DescribeInvalidOrgEntries(invalid))
+}
+
+// ValidateCopyFromOrg rejects a --copy-from-org value that names a host as well as an
+// organization. Every request of a run goes to the single host set by
+// --github-enterprise-server-url, so the source organization cannot be read from a different host
+// than the targets.
+func ValidateCopyFromOrg(copyFromOrg string) error {
+	if strings.ContainsAny(copyFromOrg, "/:") {
+		return fmt.Errorf("--copy-from-org %q includes a host; the source organization is always read from the same host as the target organizations (github.com, or --github-enterprise-server-url), so copying between hosts is not supported. Pass only the organization name", copyFromOrg)
+	}
+	if strings.Contains(copyFromOrg, " ") {
+		return fmt.Errorf("invalid organization name format: %s", copyFromOrg)
+	}
+	return nil
+}
+
+// CopySourceHostError explains a --copy-from-org source that cannot be used on host. The source
+// and target organizations share the run's single host, so an organization on another host
+// cannot be copied from.
+func CopySourceHostError(copyFromOrg, problem, host string) error {
+	return fmt.Errorf("organization '%s' %s on %s. The source organization is read from the same host as the target organizations, so copying between github.com and GitHub Enterprise Server is not supported; check --github-enterprise-server-url (-u)", copyFromOrg, problem, host)
+}
+
+// Organization targeting methods offered when no targeting flag is set
+const (
+	TargetAllOrgs   = "all-orgs"
+	TargetSingleOrg = "single-org"
+	TargetOrgList   = "org-list"
+)
+
+// TargetPrompts ask for the organization targeting the flags left out, as api.ResolveTargets does
+type TargetPrompts struct {
+	SelectMethod func() (string, error) // Returns TargetAllOrgs, TargetSingleOrg or TargetOrgList
+	OrgName      func() (string, error)
+	OrgListPath  func() (string, error)
+}
+
 // HasOrgTargeting checks if any org targeting flag is set
 func HasOrgTargeting(flags *CommonFlags) bool {
 	return flags.Org != "" || flags.OrgListPath != "" || flags.AllOrgs
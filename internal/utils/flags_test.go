@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 func TestHasOrgTargeting(t *testing.T) {
@@ -69,6 +73,18 @@ func TestValidateOrgFlags_EmptyCSV(t *testing.T) {
 	}
 }
 
+func TestValidateOrgFlags_OnlyInvalidCSVReportsNoTargets(t *testing.T) {
+	path := writeTempCSV(t, "bad name\nbad/name\n")
+	err := ValidateOrgFlags(&CommonFlags{OrgListPath: path})
+	var noTargetsErr *types.NoTargetsError
+	if !errors.As(err, &noTargetsErr) {
+		t.Fatalf("expected a NoTargetsError, got %v", err)
+	}
+	if noTargetsErr.Breakdown.Invalid != 2 {
+		t.Errorf("Invalid = %d, want 2", noTargetsErr.Breakdown.Invalid)
+	}
+}
+
 func TestValidateOrgFlags_ValidCSV(t *testing.T) {
 	path := writeTempCSV(t, "org-one\norg-two\n")
 	if err := ValidateOrgFlags(&CommonFlags{OrgListPath: path}); err != nil {
@@ -94,3 +110,68 @@ func TestValidateOrgFlagsOptional_RejectsMissingCSV(t *testing.T) {
 		t.Fatal("expected error for missing CSV")
 	}
 }
+
+func TestValidateCopyFromOrg(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantErr  bool
+		wantHost bool
+	}{
+		{"source-org", false, false},
+		{"github.com/source-org", true, true},
+		{"ghes.example.com:source-org", true, true},
+		{"https://ghes.example.com/source-org", true, true},
+		{"source org", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			err := ValidateCopyFromOrg(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateCopyFromOrg(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantHost && !strings.Contains(err.Error(), "copying between hosts is not supported") {
+				t.Errorf("ValidateCopyFromOrg(%q) = %q, want it to explain the single-host limitation", tt.value, err)
+			}
+		})
+	}
+}
+
+func TestValidateOrgFlagsOptional_StrictOrgList(t *testing.T) {
+	path := writeTempCSV(t, "org-one\nbad name\n\norg/two\n")
+
+	if err := ValidateOrgFlagsOptional(&CommonFlags{OrgListPath: path}); err != nil {
+		t.Fatalf("lenient mode: unexpected error: %v", err)
+	}
+
+	err := ValidateOrgFlagsOptional(&CommonFlags{OrgListPath: path, StrictOrgList: true})
+	if err == nil {
+		t.Fatal("strict mode: expected an error for the invalid rows")
+	}
+	for _, want := range []string{"--strict-org-list", "2 invalid row(s)", "line 2: 'bad name'", "line 4: 'org/two'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// Every invalid row is reported even when no valid row remains, instead of "no targets"
+	onlyInvalid := writeTempCSV(t, "bad name\n")
+	var noTargetsErr *types.NoTargetsError
+	if err := ValidateOrgFlagsOptional(&CommonFlags{OrgListPath: onlyInvalid, StrictOrgList: true}); err == nil || errors.As(err, &noTargetsErr) {
+		t.Errorf("strict mode with only invalid rows: error = %v, want the strict error", err)
+	}
+
+	// Rows whose organization is empty once trimmed are ignored, unless the list is strict
+	spaces := writeTempCSV(t, "org-one\n   \norg-two\n\t\n")
+	if err := ValidateOrgFlagsOptional(&CommonFlags{OrgListPath: spaces}); err != nil {
+		t.Fatalf("lenient mode with empty rows: unexpected error: %v", err)
+	}
+	err = ValidateOrgFlagsOptional(&CommonFlags{OrgListPath: spaces, StrictOrgList: true})
+	if err == nil {
+		t.Fatal("strict mode: expected an error for the empty rows")
+	}
+	for _, want := range []string{"2 invalid row(s)", "line 2: (empty)", "line 4: (empty)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// ReadManifest reads and validates a run manifest from a YAML file. A relative targets.org_list
+// is resolved against the manifest's directory.
+func ReadManifest(path string) (*types.Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var manifest types.Manifest
+	if err := decoder.Decode(&manifest); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("manifest file %s is empty", path)
+		}
+		return nil, fmt.Errorf("failed to parse manifest file %s: %w", path, err)
+	}
+	if list := manifest.Targets.OrgList; list != "" && !filepath.IsAbs(list) {
+		manifest.Targets.OrgList = filepath.Join(filepath.Dir(path), list)
+	}
+	if err := ValidateManifest(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest file %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// ValidateManifest checks that a manifest declares everything a run would otherwise prompt for,
+// and that every value is one the matching flag accepts. Blank strings are trimmed in place.
+func ValidateManifest(manifest *types.Manifest) error {
+	if len(manifest.Enterprises) == 0 {
+		return fmt.Errorf("enterprises: at least one enterprise slug is required")
+	}
+	for i, slug := range manifest.Enterprises {
+		slug = strings.TrimSpace(slug)
+		if slug == "" {
+			return fmt.Errorf("enterprises: entry %d is empty", i+1)
+		}
+		if strings.Contains(slug, ",") {
+			return fmt.Errorf("enterprises: %q must be a single slug; list each enterprise separately", slug)
+		}
+		manifest.Enterprises[i] = slug
+	}
+
+	manifest.ServerURL = strings.TrimSpace(manifest.ServerURL)
+	if manifest.ServerURL == "" {
+		return fmt.Errorf("server_url is required")
+	}
+	if manifest.DependabotAlertsAvailable == nil {
+		return fmt.Errorf("dependabot_alerts_available is required (true or false)")
+	}
+	if manifest.DependabotSecurityUpdatesAvailable == nil {
+		return fmt.Errorf("dependabot_security_updates_available is required (true or false)")
+	}
+
+	if err := validateManifestTargets(&manifest.Targets); err != nil {
+		return err
+	}
+	if err := validateManifestConfiguration(manifest); err != nil {
+		return err
+	}
+
+	scopes := []string{"all", "public", "private_or_internal", "none"}
+	if !slices.Contains(scopes, manifest.Scope) {
+		return fmt.Errorf("scope: %q is not valid (must be one of: %s)", manifest.Scope, strings.Join(scopes, ", "))
+	}
+	if manifest.SetAsDefault == nil {
+		return fmt.Errorf("set_as_default is required (true or false)")
+	}
+	if manifest.ChangeRef != "" {
+		if err := ValidateChangeRef(manifest.ChangeRef); err != nil {
+			return fmt.Errorf("change_ref: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateManifestTargets checks that exactly one way of selecting organizations is given
+func validateManifestTargets(targets *types.ManifestTargets) error {
+	targets.Org = strings.TrimSpace(targets.Org)
+	set := 0
+	if targets.Org != "" {
+		set++
+	}
+	if targets.OrgList != "" {
+		set++
+	}
+	if targets.AllOrgs {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("targets: exactly one of org, org_list or all_orgs is required")
+	}
+	if strings.ContainsAny(targets.Org, "/: ") {
+		return fmt.Errorf("targets.org: %q is not an organization name; give the name alone, without a host", targets.Org)
+	}
+	if targets.OrgList != "" {
+		if _, err := os.Stat(targets.OrgList); err != nil {
+			return fmt.Errorf("targets.org_list: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateManifestConfiguration checks the configuration's name, description and settings. Every
+// setting the instance makes available must be given, since none of them is prompted for.
+func validateManifestConfiguration(manifest *types.Manifest) error {
+	config := &manifest.Configuration
+	config.Name = strings.TrimSpace(config.Name)
+	if config.Name == "" {
+		return fmt.Errorf("configuration.name is required")
+	}
+	if length := utf8.RuneCountInString(config.Name); length > MaxConfigNameLength {
+		return fmt.Errorf("configuration.name is %d characters, longer than the %d GitHub allows", length, MaxConfigNameLength)
+	}
+	config.Description = strings.TrimSpace(config.Description)
+	if config.Description == "" {
+		return fmt.Errorf("configuration.description is required")
+	}
+
+	settings := make(map[string]interface{}, len(config.Settings))
+	for key, value := range config.Settings {
+		settings[key] = value
+	}
+	if err := ValidateSettings(settings); err != nil {
+		return fmt.Errorf("configuration.settings: %w", err)
+	}
+	for key, value := range settings {
+		config.Settings[key] = value.(string)
+	}
+
+	available := map[string]bool{
+		"dependabot_alerts":           *manifest.DependabotAlertsAvailable,
+		"dependabot_security_updates": *manifest.DependabotSecurityUpdatesAvailable,
+	}
+	for _, key := range types.KnownSettingKeys() {
+		_, given := config.Settings[key]
+		isAvailable, conditional := available[key]
+		switch {
+		case conditional && !isAvailable && given:
+			return fmt.Errorf("configuration.settings: %s is given but %s_available is false", key, key)
+		case conditional && !isAvailable:
+		case !given:
+			return fmt.Errorf("configuration.settings: %s is required", key)
+		}
+	}
+	return nil
+}
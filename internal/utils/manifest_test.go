@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestReadManifest_Golden reads every manifest in testdata/manifests and compares the parsed
+// manifest, or the validation error, with the .golden file next to it. Run with -update to
+// rewrite the golden files after an intended change.
+func TestReadManifest_Golden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "manifests", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no manifests found in testdata/manifests")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			var got string
+			manifest, err := ReadManifest(path)
+			if err != nil {
+				got = "error: " + err.Error() + "\n"
+			} else {
+				// The org list is resolved with filepath, so compare it with forward slashes to
+				// share the golden files with Windows
+				manifest.Targets.OrgList = filepath.ToSlash(manifest.Targets.OrgList)
+				content, err := json.MarshalIndent(manifest, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(content) + "\n"
+			}
+			if strings.HasPrefix(name, "invalid_") != (err != nil) {
+				t.Errorf("ReadManifest(%s) error = %v; manifests named invalid_* must fail and no others", path, err)
+			}
+
+			goldenPath := strings.TrimSuffix(path, ".yaml") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("ReadManifest(%s) mismatch\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// TestReadManifest_OrgListPaths checks that a relative org list written with forward slashes is
+// resolved against the manifest's directory with the platform's separators, and that an absolute
+// one is kept
+func TestReadManifest_OrgListPaths(t *testing.T) {
+	dir := t.TempDir()
+	listPath := filepath.Join(dir, "lists", "orgs.csv")
+	if err := os.MkdirAll(filepath.Dir(listPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(listPath, []byte("octo-org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	template, err := os.ReadFile(filepath.Join("testdata", "manifests", "org_list.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		orgList string
+	}{
+		{name: "relative with forward slashes", orgList: "lists/orgs.csv"},
+		{name: "absolute", orgList: listPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Single-quoted YAML keeps the backslashes of a Windows path
+			content := strings.Replace(string(template), "org_list: orgs.csv", "org_list: '"+tt.orgList+"'", 1)
+			manifestPath := filepath.Join(dir, "manifest.yaml")
+			if err := os.WriteFile(manifestPath, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			manifest, err := ReadManifest(manifestPath)
+			if err != nil {
+				t.Fatalf("ReadManifest() error = %v", err)
+			}
+			if manifest.Targets.OrgList != listPath {
+				t.Errorf("org list = %q, want %q", manifest.Targets.OrgList, listPath)
+			}
+		})
+	}
+}
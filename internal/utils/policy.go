@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+// ReadPolicy reads and validates a compliance policy from a YAML file. JSON is valid YAML, so a
+// JSON policy file is read as well.
+func ReadPolicy(path string) (*types.Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var policy types.Policy
+	if err := decoder.Decode(&policy); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("policy file %s is empty", path)
+		}
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := ValidatePolicy(&policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// ValidatePolicy checks every rule against the security settings registry and fills in the
+// default severity
+func ValidatePolicy(policy *types.Policy) error {
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("policy has no rules")
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		setting, ok := types.LookupSecuritySetting(rule.Setting)
+		if !ok {
+			return fmt.Errorf("rule %d: unknown setting %q (must be one of: %s)", i+1, rule.Setting, strings.Join(types.KnownSettingKeys(), ", "))
+		}
+		if len(rule.Allowed) == 0 {
+			return fmt.Errorf("rule %d: %s has no allowed values", i+1, rule.Setting)
+		}
+		for _, value := range rule.Allowed {
+			if value == "" || ValidateEnumValue(rule.Setting, value, setting.Values) != nil {
+				return fmt.Errorf("rule %d: %q is not a valid value for %s (must be one of: %s)", i+1, value, rule.Setting, strings.Join(setting.Values, ", "))
+			}
+		}
+		switch rule.Severity {
+		case "":
+			rule.Severity = types.SeverityError
+		case types.SeverityError, types.SeverityWarning:
+		default:
+			return fmt.Errorf("rule %d: invalid severity %q (must be error or warning)", i+1, rule.Severity)
+		}
+	}
+	return nil
+}
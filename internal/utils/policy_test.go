@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
+)
+
+func writePolicyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPolicy(t *testing.T) {
+	path := writePolicyFile(t, `{
+		"rules": [
+			{"setting": "secret_scanning", "allowed": ["enabled", "not_set"]},
+			{"setting": "enforcement", "allowed": ["enforced"], "severity": "warning", "orgs": ["prod-a"]}
+		]
+	}`)
+
+	policy, err := ReadPolicy(path)
+	if err != nil {
+		t.Fatalf("ReadPolicy() error = %v", err)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("ReadPolicy() rules = %d, want 2", len(policy.Rules))
+	}
+	if policy.Rules[0].Severity != types.SeverityError {
+		t.Errorf("default severity = %q, want %q", policy.Rules[0].Severity, types.SeverityError)
+	}
+	if policy.Rules[1].Severity != types.SeverityWarning {
+		t.Errorf("severity = %q, want %q", policy.Rules[1].Severity, types.SeverityWarning)
+	}
+}
+
+func TestReadPolicy_YAML(t *testing.T) {
+	path := writePolicyFile(t, `rules:
+  - setting: secret_scanning
+    allowed: [enabled, not_set]
+    description: Secret scanning is never disabled
+  - setting: enforcement
+    allowed: [enforced]
+    severity: warning
+    orgs: [prod-a]
+`)
+
+	policy, err := ReadPolicy(path)
+	if err != nil {
+		t.Fatalf("ReadPolicy() error = %v", err)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("ReadPolicy() rules = %d, want 2", len(policy.Rules))
+	}
+	if got := policy.Rules[1]; got.Severity != types.SeverityWarning || !got.AppliesTo("prod-a") || got.AppliesTo("dev") {
+		t.Errorf("second rule = %+v, want a warning for prod-a only", got)
+	}
+}
+
+func TestReadPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"malformed JSON", `{"rules": [`, "failed to parse"},
+		{"empty file", ``, "is empty"},
+		{"unknown field", `{"rules": [{"setting": "secret_scanning", "allowed": ["enabled"], "allow": ["x"]}]}`, "failed to parse"},
+		{"no rules", `{"rules": []}`, "no rules"},
+		{"unknown setting", `{"rules": [{"setting": "code_scanning", "allowed": ["enabled"]}]}`, `rule 1: unknown setting "code_scanning"`},
+		{"no allowed values", `{"rules": [{"setting": "secret_scanning", "allowed": []}]}`, "has no allowed values"},
+		{"value not valid for setting", `{"rules": [{"setting": "enforcement", "allowed": ["enabled"]}]}`, `"enabled" is not a valid value for enforcement`},
+		{"empty value", `{"rules": [{"setting": "secret_scanning", "allowed": [""]}]}`, "is not a valid value"},
+		{"invalid severity", `{"rules": [{"setting": "secret_scanning", "allowed": ["enabled"], "severity": "fatal"}]}`, `invalid severity "fatal"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadPolicy(writePolicyFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadPolicy() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
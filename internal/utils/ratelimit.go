@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that caps how many organizations are dispatched per minute. The
+// bucket holds one token, so dispatches are spread evenly rather than sent in bursts.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	interval  time.Duration // Time to earn one token
+	tokens    float64
+	last      time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perMinute dispatches per minute. perMinute must be positive.
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return newRateLimiter(perMinute, time.Now)
+}
+
+// newRateLimiter creates a limiter reading the time from now, so tests can use a fake clock
+func newRateLimiter(perMinute int, now func() time.Time) *RateLimiter {
+	return &RateLimiter{
+		perMinute: perMinute,
+		interval:  time.Minute / time.Duration(perMinute),
+		tokens:    1,
+		last:      now(),
+		now:       now,
+	}
+}
+
+// Reserve takes a token and returns how long the caller must wait before dispatching. A zero
+// duration means the dispatch may happen right away. Each call reserves the next free slot, so
+// callers that do not wait still count against the rate.
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = min(1, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// String describes the rate, e.g. "max 30 orgs/min", for progress titles
+func (l *RateLimiter) String() string {
+	return fmt.Sprintf("max %d orgs/min", l.perMinute)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for the rate limiter
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.current }
+
+func (c *fakeClock) Advance(d time.Duration) { c.current = c.current.Add(d) }
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := newRateLimiter(30, clock.Now) // one dispatch every 2s
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    time.Duration
+	}{
+		{name: "first dispatch is immediate", want: 0},
+		{name: "second waits a full interval", want: 2 * time.Second},
+		{name: "reservations queue up", want: 4 * time.Second},
+		{name: "waiting out the queue frees the next slot", advance: 6 * time.Second, want: 0},
+		{name: "partial refill shortens the wait", advance: 500 * time.Millisecond, want: 1500 * time.Millisecond},
+		{name: "idle time does not build a burst", advance: time.Minute, want: 0},
+		{name: "after idle the rate still applies", want: 2 * time.Second},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		if got := limiter.Reserve(); got != step.want {
+			t.Errorf("%s: Reserve() = %s, want %s", step.name, got, step.want)
+		}
+	}
+}
+
+func TestRateLimiter_String(t *testing.T) {
+	if got := NewRateLimiter(30).String(); got != "max 30 orgs/min" {
+		t.Errorf("String() = %q, want %q", got, "max 30 orgs/min")
+	}
+}
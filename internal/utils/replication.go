@@ -2,67 +2,111 @@ package utils
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 )
 
+// ReplicationFlagOrder lists, in the order they are printed, every flag a replication command can
+// contain. Keys passed to BuildReplicationCommand that are not listed are ignored, so a command
+// that replicates a new flag must add it here.
+var ReplicationFlagOrder = []string{
+	"enterprise-slug",
+	"github-enterprise-server-url",
+	"template-org",
+	"reference-org",
+	"org",
+	"org-list",
+	"strict-org-list",
+	"fail-on-invalid-orgs",
+	"all-orgs",
+	"exclude-with-property",
+	"copy-from-org",
+	"config-name",
+	"all-configs",
+	"managed-prefix",
+	"older-than",
+	"unattached-only",
+	"config-description",
+	"new-name",
+	"name-prefix",
+	"name-suffix",
+	"new-description",
+	"config-source",
+	"sync-settings-from",
+	"policy",
+	"baseline",
+	"advanced-security",
+	"dependabot-alerts",
+	"dependabot-security-updates",
+	"secret-scanning",
+	"secret-scanning-push-protection",
+	"secret-scanning-non-provider-patterns",
+	"enforcement",
+	"scope",
+	"no-attach",
+	"exclude-repos",
+	"only-codeql-supported-repos",
+	"codeql-languages",
+	"set-as-default",
+	"apply-to-existing",
+	"skip-orgs-with-existing-default",
+	"dependabot-alerts-available",
+	"dependabot-security-updates-available",
+	"concurrency",
+	"delay",
+	"max-orgs-per-minute",
+	"per-org-timeout",
+	"report-order",
+	"log-level",
+	"skip-confirmation-message",
+	"probe",
+	"overwrite",
+	"force-overwrite",
+	"with-audit-copy",
+	"probe-feature-support",
+	"include-config-id",
+	"no-request-cache",
+	"output",
+	"csv-report",
+	"change-ref",
+	"stamp-change-ref",
+	"post-hook",
+	"post-hook-timeout",
+	"ignore-hook-failures",
+}
+
 // BuildReplicationCommand creates a command string that can be used to replicate the same action
 func BuildReplicationCommand(command string, flags map[string]interface{}) string {
 	var parts []string
 	parts = append(parts, "gh security-config", command)
 
 	// Add flags in a consistent order
-	flagOrder := []string{
-		"enterprise-slug",
-		"github-enterprise-server-url",
-		"template-org",
-		"org",
-		"org-list",
-		"all-orgs",
-		"copy-from-org",
-		"config-name",
-		"config-description",
-		"new-name",
-		"new-description",
-		"config-source",
-		"advanced-security",
-		"dependabot-alerts",
-		"dependabot-security-updates",
-		"secret-scanning",
-		"secret-scanning-push-protection",
-		"secret-scanning-non-provider-patterns",
-		"enforcement",
-		"scope",
-		"set-as-default",
-		"dependabot-alerts-available",
-		"dependabot-security-updates-available",
-		"concurrency",
-		"delay",
-		"log-level",
-		"skip-confirmation-message",
-		"overwrite",
-	}
-
-	for _, flagName := range flagOrder {
+	for _, flagName := range ReplicationFlagOrder {
 		if value, exists := flags[flagName]; exists && value != nil {
 			switch v := value.(type) {
 			case string:
 				if v != "" {
 					// Only include log-level if it's not the default
-					if flagName == "log-level" && v == "warning" {
+					if (flagName == "log-level" && v == "warning") || (flagName == "report-order" && v == ReportOrderInput) {
 						continue
 					}
-					parts = append(parts, fmt.Sprintf("--%s %s", flagName, quoteIfNeeded(v)))
+					parts = append(parts, fmt.Sprintf("--%s %s", flagName, quoteForShell(v)))
 				}
 			case bool:
 				if v {
 					// Boolean flags don't need a value
 					parts = append(parts, fmt.Sprintf("--%s", flagName))
 				}
+			case time.Duration:
+				if v > 0 {
+					parts = append(parts, fmt.Sprintf("--%s %s", flagName, v))
+				}
 			case int:
-				if (flagName == "concurrency" && v != 1) || (flagName == "delay" && v != 0) {
-					// Only include concurrency if it's not the default (1) or delay if it's not default (0)
+				if (flagName == "concurrency" && v != 1) || ((flagName == "delay" || flagName == "max-orgs-per-minute") && v != 0) {
+					// Only include concurrency if it's not the default (1), or delay and the rate cap if they are set
 					parts = append(parts, fmt.Sprintf("--%s %d", flagName, v))
 				}
 			}
@@ -72,12 +116,90 @@ func BuildReplicationCommand(command string, flags map[string]interface{}) strin
 	return strings.Join(parts, " ")
 }
 
-// quoteIfNeeded adds quotes around a string if it contains spaces
+// Values accepted by --shell, the shell replication and retry commands are quoted for. "auto"
+// picks PowerShell on Windows and bash elsewhere.
+const (
+	ShellAuto       = "auto"
+	ShellBash       = "bash"
+	ShellPowerShell = "powershell"
+)
+
+// ShellValues lists the accepted values for --shell
+var ShellValues = []string{ShellAuto, ShellBash, ShellPowerShell}
+
+// replicationShell is the shell set by SetReplicationShell
+var replicationShell = ShellBash
+
+// SetReplicationShell sets the shell that replication and retry commands are quoted for
+func SetReplicationShell(shell string) error {
+	if err := ValidateEnumValue("shell", shell, ShellValues); err != nil {
+		return err
+	}
+	replicationShell = resolveShell(shell, runtime.GOOS)
+	return nil
+}
+
+// resolveShell returns the shell to quote for, choosing one for goos when shell is "auto" or empty
+func resolveShell(shell, goos string) string {
+	if shell != ShellAuto && shell != "" {
+		return shell
+	}
+	if goos == "windows" {
+		return ShellPowerShell
+	}
+	return ShellBash
+}
+
+// quoteForShell quotes a flag value for the shell set by SetReplicationShell
+func quoteForShell(s string) string {
+	if replicationShell == ShellPowerShell {
+		return quotePowerShell(s)
+	}
+	return quoteIfNeeded(s)
+}
+
+// quoteIfNeeded quotes a string for bash when it contains spaces or characters bash would
+// interpret, such as the backslashes in a Windows path. Double quotes are used, with ", \, $ and `
+// escaped.
 func quoteIfNeeded(s string) string {
-	if strings.Contains(s, " ") {
-		return fmt.Sprintf("\"%s\"", s)
+	if !strings.ContainsAny(s, " \t\n\"'\\$`!&|;<>()*?[]{}~#") {
+		return s
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s)
+	return fmt.Sprintf("\"%s\"", escaped)
+}
+
+// quotePowerShell quotes a string for PowerShell when it contains spaces or characters PowerShell
+// would interpret. Single quotes are used, which PowerShell takes literally apart from a doubled
+// single quote, so backslashes in Windows paths need no escaping.
+func quotePowerShell(s string) string {
+	if !strings.ContainsAny(s, " \t\n\"'`$&|;<>(){}@,#") {
+		return s
 	}
-	return s
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// BuildRetryCommand builds a command that repeats an operation with the same flags but targets
+// only the organizations listed in orgListPath. --probe is dropped, as the probe organization is
+// usually not among them.
+func BuildRetryCommand(command string, flags map[string]interface{}, orgListPath string) string {
+	retryFlags := make(map[string]interface{}, len(flags))
+	for name, value := range flags {
+		retryFlags[name] = value
+	}
+	delete(retryFlags, "org")
+	delete(retryFlags, "all-orgs")
+	delete(retryFlags, "probe")
+	retryFlags["org-list"] = orgListPath
+	return BuildReplicationCommand(command, retryFlags)
+}
+
+// ShowRetryCommand displays the command that retries the organizations that failed
+func ShowRetryCommand(command string, orgCount int) {
+	pterm.Warning.Printf("To retry the %d organization(s) that failed or were not processed, use the following command:\n", orgCount)
+	pterm.Println()
+	pterm.Println(pterm.NewStyle(pterm.FgWhite).Sprint("> ") + pterm.NewStyle(pterm.FgLightYellow).Sprint(command))
+	pterm.Println()
 }
 
 // ShowReplicationCommand displays the replication command to the user
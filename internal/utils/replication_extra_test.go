@@ -1,18 +1,22 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestBuildReplicationCommand_DefaultsAreOmitted ensures that default values
-// for concurrency and delay don't end up in the replication command.
+// for concurrency, delay and report order don't end up in the replication command.
 func TestBuildReplicationCommand_DefaultsAreOmitted(t *testing.T) {
 	got := BuildReplicationCommand("generate", map[string]interface{}{
 		"enterprise-slug": "e",
 		"all-orgs":        true,
 		"concurrency":     1,
 		"delay":           0,
+		"report-order":    ReportOrderInput,
 	})
 	if strings.Contains(got, "--concurrency") {
 		t.Errorf("default concurrency should not be emitted: %s", got)
@@ -20,6 +24,9 @@ func TestBuildReplicationCommand_DefaultsAreOmitted(t *testing.T) {
 	if strings.Contains(got, "--delay") {
 		t.Errorf("default delay should not be emitted: %s", got)
 	}
+	if strings.Contains(got, "--report-order") {
+		t.Errorf("default report order should not be emitted: %s", got)
+	}
 }
 
 // TestBuildReplicationCommand_FalseBoolsOmitted ensures false bool flags are not emitted.
@@ -87,3 +94,156 @@ func TestBuildReplicationCommand_DeterministicOrder(t *testing.T) {
 		t.Errorf("flags not in expected order: %s", got)
 	}
 }
+
+// TestBuildReplicationCommand_WithAuditCopy ensures the audit copy flag is emitted only when set.
+func TestBuildReplicationCommand_WithAuditCopy(t *testing.T) {
+	got := BuildReplicationCommand("generate", map[string]interface{}{
+		"enterprise-slug": "e",
+		"all-orgs":        true,
+		"with-audit-copy": true,
+	})
+	if !strings.Contains(got, "--with-audit-copy") {
+		t.Errorf("expected --with-audit-copy in command: %s", got)
+	}
+}
+
+// TestBuildRetryCommand ensures the retry command keeps every flag but swaps the targeting for
+// the failed-org CSV.
+func TestBuildRetryCommand(t *testing.T) {
+	flags := map[string]interface{}{
+		"enterprise-slug":           "e",
+		"all-orgs":                  true,
+		"config-name":               "Baseline",
+		"scope":                     "all",
+		"skip-confirmation-message": "true",
+		"probe":                     "sandbox",
+	}
+	got := BuildRetryCommand("generate", flags, "/tmp/gh-sc-failed-1.csv")
+	want := "gh security-config generate --enterprise-slug e --org-list /tmp/gh-sc-failed-1.csv --config-name Baseline --scope all --skip-confirmation-message true"
+	if got != want {
+		t.Errorf("BuildRetryCommand() =\n  %s\nwant\n  %s", got, want)
+	}
+	if flags["all-orgs"] != true {
+		t.Error("BuildRetryCommand must not modify the caller's flags")
+	}
+
+	got = BuildRetryCommand("delete", map[string]interface{}{"org": "one-org"}, "/tmp/f.csv")
+	if strings.Contains(got, "--org ") {
+		t.Errorf("single-org targeting should be replaced: %s", got)
+	}
+}
+
+// TestBuildReplicationCommand_PerOrgTimeout ensures a per-org timeout is emitted as a duration and
+// omitted when unset.
+func TestBuildReplicationCommand_PerOrgTimeout(t *testing.T) {
+	got := BuildReplicationCommand("apply", map[string]interface{}{
+		"all-orgs":        true,
+		"per-org-timeout": 90 * time.Second,
+	})
+	if !strings.Contains(got, "--per-org-timeout 1m30s") {
+		t.Errorf("per-org timeout should be emitted as a duration: %s", got)
+	}
+
+	got = BuildReplicationCommand("apply", map[string]interface{}{
+		"all-orgs":        true,
+		"per-org-timeout": time.Duration(0),
+	})
+	if strings.Contains(got, "--per-org-timeout") {
+		t.Errorf("unset per-org timeout should not be emitted: %s", got)
+	}
+}
+
+// setReplicationShell sets the shell commands are quoted for and restores bash after the test
+func setReplicationShell(t *testing.T, shell string) {
+	t.Helper()
+	if err := SetReplicationShell(shell); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { replicationShell = ShellBash })
+}
+
+func TestResolveShell(t *testing.T) {
+	tests := []struct {
+		shell, goos, want string
+	}{
+		{shell: ShellAuto, goos: "windows", want: ShellPowerShell},
+		{shell: ShellAuto, goos: "linux", want: ShellBash},
+		{shell: "", goos: "darwin", want: ShellBash},
+		{shell: ShellBash, goos: "windows", want: ShellBash},
+		{shell: ShellPowerShell, goos: "linux", want: ShellPowerShell},
+	}
+	for _, tt := range tests {
+		if got := resolveShell(tt.shell, tt.goos); got != tt.want {
+			t.Errorf("resolveShell(%q, %q) = %q, want %q", tt.shell, tt.goos, got, tt.want)
+		}
+	}
+	if err := SetReplicationShell("cmd"); err == nil {
+		t.Error("SetReplicationShell(cmd) should be rejected")
+	}
+}
+
+// TestQuoteForShell_WindowsPaths checks that Windows paths survive both shells: bash would
+// otherwise drop the backslashes, and PowerShell must not have them escaped
+func TestQuoteForShell_WindowsPaths(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantBash       string
+		wantPowerShell string
+	}{
+		{name: "temp file", input: `C:\Users\ADMINI~1\AppData\Local\Temp\failed-orgs-123.csv`, wantBash: `"C:\\Users\\ADMINI~1\\AppData\\Local\\Temp\\failed-orgs-123.csv"`, wantPowerShell: `C:\Users\ADMINI~1\AppData\Local\Temp\failed-orgs-123.csv`},
+		{name: "path with spaces", input: `C:\Users\Jane Doe\orgs.csv`, wantBash: `"C:\\Users\\Jane Doe\\orgs.csv"`, wantPowerShell: `'C:\Users\Jane Doe\orgs.csv'`},
+		{name: "UNC path", input: `\\fileserver\share\orgs.csv`, wantBash: `"\\\\fileserver\\share\\orgs.csv"`, wantPowerShell: `\\fileserver\share\orgs.csv`},
+		{name: "forward slashes", input: "C:/Users/admin/orgs.csv", wantBash: "C:/Users/admin/orgs.csv", wantPowerShell: "C:/Users/admin/orgs.csv"},
+		{name: "unix path", input: "/tmp/gh-sc-failed-1.csv", wantBash: "/tmp/gh-sc-failed-1.csv", wantPowerShell: "/tmp/gh-sc-failed-1.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteIfNeeded(tt.input); got != tt.wantBash {
+				t.Errorf("bash quoting = %s, want %s", got, tt.wantBash)
+			}
+			if got := quotePowerShell(tt.input); got != tt.wantPowerShell {
+				t.Errorf("PowerShell quoting = %s, want %s", got, tt.wantPowerShell)
+			}
+		})
+	}
+}
+
+// TestBuildRetryCommand_ShellGolden compares a retry command for a Windows temp file, with values
+// that need quoting, against testdata/replication/<shell>.golden. Run with -update to rewrite them.
+func TestBuildRetryCommand_ShellGolden(t *testing.T) {
+	flags := map[string]interface{}{
+		"enterprise-slug":           "acme,acme-labs",
+		"all-orgs":                  true,
+		"config-name":               "Team's Baseline",
+		"config-description":        `Costs $0 & uses "defaults"`,
+		"name-suffix":               " (rollout 2)",
+		"exclude-repos":             `C:\Users\Jane Doe\exclusions.txt`,
+		"scope":                     "all",
+		"skip-confirmation-message": "true",
+	}
+	for _, shell := range []string{ShellBash, ShellPowerShell} {
+		t.Run(shell, func(t *testing.T) {
+			setReplicationShell(t, shell)
+			got := BuildRetryCommand("generate", flags, `C:\Users\ADMINI~1\AppData\Local\Temp\failed-orgs-123.csv`) + "\n"
+
+			goldenPath := filepath.Join("testdata", "replication", shell+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("BuildRetryCommand() mismatch\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
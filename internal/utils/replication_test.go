@@ -251,6 +251,20 @@ func TestBuildReplicationCommand(t *testing.T) {
 				"--new-description updated",
 			},
 		},
+		{
+			name:    "Copy with name prefix and suffix",
+			command: "generate",
+			flags: map[string]interface{}{
+				"all-orgs":      true,
+				"copy-from-org": "source-org",
+				"config-name":   "Baseline",
+				"name-prefix":   "Q3-",
+				"name-suffix":   " (rollout 2)",
+			},
+			expected: []string{
+				"--copy-from-org source-org --config-name Baseline --name-prefix Q3- --name-suffix \" (rollout 2)\"",
+			},
+		},
 		{
 			name:    "String with spaces gets quoted",
 			command: "generate",
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReadSettingsFile reads a JSON object of security settings keyed by API field name, such as
+// {"secret_scanning": "enabled"}, and validates every key and value
+func ReadSettingsFile(path string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var values map[string]string
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file %s: %w", path, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("settings file %s has no settings", path)
+	}
+
+	settings := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		settings[key] = value
+	}
+	if err := ValidateSettings(settings); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return settings, nil
+}
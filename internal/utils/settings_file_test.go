@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSettingsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "valid settings",
+			content: `{"secret_scanning": "enabled", "enforcement": "enforced"}`,
+			want:    map[string]interface{}{"secret_scanning": "enabled", "enforcement": "enforced"},
+		},
+		{name: "empty object", content: `{}`, wantErr: "has no settings"},
+		{name: "unknown key", content: `{"code_scanning": "enabled"}`, wantErr: `unknown setting "code_scanning"`},
+		{name: "invalid value", content: `{"enforcement": "on"}`, wantErr: `enforcement has invalid value "on"`},
+		{name: "non-string value", content: `{"secret_scanning": true}`, wantErr: "failed to parse"},
+		{name: "malformed", content: `{"secret_scanning": `, wantErr: "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadSettingsFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("got[%q] = %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestReadSettingsFile_Missing(t *testing.T) {
+	if _, err := ReadSettingsFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
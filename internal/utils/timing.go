@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// FormatElapsed renders a run duration for humans, e.g. "12m34s". Durations under a second keep
+// millisecond precision so very short runs do not read as "0s".
+func FormatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// PrintElapsedTime prints the wall-clock time since start, shown after the completion header
+func PrintElapsedTime(start time.Time) {
+	pterm.Info.Printf("Completed in %s\n", FormatElapsed(time.Since(start)))
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{"sub-second keeps milliseconds", 420*time.Millisecond + 300*time.Microsecond, "420ms"},
+		{"seconds", 5*time.Second + 400*time.Millisecond, "5s"},
+		{"minutes and seconds", 12*time.Minute + 34*time.Second + 200*time.Millisecond, "12m34s"},
+		{"rounds up", 59*time.Second + 600*time.Millisecond, "1m0s"},
+		{"hours", 1*time.Hour + 2*time.Minute + 3*time.Second, "1h2m3s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatElapsed(tt.d); got != tt.want {
+				t.Errorf("FormatElapsed(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
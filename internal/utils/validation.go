@@ -2,7 +2,12 @@ package utils
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 // ValidateEnumValue validates that a value is one of the allowed options.
@@ -20,6 +25,143 @@ func ValidateEnumValue(flagName, value string, allowed []string) error {
 	return fmt.Errorf("invalid value for --%s: %q (must be one of: %s)", flagName, value, strings.Join(allowed, ", "))
 }
 
+// ValidateSettings checks a complete settings map before any organization is processed, so a bad
+// value is reported once instead of being rejected by the API for every organization. Keys must be
+// managed security settings and values one of the values the API accepts for them. Surrounding
+// whitespace is trimmed from values in place, and empty or whitespace-only values, as a malformed
+// file can produce, are rejected. A nil value means the setting is absent and is accepted.
+func ValidateSettings(settings map[string]interface{}) error {
+	var problems []string
+	// Keys are sorted so errors are reported deterministically
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		value := settings[key]
+		if value == nil {
+			continue
+		}
+		setting, known := types.LookupSecuritySetting(key)
+		if !known {
+			problems = append(problems, fmt.Sprintf("unknown setting %q", key))
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a string, got %v", key, value))
+			continue
+		}
+		str = strings.TrimSpace(str)
+		if str == "" {
+			problems = append(problems, fmt.Sprintf("%s is empty (must be one of: %s)", key, strings.Join(setting.Values, ", ")))
+			continue
+		}
+		settings[key] = str
+		if !slices.Contains(setting.Values, str) {
+			problems = append(problems, fmt.Sprintf("%s has invalid value %q (must be one of: %s)", key, str, strings.Join(setting.Values, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid security settings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// advancedSecurityDependents are the settings that need GitHub Advanced Security on private and
+// internal repositories
+var advancedSecurityDependents = []string{
+	"secret_scanning",
+	"secret_scanning_push_protection",
+	"secret_scanning_non_provider_patterns",
+}
+
+// SettingDependencyWarnings reports settings that are enabled although a setting they depend on is
+// disabled. Such a combination is valid for public repositories only, so GitHub may reject the
+// configuration or leave the feature off for private and internal repositories. The settings are
+// not rejected outright because a configuration meant for public repositories is legitimate.
+func SettingDependencyWarnings(settings map[string]interface{}) []string {
+	if types.SettingValue(settings, "advanced_security") != "disabled" {
+		return nil
+	}
+	var warnings []string
+	for _, key := range advancedSecurityDependents {
+		if types.SettingValue(settings, key) == "enabled" {
+			warnings = append(warnings, fmt.Sprintf("%s is enabled but advanced_security is disabled; on private and internal repositories %s requires GitHub Advanced Security, so GitHub may reject the configuration or leave it off there", key, key))
+		}
+	}
+	return warnings
+}
+
+// ParseSettingFlags parses --settings values, each a comma-separated list of key=value pairs, into
+// a map keyed by API field name. --setting is an alias, so its values arrive here too. Each key
+// must be a managed security setting and each value one the API accepts for it; repeating a key
+// is allowed only when the value is the same. Errors give the position of the offending pair
+// across all values, counting from 1, and suggest the closest key or value when one looks
+// misspelled.
+func ParseSettingFlags(values []string) (map[string]string, error) {
+	settings := make(map[string]string)
+	position := 0
+	for _, list := range values {
+		for _, raw := range strings.Split(list, ",") {
+			position++
+			pair := strings.TrimSpace(raw)
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+			if !ok || key == "" || value == "" {
+				return nil, fmt.Errorf("--settings[%d]: '%s' is not key=value", position, pair)
+			}
+			setting, known := types.LookupSecuritySetting(key)
+			if !known {
+				return nil, fmt.Errorf("--settings[%d]: unknown key '%s', %s", position, key, suggestion(key, types.KnownSettingKeys()))
+			}
+			if !slices.Contains(setting.Values, value) {
+				return nil, fmt.Errorf("--settings[%d]: invalid value '%s' for %s, %s", position, value, key, suggestion(value, setting.Values))
+			}
+			if previous, seen := settings[key]; seen && previous != value {
+				return nil, fmt.Errorf("--settings[%d]: %s is already set to '%s'", position, key, previous)
+			}
+			settings[key] = value
+		}
+	}
+	return settings, nil
+}
+
+// suggestion returns "did you mean '<candidate>'?" for the candidate closest to word, or the list
+// of candidates when none is close enough to be a likely misspelling
+func suggestion(word string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := editDistance(word, candidate)
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if bestDistance >= 0 && bestDistance <= max(2, utf8.RuneCountInString(word)/4) {
+		return fmt.Sprintf("did you mean '%s'?", best)
+	}
+	return fmt.Sprintf("must be one of: %s", strings.Join(candidates, ", "))
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
 // ParseBoolStringFlag converts a string flag value ("true"/"false"/"") to a *bool.
 // An empty string returns nil (meaning "not provided"). Any other value returns an error.
 func ParseBoolStringFlag(flagName, value string) (*bool, error) {
@@ -38,6 +180,44 @@ func ParseBoolStringFlag(flagName, value string) (*bool, error) {
 	}
 }
 
+// MaxConfigNameLength is the longest security configuration name the API accepts
+const MaxConfigNameLength = 100
+
+// ApplyNameAffixes returns name with prefix and suffix added, as given by --name-prefix and
+// --name-suffix. The result must still fit within MaxConfigNameLength characters.
+func ApplyNameAffixes(name, prefix, suffix string) (string, error) {
+	final := prefix + name + suffix
+	if length := utf8.RuneCountInString(final); length > MaxConfigNameLength {
+		return "", fmt.Errorf("configuration name %q is %d characters after adding --name-prefix/--name-suffix, over the %d-character limit", final, length, MaxConfigNameLength)
+	}
+	return final, nil
+}
+
+// ParsePropertyFilters parses --exclude-with-property values, each a key=value pair naming an
+// organization custom property and the value that excludes an organization
+func ParsePropertyFilters(values []string) ([]types.PropertyFilter, error) {
+	var filters []types.PropertyFilter
+	for _, value := range values {
+		name, propertyValue, ok := strings.Cut(value, "=")
+		name = strings.TrimSpace(name)
+		propertyValue = strings.TrimSpace(propertyValue)
+		if !ok || name == "" || propertyValue == "" {
+			return nil, fmt.Errorf("invalid --exclude-with-property %q: expected key=value", value)
+		}
+		filters = append(filters, types.PropertyFilter{Name: name, Value: propertyValue})
+	}
+	return filters, nil
+}
+
+// FormatPropertyFilters joins filters as --exclude-with-property takes them, for replication commands
+func FormatPropertyFilters(filters []types.PropertyFilter) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		parts[i] = filter.String()
+	}
+	return strings.Join(parts, ",")
+}
+
 // ValidateConcurrency validates the concurrency flag value
 func ValidateConcurrency(concurrency int) error {
 	if concurrency < 1 || concurrency > 20 {
@@ -54,6 +234,14 @@ func ValidateDelay(delay int) error {
 	return nil
 }
 
+// ValidateMaxOrgsPerMinute validates the max-orgs-per-minute flag value; 0 disables the cap
+func ValidateMaxOrgsPerMinute(perMinute int) error {
+	if perMinute < 0 || perMinute > 6000 {
+		return fmt.Errorf("max-orgs-per-minute must be 0 (no limit) or between 1 and 6000, got %d", perMinute)
+	}
+	return nil
+}
+
 // ValidateConcurrencyAndDelay validates that concurrency and delay are mutually exclusive
 func ValidateConcurrencyAndDelay(concurrency, delay int) error {
 	// If concurrency is not default (1) and delay is specified, that's an error
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"maps"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/callmegreg/gh-security-config/internal/types"
 )
 
 func TestValidateEnumValue(t *testing.T) {
@@ -82,6 +86,161 @@ func TestParseBoolStringFlag(t *testing.T) {
 	}
 }
 
+func TestValidateSettings_AcceptsEveryAllowedValue(t *testing.T) {
+	for _, setting := range types.SecuritySettings {
+		for _, value := range setting.Values {
+			if err := ValidateSettings(map[string]interface{}{setting.Key: value}); err != nil {
+				t.Errorf("ValidateSettings(%s=%s) = %v, want nil", setting.Key, value, err)
+			}
+		}
+	}
+}
+
+func TestValidateSettings_RejectsEmptyValues(t *testing.T) {
+	for _, setting := range types.SecuritySettings {
+		for _, value := range []string{"", "   ", "\t\n"} {
+			err := ValidateSettings(map[string]interface{}{setting.Key: value})
+			want := setting.Key + " is empty"
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("ValidateSettings(%s=%q) = %v, want error containing %q", setting.Key, value, err, want)
+			}
+		}
+	}
+}
+
+func TestValidateSettings_TrimsValues(t *testing.T) {
+	settings := map[string]interface{}{"secret_scanning": "  disabled ", "enforcement": "\tenforced"}
+	if err := ValidateSettings(settings); err != nil {
+		t.Fatalf("ValidateSettings() error = %v", err)
+	}
+	if settings["secret_scanning"] != "disabled" || settings["enforcement"] != "enforced" {
+		t.Errorf("settings after ValidateSettings() = %v, want trimmed values", settings)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		wantErr  []string
+	}{
+		{name: "nil map", settings: nil},
+		{name: "empty map", settings: map[string]interface{}{}},
+		{name: "complete valid settings", settings: map[string]interface{}{
+			"advanced_security":                     "enabled",
+			"dependabot_alerts":                     "not_set",
+			"dependabot_security_updates":           "disabled",
+			"secret_scanning":                       "enabled",
+			"secret_scanning_push_protection":       "enabled",
+			"secret_scanning_non_provider_patterns": "not_set",
+			"enforcement":                           "unenforced",
+		}},
+		{name: "nil value is absent", settings: map[string]interface{}{"secret_scanning": nil}},
+		{name: "not_set where unsupported", settings: map[string]interface{}{"advanced_security": "not_set"}, wantErr: []string{`advanced_security has invalid value "not_set"`}},
+		{name: "wrong case", settings: map[string]interface{}{"enforcement": "Enforced"}, wantErr: []string{`enforcement has invalid value "Enforced"`}},
+		{name: "empty string", settings: map[string]interface{}{"secret_scanning": ""}, wantErr: []string{"secret_scanning is empty (must be one of: enabled, disabled, not_set)"}},
+		{name: "whitespace only", settings: map[string]interface{}{"enforcement": " \t"}, wantErr: []string{"enforcement is empty"}},
+		{name: "surrounding whitespace is trimmed", settings: map[string]interface{}{"advanced_security": " enabled\n"}},
+		{name: "non-string value", settings: map[string]interface{}{"secret_scanning": true}, wantErr: []string{"secret_scanning must be a string"}},
+		{name: "unknown key", settings: map[string]interface{}{"code_scanning": "enabled"}, wantErr: []string{`unknown setting "code_scanning"`}},
+		{name: "every problem reported", settings: map[string]interface{}{"enforcement": "on", "advanced_security": "maybe"}, wantErr: []string{
+			`advanced_security has invalid value "maybe"`,
+			`enforcement has invalid value "on"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSettings(tt.settings)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSettingFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr string
+	}{
+		{"none", nil, map[string]string{}, ""},
+		{"one pair per value", []string{"advanced_security=enabled", "enforcement=enforced"}, map[string]string{"advanced_security": "enabled", "enforcement": "enforced"}, ""},
+		{"one list", []string{"advanced_security=enabled,enforcement=enforced"}, map[string]string{"advanced_security": "enabled", "enforcement": "enforced"}, ""},
+		{"repeated lists", []string{"advanced_security=enabled", " secret_scanning = not_set , enforcement=enforced"}, map[string]string{"advanced_security": "enabled", "secret_scanning": "not_set", "enforcement": "enforced"}, ""},
+		{"same key repeated with same value", []string{"enforcement=enforced,enforcement=enforced"}, map[string]string{"enforcement": "enforced"}, ""},
+		{"misspelled key", []string{"advanced_security=enabled,secret_scaning=enabled"}, nil, "--settings[2]: unknown key 'secret_scaning', did you mean 'secret_scanning'?"},
+		{"unrelated key", []string{"code_scanning=enabled"}, nil, "--settings[1]: unknown key 'code_scanning', must be one of: advanced_security"},
+		{"misspelled value", []string{"secret_scanning=enabeld"}, nil, "--settings[1]: invalid value 'enabeld' for secret_scanning, did you mean 'enabled'?"},
+		{"value of another setting", []string{"advanced_security=not_set"}, nil, "--settings[1]: invalid value 'not_set' for advanced_security, must be one of: enabled, disabled"},
+		{"position counts across values", []string{"advanced_security=enabled,secret_scanning=enabled", "enforcement"}, nil, "--settings[3]: 'enforcement' is not key=value"},
+		{"empty value", []string{"advanced_security="}, nil, "--settings[1]: 'advanced_security=' is not key=value"},
+		{"empty entry", []string{"advanced_security=enabled,"}, nil, "--settings[2]: '' is not key=value"},
+		{"conflicting repeat", []string{"enforcement=enforced", "enforcement=unenforced"}, nil, "--settings[2]: enforcement is already set to 'enforced'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSettingFlags(tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSettingFlags(%v) error = %v, want containing %q", tt.values, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePropertyFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []types.PropertyFilter
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"pairs", []string{"managed=external", " tier = gold "}, []types.PropertyFilter{{Name: "managed", Value: "external"}, {Name: "tier", Value: "gold"}}, false},
+		{"value containing equals", []string{"note=a=b"}, []types.PropertyFilter{{Name: "note", Value: "a=b"}}, false},
+		{"missing equals", []string{"managed"}, nil, true},
+		{"missing name", []string{"=external"}, nil, true},
+		{"missing value", []string{"managed="}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePropertyFilters(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePropertyFilters(%q) error = %v, wantErr %v", tt.values, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParsePropertyFilters(%q) = %v, want %v", tt.values, got, tt.want)
+			}
+			if !tt.wantErr && FormatPropertyFilters(got) != FormatPropertyFilters(tt.want) {
+				t.Errorf("FormatPropertyFilters() = %q", FormatPropertyFilters(got))
+			}
+		})
+	}
+}
+
 func TestValidateConcurrency(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -129,6 +288,57 @@ func TestValidateDelay(t *testing.T) {
 	}
 }
 
+func TestValidateMaxOrgsPerMinute(t *testing.T) {
+	tests := []struct {
+		name    string
+		val     int
+		wantErr bool
+	}{
+		{"negative invalid", -1, true},
+		{"zero disables the cap", 0, false},
+		{"middle valid", 30, false},
+		{"max valid", 6000, false},
+		{"over max invalid", 6001, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaxOrgsPerMinute(tt.val)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMaxOrgsPerMinute(%d) error = %v, wantErr %v", tt.val, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyNameAffixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		prefix  string
+		suffix  string
+		want    string
+		wantErr bool
+	}{
+		{name: "no affixes keeps the name", base: "Baseline", want: "Baseline"},
+		{name: "prefix and suffix", base: "Baseline", prefix: "Q3-", suffix: " (rollout 2)", want: "Q3-Baseline (rollout 2)"},
+		{name: "exactly at the limit", base: strings.Repeat("a", MaxConfigNameLength-1), suffix: "é", want: strings.Repeat("a", MaxConfigNameLength-1) + "é"},
+		{name: "over the limit", base: strings.Repeat("a", MaxConfigNameLength), prefix: "Q3-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyNameAffixes(tt.base, tt.prefix, tt.suffix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyNameAffixes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyNameAffixes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateConcurrencyAndDelay(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -151,3 +361,35 @@ func TestValidateConcurrencyAndDelay(t *testing.T) {
 		})
 	}
 }
+
+func TestSettingDependencyWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     []string
+	}{
+		{name: "advanced security enabled", settings: map[string]interface{}{"advanced_security": "enabled", "secret_scanning": "enabled"}},
+		{name: "advanced security absent", settings: map[string]interface{}{"secret_scanning_push_protection": "enabled"}},
+		{name: "dependents disabled", settings: map[string]interface{}{"advanced_security": "disabled", "secret_scanning": "disabled", "secret_scanning_push_protection": "not_set"}},
+		{name: "dependents enabled", settings: map[string]interface{}{
+			"advanced_security":                     "disabled",
+			"secret_scanning":                       "enabled",
+			"secret_scanning_push_protection":       "enabled",
+			"secret_scanning_non_provider_patterns": "disabled",
+			"dependabot_alerts":                     "enabled",
+		}, want: []string{"secret_scanning is enabled", "secret_scanning_push_protection is enabled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SettingDependencyWarnings(tt.settings)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SettingDependencyWarnings() = %v, want %d warnings", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) || !strings.Contains(got[i], "requires GitHub Advanced Security") {
+					t.Errorf("warning %d = %q, want it to start with %q and explain the dependency", i, got[i], want)
+				}
+			}
+		})
+	}
+}